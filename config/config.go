@@ -5,7 +5,9 @@ import (
 	"log"
 	"math"
 	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // APP_TIMEZONE works on images without zoneinfo
 
 	"github.com/joho/godotenv"
 )
@@ -25,7 +27,13 @@ func LoadConfig(path string) IConfig {
 				}
 				return p
 			}(),
-			appUrl:  envMap["APP_URL"], // เพิ่มใหม่
+			appUrl: envMap["APP_URL"], // เพิ่มใหม่
+			siteUrl: func() string {
+				if envMap["APP_SITE_URL"] == "" {
+					return strings.TrimRight(envMap["APP_URL"], "/")
+				}
+				return strings.TrimRight(envMap["APP_SITE_URL"], "/")
+			}(),
 			name:    envMap["APP_NAME"],
 			version: envMap["APP_VERSION"],
 			readTimeout: func() time.Duration {
@@ -57,6 +65,149 @@ func LoadConfig(path string) IConfig {
 				return f
 			}(),
 			gcpbucket: envMap["APP_GCP_BUCKET"],
+			imageWidths: func() []int {
+				if envMap["APP_IMAGE_WIDTHS"] == "" {
+					return []int{320, 640, 1280, 1920}
+				}
+				widths := make([]int, 0)
+				for _, v := range strings.Split(envMap["APP_IMAGE_WIDTHS"], ",") {
+					w, err := strconv.Atoi(strings.TrimSpace(v))
+					if err != nil {
+						log.Fatalf("load image widths failed: %v", err)
+					}
+					widths = append(widths, w)
+				}
+				return widths
+			}(),
+			requireApiKey: envMap["APP_REQUIRE_API_KEY"] == "true",
+			proxyHeader:   envMap["APP_PROXY_HEADER"],
+			trustedProxies: func() []string {
+				proxies := make([]string, 0)
+				for _, v := range strings.Split(envMap["APP_TRUSTED_PROXIES"], ",") {
+					if v = strings.TrimSpace(v); v != "" {
+						proxies = append(proxies, v)
+					}
+				}
+				if envMap["APP_PROXY_HEADER"] != "" && len(proxies) == 0 {
+					log.Fatalf("load trusted proxies failed: APP_TRUSTED_PROXIES is required with APP_PROXY_HEADER")
+				}
+				return proxies
+			}(),
+			leadSlaHours: func() int {
+				if envMap["APP_LEAD_SLA_HOURS"] == "" {
+					return 24
+				}
+				h, err := strconv.Atoi(envMap["APP_LEAD_SLA_HOURS"])
+				if err != nil {
+					log.Fatalf("load lead sla hours failed: %v", err)
+				}
+				return h
+			}(),
+			leadRateLimit: func() int {
+				if envMap["APP_LEAD_RATE_LIMIT"] == "" {
+					return 5
+				}
+				l, err := strconv.Atoi(envMap["APP_LEAD_RATE_LIMIT"])
+				if err != nil {
+					log.Fatalf("load lead rate limit failed: %v", err)
+				}
+				return l
+			}(),
+			timezone: func() *time.Location {
+				name := envMap["APP_TIMEZONE"]
+				if name == "" {
+					name = "Asia/Bangkok"
+				}
+				loc, err := time.LoadLocation(name)
+				if err != nil {
+					log.Fatalf("load timezone failed: %v", err)
+				}
+				return loc
+			}(),
+			reservationHoldMinutes: func() int {
+				if envMap["APP_RESERVATION_HOLD_MINUTES"] == "" {
+					return 15
+				}
+				m, err := strconv.Atoi(envMap["APP_RESERVATION_HOLD_MINUTES"])
+				if err != nil {
+					log.Fatalf("load reservation hold minutes failed: %v", err)
+				}
+				return m
+			}(),
+			reservationDeposit: func() float64 {
+				if envMap["APP_RESERVATION_DEPOSIT"] == "" {
+					return 10000
+				}
+				d, err := strconv.ParseFloat(envMap["APP_RESERVATION_DEPOSIT"], 64)
+				if err != nil {
+					log.Fatalf("load reservation deposit failed: %v", err)
+				}
+				return d
+			}(),
+			paymentSecret:  envMap["APP_PAYMENT_SECRET"],
+			paymentGateway: envMap["APP_PAYMENT_GATEWAY"],
+			env: func() string {
+				if envMap["APP_ENV"] == "" {
+					return "production"
+				}
+				return envMap["APP_ENV"]
+			}(),
+			authRateLimit: func() int {
+				if envMap["APP_AUTH_RATE_LIMIT"] == "" {
+					return 10
+				}
+				l, err := strconv.Atoi(envMap["APP_AUTH_RATE_LIMIT"])
+				if err != nil {
+					log.Fatalf("load auth rate limit failed: %v", err)
+				}
+				return l
+			}(),
+			adminUrl: func() string {
+				if envMap["APP_ADMIN_URL"] == "" {
+					return strings.TrimRight(envMap["APP_URL"], "/")
+				}
+				return strings.TrimRight(envMap["APP_ADMIN_URL"], "/")
+			}(),
+			passwordResetMinutes: func() int {
+				if envMap["APP_PASSWORD_RESET_MINUTES"] == "" {
+					return 30
+				}
+				m, err := strconv.Atoi(envMap["APP_PASSWORD_RESET_MINUTES"])
+				if err != nil {
+					log.Fatalf("load password reset minutes failed: %v", err)
+				}
+				return m
+			}(),
+			loginMaxAttempts: func() int {
+				if envMap["APP_LOGIN_MAX_ATTEMPTS"] == "" {
+					return 5
+				}
+				n, err := strconv.Atoi(envMap["APP_LOGIN_MAX_ATTEMPTS"])
+				if err != nil {
+					log.Fatalf("load login max attempts failed: %v", err)
+				}
+				return n
+			}(),
+			loginMaxIpAttempts: func() int {
+				if envMap["APP_LOGIN_MAX_IP_ATTEMPTS"] == "" {
+					return 20
+				}
+				n, err := strconv.Atoi(envMap["APP_LOGIN_MAX_IP_ATTEMPTS"])
+				if err != nil {
+					log.Fatalf("load login max ip attempts failed: %v", err)
+				}
+				return n
+			}(),
+			loginLockMinutes: func() int {
+				if envMap["APP_LOGIN_LOCK_MINUTES"] == "" {
+					return 15
+				}
+				m, err := strconv.Atoi(envMap["APP_LOGIN_LOCK_MINUTES"])
+				if err != nil {
+					log.Fatalf("load login lock minutes failed: %v", err)
+				}
+				return m
+			}(),
 		},
 		db: &db{
 			host: envMap["DB_HOST"],
@@ -99,6 +250,47 @@ func LoadConfig(path string) IConfig {
 				return t
 			}(),
 		},
+		storage: &storage{
+			driver: func() string {
+				if envMap["STORAGE_DRIVER"] == "" {
+					return "local"
+				}
+				return envMap["STORAGE_DRIVER"]
+			}(),
+			endpoint: envMap["STORAGE_ENDPOINT"],
+			region: func() string {
+				if envMap["STORAGE_REGION"] == "" {
+					return "us-east-1"
+				}
+				return envMap["STORAGE_REGION"]
+			}(),
+			bucket: func() string {
+				if envMap["STORAGE_BUCKET"] == "" {
+					return envMap["APP_GCP_BUCKET"]
+				}
+				return envMap["STORAGE_BUCKET"]
+			}(),
+			accessKey: envMap["STORAGE_ACCESS_KEY"],
+			secretKey: envMap["STORAGE_SECRET_KEY"],
+			publicUrl: envMap["STORAGE_PUBLIC_URL"],
+			pathStyle: envMap["STORAGE_PATH_STYLE"] == "true",
+		},
+		mail: &mail{
+			host: envMap["MAIL_HOST"],
+			port: func() int {
+				if envMap["MAIL_PORT"] == "" {
+					return 587
+				}
+				p, err := strconv.Atoi(envMap["MAIL_PORT"])
+				if err != nil {
+					log.Fatalf("load mail port failed: %v", err)
+				}
+				return p
+			}(),
+			username: envMap["MAIL_USERNAME"],
+			password: envMap["MAIL_PASSWORD"],
+			from:     envMap["MAIL_FROM"],
+		},
 	}
 }
 
@@ -106,17 +298,22 @@ type IConfig interface {
 	App() IAppConfig
 	Db() IDbConfig
 	Jwt() IJwtConfig
+	Storage() IStorageConfig
+	Mail() IMailConfig
 }
 
 type config struct {
-	app *app
-	db  *db
-	jwt *jwt
+	app     *app
+	db      *db
+	jwt     *jwt
+	storage *storage
+	mail    *mail
 }
 
 type IAppConfig interface {
 	Url() string    //host:port
 	AppUrl() string // เพิ่มใหม่
+	SiteUrl() string
 	Name() string
 	Version() string
 	ReadTimeout() time.Duration
@@ -124,21 +321,63 @@ type IAppConfig interface {
 	BodyLimit() int
 	FileLimit() int
 	GCPBucket() string
+	ImageWidths() []int
+	RequireApiKey() bool
+	ProxyHeader() string
+	TrustedProxies() []string
+	LeadSlaHours() int
+	LeadRateLimit() int
+	Timezone() *time.Location
+	ReservationHoldMinutes() int
+	ReservationDeposit() float64
+	PaymentSecret() string
+	PaymentGateway() string
+	Env() string
+	AuthRateLimit() int
+	AdminUrl() string
+	PasswordResetMinutes() int
+	LoginMaxAttempts() int
+	LoginMaxIpAttempts() int
+	LoginLockMinutes() int
 	Host() string
 	Port() int
 }
 
 type app struct {
-	host         string
-	port         int
-	appUrl       string // เพิ่มใหม่
-	name         string
-	version      string
-	readTimeout  time.Duration
-	writeTimeout time.Duration
-	bodyLimit    int //bytes
-	fileLimit    int //bytes
-	gcpbucket    string
+	host          string
+	port          int
+	appUrl        string // เพิ่มใหม่
+	siteUrl       string // landing page the sitemap and structured data link to
+	name          string
+	version       string
+	readTimeout   time.Duration
+	writeTimeout  time.Duration
+	bodyLimit     int //bytes
+	fileLimit     int //bytes
+	gcpbucket     string
+	imageWidths   []int          // widths of the webp variants made on upload
+	requireApiKey bool           // public read routes need X-Api-Key
+	leadSlaHours  int            // hours a new lead may stay untouched before it is flagged
+	leadRateLimit int            // lead submissions allowed per ip per minute
+	timezone      *time.Location // local time of opening hours and bookings
+
+	// proxyHeader carries the client ip set by the load balancer, e.g. X-Real-Ip.
+	// It is only read from the trustedProxies, ips or cidrs.
+	proxyHeader    string
+	trustedProxies []string
+
+	reservationHoldMinutes int     // minutes a plot is held while the deposit is paid
+	reservationDeposit     float64 // deposit of plots without their own
+	paymentSecret          string  // signs payment gateway callbacks
+	paymentGateway         string  // empty turns online reservations off
+	env                    string  // production, development or test
+
+	authRateLimit        int    // unauthenticated auth requests allowed per ip per minute
+	adminUrl             string // back office the password reset links open
+	passwordResetMinutes int    // minutes a password reset link stays valid
+	loginMaxAttempts     int    // failed sign ins of an email before it is locked
+	loginMaxIpAttempts   int    // failed sign ins from an ip before it is locked
+	loginLockMinutes     int    // how long a lock lasts, and how long failures are remembered
 }
 
 func (c *config) App() IAppConfig {
@@ -147,6 +386,7 @@ func (c *config) App() IAppConfig {
 
 func (a *app) Url() string                 { return fmt.Sprintf("%s:%d", a.host, a.port) } // host:port
 func (a *app) AppUrl() string              { return a.appUrl }                             /// เพิ่มใหม่
+func (a *app) SiteUrl() string             { return a.siteUrl }
 func (a *app) Name() string                { return a.name }
 func (a *app) Version() string             { return a.version }
 func (a *app) ReadTimeout() time.Duration  { return a.readTimeout }
@@ -154,6 +394,24 @@ func (a *app) WriteTimeout() time.Duration { return a.writeTimeout }
 func (a *app) BodyLimit() int              { return a.bodyLimit }
 func (a *app) FileLimit() int              { return a.fileLimit }
 func (a *app) GCPBucket() string           { return a.gcpbucket }
+func (a *app) ImageWidths() []int          { return a.imageWidths }
+func (a *app) RequireApiKey() bool         { return a.requireApiKey }
+func (a *app) ProxyHeader() string         { return a.proxyHeader }
+func (a *app) TrustedProxies() []string    { return a.trustedProxies }
+func (a *app) LeadSlaHours() int           { return a.leadSlaHours }
+func (a *app) LeadRateLimit() int          { return a.leadRateLimit }
+func (a *app) Timezone() *time.Location    { return a.timezone }
+func (a *app) ReservationHoldMinutes() int { return a.reservationHoldMinutes }
+func (a *app) ReservationDeposit() float64 { return a.reservationDeposit }
+func (a *app) PaymentSecret() string       { return a.paymentSecret }
+func (a *app) PaymentGateway() string      { return a.paymentGateway }
+func (a *app) Env() string                 { return a.env }
+func (a *app) AuthRateLimit() int          { return a.authRateLimit }
+func (a *app) AdminUrl() string            { return a.adminUrl }
+func (a *app) PasswordResetMinutes() int   { return a.passwordResetMinutes }
+func (a *app) LoginMaxAttempts() int       { return a.loginMaxAttempts }
+func (a *app) LoginMaxIpAttempts() int     { return a.loginMaxIpAttempts }
+func (a *app) LoginLockMinutes() int       { return a.loginLockMinutes }
 func (a *app) Host() string                { return a.host }
 func (a *app) Port() int                   { return a.port }
 
@@ -219,3 +477,64 @@ func (j *jwt) AccessExpiresAt() int       { return j.accessExpiresAt }
 func (j *jwt) RefreshExpiresAt() int      { return j.refreshExpiresAt }
 func (j *jwt) SetJwtAccessExpires(t int)  { j.accessExpiresAt = t }
 func (j *jwt) SetJwtRefreshExpires(t int) { j.refreshExpiresAt = t }
+
+type IStorageConfig interface {
+	Driver() string // local, s3
+	Endpoint() string
+	Region() string
+	Bucket() string
+	AccessKey() string
+	SecretKey() string
+	PublicUrl() string
+	PathStyle() bool
+}
+
+type storage struct {
+	driver    string
+	endpoint  string // e.g. https://storage.googleapis.com, http://minio:9000
+	region    string
+	bucket    string // falls back to APP_GCP_BUCKET
+	accessKey string
+	secretKey string
+	publicUrl string // optional CDN or public bucket url
+	pathStyle bool
+}
+
+func (c *config) Storage() IStorageConfig {
+	return c.storage
+}
+
+func (s *storage) Driver() string    { return s.driver }
+func (s *storage) Endpoint() string  { return s.endpoint }
+func (s *storage) Region() string    { return s.region }
+func (s *storage) Bucket() string    { return s.bucket }
+func (s *storage) AccessKey() string { return s.accessKey }
+func (s *storage) SecretKey() string { return s.secretKey }
+func (s *storage) PublicUrl() string { return s.publicUrl }
+func (s *storage) PathStyle() bool   { return s.pathStyle }
+
+type IMailConfig interface {
+	Host() string // empty logs the mails instead, in development and test only
+	Port() int
+	Username() string
+	Password() string
+	From() string
+}
+
+type mail struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func (c *config) Mail() IMailConfig {
+	return c.mail
+}
+
+func (m *mail) Host() string     { return m.host }
+func (m *mail) Port() int        { return m.port }
+func (m *mail) Username() string { return m.username }
+func (m *mail) Password() string { return m.password }
+func (m *mail) From() string     { return m.from }
@@ -6,17 +6,21 @@ type Activity struct {
 	Id          int               `db:"id" json:"id"`
 	Index       int               `db:"index" json:"index"`
 	Heading     string            `db:"heading" json:"heading"`
+	Slug        string            `db:"slug" json:"slug"` // empty on create is made from the heading, on update keeps the stored one
 	Description string            `db:"description" json:"description"`
 	StartDate   string            `db:"start_date" json:"start_date"`
 	EndDate     string            `db:"end_date" json:"end_date"`
 	VideoLink   string            `db:"video_link" json:"video_link"`
 	Display     string            `db:"display" json:"display"`
+	PublishAt   *string           `db:"publish_at" json:"publish_at"`
+	UnpublishAt *string           `db:"unpublish_at" json:"unpublish_at"`
 	Images      []*entities.Image `json:"images"`
 }
 
 type ActivityFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // title & description
+	Id      string `query:"id"`
+	Search  string `query:"search"` // title & description
+	Display string `query:"display"`
 	*entities.PaginationReq
 	*entities.SortReq
 }
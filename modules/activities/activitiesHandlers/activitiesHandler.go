@@ -2,8 +2,8 @@ package activitiesHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path"
 	"strconv"
 	"strings"
 
@@ -12,7 +12,6 @@ import (
 	"github.com/yporn/sirarom-backend/modules/activities"
 	"github.com/yporn/sirarom-backend/modules/activities/activitiesUsecases"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/pkg/utils"
 )
@@ -20,19 +19,25 @@ import (
 type activitiesHandlersErrCode string
 
 const (
-	findOneActivityErr activitiesHandlersErrCode = "activities-001"
-	findActivityErr    activitiesHandlersErrCode = "activities-002"
-	insertActivityErr  activitiesHandlersErrCode = "activities-003"
-	deleteActivityErr  activitiesHandlersErrCode = "activities-004"
-	updateActivityErr  activitiesHandlersErrCode = "activities-005"
+	findOneActivityErr       activitiesHandlersErrCode = "activities-001"
+	findActivityErr          activitiesHandlersErrCode = "activities-002"
+	insertActivityErr        activitiesHandlersErrCode = "activities-003"
+	deleteActivityErr        activitiesHandlersErrCode = "activities-004"
+	updateActivityErr        activitiesHandlersErrCode = "activities-005"
+	updateActivityOrderErr   activitiesHandlersErrCode = "activities-006"
+	findOneActivityBySlugErr activitiesHandlersErrCode = "activities-007"
 )
 
 type IActivitiesHandler interface {
 	FindOneActivity(c *fiber.Ctx) error
+	FindOneActivityBySlug(c *fiber.Ctx) error
+	FindOneAdminActivity(c *fiber.Ctx) error
 	FindActivity(c *fiber.Ctx) error
+	FindAdminActivity(c *fiber.Ctx) error
 	AddActivity(c *fiber.Ctx) error
 	UpdateActivity(c *fiber.Ctx) error
 	DeleteActivity(c *fiber.Ctx) error
+	UpdateActivityOrder(c *fiber.Ctx) error
 }
 
 type activitiesHandler struct {
@@ -51,9 +56,40 @@ func ActivitiesHandler(cfg config.IConfig, activitiesUsecase activitiesUsecases.
 	}
 }
 
-func (h *activitiesHandler) FindOneActivity(c *fiber.Ctx) error {  
-	activityId := strings.Trim(c.Params("activity_id"), " ")
+func (h *activitiesHandler) FindOneActivity(c *fiber.Ctx) error {
+	return h.findOneActivity(c, strings.Trim(c.Params("activity_id"), " "), true)
+}
+
+func (h *activitiesHandler) FindOneAdminActivity(c *fiber.Ctx) error {
+	return h.findOneActivity(c, strings.Trim(c.Params("activity_id"), " "), false)
+}
+
+// FindOneActivityBySlug answers an old slug with a redirect to the current one.
+func (h *activitiesHandler) FindOneActivityBySlug(c *fiber.Ctx) error {
+	slug := strings.Trim(c.Params("slug"), " ")
+
+	activityId, current, err := h.activitiesUsecase.FindActivitySlug(utils.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, utils.ErrSlugNotFound) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(findOneActivityBySlugErr),
+				"activity not found",
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneActivityBySlugErr),
+			err.Error(),
+		).Res()
+	}
+	if current != slug {
+		return c.Redirect(utils.SlugPath(c.Path(), current), fiber.StatusMovedPermanently)
+	}
+	return h.findOneActivity(c, strconv.Itoa(activityId), true)
+}
 
+func (h *activitiesHandler) findOneActivity(c *fiber.Ctx, activityId string, public bool) error {
 	activity, err := h.activitiesUsecase.FindOneActivity(activityId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -62,10 +98,26 @@ func (h *activitiesHandler) FindOneActivity(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && activity.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneActivityErr),
+			"activity not found",
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, activity).Res()
 }
 
 func (h *activitiesHandler) FindActivity(c *fiber.Ctx) error {
+	return h.findActivity(c, true)
+}
+
+// FindAdminActivity returns rows in every display state, optionally filtered by ?display=.
+func (h *activitiesHandler) FindAdminActivity(c *fiber.Ctx) error {
+	return h.findActivity(c, false)
+}
+
+func (h *activitiesHandler) findActivity(c *fiber.Ctx, public bool) error {
 	req := &activities.ActivityFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -79,6 +131,16 @@ func (h *activitiesHandler) FindActivity(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findActivityErr),
+			err.Error(),
+		).Res()
+	}
+
 	
 	if req.Page < 1 {
 		req.Page = 1
@@ -110,8 +172,23 @@ func (h *activitiesHandler) AddActivity(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertActivityErr),
+			err.Error(),
+		).Res()
+	}
+
 	activity, err := h.activitiesUsecase.AddActivity(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(insertActivityErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(insertActivityErr),
@@ -155,10 +232,25 @@ func (h *activitiesHandler) UpdateActivity(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateActivityErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = activityId
 
 	activity, err := h.activitiesUsecase.UpdateActivity(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(updateActivityErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(updateActivityErr),
@@ -192,40 +284,75 @@ func (h *activitiesHandler) DeleteActivity(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range activity.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("activities/%s", path.Base(p.Url)),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.activitiesUsecase.DeleteActivity(activityId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteActivityErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลกิจกรรม : "+activity.Heading)
+	if err != nil {
+		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteActivityErr),
+			fmt.Sprintf("Failed to log activity %v",userID),
 			err.Error(),
 		).Res()
 	}
 
-	if err := h.activitiesUsecase.DeleteActivity(activityId); err != nil {
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UpdateActivityOrder rewrites the display order from the ids in the body, first id first.
+func (h *activitiesHandler) UpdateActivityOrder(c *fiber.Ctx) error {
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateActivityOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateActivityOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.activitiesUsecase.UpdateActivityOrder(req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateActivityOrderErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteActivityErr),
+			string(updateActivityOrderErr),
 			err.Error(),
 		).Res()
 	}
 
 	// Log activity
 	userID := utils.GetUserIDFromContext(c)
-	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลกิจกรรม : "+activity.Heading)
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลกิจกรรม")
 	if err != nil {
 		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			fmt.Sprintf("Failed to log activity %v",userID),
+			fmt.Sprintf("Failed to log activity %v", userID),
 			err.Error(),
 		).Res()
 	}
 
-	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
 }
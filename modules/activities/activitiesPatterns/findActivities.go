@@ -68,7 +68,7 @@ func (b *findActivityBuilder) initQuery() {
 				) AS "it"
 			) AS "images"
 		FROM "activities" "a"
-		WHERE 1 = 1`
+		WHERE "a"."deleted_at" IS NULL`
 }
 
 func (b *findActivityBuilder) countQuery() {
@@ -76,13 +76,21 @@ func (b *findActivityBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "activities" "a"
-		WHERE 1 = 1`
+		WHERE "a"."deleted_at" IS NULL`
 }
 
 func (b *findActivityBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "a"."display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
@@ -56,9 +56,12 @@ func (b *insertActivityBuilder) insertActivity() error {
 		"start_date",
 		"end_date",
 		"video_link",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at",
+		"slug"
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::timestamptz, NULLIF($9, '')::timestamptz, $10)
 		RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -71,6 +74,9 @@ func (b *insertActivityBuilder) insertActivity() error {
 		b.req.EndDate,
 		b.req.VideoLink,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
+		b.req.Slug,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert activity failed: %v", err)
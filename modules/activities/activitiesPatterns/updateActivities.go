@@ -85,6 +85,13 @@ func (b *updateActivityBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"heading" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.Slug != "" {
+		b.values = append(b.values, b.req.Slug)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"slug" = $%d`, b.lastStackIndex))
+	}
+
 	if b.req.Description != "" {
 		b.values = append(b.values, b.req.Description)
 		b.lastStackIndex = len(b.values)
@@ -120,6 +127,20 @@ func (b *updateActivityBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"display" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
 	b.query += strings.Join(setStatements, ", ")
 }
 
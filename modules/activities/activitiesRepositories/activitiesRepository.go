@@ -13,6 +13,7 @@ import (
 	"github.com/yporn/sirarom-backend/modules/activities/activitiesPatterns"
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type IActivitiesRepository interface {
@@ -21,6 +22,8 @@ type IActivitiesRepository interface {
 	InsertActivity(req *activities.Activity) (*activities.Activity, error)
 	DeleteActivity(activityId string) error
 	UpdateActivity(req *activities.Activity) (*activities.Activity, error)
+	FindActivitySlug(slug string) (int, string, error)
+	UpdateActivityOrder(ids []int) error
 }
 
 type activitiesRepository struct {
@@ -46,11 +49,14 @@ func (r *activitiesRepository) FindOneActivity(activityId string) (*activities.A
 			"a"."id",
 			"a"."index",
 			"a"."heading",
+			"a"."slug",
 			"a"."description",
 			"a"."start_date",
 			"a"."end_date",
 			"a"."video_link",
 			"a"."display",
+			"a"."publish_at",
+			"a"."unpublish_at",
 			"a"."created_at",
 			"a"."updated_at",
 			(
@@ -67,6 +73,7 @@ func (r *activitiesRepository) FindOneActivity(activityId string) (*activities.A
 			) AS "images"
 		FROM "activities" "a"
 		WHERE "a"."id" = $1
+		AND "a"."deleted_at" IS NULL
 		LIMIT 1
 	) AS "t";`
 
@@ -94,6 +101,12 @@ func (r *activitiesRepository) FindActivity(req *activities.ActivityFilter) ([]*
 }
 
 func (r *activitiesRepository) InsertActivity(req *activities.Activity) (*activities.Activity, error) {
+	slug, err := utils.UniqueSlug(r.db, "activities", 0, req.Heading, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Slug = slug
+
 	builder := activitiesPatterns.InsertActivityBuilder(r.db, req)
 	activityId, err := activitiesPatterns.InsertActivityEngineer(builder).InsertActivity()
 	if err != nil {
@@ -108,6 +121,15 @@ func (r *activitiesRepository) InsertActivity(req *activities.Activity) (*activi
 }
 
 func (r *activitiesRepository) UpdateActivity(req *activities.Activity) (*activities.Activity, error) {
+	// The slug only changes when one is given, so renaming keeps the links
+	if req.Slug != "" {
+		slug, err := utils.UniqueSlug(r.db, "activities", req.Id, "", req.Slug)
+		if err != nil {
+			return nil, err
+		}
+		req.Slug = slug
+	}
+
 	builder := activitiesPatterns.UpdateActivityBuilder(r.db, req, r.filesUsecase)
 	engineer := activitiesPatterns.UpdateActivityEngineer(builder)
 
@@ -123,10 +145,18 @@ func (r *activitiesRepository) UpdateActivity(req *activities.Activity) (*activi
 }
 
 func (r *activitiesRepository) DeleteActivity(activityId string) error {
-	query := `DELETE FROM "activities" WHERE "id" = $1;`
+	query := `UPDATE "activities" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, activityId); err != nil {
 		return fmt.Errorf("delete activity failed: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *activitiesRepository) UpdateActivityOrder(ids []int) error {
+	return utils.Reorder(r.db, "activities", ids, "", nil)
+}
+
+func (r *activitiesRepository) FindActivitySlug(slug string) (int, string, error) {
+	return utils.FindSlug(r.db, "activities", slug)
+}
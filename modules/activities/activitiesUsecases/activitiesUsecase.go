@@ -13,7 +13,9 @@ type IActivitiesUsecase interface {
 	FindActivity(req *activities.ActivityFilter) *entities.PaginateRes
 	AddActivity(req *activities.Activity) (*activities.Activity, error)
 	UpdateActivity(req *activities.Activity) (*activities.Activity, error)
+	FindActivitySlug(slug string) (int, string, error)
 	DeleteActivity(activityId string) error
+	UpdateActivityOrder(ids []int) error
 }
 
 type activitiesUsecase struct {
@@ -68,3 +70,13 @@ func (u *activitiesUsecase) DeleteActivity(activityId string) error {
 	}
 	return nil
 }
+
+func (u *activitiesUsecase) UpdateActivityOrder(ids []int) error {
+	return u.activitiesRepository.UpdateActivityOrder(ids)
+}
+
+// FindActivitySlug returns the id and current slug of the activity with the slug
+// or with an old slug of it.
+func (u *activitiesUsecase) FindActivitySlug(slug string) (int, string, error) {
+	return u.activitiesRepository.FindActivitySlug(slug)
+}
@@ -25,8 +25,8 @@ func (r *activityLogsRepository) FindActivityLog() ([]activityLogs.ActivityLog,
     query := `
         SELECT 
 		"a"."id",
-		"a"."user_id",
-		"u"."name",
+		COALESCE("a"."user_id", 0),
+		COALESCE("u"."name", 'ระบบ'),
 		"a"."action",
 		"a"."details",
         "a"."created_at"
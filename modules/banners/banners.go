@@ -3,16 +3,19 @@ package banners
 import "github.com/yporn/sirarom-backend/modules/entities"
 
 type Banner struct {
-	Id      int               `db:"id" json:"id"`
-	Index   int               `db:"index" json:"index"`
-	Delay   int               `db:"delay" json:"delay"`
-	Display string            `db:"display" json:"display"`
-	Images  []*entities.Image `json:"images"`
+	Id          int               `db:"id" json:"id"`
+	Index       int               `db:"index" json:"index"`
+	Delay       int               `db:"delay" json:"delay"`
+	Display     string            `db:"display" json:"display"`
+	PublishAt   *string           `db:"publish_at" json:"publish_at"`
+	UnpublishAt *string           `db:"unpublish_at" json:"unpublish_at"`
+	Images      []*entities.Image `json:"images"`
 }
 
 type BannerFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // title & description
+	Id      string `query:"id"`
+	Search  string `query:"search"` // title & description
+	Display string `query:"display"`
 	*entities.PaginationReq
 	*entities.SortReq
-}
\ No newline at end of file
+}
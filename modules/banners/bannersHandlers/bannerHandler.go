@@ -2,6 +2,7 @@ package bannersHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -11,7 +12,6 @@ import (
 	"github.com/yporn/sirarom-backend/modules/banners"
 	"github.com/yporn/sirarom-backend/modules/banners/bannersUsecases"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/pkg/utils"
 )
@@ -19,19 +19,23 @@ import (
 type bannersHandlersErrCode string
 
 const (
-	findOneBannerErr bannersHandlersErrCode = "banners-001"
-	findBannerErr    bannersHandlersErrCode = "banners-002"
-	insertBannerErr  bannersHandlersErrCode = "banners-003"
-	deleteBannerErr  bannersHandlersErrCode = "banners-004"
-	updateBannerErr  bannersHandlersErrCode = "banners-005"
+	findOneBannerErr     bannersHandlersErrCode = "banners-001"
+	findBannerErr        bannersHandlersErrCode = "banners-002"
+	insertBannerErr      bannersHandlersErrCode = "banners-003"
+	deleteBannerErr      bannersHandlersErrCode = "banners-004"
+	updateBannerErr      bannersHandlersErrCode = "banners-005"
+	updateBannerOrderErr bannersHandlersErrCode = "banners-006"
 )
 
 type IBannersHandler interface {
 	FindOneBanner(c *fiber.Ctx) error
+	FindOneAdminBanner(c *fiber.Ctx) error
 	FindBanner(c *fiber.Ctx) error
+	FindAdminBanner(c *fiber.Ctx) error
 	AddBanner(c *fiber.Ctx) error
 	UpdateBanner(c *fiber.Ctx) error
 	DeleteBanner(c *fiber.Ctx) error
+	UpdateBannerOrder(c *fiber.Ctx) error
 }
 
 type bannersHandler struct {
@@ -51,6 +55,14 @@ func BannersHandler(cfg config.IConfig, bannersUsecase bannersUsecases.IBannersU
 }
 
 func (h *bannersHandler) FindOneBanner(c *fiber.Ctx) error {
+	return h.findOneBanner(c, true)
+}
+
+func (h *bannersHandler) FindOneAdminBanner(c *fiber.Ctx) error {
+	return h.findOneBanner(c, false)
+}
+
+func (h *bannersHandler) findOneBanner(c *fiber.Ctx, public bool) error {
 	bannerId := strings.Trim(c.Params("banner_id"), " ")
 
 	banner, err := h.bannersUsecase.FindOneBanner(bannerId)
@@ -61,10 +73,26 @@ func (h *bannersHandler) FindOneBanner(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && banner.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneBannerErr),
+			"banner not found",
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, banner).Res()
 }
 
 func (h *bannersHandler) FindBanner(c *fiber.Ctx) error {
+	return h.findBanner(c, true)
+}
+
+// FindAdminBanner returns rows in every display state, optionally filtered by ?display=.
+func (h *bannersHandler) FindAdminBanner(c *fiber.Ctx) error {
+	return h.findBanner(c, false)
+}
+
+func (h *bannersHandler) findBanner(c *fiber.Ctx, public bool) error {
 	req := &banners.BannerFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -78,6 +106,16 @@ func (h *bannersHandler) FindBanner(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findBannerErr),
+			err.Error(),
+		).Res()
+	}
+
 	if req.Page < 1 {
 		req.Page = 1
 	}
@@ -108,6 +146,14 @@ func (h *bannersHandler) AddBanner(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertBannerErr),
+			err.Error(),
+		).Res()
+	}
+
 	banner, err := h.bannersUsecase.AddBanner(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -154,6 +200,14 @@ func (h *bannersHandler) UpdateBanner(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateBannerErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = bannerId
 
 	banner, err := h.bannersUsecase.UpdateBanner(req)
@@ -183,7 +237,7 @@ func (h *bannersHandler) UpdateBanner(c *fiber.Ctx) error {
 func (h *bannersHandler) DeleteBanner(c *fiber.Ctx) error {
 	bannerId := strings.Trim(c.Params("banner_id"), " ")
 
-	banner, err := h.bannersUsecase.FindOneBanner(bannerId)
+	_, err := h.bannersUsecase.FindOneBanner(bannerId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
@@ -192,32 +246,67 @@ func (h *bannersHandler) DeleteBanner(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range banner.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("banner/%s", p.FileName),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.bannersUsecase.DeleteBanner(bannerId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteBannerErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบนเนอร์")
+	if err != nil {
+		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteBannerErr),
+			fmt.Sprintf("Failed to log activity %v", userID),
 			err.Error(),
 		).Res()
 	}
 
-	if err := h.bannersUsecase.DeleteBanner(bannerId); err != nil {
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UpdateBannerOrder rewrites the display order from the ids in the body, first id first.
+func (h *bannersHandler) UpdateBannerOrder(c *fiber.Ctx) error {
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateBannerOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateBannerOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.bannersUsecase.UpdateBannerOrder(req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateBannerOrderErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteBannerErr),
+			string(updateBannerOrderErr),
 			err.Error(),
 		).Res()
 	}
 
 	// Log activity
 	userID := utils.GetUserIDFromContext(c)
-	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบนเนอร์")
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลแบนเนอร์")
 	if err != nil {
 		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
@@ -227,5 +316,5 @@ func (h *bannersHandler) DeleteBanner(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
 }
@@ -57,6 +57,8 @@ func (b *findBannerBuilder) initQuery() {
 			"b"."index",
 			"b"."delay",
 			"b"."display",
+			"b"."publish_at",
+			"b"."unpublish_at",
 			"b"."created_at",
 			"b"."updated_at",
 			(
@@ -66,13 +68,14 @@ func (b *findBannerBuilder) initQuery() {
 					SELECT
 						"i"."id",
 						"i"."filename",
-						"i"."url"
+						"i"."url",
+						"i"."srcset"
 					FROM "banner_images" "i"
 					WHERE "i"."banner_id" = "b"."id"
 				) AS "it"
 			) AS "images"
 		FROM "banners" "b"
-		WHERE 1 = 1`
+		WHERE "b"."deleted_at" IS NULL`
 }
 
 func (b *findBannerBuilder) countQuery() {
@@ -80,13 +83,21 @@ func (b *findBannerBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "banners" "b"
-		WHERE 1 = 1`
+		WHERE "b"."deleted_at" IS NULL`
 }
 
 func (b *findBannerBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "b"."display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
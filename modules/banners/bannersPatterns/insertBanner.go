@@ -52,9 +52,11 @@ func (b *insertBannerBuilder) insertBanner() error {
 	INSERT INTO "banners" (
 		"index",
 		"delay",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at"
 	)
-	VALUES ($1, $2, $3)
+	VALUES ($1, $2, $3, NULLIF($4, '')::timestamptz, NULLIF($5, '')::timestamptz)
 		RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -63,6 +65,8 @@ func (b *insertBannerBuilder) insertBanner() error {
 		b.req.Index,
 		b.req.Delay,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert banner failed: %v", err)
@@ -78,6 +82,7 @@ func (b *insertBannerBuilder) insertAttachment() error {
 	INSERT INTO "banner_images" (
 		"filename",
 		"url",
+		"srcset",
 		"banner_id"
 	)
 	VALUES`
@@ -88,17 +93,18 @@ func (b *insertBannerBuilder) insertAttachment() error {
 		valueStack = append(valueStack,
 			b.req.Images[i].FileName,
 			b.req.Images[i].Url,
+			b.req.Images[i].SrcSet,
 			b.req.Id,
 		)
 
 		if i != len(b.req.Images)-1 {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d),`, index+1, index+2, index+3, index+4)
 		} else {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d);`, index+1, index+2, index+3, index+4)
 		}
-		index += 3
+		index += 4
 	}
 
 	if _, err := b.tx.ExecContext(
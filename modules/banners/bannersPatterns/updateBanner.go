@@ -91,6 +91,20 @@ func (b *updateBannerBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"display" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
 	b.query += strings.Join(setStatements, ", ")
 }
 
@@ -99,6 +113,7 @@ func (b *updateBannerBuilder) insertImages() error {
 	INSERT INTO "banner_images" (
 		"filename",
 		"url",
+		"srcset",
 		"banner_id"
 	)
 	VALUES`
@@ -109,17 +124,18 @@ func (b *updateBannerBuilder) insertImages() error {
 		valueStack = append(valueStack,
 			b.req.Images[i].FileName,
 			b.req.Images[i].Url,
+			b.req.Images[i].SrcSet,
 			b.req.Id,
 		)
 
 		if i != len(b.req.Images)-1 {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d),`, index+1, index+2, index+3, index+4)
 		} else {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d);`, index+1, index+2, index+3, index+4)
 		}
-		index += 3
+		index += 4
 	}
 
 	if _, err := b.tx.ExecContext(
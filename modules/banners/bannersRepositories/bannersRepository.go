@@ -12,6 +12,7 @@ import (
 	"github.com/yporn/sirarom-backend/modules/banners/bannersPatterns"
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type IBannersRepository interface {
@@ -20,6 +21,7 @@ type IBannersRepository interface {
 	InsertBanner(req *banners.Banner) (*banners.Banner, error)
 	UpdateBanner(req *banners.Banner) (*banners.Banner, error) 
 	DeleteBanner(bannerId string) error
+	UpdateBannerOrder(ids []int) error
 }
 
 type bannersRepository struct {
@@ -46,6 +48,8 @@ func (r *bannersRepository) FindOneBanner(bannerId string) (*banners.Banner, err
 				"b"."index",
 				"b"."delay",
 				"b"."display",
+				"b"."publish_at",
+				"b"."unpublish_at",
 				(
 					SELECT
 						COALESCE(array_to_json(array_agg("it")), '[]'::json)
@@ -53,13 +57,15 @@ func (r *bannersRepository) FindOneBanner(bannerId string) (*banners.Banner, err
 						SELECT
 							"i"."id",
 							"i"."filename",
-							"i"."url"
+							"i"."url",
+							"i"."srcset"
 						FROM "banner_images" "i"
 						WHERE "i"."banner_id" = "b"."id"
 					) AS "it"
 				) AS "images"
 				FROM "banners" "b"
 		WHERE "b"."id" = $1
+		AND "b"."deleted_at" IS NULL
 		LIMIT 1
 		) AS "t";
 	`
@@ -117,10 +123,14 @@ func (r *bannersRepository) UpdateBanner(req *banners.Banner) (*banners.Banner,
 }
 
 func (r *bannersRepository) DeleteBanner(bannerId string) error {
-	query := `DELETE FROM "banners" WHERE "id" = $1;`
+	query := `UPDATE "banners" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, bannerId); err != nil {
 		return fmt.Errorf("delete banner failed: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *bannersRepository) UpdateBannerOrder(ids []int) error {
+	return utils.Reorder(r.db, "banners", ids, "", nil)
+}
@@ -14,6 +14,7 @@ type IBannersUsecase interface {
 	AddBanner(req *banners.Banner) (*banners.Banner, error)
 	UpdateBanner(req *banners.Banner) (*banners.Banner, error)
 	DeleteBanner(bannerId string) error
+	UpdateBannerOrder(ids []int) error
 }
 
 type bannersUsecase struct {
@@ -69,3 +70,7 @@ func (u *bannersUsecase) DeleteBanner(bannerId string) error {
 	}
 	return nil
 }
+
+func (u *bannersUsecase) UpdateBannerOrder(ids []int) error {
+	return u.bannersRepository.UpdateBannerOrder(ids)
+}
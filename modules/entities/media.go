@@ -1,7 +1,42 @@
 package entities
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
 type Image struct {
-	Id       int `db:"id" json:"id"`
+	Id       int    `db:"id" json:"id"`
 	FileName string `db:"filename" json:"filename"`
 	Url      string `db:"url" json:"url"`
-}
\ No newline at end of file
+	SrcSet   SrcSet `db:"srcset" json:"srcset,omitempty"`
+}
+
+// SrcSet maps a width descriptor such as "640w" to the url of that webp variant.
+type SrcSet map[string]string
+
+func (s SrcSet) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *SrcSet) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("scan srcset failed: unsupported type %T", src)
+	}
+}
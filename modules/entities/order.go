@@ -0,0 +1,22 @@
+package entities
+
+import "fmt"
+
+// OrderReq is the body of the bulk reorder endpoints, ids listed in display order.
+type OrderReq struct {
+	Ids []int `json:"ids"`
+}
+
+func (r *OrderReq) Validate() error {
+	if len(r.Ids) == 0 {
+		return fmt.Errorf("ids is required")
+	}
+	seen := make(map[int]bool, len(r.Ids))
+	for _, id := range r.Ids {
+		if seen[id] {
+			return fmt.Errorf("id %d is duplicated", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
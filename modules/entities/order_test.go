@@ -0,0 +1,24 @@
+package entities
+
+import "testing"
+
+func TestOrderReqValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int
+		wantErr bool
+	}{
+		{name: "ids in order", ids: []int{3, 1, 2}},
+		{name: "no ids", ids: nil, wantErr: true},
+		{name: "duplicated id", ids: []int{1, 2, 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &OrderReq{Ids: tt.ids}
+			if err := req.Validate(); tt.wantErr != (err != nil) {
+				t.Errorf("Validate(%v) = %v, want error %v", tt.ids, err, tt.wantErr)
+			}
+		})
+	}
+}
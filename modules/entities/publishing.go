@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// Display states shared by every publishable module. "unpublished" is kept
+// for rows created before the draft/scheduled workflow existed.
+const (
+	DisplayDraft       = "draft"
+	DisplayScheduled   = "scheduled"
+	DisplayPublished   = "published"
+	DisplayUnpublished = "unpublished"
+	DisplayArchived    = "archived"
+)
+
+// ValidatePublishing checks the display state and schedule of a create or update
+// request. An empty display is allowed so partial updates keep the stored value.
+func ValidatePublishing(display string, publishAt, unpublishAt *string) error {
+	switch display {
+	case "", DisplayDraft, DisplayScheduled, DisplayPublished, DisplayUnpublished, DisplayArchived:
+	default:
+		return fmt.Errorf("display is invalid: %s", display)
+	}
+
+	start, err := parsePublishTime("publish_at", publishAt)
+	if err != nil {
+		return err
+	}
+	end, err := parsePublishTime("unpublish_at", unpublishAt)
+	if err != nil {
+		return err
+	}
+
+	if display == DisplayScheduled && start == nil {
+		return fmt.Errorf("publish_at is required when display is scheduled")
+	}
+	if start != nil && end != nil && !end.After(*start) {
+		return fmt.Errorf("unpublish_at must be after publish_at")
+	}
+	return nil
+}
+
+func parsePublishTime(field string, value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be RFC3339, e.g. 2024-01-02T00:00:00+07:00", field)
+	}
+	return &t, nil
+}
@@ -1,19 +1,32 @@
 package files
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
 
 type FileReq struct {
 	File        *multipart.FileHeader `form:"file"`
 	Destination string                `form:"destination"`
 	Extension   string
 	FileName    string
+	Data        []byte // encoded file, used instead of File when set
 }
 
 type FileRes struct {
-	FileName string `json:"filename"`
-	Url      string `json:"url"`
+	MediaId  int             `json:"media_id,omitempty"`
+	FileName string          `json:"filename"`
+	Url      string          `json:"url"`
+	SrcSet   entities.SrcSet `json:"srcset,omitempty"`
+	Path     string          `json:"-"`
+	Hash     string          `json:"-"`
+	Size     int             `json:"size"`
+	Width    int             `json:"width,omitempty"`
+	Height   int             `json:"height,omitempty"`
+	MimeType string          `json:"mime_type"`
 }
 
 type DeleteFileReq struct {
 	Destination string `json:"destination"`
-}
\ No newline at end of file
+}
@@ -1,13 +1,13 @@
 package filesHandlers
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
 	"math"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -17,6 +17,7 @@ import (
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/modules/media/mediaUsecases"
 	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
@@ -35,12 +36,14 @@ type IFilesHandler interface {
 type filesHandler struct {
 	cfg          config.IConfig
 	filesUsecase filesUsecases.IFilesUsecase
+	mediaUsecase mediaUsecases.IMediaUsecase
 }
 
-func FilesHandler(cfg config.IConfig, filesUsecase filesUsecases.IFilesUsecase) IFilesHandler {
+func FilesHandler(cfg config.IConfig, filesUsecase filesUsecases.IFilesUsecase, mediaUsecase mediaUsecases.IMediaUsecase) IFilesHandler {
 	return &filesHandler{
 		cfg:          cfg,
 		filesUsecase: filesUsecase,
+		mediaUsecase: mediaUsecase,
 	}
 }
 
@@ -94,10 +97,9 @@ func (h *filesHandler) UploadFiles(c *fiber.Ctx) error {
 		} else {
 			// Convert other image formats to webp
 			webPFileName := utils.RandFileName("webp")
-			webPFilePath := filepath.Join(destination, webPFileName)
 
-			// Convert to WebP directly without saving to temporary location
-			if err := convertToWebP(file, webPFilePath); err != nil {
+			data, err := convertToWebP(file)
+			if err != nil {
 				return entities.NewResponse(c).Error(
 					fiber.ErrInternalServerError.Code,
 					string(uploadErr),
@@ -111,6 +113,7 @@ func (h *filesHandler) UploadFiles(c *fiber.Ctx) error {
 				Destination: destination + "/" + webPFileName,
 				FileName:    webPFileName,
 				Extension:   "webp",
+				Data:        data,
 			})
 		}
 	}
@@ -123,6 +126,15 @@ func (h *filesHandler) UploadFiles(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	// Record the uploads in the media library
+	if err := h.mediaUsecase.AddMedia(res, utils.GetUserIDFromContext(c)); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(uploadErr),
+			err.Error(),
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusCreated, res).Res()
 }
 
@@ -146,11 +158,11 @@ func (h *filesHandler) DeleteFile(c *fiber.Ctx) error {
 	return entities.NewResponse(c).Success(fiber.StatusOK, nil).Res()
 }
 
-func convertToWebP(file *multipart.FileHeader, outputPath string) error {
+func convertToWebP(file *multipart.FileHeader) ([]byte, error) {
 	//open file
 	inputFile, err := file.Open()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer inputFile.Close()
 
@@ -161,39 +173,20 @@ func convertToWebP(file *multipart.FileHeader, outputPath string) error {
 	case ".png":
 		img, err = png.Decode(inputFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	case ".jpg", ".jpeg":
 		img, err = jpeg.Decode(inputFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	default:
-		return fmt.Errorf("unsupported image format")
-	}
-
-	// Create the directory if it doesn't exist
-	err = os.MkdirAll("./assets/images/convert/"+filepath.Dir(outputPath), 0755)
-	if err != nil {
-		return err
-	}
-
-	outputFile, err := os.Create("./assets/images/convert/" + outputPath)
-	if err != nil {
-		return err
+		return nil, fmt.Errorf("unsupported image format")
 	}
-	defer outputFile.Close()
 
-	err = webp.Encode(outputFile, img, nil)
-	if err != nil {
-		return err
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, nil); err != nil {
+		return nil, err
 	}
-	defer outputFile.Close()
-	
-	// Delete the directory after encoding the file to WebP
-	if err := os.RemoveAll("./assets/images/convert/"); err != nil {
-		return err
-	}
-
-	return nil
+	return buf.Bytes(), nil
 }
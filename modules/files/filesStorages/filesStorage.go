@@ -0,0 +1,32 @@
+package filesStorages
+
+import (
+	"context"
+	"log"
+
+	"github.com/yporn/sirarom-backend/config"
+)
+
+// IFilesStorage is the driver that filesUsecases writes objects through.
+// Keys are relative paths such as "assets/images/banner/xxx.webp", the same
+// on every driver, so files can be moved between drivers by copying them.
+// Only keys under "assets/" are meant to be served publicly.
+type IFilesStorage interface {
+	Upload(ctx context.Context, key string, data []byte, contentType string) error
+	Download(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	Url(key string) string
+}
+
+func FilesStorage(cfg config.IConfig) IFilesStorage {
+	switch cfg.Storage().Driver() {
+	case "local":
+		return LocalStorage(cfg)
+	case "s3":
+		return S3Storage(cfg)
+	default:
+		log.Fatalf("storage driver: %s is not supported", cfg.Storage().Driver())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package filesStorages
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/yporn/sirarom-backend/config"
+)
+
+type localStorage struct {
+	cfg config.IConfig
+}
+
+func LocalStorage(cfg config.IConfig) IFilesStorage {
+	return &localStorage{
+		cfg: cfg,
+	}
+}
+
+func (s *localStorage) Upload(ctx context.Context, key string, data []byte, contentType string) error {
+	dest := "./" + key
+	if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+		return fmt.Errorf("mkdir \"%s\" failed: %v", filepath.Dir(dest), err)
+	}
+	if err := os.WriteFile(dest, data, 0777); err != nil {
+		return fmt.Errorf("write file failed: %v", err)
+	}
+	return nil
+}
+
+func (s *localStorage) Download(ctx context.Context, key string) ([]byte, error) {
+	b, err := os.ReadFile("./" + key)
+	if err != nil {
+		return nil, fmt.Errorf("read file failed: %v", err)
+	}
+	return b, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	if err := os.Remove("./" + key); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := filepath.WalkDir("./"+prefix, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			keys = append(keys, filepath.ToSlash(filepath.Clean(p)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list files: %s failed: %v", prefix, err)
+	}
+	return keys, nil
+}
+
+func (s *localStorage) Url(key string) string {
+	return fmt.Sprintf("%s/%s", s.cfg.App().AppUrl(), key)
+}
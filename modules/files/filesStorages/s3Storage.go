@@ -0,0 +1,273 @@
+package filesStorages
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yporn/sirarom-backend/config"
+)
+
+// s3Storage talks to any S3-compatible object store (AWS S3, GCS interoperability,
+// MinIO) with AWS Signature Version 4. Set STORAGE_PATH_STYLE=true for MinIO.
+type s3Storage struct {
+	cfg    config.IConfig
+	client *http.Client
+	now    func() time.Time // clock of the signatures
+}
+
+func S3Storage(cfg config.IConfig) IFilesStorage {
+	return &s3Storage{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Second * 60},
+		now:    time.Now,
+	}
+}
+
+func (s *s3Storage) Upload(ctx context.Context, key string, data []byte, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	res, err := s.do(ctx, http.MethodPut, key, nil, header, data)
+	if err != nil {
+		return fmt.Errorf("upload object: %s failed: %v", key, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("upload object: %s failed: %s %s", key, res.Status, string(b))
+	}
+	return nil
+}
+
+func (s *s3Storage) Download(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, http.Header{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("download object: %s failed: %v", key, err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("download object: %s failed: %v", key, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download object: %s failed: %s %s", key, res.Status, string(b))
+	}
+	return b, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, http.Header{}, nil)
+	if err != nil {
+		return fmt.Errorf("delete object: %s failed: %v", key, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("delete object: %s failed: %s %s", key, res.Status, string(b))
+	}
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		res, err := s.do(ctx, http.MethodGet, "", query, http.Header{}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %s failed: %v", prefix, err)
+		}
+		b, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %s failed: %v", prefix, err)
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("list objects: %s failed: %s %s", prefix, res.Status, string(b))
+		}
+
+		result := &listBucketResult{}
+		if err := xml.Unmarshal(b, result); err != nil {
+			return nil, fmt.Errorf("unmarshal list objects failed: %v", err)
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return keys, nil
+}
+
+func (s *s3Storage) Url(key string) string {
+	if s.cfg.Storage().PublicUrl() != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(s.cfg.Storage().PublicUrl(), "/"), key)
+	}
+	return s.objectUrl(key).String()
+}
+
+func (s *s3Storage) objectUrl(key string) *url.URL {
+	u, err := url.Parse(strings.TrimRight(s.cfg.Storage().Endpoint(), "/"))
+	if err != nil || u.Host == "" {
+		u = &url.URL{Scheme: "https", Host: "s3." + s.cfg.Storage().Region() + ".amazonaws.com"}
+	}
+	if s.cfg.Storage().PathStyle() {
+		u.Path = "/" + s.cfg.Storage().Bucket() + "/" + key
+	} else {
+		u.Host = s.cfg.Storage().Bucket() + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+func (s *s3Storage) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.objectUrl(key)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, u, body)
+
+	return s.client.Do(req)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req.
+func (s *s3Storage) sign(req *http.Request, u *url.URL, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", u.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		names = append(names, strings.ToLower(k))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.cfg.Storage().Region())
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.Storage().SecretKey()), date)
+	key = hmacSHA256(key, s.cfg.Storage().Region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.Storage().AccessKey(),
+		scope,
+		signedHeaders,
+		signature,
+	))
+	req.Host = u.Host
+	req.Header.Del("Host")
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode follows the S3 rules: only unreserved characters are kept as is.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
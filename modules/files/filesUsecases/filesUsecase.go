@@ -1,30 +1,50 @@
 package filesUsecases
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"io/ioutil"
-	"os"
-	
+	"math"
+	"mime"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/chai2010/webp"
 	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/files"
+	"github.com/yporn/sirarom-backend/modules/files/filesStorages"
+	"golang.org/x/image/draw"
 )
 
+// PrivatePrefix holds the files that are never served as static assets and
+// are only read back through an authorized handler, such as résumés.
+const PrivatePrefix = "private/"
+
 type IFilesUsecase interface {
 	UploadToStorage(req []*files.FileReq) ([]*files.FileRes, error)
 	DeleteFileOnStorage(req []*files.DeleteFileReq) error
+	ListFileOnStorage(destination string) ([]string, error)
+	UploadPrivate(req *files.FileReq) (*files.FileRes, error)
+	DownloadPrivate(path string) ([]byte, error)
+	DeletePrivate(path string) error
 }
 
 type filesUsecase struct {
-	cfg config.IConfig
+	cfg     config.IConfig
+	storage filesStorages.IFilesStorage
 }
 
 func FilesUsecase(cfg config.IConfig) IFilesUsecase {
 	return &filesUsecase{
-		cfg: cfg,
+		cfg:     cfg,
+		storage: filesStorages.FilesStorage(cfg),
 	}
 }
 
@@ -35,37 +55,53 @@ type filesPub struct {
 
 func (u *filesUsecase) uploadToStorageWorker(ctx context.Context, jobs <-chan *files.FileReq, results chan<- *files.FileRes, errs chan<- error) {
 	for job := range jobs {
-		container, err := job.File.Open()
-		if err != nil {
-			errs <- err
-			return
-		}
-		b, err := ioutil.ReadAll(container)
-		if err != nil {
-			errs <- err
-			return
-		}
-
-		// Upload an object to storage
-		dest := fmt.Sprintf("assets/images/%s", job.Destination)
-		if err := os.WriteFile(dest, b, 0777); err != nil {
-			if err := os.MkdirAll("./assets/images/"+strings.Replace(job.Destination, job.FileName, "", 1), 0777); err != nil {
-				errs <- fmt.Errorf("mkdir \"./assets/images/%s\" failed: %v", err, job.Destination)
+		b := job.Data
+		if b == nil {
+			container, err := job.File.Open()
+			if err != nil {
+				errs <- err
 				return
 			}
-			if err := os.WriteFile(dest, b, 0777); err != nil {
-				errs <- fmt.Errorf("write file failed: %v", err)
+			b, err = ioutil.ReadAll(container)
+			container.Close()
+			if err != nil {
+				errs <- err
 				return
 			}
 		}
-		// fmt.Println("http://" + u.cfg.App().Host() + ":" + strconv.Itoa(u.cfg.App().Port()) + "/" + dest)
+
+		// Upload an object to storage
+		dest := fmt.Sprintf("assets/images/%s", job.Destination)
+		mimeType := mime.TypeByExtension(filepath.Ext(dest))
+		if err := u.storage.Upload(ctx, dest, b, mimeType); err != nil {
+			errs <- err
+			return
+		}
+
 		newFile := &filesPub{
 			file: &files.FileRes{
 				FileName: job.FileName,
-				Url:      fmt.Sprintf("%s/%s", u.cfg.App().AppUrl(), dest),
+				Url:      u.storage.Url(dest),
+				Path:     dest,
+				Hash:     fmt.Sprintf("%x", sha256.Sum256(b)),
+				Size:     len(b),
+				MimeType: mimeType,
 			},
 			destination: dest,
 		}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(b)); err == nil {
+			newFile.file.Width = cfg.Width
+			newFile.file.Height = cfg.Height
+		}
+
+		if job.Extension == "webp" {
+			srcSet, err := u.uploadVariants(ctx, dest, b)
+			if err != nil {
+				errs <- err
+				return
+			}
+			newFile.file.SrcSet = srcSet
+		}
 
 		errs <- nil
 		results <- newFile.file
@@ -106,10 +142,14 @@ func (u *filesUsecase) UploadToStorage(req []*files.FileReq) ([]*files.FileRes,
 
 func (u *filesUsecase) deleteFromStorageFileWorkers(ctx context.Context, jobs <-chan *files.DeleteFileReq, errs chan<- error) {
 	for job := range jobs {
-		if err := os.Remove("./assets/images/" + job.Destination); err != nil {
+		if err := u.storage.Delete(ctx, "assets/images/"+job.Destination); err != nil {
 			errs <- fmt.Errorf("remove file: %s failed: %v", job.Destination, err)
 			return
 		}
+		// Variants may not exist for every width, e.g. when the original was smaller
+		for _, w := range u.cfg.App().ImageWidths() {
+			u.storage.Delete(ctx, variantKey("assets/images/"+job.Destination, w))
+		}
 		errs <- nil
 	}
 }
@@ -132,8 +172,118 @@ func (u *filesUsecase) DeleteFileOnStorage(req []*files.DeleteFileReq) error {
 	}
 
 	for a := 0; a < len(req); a++ {
-		err := <-errsCh
-		return err
+		if err := <-errsCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *filesUsecase) ListFileOnStorage(destination string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	keys, err := u.storage.List(ctx, "assets/images/"+destination)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, strings.TrimPrefix(key, "assets/images/"))
+	}
+	return res, nil
+}
+
+// UploadPrivate stores the file under PrivatePrefix. The result has no url,
+// its Path is what DownloadPrivate and DeletePrivate take.
+func (u *filesUsecase) UploadPrivate(req *files.FileReq) (*files.FileRes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	b := req.Data
+	if b == nil {
+		container, err := req.File.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err = ioutil.ReadAll(container)
+		container.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dest := PrivatePrefix + req.Destination
+	mimeType := mime.TypeByExtension(filepath.Ext(dest))
+	if err := u.storage.Upload(ctx, dest, b, mimeType); err != nil {
+		return nil, err
+	}
+	return &files.FileRes{
+		FileName: req.FileName,
+		Path:     dest,
+		Hash:     fmt.Sprintf("%x", sha256.Sum256(b)),
+		Size:     len(b),
+		MimeType: mimeType,
+	}, nil
+}
+
+func (u *filesUsecase) DownloadPrivate(path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	return u.storage.Download(ctx, path)
+}
+
+func (u *filesUsecase) DeletePrivate(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	if err := u.storage.Delete(ctx, path); err != nil {
+		return fmt.Errorf("remove file: %s failed: %v", path, err)
 	}
 	return nil
 }
+
+// uploadVariants stores a resized webp copy of the image for every configured
+// width narrower than the original and returns them as a srcset map.
+func (u *filesUsecase) uploadVariants(ctx context.Context, dest string, b []byte) (entities.SrcSet, error) {
+	img, err := webp.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("decode webp: %s failed: %v", dest, err)
+	}
+	bounds := img.Bounds()
+
+	srcSet := make(entities.SrcSet)
+	for _, w := range u.cfg.App().ImageWidths() {
+		if w <= 0 || w >= bounds.Dx() {
+			continue
+		}
+		h := int(math.Round(float64(bounds.Dy()) * float64(w) / float64(bounds.Dx())))
+		if h < 1 {
+			h = 1
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+		data, err := webp.EncodeRGBA(dst, webp.DefaulQuality)
+		if err != nil {
+			return nil, fmt.Errorf("encode webp variant %dw failed: %v", w, err)
+		}
+
+		key := variantKey(dest, w)
+		if err := u.storage.Upload(ctx, key, data, "image/webp"); err != nil {
+			return nil, err
+		}
+		srcSet[fmt.Sprintf("%dw", w)] = u.storage.Url(key)
+	}
+	srcSet[fmt.Sprintf("%dw", bounds.Dx())] = u.storage.Url(dest)
+
+	return srcSet, nil
+}
+
+// variantKey turns "assets/images/banner/a.webp" into "assets/images/banner/a_640w.webp".
+func variantKey(key string, width int) string {
+	ext := filepath.Ext(key)
+	return fmt.Sprintf("%s_%dw%s", strings.TrimSuffix(key, ext), width, ext)
+}
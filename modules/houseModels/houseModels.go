@@ -8,11 +8,18 @@ import (
 type HouseModel struct {
 	Id              int                   `db:"id" json:"id"`
 	ProjectId       int                   `db:"project_id" json:"project_id"`
+	PhaseId         *int                  `db:"phase_id" json:"phase_id"` // 0 on update detaches the phase
 	Name            string                `db:"name" json:"name"`
+	Slug            string                `db:"slug" json:"slug"` // empty on create is made from the name, on update keeps the stored one
 	Description     string                `db:"description"`
 	LinkVideo       string                `db:"link_video" json:"link_video"`
 	LinkVirtualTour string                `db:"link_virtual_tour" json:"link_virtual_tour"`
+	Price           *float64              `db:"price" json:"price"` // empty falls back to the project price
+	AvailableUnits  *int                  `json:"available_units,omitempty"` // plot counts, only on the project house model list
+	TotalUnits      *int                  `json:"total_units,omitempty"`
 	Display         string                `db:"display" json:"display"`
+	PublishAt       *string               `db:"publish_at" json:"publish_at"`
+	UnpublishAt     *string               `db:"unpublish_at" json:"unpublish_at"`
 	Index           int                   `db:"index" json:"index"`
 	CreatedAt       string                `db:"created_at" json:"created_at"`
 	UpdatedAt       string                `db:"updated_at" json:"updated_at"`
@@ -53,6 +60,8 @@ type HouseModelFilter struct {
 	Id        string `query:"id"`
 	ProjectId int    `query:"project_id"`
 	Search    string `query:"search"` // name
+	Display   string `query:"display"`
+	Public    bool   `query:"-"` // only house models of a published project
 	*entities.PaginationReq
 	*entities.SortReq
 }
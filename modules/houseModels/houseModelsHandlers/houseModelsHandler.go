@@ -2,39 +2,54 @@ package houseModelsHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/houseModels"
 	"github.com/yporn/sirarom-backend/modules/houseModels/houseModelsUsecases"
+	"github.com/yporn/sirarom-backend/modules/revisions"
 	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type houseModelsHandlersErrCode string
 
 const (
-	findOneHouseModelErr houseModelsHandlersErrCode = "houses-001"
-	findHouseModelErr    houseModelsHandlersErrCode = "houses-002"
-	findAllHouseModelErr houseModelsHandlersErrCode = "houses-006"
-	insertHouseModelErr  houseModelsHandlersErrCode = "houses-003"
-	deleteHouseModelErr  houseModelsHandlersErrCode = "houses-004"
-	updateHouseModelErr  houseModelsHandlersErrCode = "houses-005"
+	findOneHouseModelErr         houseModelsHandlersErrCode = "houses-001"
+	findHouseModelErr            houseModelsHandlersErrCode = "houses-002"
+	findAllHouseModelErr         houseModelsHandlersErrCode = "houses-006"
+	insertHouseModelErr          houseModelsHandlersErrCode = "houses-003"
+	deleteHouseModelErr          houseModelsHandlersErrCode = "houses-004"
+	updateHouseModelErr          houseModelsHandlersErrCode = "houses-005"
+	findHouseModelRevisionsErr   houseModelsHandlersErrCode = "houses-007"
+	findOneHouseModelRevisionErr houseModelsHandlersErrCode = "houses-008"
+	diffHouseModelRevisionsErr   houseModelsHandlersErrCode = "houses-009"
+	restoreHouseModelRevisionErr houseModelsHandlersErrCode = "houses-010"
+	updateHouseModelOrderErr     houseModelsHandlersErrCode = "houses-011"
+	findOneHouseModelBySlugErr   houseModelsHandlersErrCode = "houses-012"
 )
 
 type IHouseModelsHandler interface {
 	FindOneHouseModel(c *fiber.Ctx) error
+	FindOneHouseModelBySlug(c *fiber.Ctx) error
+	FindOneAdminHouseModel(c *fiber.Ctx) error
 	FindHouseModel(c *fiber.Ctx) error
+	FindAdminHouseModel(c *fiber.Ctx) error
 	FindAllHouseModel(c *fiber.Ctx) error
+	FindAllAdminHouseModel(c *fiber.Ctx) error
 	AddHouseModel(c *fiber.Ctx) error
 	UpdateHouseModel(c *fiber.Ctx) error
 	DeleteHouseModel(c *fiber.Ctx) error
+	FindHouseModelRevisions(c *fiber.Ctx) error
+	FindOneHouseModelRevision(c *fiber.Ctx) error
+	DiffHouseModelRevisions(c *fiber.Ctx) error
+	RestoreHouseModelRevision(c *fiber.Ctx) error
+	UpdateHouseModelOrder(c *fiber.Ctx) error
 }
 
 type houseModelsHandler struct {
@@ -54,8 +69,39 @@ func HouseModelsHandler(cfg config.IConfig, houseModelsUsecase houseModelsUsecas
 }
 
 func (h *houseModelsHandler) FindOneHouseModel(c *fiber.Ctx) error {
-	houseId := strings.Trim(c.Params("house_model_id"), " ")
+	return h.findOneHouseModel(c, strings.Trim(c.Params("house_model_id"), " "), true)
+}
+
+func (h *houseModelsHandler) FindOneAdminHouseModel(c *fiber.Ctx) error {
+	return h.findOneHouseModel(c, strings.Trim(c.Params("house_model_id"), " "), false)
+}
 
+// FindOneHouseModelBySlug answers an old slug with a redirect to the current one.
+func (h *houseModelsHandler) FindOneHouseModelBySlug(c *fiber.Ctx) error {
+	slug := strings.Trim(c.Params("slug"), " ")
+
+	houseId, current, err := h.houseModelsUsecases.FindHouseModelSlug(utils.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, utils.ErrSlugNotFound) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(findOneHouseModelBySlugErr),
+				"house model not found",
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneHouseModelBySlugErr),
+			err.Error(),
+		).Res()
+	}
+	if current != slug {
+		return c.Redirect(utils.SlugPath(c.Path(), current), fiber.StatusMovedPermanently)
+	}
+	return h.findOneHouseModel(c, strconv.Itoa(houseId), true)
+}
+
+func (h *houseModelsHandler) findOneHouseModel(c *fiber.Ctx, houseId string, public bool) error {
 	house, err := h.houseModelsUsecases.FindOneHouseModel(houseId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -64,11 +110,52 @@ func (h *houseModelsHandler) FindOneHouseModel(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && house.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneHouseModelErr),
+			"house model not found",
+		).Res()
+	}
+	if public {
+		published, err := h.houseModelsUsecases.ProjectPublished(house.ProjectId)
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				string(findOneHouseModelErr),
+				err.Error(),
+			).Res()
+		}
+		if !published {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(findOneHouseModelErr),
+				"house model not found",
+			).Res()
+		}
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, house).Res()
 }
 
 func (h *houseModelsHandler) FindAllHouseModel(c *fiber.Ctx) error {
-	houses, err := h.houseModelsUsecases.FindAllHouseModels()
+	return h.findAllHouseModel(c, entities.DisplayPublished, true)
+}
+
+// FindAllAdminHouseModel lists house model names in every display state, optionally filtered by ?display=.
+func (h *houseModelsHandler) FindAllAdminHouseModel(c *fiber.Ctx) error {
+	display := c.Query("display")
+	if err := entities.ValidatePublishing(display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findAllHouseModelErr),
+			err.Error(),
+		).Res()
+	}
+	return h.findAllHouseModel(c, display, false)
+}
+
+func (h *houseModelsHandler) findAllHouseModel(c *fiber.Ctx, display string, public bool) error {
+	houses, err := h.houseModelsUsecases.FindAllHouseModels(display, public)
 	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
@@ -80,6 +167,15 @@ func (h *houseModelsHandler) FindAllHouseModel(c *fiber.Ctx) error {
 }
 
 func (h *houseModelsHandler) FindHouseModel(c *fiber.Ctx) error {
+	return h.findHouseModel(c, true)
+}
+
+// FindAdminHouseModel returns rows in every display state, optionally filtered by ?display=.
+func (h *houseModelsHandler) FindAdminHouseModel(c *fiber.Ctx) error {
+	return h.findHouseModel(c, false)
+}
+
+func (h *houseModelsHandler) findHouseModel(c *fiber.Ctx, public bool) error {
 	projectIdStr := c.Params("project_id")
 
 	projectId, err := strconv.Atoi(projectIdStr)
@@ -106,6 +202,17 @@ func (h *houseModelsHandler) FindHouseModel(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+		req.Public = true
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findHouseModelErr),
+			err.Error(),
+		).Res()
+	}
+
 	// Set the project ID in the filter
 	req.ProjectId = projectId
 
@@ -158,6 +265,14 @@ func (h *houseModelsHandler) AddHouseModel(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertHouseModelErr),
+			err.Error(),
+		).Res()
+	}
+
 	if len(req.TypeItem) == 0 {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
@@ -176,6 +291,13 @@ func (h *houseModelsHandler) AddHouseModel(c *fiber.Ctx) error {
 
 	houseModel, err := h.houseModelsUsecases.AddHouseModel(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(insertHouseModelErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(insertHouseModelErr),
@@ -222,10 +344,26 @@ func (h *houseModelsHandler) UpdateHouseModel(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateHouseModelErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = houseModelId
 
-	houseModel, err := h.houseModelsUsecases.UpdateHouseModel(req)
+	userID := utils.GetUserIDFromContext(c)
+	houseModel, err := h.houseModelsUsecases.UpdateHouseModel(req, userID)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(updateHouseModelErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(updateHouseModelErr),
@@ -234,7 +372,6 @@ func (h *houseModelsHandler) UpdateHouseModel(c *fiber.Ctx) error {
 	}
 
 	// Log activity
-	userID := utils.GetUserIDFromContext(c)
 	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขข้อมูลแบบบ้าน : "+houseModel.Name)
 	if err != nil {
 		// Handle error if logging fails
@@ -260,48 +397,213 @@ func (h *houseModelsHandler) DeleteHouseModel(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range houseModel.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("house_models/%s", path.Base(p.Url)),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.houseModelsUsecases.DeleteHouseModel(houseId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteHouseModelErr),
+			err.Error(),
+		).Res()
 	}
 
-	for _, housePlan := range houseModel.HousePlan {
-		for _, image := range housePlan.Images {
-			deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-				Destination: fmt.Sprintf("house_model_plans/%s", path.Base(image.Url)),
-			})
+		// Log activity
+		userID := utils.GetUserIDFromContext(c)
+		err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบบบ้าน : "+houseModel.Name)
+		if err != nil {
+			// Handle error if logging fails
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				fmt.Sprintf("Failed to log activity %v", userID),
+				err.Error(),
+			).Res()
 		}
+
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *houseModelsHandler) FindHouseModelRevisions(c *fiber.Ctx) error {
+	houseModelId, err := strconv.Atoi(strings.Trim(c.Params("house_model_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findHouseModelRevisionsErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	revisionsData, err := h.houseModelsUsecases.FindHouseModelRevisions(houseModelId)
+	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteHouseModelErr),
+			string(findHouseModelRevisionsErr),
 			err.Error(),
 		).Res()
 	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, revisionsData).Res()
+}
 
-	if err := h.houseModelsUsecases.DeleteHouseModel(houseId); err != nil {
+func (h *houseModelsHandler) FindOneHouseModelRevision(c *fiber.Ctx) error {
+	houseModelId, err := strconv.Atoi(strings.Trim(c.Params("house_model_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneHouseModelRevisionErr),
+			err.Error(),
+		).Res()
+	}
+	revisionId, err := strconv.Atoi(strings.Trim(c.Params("revision_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneHouseModelRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	revision, err := h.houseModelsUsecases.FindOneHouseModelRevision(houseModelId, revisionId)
+	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteHouseModelErr),
+			string(findOneHouseModelRevisionErr),
 			err.Error(),
 		).Res()
 	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, revision).Res()
+}
 
-		// Log activity
-		userID := utils.GetUserIDFromContext(c)
-		err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบบบ้าน : "+houseModel.Name)
-		if err != nil {
-			// Handle error if logging fails
+func (h *houseModelsHandler) DiffHouseModelRevisions(c *fiber.Ctx) error {
+	houseModelId, err := strconv.Atoi(strings.Trim(c.Params("house_model_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffHouseModelRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(revisions.RevisionDiffReq)
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffHouseModelRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+	if req.From < 1 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffHouseModelRevisionsErr),
+			"from is required",
+		).Res()
+	}
+
+	diff, err := h.houseModelsUsecases.DiffHouseModelRevisions(houseModelId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(diffHouseModelRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, diff).Res()
+}
+
+func (h *houseModelsHandler) RestoreHouseModelRevision(c *fiber.Ctx) error {
+	houseModelId, err := strconv.Atoi(strings.Trim(c.Params("house_model_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(restoreHouseModelRevisionErr),
+			err.Error(),
+		).Res()
+	}
+	revisionId, err := strconv.Atoi(strings.Trim(c.Params("revision_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(restoreHouseModelRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	houseModel, err := h.houseModelsUsecases.RestoreHouseModelRevision(houseModelId, revisionId, userID)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(restoreHouseModelRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "กู้คืนข้อมูลแบบบ้าน : "+houseModel.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, houseModel).Res()
+}
+
+// UpdateHouseModelOrder rewrites the display order from the ids in the body, first id first.
+func (h *houseModelsHandler) UpdateHouseModelOrder(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateHouseModelOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateHouseModelOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateHouseModelOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.houseModelsUsecases.UpdateHouseModelOrder(projectId, req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
 			return entities.NewResponse(c).Error(
-				fiber.ErrInternalServerError.Code,
-				fmt.Sprintf("Failed to log activity %v", userID),
+				fiber.ErrBadRequest.Code,
+				string(updateHouseModelOrderErr),
 				err.Error(),
 			).Res()
 		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateHouseModelOrderErr),
+			err.Error(),
+		).Res()
+	}
 
-	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลแบบบ้าน")
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
 }
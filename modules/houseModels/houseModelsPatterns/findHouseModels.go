@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
 	"time"
 
@@ -63,8 +62,6 @@ func (b *findHouseModelBuilder) openJsonQuery() {
 
 func (b *findHouseModelBuilder) initQuery() {
 	b.query += `
-	SELECT to_jsonb("at")
-	FROM (
 		SELECT
 			"hm".*,
 			(
@@ -84,7 +81,8 @@ func (b *findHouseModelBuilder) initQuery() {
 					SELECT
 						"ihm"."id",
 						"ihm"."filename",
-						"ihm"."url"
+						"ihm"."url",
+						"ihm"."srcset"
 					FROM "house_model_images" "ihm"
 					WHERE "ihm"."house_model_id" = "hm"."id"
 				) AS "ihm"
@@ -112,7 +110,8 @@ func (b *findHouseModelBuilder) initQuery() {
 								SELECT
 									"ihmp"."id",
 									"ihmp"."filename",
-									"ihmp"."url"
+									"ihmp"."url",
+									"ihmp"."srcset"
 								FROM "house_model_plan_images" "ihmp"
 								WHERE "ihmp"."house_model_plan_id" = "hmp"."id"
 							) AS "ihmp"
@@ -135,16 +134,17 @@ func (b *findHouseModelBuilder) initQuery() {
 									"pt".*
 								FROM "promotions" "pt"
 								WHERE "pt"."id" = "ptm"."promotion_id"
+								AND "pt"."display" = 'published'
+								AND "pt"."deleted_at" IS NULL
 							) AS "pt"
-						) AS "promotions",
+						) AS "promotions"
 					FROM "promotion_house_models" "ptm"
 					WHERE "ptm"."house_model_id" = "hm"."id"
 				) AS "ptm"
-			) AS "promotions"
-			FROM "house_models" "hm"
-		WHERE "hm"."project_id" = $1
-	)
-	`
+			) AS "houseModel_promotions"
+		FROM "house_models" "hm"
+		WHERE "hm"."deleted_at" IS NULL
+		AND NOT EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."deleted_at" IS NOT NULL)`
 }
 
 func (b *findHouseModelBuilder) countQuery() {
@@ -152,52 +152,51 @@ func (b *findHouseModelBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "house_models" "hm"
-		WHERE "hm"."project_id" = $1
-	`
+		WHERE "hm"."deleted_at" IS NULL
+		AND NOT EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."deleted_at" IS NOT NULL)`
 }
 
 func (b *findHouseModelBuilder) whereQuery() {
-	var queryWhere string
-	queryWhereStack := make([]string, 0)
+	// Project check
+	b.values = append(b.values, b.projectId)
+	b.query += fmt.Sprintf(`
+		AND "hm"."project_id" = $%d`, len(b.values))
 
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
-
-		queryWhereStack = append(queryWhereStack, `
-		AND "hm"."id" = ?`)
+		b.query += fmt.Sprintf(`
+		AND "hm"."id" = $%d`, len(b.values))
 	}
 
 	// Search check
 	if b.req.Search != "" {
-		b.values = append(
-			b.values,
-			"%"+strings.ToLower(b.req.Search)+"%",
-		)
+		b.values = append(b.values, "%"+strings.ToLower(b.req.Search)+"%")
+		b.query += fmt.Sprintf(`
+		AND LOWER("hm"."name") LIKE $%d`, len(b.values))
+	}
 
-		queryWhereStack = append(queryWhereStack, `
-		AND (LOWER("hm"."name") LIKE ?)`)
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+		b.query += fmt.Sprintf(`
+		AND "hm"."display" = $%d`, len(b.values))
 	}
 
-	for i := range queryWhereStack {
-		if i != len(queryWhereStack)-1 {
-			queryWhere += strings.Replace(queryWhereStack[i], "?", "$"+strconv.Itoa(i+1), 1)
-		} else {
-			queryWhere += strings.Replace(queryWhereStack[i], "?", "$"+strconv.Itoa(i+1), 1)
-			queryWhere = strings.Replace(queryWhere, "?", "$"+strconv.Itoa(i+2), 1)
-		}
+	// Public check
+	if b.req.Public {
+		b.query += `
+		AND EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."display" = 'published')`
 	}
+
 	// Last stack record
 	b.lastStackIndex = len(b.values)
-
-	// Summary query
-	b.query += queryWhere
 }
 
 func (b *findHouseModelBuilder) sort() {
 	orderByMap := map[string]string{
-		"id":   "\"p\".\"id\"",
-		"name": "\"p\".\"name\"",
+		"id":   "\"hm\".\"id\"",
+		"name": "\"hm\".\"name\"",
 	}
 
 	orderBy := orderByMap[b.req.OrderBy]
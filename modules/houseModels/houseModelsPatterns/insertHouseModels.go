@@ -69,9 +69,14 @@ func (b *insertHouseModelBuilder) insertHouseModel() error {
 		"link_video",
 		"link_virtual_tour",
 		"display",
-		"index"
+		"index",
+		"publish_at",
+		"unpublish_at",
+		"price",
+		"phase_id",
+		"slug"
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::timestamptz, NULLIF($9, '')::timestamptz, $10, NULLIF($11, 0), $12)
 	RETURNING "id";
 	`
 
@@ -85,6 +90,11 @@ func (b *insertHouseModelBuilder) insertHouseModel() error {
 		b.req.LinkVirtualTour,
 		b.req.Display,
 		b.req.Index,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
+		b.req.Price,
+		b.req.PhaseId,
+		b.req.Slug,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert house model failed: %v", err)
@@ -129,6 +139,7 @@ func (b *insertHouseModelBuilder) insertAttachmentHouseModel() error {
 	INSERT INTO "house_model_images" (
 		"filename",
 		"url",
+		"srcset",
 		"house_model_id"
 	)
 	VALUES`
@@ -139,17 +150,18 @@ func (b *insertHouseModelBuilder) insertAttachmentHouseModel() error {
 		valueStack = append(valueStack,
 			b.req.Images[i].FileName,
 			b.req.Images[i].Url,
+			b.req.Images[i].SrcSet,
 			b.req.Id,
 		)
 
 		if i != len(b.req.Images)-1 {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d),`, index+1, index+2, index+3, index+4)
 		} else {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d);`, index+1, index+2, index+3, index+4)
 		}
-		index += 3
+		index += 4
 	}
 
 	if _, err := b.tx.ExecContext(
@@ -236,6 +248,7 @@ func (b *insertHouseModelBuilder) insertAttachmentHouseModelPlan() error {
 	INSERT INTO "house_model_plan_images" (
 		"filename",
 		"url",
+		"srcset",
 		"house_model_plan_id"
 	)
 	VALUES`
@@ -250,14 +263,15 @@ func (b *insertHouseModelBuilder) insertAttachmentHouseModelPlan() error {
 			valueStack = append(valueStack,
 				image.FileName,
 				image.Url,
+				image.SrcSet,
 				housePlan.Id,
 			)
 
 			if index != 0 {
 				query += ","
 			}
-			query += fmt.Sprintf("($%d, $%d, $%d)", index+1, index+2, index+3)
-			index += 3
+			query += fmt.Sprintf("($%d, $%d, $%d, $%d)", index+1, index+2, index+3, index+4)
+			index += 4
 		}
 	}
 
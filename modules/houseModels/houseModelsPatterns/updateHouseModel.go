@@ -3,12 +3,10 @@ package houseModelsPatterns
 import (
 	"context"
 	"fmt"
-	"path"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/houseModels"
 )
@@ -29,6 +27,7 @@ type IUpdateHouseModelBuilder interface {
 	getQuery() string
 	setQuery(query string)
 	getImagesLen() int
+	beforeCommit() error
 	commit() error
 }
 
@@ -41,15 +40,25 @@ type updateHouseModelBuilder struct {
 	queryFields    []string
 	lastStackIndex int
 	values         []any
+	hook           *UpdateHouseModelHook
 }
 
-func UpdateHouseModelBuilder(db *sqlx.DB, req *houseModels.HouseModel, filesUsecases filesUsecases.IFilesUsecase) IUpdateHouseModelBuilder {
+// UpdateHouseModelHook runs inside the transaction of the update: Begin before
+// anything is written, Commit right before it is committed. Either one failing
+// rolls the update back.
+type UpdateHouseModelHook struct {
+	Begin  func(tx *sqlx.Tx) error
+	Commit func(tx *sqlx.Tx) error
+}
+
+func UpdateHouseModelBuilder(db *sqlx.DB, req *houseModels.HouseModel, filesUsecases filesUsecases.IFilesUsecase, hook *UpdateHouseModelHook) IUpdateHouseModelBuilder {
 	return &updateHouseModelBuilder{
 		db:            db,
 		req:           req,
 		filesUsecases: filesUsecases,
 		queryFields:   make([]string, 0),
 		values:        make([]any, 0),
+		hook:          hook,
 	}
 }
 type updateHouseModelEngineer struct {
@@ -62,6 +71,13 @@ func (b *updateHouseModelBuilder) initTransaction() error {
 		return err
 	}
 	b.tx = tx
+
+	if b.hook != nil && b.hook.Begin != nil {
+		if err := b.hook.Begin(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 	return nil
 }
 
@@ -79,6 +95,12 @@ func (b *updateHouseModelBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"name" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.Slug != "" {
+		b.values = append(b.values, b.req.Slug)
+		b.lastStackIndex = len(b.values)
+		setStatements = append(setStatements, fmt.Sprintf(`"slug" = $%d`, b.lastStackIndex))
+	}
+
 	if b.req.Description != "" {
 		b.values = append(b.values, b.req.Description)
 		b.lastStackIndex = len(b.values)
@@ -94,6 +116,16 @@ func (b *updateHouseModelBuilder) updateQuery() {
 		b.lastStackIndex = len(b.values)
 		setStatements = append(setStatements, fmt.Sprintf(`"link_virtual_tour" = $%d`, b.lastStackIndex))
 	}
+	if b.req.Price != nil {
+		b.values = append(b.values, *b.req.Price)
+		b.lastStackIndex = len(b.values)
+		setStatements = append(setStatements, fmt.Sprintf(`"price" = $%d`, b.lastStackIndex))
+	}
+	if b.req.PhaseId != nil {
+		b.values = append(b.values, *b.req.PhaseId)
+		b.lastStackIndex = len(b.values)
+		setStatements = append(setStatements, fmt.Sprintf(`"phase_id" = NULLIF($%d, 0)`, b.lastStackIndex))
+	}
 	if b.req.Display != "" {
 		b.values = append(b.values, b.req.Display)
 		b.lastStackIndex = len(b.values)
@@ -104,6 +136,16 @@ func (b *updateHouseModelBuilder) updateQuery() {
 		b.lastStackIndex = len(b.values)
 		setStatements = append(setStatements, fmt.Sprintf(`"index" = $%d`, b.lastStackIndex))
 	}
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
 
 	b.query += strings.Join(setStatements, ", ")
 }
@@ -218,10 +260,7 @@ func (b *updateHouseModelBuilder) updateImagesHouseModel() error {
 				b.tx.Rollback()
 				return fmt.Errorf("failed to delete existing image: %v", err)
 			}
-			// Also delete the file from storage
-			b.filesUsecases.DeleteFileOnStorage([]*files.DeleteFileReq{
-				{Destination: fmt.Sprintf("images/house_models/%s", path.Base(existingImage.Url))},
-			})
+			// The file stays on storage for older revisions, the media sweep removes it once unused
 		}
 	}
 
@@ -238,8 +277,8 @@ func (b *updateHouseModelBuilder) updateImagesHouseModel() error {
 		if !imageFound {
 			if _, err := b.tx.ExecContext(
 				context.Background(),
-				`INSERT INTO "house_model_images" ("filename", "url", "house_model_id") VALUES ($1, $2, $3);`,
-				newImage.FileName, newImage.Url, b.req.Id,
+				`INSERT INTO "house_model_images" ("filename", "url", "srcset", "house_model_id") VALUES ($1, $2, $3, $4);`,
+				newImage.FileName, newImage.Url, newImage.SrcSet, b.req.Id,
 			); err != nil {
 				b.tx.Rollback()
 				return fmt.Errorf("failed to insert new image: %v", err)
@@ -474,10 +513,7 @@ func (b *updateHouseModelBuilder) updateImagesHousePlan() error {
 					b.tx.Rollback()
 					return fmt.Errorf("failed to delete existing image: %v", err)
 				}
-				// Also delete the file from storage
-				b.filesUsecases.DeleteFileOnStorage([]*files.DeleteFileReq{
-					{Destination: fmt.Sprintf("images/house_model_plans/%s", path.Base(existingImage.Url))},
-				})
+				// The file stays on storage for older revisions, the media sweep removes it once unused
 			}
 		}
 
@@ -494,8 +530,8 @@ func (b *updateHouseModelBuilder) updateImagesHousePlan() error {
 			if !imageFound {
 				if _, err := b.tx.ExecContext(
 					context.Background(),
-					`INSERT INTO "house_model_plan_images" ("filename", "url", "house_model_plan_id") VALUES ($1, $2, $3);`,
-					newImage.FileName, newImage.Url, housePlan.Id,
+					`INSERT INTO "house_model_plan_images" ("filename", "url", "srcset", "house_model_plan_id") VALUES ($1, $2, $3, $4);`,
+					newImage.FileName, newImage.Url, newImage.SrcSet, housePlan.Id,
 				); err != nil {
 					b.tx.Rollback()
 					return fmt.Errorf("failed to insert new image for house plan %d: %v", housePlan.Id, err)
@@ -529,6 +565,15 @@ func (b *updateHouseModelBuilder) getValues() []any         { return b.values }
 func (b *updateHouseModelBuilder) getQuery() string         { return b.query }
 func (b *updateHouseModelBuilder) setQuery(query string)    { b.query = query }
 func (b *updateHouseModelBuilder) getImagesLen() int        { return len(b.req.Images) }
+func (b *updateHouseModelBuilder) beforeCommit() error {
+	if b.hook != nil && b.hook.Commit != nil {
+		if err := b.hook.Commit(b.tx); err != nil {
+			b.tx.Rollback()
+			return err
+		}
+	}
+	return nil
+}
 func (b *updateHouseModelBuilder) commit() error {
 	if err := b.tx.Commit(); err != nil {
 		return err
@@ -556,7 +601,9 @@ func (en *updateHouseModelEngineer) sumQueryFields() {
 }
 
 func (en *updateHouseModelEngineer) UpdateHouseModel() error {
-	en.builder.initTransaction()
+	if err := en.builder.initTransaction(); err != nil {
+		return err
+	}
 
 	en.builder.initQuery()
 	en.sumQueryFields()
@@ -591,6 +638,10 @@ func (en *updateHouseModelEngineer) UpdateHouseModel() error {
 		return err
 	}
 
+	if err := en.builder.beforeCommit(); err != nil {
+		return err
+	}
+
 	// Commit
 	if err := en.builder.commit(); err != nil {
 		return err
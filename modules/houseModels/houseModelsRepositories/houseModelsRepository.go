@@ -2,24 +2,34 @@ package houseModelsRepositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/houseModels"
 	"github.com/yporn/sirarom-backend/modules/houseModels/houseModelsPatterns"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type IHouseModelsRepository interface {
 	FindOneHouseModel(houseId string) (*houseModels.HouseModel, error)
-	FindAllHouseModels() ([]houseModels.HouseModelName, error)
+	FindAllHouseModels(display string, public bool) ([]houseModels.HouseModelName, error)
 	InsertHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error)
 	FindHouseModel(projectId string, req *houseModels.HouseModelFilter) ([]*houseModels.HouseModel, int)
-	UpdateHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error)
+	UpdateHouseModel(req *houseModels.HouseModel, revise revisions.Revise) (*houseModels.HouseModel, error)
+	FindHouseModelSlug(slug string) (int, string, error)
 	DeleteHouseModel(houseId string) error
+	RestoreHouseModel(req *houseModels.HouseModel, revise revisions.Revise) (*houseModels.HouseModel, error)
+	UpdateHouseModelOrder(projectId int, ids []int) error
+	PhaseInProject(projectId, phaseId int) (bool, error)
+	ProjectPublished(projectId int) (bool, error)
 }
 
 
@@ -37,14 +47,19 @@ func HouseModelsRepository(db *sqlx.DB, cfg config.IConfig, filesUsecase filesUs
 	}
 }
 
-func (r *houseModelsRepository) FindAllHouseModels() ([]houseModels.HouseModelName, error) {
+// FindAllHouseModels lists house model names; public leaves out the ones of an unpublished project.
+func (r *houseModelsRepository) FindAllHouseModels(display string, public bool) ([]houseModels.HouseModelName, error) {
     query := `
         SELECT 
 			"hm"."id",
 			"hm"."name"
-        FROM "house_models" "hm";
+        FROM "house_models" "hm"
+        WHERE "hm"."deleted_at" IS NULL
+        AND NOT EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."deleted_at" IS NOT NULL)
+        AND ($1 = '' OR "hm"."display"::text = $1)
+        AND (NOT $2 OR EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."display" = 'published'));
     `
-    rows, err := r.db.Query(query)
+    rows, err := r.db.Query(query, display, public)
     if err != nil {
         return nil, fmt.Errorf("get house models failed: %v", err)
     }
@@ -69,6 +84,11 @@ func (r *houseModelsRepository) FindAllHouseModels() ([]houseModels.HouseModelNa
 
 
 func (r *houseModelsRepository) FindOneHouseModel(houseId string) (*houseModels.HouseModel, error) {
+	return findOneHouseModel(r.db, houseId)
+}
+
+// findOneHouseModel reads the house model on q, the db or the transaction of an edit.
+func findOneHouseModel(q sqlx.Queryer, houseId string) (*houseModels.HouseModel, error) {
 	query := `
 	SELECT to_jsonb("t")
 	FROM (
@@ -91,7 +111,8 @@ func (r *houseModelsRepository) FindOneHouseModel(houseId string) (*houseModels.
 					SELECT
 						"ihm"."id",
 						"ihm"."filename",
-						"ihm"."url"
+						"ihm"."url",
+						"ihm"."srcset"
 					FROM "house_model_images" "ihm"
 					WHERE "ihm"."house_model_id" = "hm"."id"
 				) AS "ihm"
@@ -119,7 +140,8 @@ func (r *houseModelsRepository) FindOneHouseModel(houseId string) (*houseModels.
 								SELECT
 									"ihmp"."id",
 									"ihmp"."filename",
-									"ihmp"."url"
+									"ihmp"."url",
+									"ihmp"."srcset"
 								FROM "house_model_plan_images" "ihmp"
 								WHERE "ihmp"."house_model_plan_id" = "hmp"."id"
 							) AS "ihmp"
@@ -130,11 +152,13 @@ func (r *houseModelsRepository) FindOneHouseModel(houseId string) (*houseModels.
 			) AS "house_plan"
 			FROM "house_models" "hm"
 		WHERE "hm"."id" = $1
+		AND "hm"."deleted_at" IS NULL
+		AND NOT EXISTS (SELECT 1 FROM "projects" "p" WHERE "p"."id" = "hm"."project_id" AND "p"."deleted_at" IS NOT NULL)
 	) AS "t";
 	`
 	var houseModelJSON []byte
 
-	err := r.db.QueryRow(query, houseId).Scan(&houseModelJSON)
+	err := q.QueryRowx(query, houseId).Scan(&houseModelJSON)
 	if err != nil {
 		return nil, fmt.Errorf("get house model failed: %v", err)
 	}
@@ -155,6 +179,12 @@ func (r *houseModelsRepository) FindHouseModel(projectId string, req *houseModel
 }
 
 func (r *houseModelsRepository) InsertHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error) {
+	slug, err := utils.UniqueSlug(r.db, "house_models", 0, req.Name, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Slug = slug
+
 	planItems := []*houseModels.HouseModelPlanItem{}
 	builder := houseModelsPatterns.InsertHouseModelBuilder(r.db, req, planItems)
 	projectId, err := houseModelsPatterns.InsertProjectEngineer(builder).InsertHouseModel()
@@ -169,26 +199,250 @@ func (r *houseModelsRepository) InsertHouseModel(req *houseModels.HouseModel) (*
 	return houseModel, nil
 }
 
-func (r *houseModelsRepository) UpdateHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error) {
-	builder := houseModelsPatterns.UpdateHouseModelBuilder(r.db, req, r.filesUsecase)
+// UpdateHouseModel writes the edit and its revision in one transaction. The
+// state before is read there too, with the row locked, so it is the one replaced.
+func (r *houseModelsRepository) UpdateHouseModel(req *houseModels.HouseModel, revise revisions.Revise) (*houseModels.HouseModel, error) {
+	// The slug only changes when one is given, so renaming keeps the links
+	if req.Slug != "" {
+		slug, err := utils.UniqueSlug(r.db, "house_models", req.Id, "", req.Slug)
+		if err != nil {
+			return nil, err
+		}
+		req.Slug = slug
+	}
+
+	houseId := strconv.Itoa(req.Id)
+	var before, after *houseModels.HouseModel
+	builder := houseModelsPatterns.UpdateHouseModelBuilder(r.db, req, r.filesUsecase, &houseModelsPatterns.UpdateHouseModelHook{
+		Begin: func(tx *sqlx.Tx) error {
+			if err := lockHouseModel(tx, req.Id); err != nil {
+				return err
+			}
+			houseModel, err := findOneHouseModel(tx, houseId)
+			if err != nil {
+				return err
+			}
+			before = houseModel
+			return nil
+		},
+		Commit: func(tx *sqlx.Tx) error {
+			houseModel, err := findOneHouseModel(tx, houseId)
+			if err != nil {
+				return err
+			}
+			after = houseModel
+			return revise(tx, before, after)
+		},
+	})
 	engineer := houseModelsPatterns.UpdateHouseModelEngineer(builder)
 
 	if err := engineer.UpdateHouseModel(); err != nil {
 		return nil, err
 	}
+	return after, nil
+}
 
-	houseModel, err := r.FindOneHouseModel(strconv.Itoa(req.Id))
-	if err != nil {
-		return nil, err
+// lockHouseModel locks the row of a house model that is not in the trash for the rest of tx.
+func lockHouseModel(tx *sqlx.Tx, houseId int) error {
+	var id int
+	if err := tx.Get(&id, `SELECT "id" FROM "house_models" WHERE "id" = $1 AND "deleted_at" IS NULL FOR UPDATE;`, houseId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("house model %d not found", houseId)
+		}
+		return fmt.Errorf("lock house model failed: %v", err)
 	}
-	return houseModel, nil
+	return nil
 }
 
 func (r *houseModelsRepository) DeleteHouseModel(houseId string) error {
-	query := `DELETE FROM "house_models" WHERE "id" = $1;`
+	query := `UPDATE "house_models" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, houseId); err != nil {
 		return fmt.Errorf("delete house_models failed: %v", err)
 	}
 	return nil
 }
+
+// RestoreHouseModel overwrites a house model, its type items, images and plans with a revision snapshot.
+func (r *houseModelsRepository) RestoreHouseModel(req *houseModels.HouseModel, revise revisions.Revise) (*houseModels.HouseModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockHouseModel(tx, req.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query := `
+	UPDATE "house_models" SET
+		"project_id" = $1,
+		"name" = $2,
+		"description" = $3,
+		"link_video" = $4,
+		"link_virtual_tour" = $5,
+		"display" = $6,
+		"index" = $7,
+		"publish_at" = $8::timestamptz,
+		"unpublish_at" = $9::timestamptz,
+		"price" = $10,
+		"phase_id" = (SELECT "id" FROM "project_phases" WHERE "id" = $12 AND "project_id" = $1)
+	WHERE "id" = $11;`
+
+	if _, err := tx.ExecContext(
+		ctx,
+		query,
+		req.ProjectId,
+		req.Name,
+		req.Description,
+		req.LinkVideo,
+		req.LinkVirtualTour,
+		req.Display,
+		req.Index,
+		req.PublishAt,
+		req.UnpublishAt,
+		req.Price,
+		req.Id,
+		req.PhaseId,
+	); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("restore house model failed: %v", err)
+	}
+
+	// Plan items and plan images are removed with their plan
+	for _, table := range []string{"house_model_type_items", "house_model_images", "house_model_plans"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE "house_model_id" = $1;`, table), req.Id); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("delete %s failed: %v", table, err)
+		}
+	}
+
+	for _, item := range req.TypeItem {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "house_model_type_items" ("house_model_id", "room_type", "amount") VALUES ($1, $2, $3);`,
+			req.Id,
+			item.RoomType,
+			item.Amount,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert type item failed: %v", err)
+		}
+	}
+
+	for _, img := range req.Images {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "house_model_images" ("filename", "url", "srcset", "house_model_id") VALUES ($1, $2, $3, $4);`,
+			img.FileName,
+			img.Url,
+			img.SrcSet,
+			req.Id,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert house model image failed: %v", err)
+		}
+	}
+
+	for _, plan := range req.HousePlan {
+		var planId int
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO "house_model_plans" ("house_model_id", "floor", "size") VALUES ($1, $2, $3) RETURNING "id";`,
+			req.Id,
+			plan.Floor,
+			plan.Size,
+		).Scan(&planId); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert house plan failed: %v", err)
+		}
+
+		for _, item := range plan.PlanItem {
+			if _, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO "house_model_plan_items" ("house_model_plan_id", "room_type", "amount") VALUES ($1, $2, $3);`,
+				planId,
+				item.RoomType,
+				item.Amount,
+			); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("insert house plan item failed: %v", err)
+			}
+		}
+
+		for _, img := range plan.Images {
+			if _, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO "house_model_plan_images" ("filename", "url", "srcset", "house_model_plan_id") VALUES ($1, $2, $3, $4);`,
+				img.FileName,
+				img.Url,
+				img.SrcSet,
+				planId,
+			); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("insert house plan image failed: %v", err)
+			}
+		}
+	}
+
+	houseModel, err := findOneHouseModel(tx, strconv.Itoa(req.Id))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := revise(tx, nil, houseModel); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return houseModel, nil
+}
+
+func (r *houseModelsRepository) UpdateHouseModelOrder(projectId int, ids []int) error {
+	return utils.Reorder(r.db, "house_models", ids, "project_id", projectId)
+}
+
+func (r *houseModelsRepository) PhaseInProject(projectId, phaseId int) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM "project_phases"
+		WHERE "id" = $1
+		AND "project_id" = $2
+	);`
+
+	var ok bool
+	if err := r.db.Get(&ok, query, phaseId, projectId); err != nil {
+		return false, fmt.Errorf("check phase failed: %v", err)
+	}
+	return ok, nil
+}
+
+// ProjectPublished reports whether the project is published and not in the trash.
+func (r *houseModelsRepository) ProjectPublished(projectId int) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM "projects"
+		WHERE "id" = $1
+		AND "display" = 'published'
+		AND "deleted_at" IS NULL
+	);`
+
+	var ok bool
+	if err := r.db.Get(&ok, query, projectId); err != nil {
+		return false, fmt.Errorf("check project failed: %v", err)
+	}
+	return ok, nil
+}
+
+func (r *houseModelsRepository) FindHouseModelSlug(slug string) (int, string, error) {
+	return utils.FindSlug(r.db, "house_models", slug)
+}
@@ -1,29 +1,44 @@
 package houseModelsUsecases
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"strconv"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/houseModels"
 	"github.com/yporn/sirarom-backend/modules/houseModels/houseModelsRepositories"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+	"github.com/yporn/sirarom-backend/modules/revisions/revisionsUsecases"
 )
 
 type IHouseModelsUsecase interface {
 	FindOneHouseModel(houseId string) (*houseModels.HouseModel, error)
-	FindAllHouseModels() ([]houseModels.HouseModelName, error) 
+	FindAllHouseModels(display string, public bool) ([]houseModels.HouseModelName, error)
+	ProjectPublished(projectId int) (bool, error)
 	FindHouseModel(projectId string, req *houseModels.HouseModelFilter) *entities.PaginateRes
 	AddHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error)
-	UpdateHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error)
+	UpdateHouseModel(req *houseModels.HouseModel, userId int) (*houseModels.HouseModel, error)
+	FindHouseModelSlug(slug string) (int, string, error)
 	DeleteHouseModel(houseId string) error
+	FindHouseModelRevisions(houseId int) ([]*revisions.Revision, error)
+	FindOneHouseModelRevision(houseId, revisionId int) (*revisions.Revision, error)
+	DiffHouseModelRevisions(houseId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error)
+	RestoreHouseModelRevision(houseId, revisionId, userId int) (*houseModels.HouseModel, error)
+	UpdateHouseModelOrder(projectId int, ids []int) error
 }
 
 type houseModelsUsecase struct {
 	houseModelsRepository houseModelsRepositories.IHouseModelsRepository
+	revisionsUsecase      revisionsUsecases.IRevisionsUsecase
 }
 
-func HouseModelsUsecases(houseModelsRepository houseModelsRepositories.IHouseModelsRepository) IHouseModelsUsecase {
+func HouseModelsUsecases(houseModelsRepository houseModelsRepositories.IHouseModelsRepository, revisionsUsecase revisionsUsecases.IRevisionsUsecase) IHouseModelsUsecase {
 	return &houseModelsUsecase{
 		houseModelsRepository: houseModelsRepository,
+		revisionsUsecase:      revisionsUsecase,
 	}
 }
 
@@ -35,14 +50,18 @@ func (u *houseModelsUsecase) FindOneHouseModel(houseId string) (*houseModels.Hou
 	return houseModel, nil
 }
 
-func (u *houseModelsUsecase) FindAllHouseModels() ([]houseModels.HouseModelName, error) {
-	houseModel, err := u.houseModelsRepository.FindAllHouseModels()
+func (u *houseModelsUsecase) FindAllHouseModels(display string, public bool) ([]houseModels.HouseModelName, error) {
+	houseModel, err := u.houseModelsRepository.FindAllHouseModels(display, public)
 	if err != nil {
 		return nil, err
 	}
 	return houseModel, nil
 }
 
+func (u *houseModelsUsecase) ProjectPublished(projectId int) (bool, error) {
+	return u.houseModelsRepository.ProjectPublished(projectId)
+}
+
 func (u *houseModelsUsecase) FindHouseModel(projectId string, req *houseModels.HouseModelFilter) *entities.PaginateRes {
 	houseModels, count := u.houseModelsRepository.FindHouseModel(projectId ,req)
 	return &entities.PaginateRes{
@@ -54,7 +73,25 @@ func (u *houseModelsUsecase) FindHouseModel(projectId string, req *houseModels.H
 	}
 }
 
+// checkPhase makes sure the phase a house model is moved to belongs to its project.
+func (u *houseModelsUsecase) checkPhase(projectId int, phaseId *int) error {
+	if phaseId == nil || *phaseId == 0 {
+		return nil
+	}
+	ok, err := u.houseModelsRepository.PhaseInProject(projectId, *phaseId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("phase %d is not in this project", *phaseId)
+	}
+	return nil
+}
+
 func (u *houseModelsUsecase) AddHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error) {
+	if err := u.checkPhase(req.ProjectId, req.PhaseId); err != nil {
+		return nil, err
+	}
 	houseModel, err := u.houseModelsRepository.InsertHouseModel(req)
 	if err != nil {
 		return nil, err
@@ -62,12 +99,23 @@ func (u *houseModelsUsecase) AddHouseModel(req *houseModels.HouseModel) (*houseM
 	return houseModel, nil
 }
 
-func (u *houseModelsUsecase) UpdateHouseModel(req *houseModels.HouseModel) (*houseModels.HouseModel, error) {
-	project, err := u.houseModelsRepository.UpdateHouseModel(req)
+func (u *houseModelsUsecase) UpdateHouseModel(req *houseModels.HouseModel, userId int) (*houseModels.HouseModel, error) {
+	current, err := u.houseModelsRepository.FindOneHouseModel(strconv.Itoa(req.Id))
 	if err != nil {
 		return nil, err
 	}
-	return project, nil
+	if err := u.checkPhase(current.ProjectId, req.PhaseId); err != nil {
+		return nil, err
+	}
+
+	return u.houseModelsRepository.UpdateHouseModel(req, u.revise(req.Id, userId))
+}
+
+// revise stores a revision of the house model in the transaction of its edit.
+func (u *houseModelsUsecase) revise(houseId, userId int) revisions.Revise {
+	return func(tx *sqlx.Tx, before, after any) error {
+		return u.revisionsUsecase.AddRevision(tx, revisions.HouseModelEntity, houseId, before, after, userId)
+	}
 }
 
 func (u *houseModelsUsecase) DeleteHouseModel(houseId string) error {
@@ -75,4 +123,42 @@ func (u *houseModelsUsecase) DeleteHouseModel(houseId string) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (u *houseModelsUsecase) FindHouseModelRevisions(houseId int) ([]*revisions.Revision, error) {
+	return u.revisionsUsecase.FindRevisions(revisions.HouseModelEntity, houseId)
+}
+
+func (u *houseModelsUsecase) FindOneHouseModelRevision(houseId, revisionId int) (*revisions.Revision, error) {
+	return u.revisionsUsecase.FindOneRevision(revisions.HouseModelEntity, houseId, revisionId)
+}
+
+func (u *houseModelsUsecase) DiffHouseModelRevisions(houseId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error) {
+	return u.revisionsUsecase.DiffRevisions(revisions.HouseModelEntity, houseId, req)
+}
+
+func (u *houseModelsUsecase) RestoreHouseModelRevision(houseId, revisionId, userId int) (*houseModels.HouseModel, error) {
+	revision, err := u.revisionsUsecase.FindOneRevision(revisions.HouseModelEntity, houseId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	req := new(houseModels.HouseModel)
+	if err := json.Unmarshal(revision.Snapshot, req); err != nil {
+		return nil, fmt.Errorf("unmarshal revision failed: %v", err)
+	}
+	req.Id = houseId
+
+	// The restore is itself a new revision so it can be undone
+	return u.houseModelsRepository.RestoreHouseModel(req, u.revise(houseId, userId))
+}
+
+func (u *houseModelsUsecase) UpdateHouseModelOrder(projectId int, ids []int) error {
+	return u.houseModelsRepository.UpdateHouseModelOrder(projectId, ids)
+}
+
+// FindHouseModelSlug returns the id and current slug of the house model with the slug
+// or with an old slug of it.
+func (u *houseModelsUsecase) FindHouseModelSlug(slug string) (int, string, error) {
+	return u.houseModelsRepository.FindHouseModelSlug(slug)
+}
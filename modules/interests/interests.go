@@ -1,23 +1,119 @@
 package interests
 
-import "github.com/yporn/sirarom-backend/modules/entities"
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
 
 type Interest struct {
-	Id           int    `db:"id" json:"id"`
-	BankName     string `db:"bank_name" json:"bank_name"`
+	Id           int     `db:"id" json:"id"`
+	BankName     string  `db:"bank_name" json:"bank_name"`
 	InterestRate float32 `db:"interest_rate" json:"interest_rate"`
-	Note         string `db:"note" json:"note"`
-	Display      string `db:"display" json:"display"`
-	CreatedAt    string `db:"created_at" json:"created_at"`
-	UpdatedAt    string `db:"updated_at" json:"updated_at"`
+	Note         string  `db:"note" json:"note"`
+	Display      string  `db:"display" json:"display"`
+	PublishAt    *string `db:"publish_at" json:"publish_at"`
+	UnpublishAt  *string `db:"unpublish_at" json:"unpublish_at"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+	UpdatedAt    string  `db:"updated_at" json:"updated_at"`
 	// FileName     string `db:"filename" json:"filename"`
 	// Url          string `db:"url" json:"url"`
-	Images      []*entities.Image `json:"images"`
+	Images []*entities.Image `json:"images"`
+	Tiers  []*InterestTier   `json:"tiers"`
+	// EffectiveFrom schedules a new interest_rate, empty means right away
+	EffectiveFrom *string         `json:"effective_from,omitempty"`
+	ChangedBy     int             `json:"-"`
+	RateHistory   []*InterestRate `json:"rate_history,omitempty"`
+}
+
+// InterestRate is a rate the bank advertised from EffectiveFrom until EffectiveTo.
+// A nil EffectiveTo is still in effect, or the last one scheduled.
+type InterestRate struct {
+	Id            int     `db:"id" json:"id"`
+	InterestId    int     `db:"interest_id" json:"interest_id"`
+	Rate          float64 `db:"rate" json:"rate"`
+	EffectiveFrom string  `db:"effective_from" json:"effective_from"`
+	EffectiveTo   *string `db:"effective_to" json:"effective_to"`
+	CreatedBy     *int    `db:"created_by" json:"created_by"`
+	CreatedByName string  `db:"created_by_name" json:"created_by_name"`
+	CreatedAt     string  `db:"created_at" json:"created_at"`
+}
+
+// ValidateEffectiveFrom checks the schedule of a rate change.
+func (i *Interest) ValidateEffectiveFrom() error {
+	if i.EffectiveFrom == nil || *i.EffectiveFrom == "" {
+		return nil
+	}
+	if i.InterestRate == 0 {
+		return fmt.Errorf("interest_rate is required with effective_from")
+	}
+	if _, err := time.Parse(time.RFC3339, *i.EffectiveFrom); err != nil {
+		return fmt.Errorf("effective_from must be RFC3339, e.g. 2024-01-02T00:00:00+07:00")
+	}
+	return nil
+}
+
+// ScheduledRate reports whether the new rate starts later rather than now.
+func (i *Interest) ScheduledRate() bool {
+	if i.EffectiveFrom == nil || *i.EffectiveFrom == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, *i.EffectiveFrom)
+	return err == nil && t.After(time.Now())
+}
+
+// InterestTier overrides InterestRate for loan years FromYear to ToYear. A nil
+// ToYear runs to the end of the loan.
+type InterestTier struct {
+	Id       int     `db:"id" json:"id"`
+	FromYear int     `db:"from_year" json:"from_year"`
+	ToYear   *int    `db:"to_year" json:"to_year"`
+	Rate     float64 `db:"rate" json:"rate"`
+}
+
+// ValidateTiers checks the tiers are well formed and do not overlap.
+func (i *Interest) ValidateTiers() error {
+	tiers := make([]*InterestTier, len(i.Tiers))
+	copy(tiers, i.Tiers)
+	sort.Slice(tiers, func(a, b int) bool { return tiers[a].FromYear < tiers[b].FromYear })
+
+	for n, t := range tiers {
+		if t.FromYear < 1 {
+			return fmt.Errorf("tier from_year must be 1 or more")
+		}
+		if t.ToYear != nil && *t.ToYear < t.FromYear {
+			return fmt.Errorf("tier to_year must not be before from_year")
+		}
+		if t.Rate < 0 {
+			return fmt.Errorf("tier rate must not be negative")
+		}
+		if n > 0 {
+			prev := tiers[n-1]
+			if prev.ToYear == nil || *prev.ToYear >= t.FromYear {
+				return fmt.Errorf("tiers overlap at year %d", t.FromYear)
+			}
+		}
+	}
+	return nil
+}
+
+// RateForYear is the yearly rate in percent for loan year (1-based).
+func (i *Interest) RateForYear(year int) float64 {
+	for _, t := range i.Tiers {
+		if year >= t.FromYear && (t.ToYear == nil || year <= *t.ToYear) {
+			return t.Rate
+		}
+	}
+	return float64(i.InterestRate)
 }
 
 type InterestFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // title & description
+	Id      string `query:"id"`
+	Search  string `query:"search"` // title & description
+	Display string `query:"display"`
+	History bool   `query:"history"` // include rate_history
 	*entities.PaginationReq
 	*entities.SortReq
 }
@@ -9,7 +9,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/interests"
 	"github.com/yporn/sirarom-backend/modules/interests/interestsUsecases"
@@ -24,14 +23,18 @@ const (
 	insertInterestErr  interestsHandlersErrCode = "interests-003"
 	deleteInterestErr  interestsHandlersErrCode = "interests-004"
 	updateInterestErr  interestsHandlersErrCode = "interests-005"
+	calculateErr       interestsHandlersErrCode = "interests-006"
 )
 
 type IInterestsHandler interface {
 	FindOneInterest(c *fiber.Ctx) error
+	FindOneAdminInterest(c *fiber.Ctx) error
 	FindInterest(c *fiber.Ctx) error
+	FindAdminInterest(c *fiber.Ctx) error
 	AddInterest(c *fiber.Ctx) error
 	DeleteInterest(c *fiber.Ctx) error
 	UpdateInterest(c *fiber.Ctx) error
+	CalculateMortgage(c *fiber.Ctx) error
 }
 
 type interestsHandler struct {
@@ -51,6 +54,14 @@ func InterestsHandler(cfg config.IConfig, interestsUsecase interestsUsecases.IIn
 }
 
 func (h *interestsHandler) FindOneInterest(c *fiber.Ctx) error {
+	return h.findOneInterest(c, true)
+}
+
+func (h *interestsHandler) FindOneAdminInterest(c *fiber.Ctx) error {
+	return h.findOneInterest(c, false)
+}
+
+func (h *interestsHandler) findOneInterest(c *fiber.Ctx, public bool) error {
 	interestId := strings.Trim(c.Params("interest_id"), " ")
 
 	interest, err := h.interestsUsecase.FindOneInterest(interestId)
@@ -61,10 +72,49 @@ func (h *interestsHandler) FindOneInterest(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && interest.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneInterestErr),
+			"interest not found",
+		).Res()
+	}
+
+	if c.QueryBool("history") {
+		rates, err := h.interestsUsecase.FindInterestRates(interestId)
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				string(findOneInterestErr),
+				err.Error(),
+			).Res()
+		}
+		interest.RateHistory = rates
+		if public {
+			hideRateEditors(interest)
+		}
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, interest).Res()
 }
 
+// hideRateEditors keeps admin names out of the public rate history.
+func hideRateEditors(interest *interests.Interest) {
+	for _, rate := range interest.RateHistory {
+		rate.CreatedBy = nil
+		rate.CreatedByName = ""
+	}
+}
+
 func (h *interestsHandler) FindInterest(c *fiber.Ctx) error {
+	return h.findInterest(c, true)
+}
+
+// FindAdminInterest returns rows in every display state, optionally filtered by ?display=.
+func (h *interestsHandler) FindAdminInterest(c *fiber.Ctx) error {
+	return h.findInterest(c, false)
+}
+
+func (h *interestsHandler) findInterest(c *fiber.Ctx, public bool) error {
 	req := &interests.InterestFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -78,6 +128,16 @@ func (h *interestsHandler) FindInterest(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findInterestErr),
+			err.Error(),
+		).Res()
+	}
+
 	if req.Page < 1 {
 		req.Page = 1
 	}
@@ -92,8 +152,15 @@ func (h *interestsHandler) FindInterest(c *fiber.Ctx) error {
 		req.Sort = "DESC"
 	}
 
-	interests := h.interestsUsecase.FindInterest(req)
-	return entities.NewResponse(c).Success(fiber.StatusOK, interests).Res()
+	res := h.interestsUsecase.FindInterest(req)
+	if public && req.History {
+		if items, ok := res.Data.([]*interests.Interest); ok {
+			for _, interest := range items {
+				hideRateEditors(interest)
+			}
+		}
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
 }
 
 func (h *interestsHandler) AddInterest(c *fiber.Ctx) error {
@@ -109,6 +176,31 @@ func (h *interestsHandler) AddInterest(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertInterestErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.ValidateTiers(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertInterestErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.ValidateEffectiveFrom(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertInterestErr),
+			err.Error(),
+		).Res()
+	}
+	req.ChangedBy = utils.GetUserIDFromContext(c)
+
 	interest, err := h.interestsUsecase.AddInterest(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -153,21 +245,7 @@ func (h *interestsHandler) DeleteInterest(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range interest.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("interests/%s", p.FileName),
-		})
-	}
-
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
-		return entities.NewResponse(c).Error(
-			fiber.ErrInternalServerError.Code,
-			string(deleteInterestErr),
-			err.Error(),
-		).Res()
-	}
-
+	// Files are kept until the item is purged from the trash
 	if err := h.interestsUsecase.DeleteInterest(interestId); err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
@@ -213,6 +291,31 @@ func (h *interestsHandler) UpdateInterest(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateInterestErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.ValidateTiers(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateInterestErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.ValidateEffectiveFrom(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateInterestErr),
+			err.Error(),
+		).Res()
+	}
+	req.ChangedBy = utils.GetUserIDFromContext(c)
 	req.Id = interestId
 
 	interest, err := h.interestsUsecase.UpdateInterest(req)
@@ -238,3 +341,32 @@ func (h *interestsHandler) UpdateInterest(c *fiber.Ctx) error {
 
 	return entities.NewResponse(c).Success(fiber.StatusOK, interest).Res()
 }
+
+func (h *interestsHandler) CalculateMortgage(c *fiber.Ctx) error {
+	req := new(interests.MortgageReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(calculateErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(calculateErr),
+			err.Error(),
+		).Res()
+	}
+
+	res, err := h.interestsUsecase.CalculateMortgage(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(calculateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
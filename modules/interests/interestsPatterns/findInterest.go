@@ -65,9 +65,48 @@ func (b *findInterestBuilder) initQuery() {
 					FROM "interest_images" "i"
 					WHERE "i"."interest_id" = "bi"."id"
 				) AS "it"
-			) AS "images"
+			) AS "images",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("rt")), '[]'::json)
+				FROM (
+					SELECT
+						"r"."id",
+						"r"."from_year",
+						"r"."to_year",
+						"r"."rate"
+					FROM "interest_rate_tiers" "r"
+					WHERE "r"."interest_id" = "bi"."id"
+					ORDER BY "r"."from_year" ASC
+				) AS "rt"
+			) AS "tiers"`
+
+	if b.req.History {
+		b.query += `,
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("rh")), '[]'::json)
+				FROM (
+					SELECT
+						"h"."id",
+						"h"."interest_id",
+						"h"."rate",
+						"h"."effective_from",
+						"h"."effective_to",
+						"h"."created_by",
+						"u"."name" AS "created_by_name",
+						"h"."created_at"
+					FROM "interest_rate_history" "h"
+					LEFT JOIN "users" "u" ON "u"."id" = "h"."created_by"
+					WHERE "h"."interest_id" = "bi"."id"
+					ORDER BY "h"."effective_from" DESC
+				) AS "rh"
+			) AS "rate_history"`
+	}
+
+	b.query += `
 		FROM "interests" "bi"
-		WHERE 1 = 1`
+		WHERE "bi"."deleted_at" IS NULL`
 }
 
 func (b *findInterestBuilder) countQuery() {
@@ -75,13 +114,21 @@ func (b *findInterestBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "interests" "bi"
-		WHERE 1 = 1`
+		WHERE "bi"."deleted_at" IS NULL`
 }
 
 func (b *findInterestBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "bi"."display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
@@ -16,6 +16,8 @@ type IInsertInterestBuidler interface {
 	commit() error
 	getInterestId() string
 	insertAttachment() error
+	insertTiers() error
+	insertRate() error
 }
 
 type insertInterestBuilder struct {
@@ -53,9 +55,11 @@ func (b *insertInterestBuilder) insertInterest() error {
 		"bank_name",
 		"interest_rate",
 		"note",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at"
 	)
-	VALUES ($1, $2, $3, $4)
+	VALUES ($1, $2, $3, $4, NULLIF($5, '')::timestamptz, NULLIF($6, '')::timestamptz)
 		RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -65,6 +69,8 @@ func (b *insertInterestBuilder) insertInterest() error {
 		b.req.InterestRate,
 		b.req.Note,
 		b.req.Display,	
+		b.req.PublishAt,
+		b.req.UnpublishAt,
 		// b.req.FileName,
 		// b.req.Url,	
 	).Scan(&b.req.Id); err != nil {
@@ -116,6 +122,25 @@ func (b *insertInterestBuilder) insertAttachment() error {
 	return nil
 }
 
+func (b *insertInterestBuilder) insertTiers() error {
+	if len(b.req.Tiers) == 0 {
+		return nil
+	}
+	if err := insertInterestTiers(b.tx, b.req.Id, b.req.Tiers); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (b *insertInterestBuilder) insertRate() error {
+	if err := insertInterestRate(b.tx, b.req.Id, float64(b.req.InterestRate), nil, b.req.ChangedBy); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
 func (b *insertInterestBuilder) commit() error {
 	if err := b.tx.Commit(); err != nil {
 		return err
@@ -143,6 +168,14 @@ func (en *insertInterestEngineer) InsertInterest() (string, error) {
 	if err := en.builder.insertAttachment(); err != nil {
 		return "", err
 	}
+
+	if err := en.builder.insertTiers(); err != nil {
+		return "", err
+	}
+
+	if err := en.builder.insertRate(); err != nil {
+		return "", err
+	}
 	
 	if err := en.builder.commit(); err != nil {
 		return "", err
@@ -0,0 +1,48 @@
+package interestsPatterns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertInterestRate adds a rate to the history of an interest, effective from
+// effectiveFrom or now when empty, then closes every row at the start of the next.
+func insertInterestRate(tx *sqlx.Tx, interestId int, rate float64, effectiveFrom *string, userId int) error {
+	ctx := context.Background()
+
+	query := `
+	INSERT INTO "interest_rate_history" (
+		"interest_id",
+		"rate",
+		"effective_from",
+		"created_by"
+	)
+	VALUES ($1, $2, COALESCE(NULLIF($3, '')::timestamptz, now()), NULLIF($4, 0))
+	ON CONFLICT ("interest_id", "effective_from") DO UPDATE SET
+		"rate" = EXCLUDED."rate",
+		"created_by" = EXCLUDED."created_by";`
+
+	if _, err := tx.ExecContext(ctx, query, interestId, rate, effectiveFrom, userId); err != nil {
+		return fmt.Errorf("insert rate history failed: %v", err)
+	}
+
+	query = `
+	UPDATE "interest_rate_history" "h" SET
+		"effective_to" = "n"."next_from"
+	FROM (
+		SELECT
+			"id",
+			LEAD("effective_from") OVER (ORDER BY "effective_from") AS "next_from"
+		FROM "interest_rate_history"
+		WHERE "interest_id" = $1
+	) AS "n"
+	WHERE "h"."id" = "n"."id"
+	AND "h"."effective_to" IS DISTINCT FROM "n"."next_from";`
+
+	if _, err := tx.ExecContext(ctx, query, interestId); err != nil {
+		return fmt.Errorf("update rate history failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package interestsPatterns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/interests"
+)
+
+// insertInterestTiers is shared by the insert and update builders.
+func insertInterestTiers(tx *sqlx.Tx, interestId int, tiers []*interests.InterestTier) error {
+	query := `
+	INSERT INTO "interest_rate_tiers" (
+		"interest_id",
+		"from_year",
+		"to_year",
+		"rate"
+	)
+	VALUES ($1, $2, $3, $4);`
+
+	for _, t := range tiers {
+		if _, err := tx.ExecContext(context.Background(), query, interestId, t.FromYear, t.ToYear, t.Rate); err != nil {
+			return fmt.Errorf("insert tiers failed: %v", err)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package interestsPatterns
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -26,6 +27,8 @@ type IUpdateInterestBuilder interface {
 	getQuery() string
 	setQuery(query string)
 	getImagesLen() int
+	replaceTiers() error
+	recordRate() error
 	commit() error
 }
 
@@ -77,7 +80,8 @@ func (b *updateInterestBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"bank_name" = $%d`, b.lastStackIndex))
 	}
 
-	if b.req.InterestRate != 0 {
+	// A scheduled rate is switched over by the scheduler when it starts
+	if b.req.InterestRate != 0 && !b.req.ScheduledRate() {
 		b.values = append(b.values, b.req.InterestRate)
 		b.lastStackIndex = len(b.values)
 
@@ -98,6 +102,25 @@ func (b *updateInterestBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"display" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	// Nothing else changed, e.g. only a scheduled rate or new tiers
+	if len(setStatements) == 0 {
+		setStatements = append(setStatements, `"updated_at" = now()`)
+	}
+
 	b.query += strings.Join(setStatements, ", ")
 }
 
@@ -203,6 +226,56 @@ func (b *updateInterestBuilder) updateInterest() error {
 	return nil
 }
 
+// replaceTiers swaps every tier for the ones in the request. A request without
+// "tiers" keeps the stored ones, an empty list removes them.
+func (b *updateInterestBuilder) replaceTiers() error {
+	if b.req.Tiers == nil {
+		return nil
+	}
+
+	if _, err := b.tx.ExecContext(context.Background(), `DELETE FROM "interest_rate_tiers" WHERE "interest_id" = $1;`, b.req.Id); err != nil {
+		b.tx.Rollback()
+		return fmt.Errorf("delete tiers failed: %v", err)
+	}
+	if err := insertInterestTiers(b.tx, b.req.Id, b.req.Tiers); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// recordRate adds the new rate to the history before the row is updated. A rate
+// equal to the current one is not a change unless it is scheduled.
+func (b *updateInterestBuilder) recordRate() error {
+	if b.req.InterestRate == 0 {
+		return nil
+	}
+
+	if !b.req.ScheduledRate() {
+		var current sql.NullFloat64
+		if err := b.tx.GetContext(
+			context.Background(),
+			&current,
+			`SELECT "interest_rate" FROM "interests" WHERE "id" = $1 FOR UPDATE;`,
+			b.req.Id,
+		); err != nil {
+			b.tx.Rollback()
+			return fmt.Errorf("get interest rate failed: %v", err)
+		}
+		if current.Valid && float32(current.Float64) == b.req.InterestRate {
+			return nil
+		}
+		// Right away, whatever past date was sent
+		b.req.EffectiveFrom = nil
+	}
+
+	if err := insertInterestRate(b.tx, b.req.Id, float64(b.req.InterestRate), b.req.EffectiveFrom, b.req.ChangedBy); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
 func (b *updateInterestBuilder) getQueryFields() []string { return b.queryFields }
 func (b *updateInterestBuilder) getValues() []any         { return b.values }
 func (b *updateInterestBuilder) getQuery() string         { return b.query }
@@ -243,6 +316,10 @@ func (en *updateInterestEngineer) UpdateInterest() error {
 
 	fmt.Println(en.builder.getQuery())
 
+	if err := en.builder.recordRate(); err != nil {
+		return err
+	}
+
 	if err := en.builder.updateInterest(); err != nil {
 		return err
 	}
@@ -256,6 +333,10 @@ func (en *updateInterestEngineer) UpdateInterest() error {
 		}
 	}
 
+	if err := en.builder.replaceTiers(); err != nil {
+		return err
+	}
+
 	// Commit
 	if err := en.builder.commit(); err != nil {
 		return err
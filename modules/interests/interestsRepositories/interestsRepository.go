@@ -2,12 +2,15 @@ package interestsRepositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/interests"
 	"github.com/yporn/sirarom-backend/modules/interests/interestsPatterns"
@@ -19,6 +22,9 @@ type IInterestRepository interface {
 	InsertInterest(req *interests.Interest) (*interests.Interest, error)
 	UpdateInterest(req *interests.Interest) (*interests.Interest, error)
 	DeleteInterest(interestId string) error
+	FindMortgagePrice(houseModelId, projectId int) (float64, error)
+	FindMortgageRates() ([]*interests.Interest, error)
+	FindInterestRates(interestId string) ([]*interests.InterestRate, error)
 }
 
 type interestsRepository struct {
@@ -53,9 +59,24 @@ func (r *interestsRepository) FindOneInterest(interestId string) (*interests.Int
 					FROM "interest_images" "i"
 					WHERE "i"."interest_id" = "bi"."id"
 				) AS "it"
-			) AS "images"
+			) AS "images",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("rt")), '[]'::json)
+				FROM (
+					SELECT
+						"r"."id",
+						"r"."from_year",
+						"r"."to_year",
+						"r"."rate"
+					FROM "interest_rate_tiers" "r"
+					WHERE "r"."interest_id" = "bi"."id"
+					ORDER BY "r"."from_year" ASC
+				) AS "rt"
+			) AS "tiers"
 		FROM "interests" "bi"
 		WHERE "id" = $1
+		AND "deleted_at" IS NULL
 		LIMIT 1
 	) AS "t";`
 
@@ -112,10 +133,109 @@ func (r *interestsRepository) UpdateInterest(req *interests.Interest) (*interest
 }
 
 func (r *interestsRepository) DeleteInterest(interestId string) error {
-	query := `DELETE FROM "interests" WHERE "id" = $1;`
+	query := `UPDATE "interests" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, interestId); err != nil {
 		return fmt.Errorf("delete interest failed: %v", err)
 	}
 	return nil
 }
+
+// FindMortgagePrice is the price of a published house model, or of its project
+// when the house model has none, else the price of a published project.
+func (r *interestsRepository) FindMortgagePrice(houseModelId, projectId int) (float64, error) {
+	query := `
+	SELECT
+		COALESCE("p"."price", 0)
+	FROM "projects" "p"
+	WHERE "p"."id" = $1
+	AND "p"."display" = $2
+	AND "p"."deleted_at" IS NULL;`
+	args := []any{projectId, entities.DisplayPublished}
+
+	if houseModelId != 0 {
+		query = `
+		SELECT
+			COALESCE("hm"."price", "p"."price", 0)
+		FROM "house_models" "hm"
+		JOIN "projects" "p" ON "p"."id" = "hm"."project_id"
+		WHERE "hm"."id" = $1
+		AND "hm"."display" = $2
+		AND "hm"."deleted_at" IS NULL;`
+		args = []any{houseModelId, entities.DisplayPublished}
+	}
+
+	var price float64
+	if err := r.db.Get(&price, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("house model or project not found")
+		}
+		return 0, fmt.Errorf("get price failed: %v", err)
+	}
+	return price, nil
+}
+
+// FindMortgageRates lists the published banks with their tiers.
+func (r *interestsRepository) FindMortgageRates() ([]*interests.Interest, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"bi"."id",
+			"bi"."bank_name",
+			"bi"."interest_rate",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("rt")), '[]'::json)
+				FROM (
+					SELECT
+						"r"."id",
+						"r"."from_year",
+						"r"."to_year",
+						"r"."rate"
+					FROM "interest_rate_tiers" "r"
+					WHERE "r"."interest_id" = "bi"."id"
+					ORDER BY "r"."from_year" ASC
+				) AS "rt"
+			) AS "tiers"
+		FROM "interests" "bi"
+		WHERE "bi"."display" = $1
+		AND "bi"."deleted_at" IS NULL
+		ORDER BY "bi"."id" ASC
+	) AS "t";`
+
+	bytes := make([]byte, 0)
+	rates := make([]*interests.Interest, 0)
+	if err := r.db.Get(&bytes, query, entities.DisplayPublished); err != nil {
+		return nil, fmt.Errorf("find interest rates failed: %v", err)
+	}
+	if err := json.Unmarshal(bytes, &rates); err != nil {
+		return nil, fmt.Errorf("unmarshal interest rates failed: %v", err)
+	}
+	return rates, nil
+}
+
+// FindInterestRates is the rate history of a bank, latest first, scheduled rates included.
+func (r *interestsRepository) FindInterestRates(interestId string) ([]*interests.InterestRate, error) {
+	query := `
+	SELECT
+		"h"."id",
+		"h"."interest_id",
+		"h"."rate",
+		"h"."effective_from",
+		"h"."effective_to",
+		"h"."created_by",
+		COALESCE("u"."name", '') AS "created_by_name",
+		"h"."created_at"
+	FROM "interest_rate_history" "h"
+	LEFT JOIN "users" "u" ON "u"."id" = "h"."created_by"
+	WHERE "h"."interest_id" = $1
+	ORDER BY "h"."effective_from" DESC;`
+
+	rates := make([]*interests.InterestRate, 0)
+	if err := r.db.Select(&rates, query, interestId); err != nil {
+		return nil, fmt.Errorf("find rate history failed: %v", err)
+	}
+	return rates, nil
+}
@@ -14,6 +14,8 @@ type IInterestsUsecase interface {
 	AddInterest(req *interests.Interest) (*interests.Interest, error)
 	UpdateInterest(req *interests.Interest) (*interests.Interest, error)
 	DeleteInterest(interestId string) error
+	CalculateMortgage(req *interests.MortgageReq) (*interests.MortgageRes, error)
+	FindInterestRates(interestId string) ([]*interests.InterestRate, error)
 }
 
 type interestsUsecase struct {
@@ -68,3 +70,7 @@ func (u *interestsUsecase) DeleteInterest(interestId string) error {
 	}
 	return nil
 }
+
+func (u *interestsUsecase) FindInterestRates(interestId string) ([]*interests.InterestRate, error) {
+	return u.interestsRepository.FindInterestRates(interestId)
+}
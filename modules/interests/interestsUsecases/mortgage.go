@@ -0,0 +1,147 @@
+package interestsUsecases
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/yporn/sirarom-backend/modules/interests"
+)
+
+// CalculateMortgage works out the loan of every published bank and picks the
+// one with the lowest total interest.
+func (u *interestsUsecase) CalculateMortgage(req *interests.MortgageReq) (*interests.MortgageRes, error) {
+	price := req.Price
+	if price <= 0 {
+		p, err := u.interestsRepository.FindMortgagePrice(req.HouseModelId, req.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		price = p
+	}
+	if price <= 0 {
+		return nil, fmt.Errorf("price is not set for this house model or project")
+	}
+
+	downPayment := req.DownPayment
+	if downPayment == 0 {
+		downPayment = price * req.DownPaymentPercent / 100
+	}
+	if downPayment >= price {
+		return nil, fmt.Errorf("down_payment must be less than the price")
+	}
+
+	rates, err := u.interestsRepository.FindMortgageRates()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &interests.MortgageRes{
+		Price:       roundMoney(price),
+		DownPayment: roundMoney(downPayment),
+		LoanAmount:  roundMoney(price - downPayment),
+		TermYears:   req.TermYears,
+		Options:     make([]*interests.MortgageOption, 0, len(rates)),
+	}
+	for _, rate := range rates {
+		option := amortize(rate, price-downPayment, req.TermYears, req.Schedule == "monthly")
+		res.Options = append(res.Options, option)
+		if res.Best == nil || option.TotalInterest < res.Best.TotalInterest {
+			res.Best = option
+		}
+	}
+	return res, nil
+}
+
+// amortize builds the schedule of an annuity loan. The installment is worked out
+// again for the remaining balance whenever a tier changes the rate.
+func amortize(rate *interests.Interest, loan float64, termYears int, monthly bool) *interests.MortgageOption {
+	option := &interests.MortgageOption{
+		InterestId:   rate.Id,
+		BankName:     rate.BankName,
+		InterestRate: float64(rate.InterestRate),
+		Tiers:        rate.Tiers,
+		Schedule:     make([]*interests.MortgageInstallment, 0),
+	}
+	if option.Tiers == nil {
+		option.Tiers = make([]*interests.InterestTier, 0)
+	}
+
+	months := termYears * 12
+	balance := loan
+	var payment, currentRate float64
+	var year *interests.MortgageInstallment
+
+	for m := 1; m <= months; m++ {
+		yearRate := rate.RateForYear((m-1)/12 + 1)
+		if m == 1 || yearRate != currentRate {
+			currentRate = yearRate
+			payment = annuity(balance, currentRate/1200, months-m+1)
+		}
+
+		interest := balance * currentRate / 1200
+		principal := payment - interest
+		if m == months {
+			principal = balance
+		}
+		balance -= principal
+		paid := principal + interest
+
+		if m == 1 {
+			option.MonthlyPayment = roundMoney(paid)
+		}
+		option.TotalPayment += paid
+		option.TotalInterest += interest
+
+		if monthly {
+			option.Schedule = append(option.Schedule, &interests.MortgageInstallment{
+				Period:    m,
+				Rate:      currentRate,
+				Payment:   roundMoney(paid),
+				Principal: roundMoney(principal),
+				Interest:  roundMoney(interest),
+				Balance:   roundMoney(balance),
+			})
+			continue
+		}
+
+		if (m-1)%12 == 0 {
+			year = &interests.MortgageInstallment{
+				Period: (m-1)/12 + 1,
+				Rate:   currentRate,
+			}
+			option.Schedule = append(option.Schedule, year)
+		}
+		year.Payment += paid
+		year.Principal += principal
+		year.Interest += interest
+		year.Balance = balance
+	}
+
+	if !monthly {
+		for _, y := range option.Schedule {
+			y.Payment = roundMoney(y.Payment)
+			y.Principal = roundMoney(y.Principal)
+			y.Interest = roundMoney(y.Interest)
+			y.Balance = roundMoney(y.Balance)
+		}
+	}
+	option.TotalPayment = roundMoney(option.TotalPayment)
+	option.TotalInterest = roundMoney(option.TotalInterest)
+	return option
+}
+
+// annuity is the fixed installment paying off balance in n months at monthly rate r.
+func annuity(balance, r float64, n int) float64 {
+	if r == 0 {
+		return balance / float64(n)
+	}
+	return balance * r / (1 - math.Pow(1+r, -float64(n)))
+}
+
+func roundMoney(v float64) float64 {
+	r := math.Round(v*100) / 100
+	if r == 0 {
+		return 0 // no -0 in the json
+	}
+	return r
+}
@@ -0,0 +1,103 @@
+package interestsUsecases
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yporn/sirarom-backend/modules/interests"
+)
+
+func TestAmortize(t *testing.T) {
+	one := 1
+	tests := []struct {
+		name        string
+		rate        *interests.Interest
+		loan        float64
+		termYears   int
+		monthly     bool
+		wantPeriods int
+		wantFirst   float64 // first monthly installment
+		wantRates   []float64
+	}{
+		{
+			name:        "no interest",
+			rate:        &interests.Interest{InterestRate: 0},
+			loan:        120000,
+			termYears:   1,
+			monthly:     true,
+			wantPeriods: 12,
+			wantFirst:   10000,
+		},
+		{
+			name:        "fixed rate",
+			rate:        &interests.Interest{InterestRate: 6},
+			loan:        1000000,
+			termYears:   30,
+			wantPeriods: 30,
+			wantFirst:   5995.51,
+		},
+		{
+			name: "tiered rate",
+			rate: &interests.Interest{
+				InterestRate: 6,
+				Tiers:        []*interests.InterestTier{{FromYear: 1, ToYear: &one, Rate: 3}},
+			},
+			loan:        1000000,
+			termYears:   2,
+			wantPeriods: 2,
+			wantFirst:   42981.21,
+			wantRates:   []float64{3, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option := amortize(tt.rate, tt.loan, tt.termYears, tt.monthly)
+
+			if len(option.Schedule) != tt.wantPeriods {
+				t.Fatalf("got %d periods, want %d", len(option.Schedule), tt.wantPeriods)
+			}
+			if option.MonthlyPayment != tt.wantFirst {
+				t.Errorf("monthly payment = %v, want %v", option.MonthlyPayment, tt.wantFirst)
+			}
+			if last := option.Schedule[len(option.Schedule)-1]; last.Balance != 0 {
+				t.Errorf("last balance = %v, want 0", last.Balance)
+			}
+
+			var principal, interest float64
+			for n, period := range option.Schedule {
+				principal += period.Principal
+				interest += period.Interest
+				if n < len(tt.wantRates) && period.Rate != tt.wantRates[n] {
+					t.Errorf("period %d rate = %v, want %v", period.Period, period.Rate, tt.wantRates[n])
+				}
+			}
+			if math.Abs(principal-tt.loan) > 0.01*float64(len(option.Schedule)) {
+				t.Errorf("principal paid = %v, want %v", principal, tt.loan)
+			}
+			if math.Abs(option.TotalPayment-option.TotalInterest-tt.loan) > 0.01 {
+				t.Errorf("total payment %v less total interest %v is not the loan %v", option.TotalPayment, option.TotalInterest, tt.loan)
+			}
+			if math.Abs(interest-option.TotalInterest) > 0.01*float64(len(option.Schedule)) {
+				t.Errorf("interest of the schedule = %v, want %v", interest, option.TotalInterest)
+			}
+		})
+	}
+}
+
+func TestAmortizeRecomputesOnTierChange(t *testing.T) {
+	one := 1
+	rate := &interests.Interest{
+		InterestRate: 6,
+		Tiers:        []*interests.InterestTier{{FromYear: 1, ToYear: &one, Rate: 3}},
+	}
+	option := amortize(rate, 1000000, 2, true)
+
+	first, thirteenth := option.Schedule[0].Payment, option.Schedule[12].Payment
+	if thirteenth <= first {
+		t.Errorf("installment after the rate rises = %v, want more than %v", thirteenth, first)
+	}
+	if option.Schedule[11].Payment != first {
+		t.Errorf("installment in the first tier changed from %v to %v", first, option.Schedule[11].Payment)
+	}
+}
@@ -0,0 +1,68 @@
+package interests
+
+import "fmt"
+
+// MortgageReq asks for the loan of a house model or project price, or of an
+// explicit price. DownPayment is an amount, DownPaymentPercent is used when it is 0.
+type MortgageReq struct {
+	HouseModelId       int     `json:"house_model_id"`
+	ProjectId          int     `json:"project_id"`
+	Price              float64 `json:"price"`
+	DownPayment        float64 `json:"down_payment"`
+	DownPaymentPercent float64 `json:"down_payment_percent"`
+	TermYears          int     `json:"term_years"`
+	Schedule           string  `json:"schedule"` // yearly (default) | monthly
+}
+
+func (r *MortgageReq) Validate() error {
+	if r.HouseModelId == 0 && r.ProjectId == 0 && r.Price <= 0 {
+		return fmt.Errorf("house_model_id, project_id or price is required")
+	}
+	if r.Price < 0 || r.DownPayment < 0 {
+		return fmt.Errorf("price and down_payment must not be negative")
+	}
+	if r.DownPaymentPercent < 0 || r.DownPaymentPercent >= 100 {
+		return fmt.Errorf("down_payment_percent must be between 0 and 100")
+	}
+	if r.TermYears < 1 || r.TermYears > 40 {
+		return fmt.Errorf("term_years must be between 1 and 40")
+	}
+	switch r.Schedule {
+	case "":
+		r.Schedule = "yearly"
+	case "yearly", "monthly":
+	default:
+		return fmt.Errorf("schedule must be yearly or monthly")
+	}
+	return nil
+}
+
+type MortgageRes struct {
+	Price       float64           `json:"price"`
+	DownPayment float64           `json:"down_payment"`
+	LoanAmount  float64           `json:"loan_amount"`
+	TermYears   int               `json:"term_years"`
+	Options     []*MortgageOption `json:"options"`
+	Best        *MortgageOption   `json:"best"` // lowest total interest
+}
+
+type MortgageOption struct {
+	InterestId     int                    `json:"interest_id"`
+	BankName       string                 `json:"bank_name"`
+	InterestRate   float64                `json:"interest_rate"`
+	Tiers          []*InterestTier        `json:"tiers"`
+	MonthlyPayment float64                `json:"monthly_payment"` // first installment
+	TotalPayment   float64                `json:"total_payment"`
+	TotalInterest  float64                `json:"total_interest"`
+	Schedule       []*MortgageInstallment `json:"schedule"`
+}
+
+// MortgageInstallment is one month, or one year summed up, of the schedule.
+type MortgageInstallment struct {
+	Period    int     `json:"period"` // month or year number, from 1
+	Rate      float64 `json:"rate"`
+	Payment   float64 `json:"payment"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Balance   float64 `json:"balance"`
+}
@@ -1,25 +1,158 @@
 package jobs
 
-import "github.com/yporn/sirarom-backend/modules/entities"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
+
+// Values of the status_career type.
+const (
+	StatusOpening = "opening"
+	StatusClosed  = "closed"
+)
+
+// Stages an applicant moves through.
+const (
+	StageNew       = "new"
+	StageScreening = "screening"
+	StageInterview = "interview"
+	StageOffer     = "offer"
+	StageRejected  = "rejected"
+)
 
 type Job struct {
-	Id            int    `db:"id" json:"id"`
-	Position      string `db:"position" json:"position"`
-	Amount        int    `db:"amount" json:"amount"`
-	Location      string `db:"location" json:"location"`
-	Description   string `db:"description" json:"description"`
-	Qualification string `db:"qualification" json:"qualification"`
-	StartDate     string `db:"start_date" json:"start_date"`
-	EndDate       string `db:"end_date" json:"end_date"`
-	Status        string `db:"status" json:"status"`
-	Display       string `db:"display" json:"display"`
-	CreatedAt     string `db:"created_at" json:"created_at"`
-	UpdatedAt     string `db:"updated_at" json:"updated_at"`
+	Id            int     `db:"id" json:"id"`
+	Position      string  `db:"position" json:"position"`
+	Amount        int     `db:"amount" json:"amount"`
+	Location      string  `db:"location" json:"location"`
+	Description   string  `db:"description" json:"description"`
+	Qualification string  `db:"qualification" json:"qualification"`
+	StartDate     string  `db:"start_date" json:"start_date"`
+	EndDate       string  `db:"end_date" json:"end_date"`
+	Status        string  `db:"status" json:"status"`
+	Display       string  `db:"display" json:"display"`
+	PublishAt     *string `db:"publish_at" json:"publish_at"`
+	UnpublishAt   *string `db:"unpublish_at" json:"unpublish_at"`
+	CreatedAt     string  `db:"created_at" json:"created_at"`
+	UpdatedAt     string  `db:"updated_at" json:"updated_at"`
 }
 
 type JobFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"`
+	Id       string `query:"id"`
+	Search   string `query:"search"`
+	Display  string `query:"display"`
+	Status   string `query:"status"`
+	ActiveOn string `query:"active_on"` // YYYY-MM-DD within start_date and end_date
 	*entities.PaginationReq
 	*entities.SortReq
 }
+
+func (f *JobFilter) Validate() error {
+	switch f.Status {
+	case "", StatusOpening, StatusClosed:
+	default:
+		return fmt.Errorf("status is invalid: %s", f.Status)
+	}
+	if f.ActiveOn != "" {
+		if _, err := time.Parse("2006-01-02", f.ActiveOn); err != nil {
+			return fmt.Errorf("active_on must be YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+type Application struct {
+	Id             int                `db:"id" json:"id"`
+	JobId          int                `db:"career_id" json:"job_id"`
+	Position       string             `db:"position" json:"position"`
+	Name           string             `db:"name" json:"name"`
+	Email          string             `db:"email" json:"email"`
+	Tel            string             `db:"tel" json:"tel"`
+	CoverLetter    string             `db:"cover_letter" json:"cover_letter"`
+	ResumeFilename string             `db:"resume_filename" json:"resume_filename"`
+	ResumeUrl      string             `json:"resume_url"` // admin download link, see ResumeUrl
+	Stage          string             `db:"stage" json:"stage"`
+	CreatedAt      string             `db:"created_at" json:"created_at"`
+	UpdatedAt      string             `db:"updated_at" json:"updated_at"`
+	Notes          []*ApplicationNote `json:"notes,omitempty"`
+}
+
+// ResumeUrl is the admin route the résumé of an application is downloaded
+// from, the file itself is not public.
+func ResumeUrl(appUrl string, applicationId int) string {
+	return fmt.Sprintf("%s/v1/jobs/admin/applications/%d/resume", appUrl, applicationId)
+}
+
+// Resume is a stored résumé file of an application.
+type Resume struct {
+	Filename string `db:"resume_filename"`
+	Path     string `db:"resume_path"`
+}
+
+// ApplicationReq is the public apply form, sent as multipart with the résumé
+// in the resume field.
+type ApplicationReq struct {
+	JobId          int    `form:"-"`
+	Name           string `form:"name"`
+	Email          string `form:"email"`
+	Tel            string `form:"tel"`
+	CoverLetter    string `form:"cover_letter"`
+	ResumeFilename string `form:"-"`
+	ResumePath     string `form:"-"`
+	Ip             string `form:"-"`
+}
+
+func (r *ApplicationReq) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Email = strings.TrimSpace(r.Email)
+	r.Tel = strings.TrimSpace(r.Tel)
+	r.CoverLetter = strings.TrimSpace(r.CoverLetter)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Email == "" || !strings.Contains(r.Email, "@") {
+		return fmt.Errorf("email is invalid")
+	}
+	if r.Tel == "" {
+		return fmt.Errorf("tel is required")
+	}
+	return nil
+}
+
+// ResumeExtensions are the résumé uploads accepted, with the leading bytes
+// every file of the type starts with. A docx is a zip archive.
+var ResumeExtensions = map[string]string{
+	"pdf":  "%PDF-",
+	"docx": "PK\x03\x04",
+}
+
+type ApplicationFilter struct {
+	JobId  int    `query:"-"`
+	Stage  string `query:"stage"`
+	Search string `query:"search"` // name, email & tel
+	*entities.PaginationReq
+}
+
+type ApplicationStageReq struct {
+	Stage string `json:"stage"`
+}
+
+func ValidateStage(stage string) error {
+	switch stage {
+	case StageNew, StageScreening, StageInterview, StageOffer, StageRejected:
+		return nil
+	}
+	return fmt.Errorf("stage is invalid: %s", stage)
+}
+
+type ApplicationNote struct {
+	Id            int    `db:"id" json:"id"`
+	ApplicationId int    `db:"application_id" json:"application_id"`
+	Note          string `db:"note" json:"note"`
+	CreatedBy     *int   `db:"created_by" json:"created_by"`
+	CreatedByName string `db:"created_by_name" json:"created_by_name"`
+	CreatedAt     string `db:"created_at" json:"created_at"`
+}
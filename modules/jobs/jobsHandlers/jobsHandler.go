@@ -1,14 +1,21 @@
 package jobsHandlers
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/jobs"
 	"github.com/yporn/sirarom-backend/modules/jobs/jobsUsecases"
 	"github.com/yporn/sirarom-backend/pkg/utils"
@@ -22,14 +29,31 @@ const (
 	insertJobErr  jobsHandlersErrCode = "jobs-003"
 	updateJobErr  jobsHandlersErrCode = "jobs-004"
 	deleteJobErr  jobsHandlersErrCode = "jobs-005"
+
+	applyJobErr               jobsHandlersErrCode = "jobs-006"
+	findOneApplicationErr     jobsHandlersErrCode = "jobs-007"
+	findApplicationErr        jobsHandlersErrCode = "jobs-008"
+	updateApplicationStageErr jobsHandlersErrCode = "jobs-009"
+	addApplicationNoteErr     jobsHandlersErrCode = "jobs-010"
+	exportApplicationErr      jobsHandlersErrCode = "jobs-011"
+	downloadResumeErr         jobsHandlersErrCode = "jobs-012"
 )
 
 type IJobsHandler interface {
 	FindOneJob(c *fiber.Ctx) error
+	FindOneAdminJob(c *fiber.Ctx) error
 	FindJob(c *fiber.Ctx) error
+	FindAdminJob(c *fiber.Ctx) error
 	AddJob(c *fiber.Ctx) error
 	UpdateJob(c *fiber.Ctx) error
 	DeleteJob(c *fiber.Ctx) error
+	ApplyJob(c *fiber.Ctx) error
+	FindOneApplication(c *fiber.Ctx) error
+	DownloadResume(c *fiber.Ctx) error
+	FindApplication(c *fiber.Ctx) error
+	UpdateApplicationStage(c *fiber.Ctx) error
+	AddApplicationNote(c *fiber.Ctx) error
+	ExportApplication(c *fiber.Ctx) error
 }
 
 type jobsHandler struct {
@@ -47,6 +71,14 @@ func JobsHandler(cfg config.IConfig, jobsUsecase jobsUsecases.IJobsUsecase, db *
 }
 
 func (h *jobsHandler) FindOneJob(c *fiber.Ctx) error {
+	return h.findOneJob(c, true)
+}
+
+func (h *jobsHandler) FindOneAdminJob(c *fiber.Ctx) error {
+	return h.findOneJob(c, false)
+}
+
+func (h *jobsHandler) findOneJob(c *fiber.Ctx, public bool) error {
 	jobId := strings.Trim(c.Params("job_id"), " ")
 
 	job, err := h.jobsUsecase.FindOneJob(jobId)
@@ -57,10 +89,26 @@ func (h *jobsHandler) FindOneJob(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && job.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneJobErr),
+			"job not found",
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, job).Res()
 }
 
 func (h *jobsHandler) FindJob(c *fiber.Ctx) error {
+	return h.findJob(c, true)
+}
+
+// FindAdminJob returns rows in every display state, optionally filtered by ?display=.
+func (h *jobsHandler) FindAdminJob(c *fiber.Ctx) error {
+	return h.findJob(c, false)
+}
+
+func (h *jobsHandler) findJob(c *fiber.Ctx, public bool) error {
 	req := &jobs.JobFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -74,6 +122,24 @@ func (h *jobsHandler) FindJob(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findJobErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findJobErr),
+			err.Error(),
+		).Res()
+	}
+
 	if req.Page < 1 {
 		req.Page = 1
 	}
@@ -104,6 +170,14 @@ func (h *jobsHandler) AddJob(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertJobErr),
+			err.Error(),
+		).Res()
+	}
+
 	job, err := h.jobsUsecase.AddJob(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -148,6 +222,14 @@ func (h *jobsHandler) UpdateJob(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateJobErr),
+			err.Error(),
+		).Res()
+	}
+
 	req.Id = jobId
 
 	job, err := h.jobsUsecase.UpdateJob(req)
@@ -206,3 +288,342 @@ func (h *jobsHandler) DeleteJob(c *fiber.Ctx) error {
 
 	return entities.NewResponse(c).Success(fiber.StatusOK, "deleted").Res()
 }
+
+// ApplyJob takes the multipart apply form of a job with the résumé, a pdf or
+// docx file, in the resume field.
+func (h *jobsHandler) ApplyJob(c *fiber.Ctx) error {
+	jobId, err := strconv.Atoi(strings.Trim(c.Params("job_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(jobs.ApplicationReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+	req.JobId = jobId
+	req.Ip = c.IP()
+
+	file, err := c.FormFile("resume")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			"resume is required",
+		).Res()
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+	signature, ok := jobs.ResumeExtensions[ext]
+	if !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			"resume must be a pdf or docx file",
+		).Res()
+	}
+
+	if file.Size > int64(h.cfg.App().FileLimit()) {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			fmt.Sprintf("file size must less than %d MiB", int(math.Ceil(float64(h.cfg.App().FileLimit())/math.Pow(1024, 2)))),
+		).Res()
+	}
+
+	container, err := file.Open()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+	data, err := io.ReadAll(container)
+	container.Close()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(applyJobErr),
+			"resume must be a pdf or docx file",
+		).Res()
+	}
+
+	fileName := utils.RandFileName(ext)
+	resume := &files.FileReq{
+		Destination: fmt.Sprintf("resumes/%d/%s", jobId, fileName),
+		FileName:    fileName,
+		Extension:   ext,
+		Data:        data,
+	}
+
+	application, err := h.jobsUsecase.ApplyJob(req, resume)
+	if err != nil {
+		code := fiber.ErrInternalServerError.Code
+		if errors.Is(err, jobsUsecases.ErrJobClosed) {
+			code = fiber.ErrBadRequest.Code
+		}
+		return entities.NewResponse(c).Error(
+			code,
+			string(applyJobErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	if err := utils.LogSystemActivity(h.db, "created", "ผู้สมัครงานตำแหน่ง "+application.Position+" : "+application.Name); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			"Failed to log activity",
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, fiber.Map{"stage": application.Stage}).Res()
+}
+
+func (h *jobsHandler) FindOneApplication(c *fiber.Ctx) error {
+	applicationId, err := strconv.Atoi(strings.Trim(c.Params("application_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneApplicationErr),
+			err.Error(),
+		).Res()
+	}
+
+	application, err := h.jobsUsecase.FindOneApplication(applicationId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneApplicationErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, application).Res()
+}
+
+// DownloadResume sends the résumé file of an application as an attachment.
+func (h *jobsHandler) DownloadResume(c *fiber.Ctx) error {
+	applicationId, err := strconv.Atoi(strings.Trim(c.Params("application_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(downloadResumeErr),
+			err.Error(),
+		).Res()
+	}
+
+	resume, data, err := h.jobsUsecase.DownloadResume(applicationId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(downloadResumeErr),
+			err.Error(),
+		).Res()
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(resume.Path))
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, resume.Filename))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Status(fiber.StatusOK).Send(data)
+}
+
+func parseApplicationFilter(c *fiber.Ctx) (*jobs.ApplicationFilter, error) {
+	jobId, err := strconv.Atoi(strings.Trim(c.Params("job_id"), " "))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &jobs.ApplicationFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return nil, err
+	}
+	if req.Stage != "" {
+		if err := jobs.ValidateStage(req.Stage); err != nil {
+			return nil, err
+		}
+	}
+	req.JobId = jobId
+	return req, nil
+}
+
+// FindApplication lists the applicants of a job, optionally filtered by ?stage=.
+func (h *jobsHandler) FindApplication(c *fiber.Ctx) error {
+	req, err := parseApplicationFilter(c)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findApplicationErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	applications := h.jobsUsecase.FindApplication(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, applications).Res()
+}
+
+func (h *jobsHandler) UpdateApplicationStage(c *fiber.Ctx) error {
+	applicationId, err := strconv.Atoi(strings.Trim(c.Params("application_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateApplicationStageErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(jobs.ApplicationStageReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateApplicationStageErr),
+			err.Error(),
+		).Res()
+	}
+	if err := jobs.ValidateStage(req.Stage); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateApplicationStageErr),
+			err.Error(),
+		).Res()
+	}
+
+	application, err := h.jobsUsecase.UpdateApplicationStage(applicationId, req.Stage)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateApplicationStageErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "เปลี่ยนสถานะผู้สมัครงานเป็น "+application.Stage+" : "+application.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, application).Res()
+}
+
+func (h *jobsHandler) AddApplicationNote(c *fiber.Ctx) error {
+	applicationId, err := strconv.Atoi(strings.Trim(c.Params("application_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addApplicationNoteErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(jobs.ApplicationNote)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addApplicationNoteErr),
+			err.Error(),
+		).Res()
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addApplicationNoteErr),
+			"note is required",
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	req.ApplicationId = applicationId
+	req.CreatedBy = &userID
+
+	application, err := h.jobsUsecase.AddApplicationNote(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(addApplicationNoteErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "เพิ่มบันทึกผู้สมัครงาน : "+application.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, application).Res()
+}
+
+// ExportApplication downloads the applicants of a job matching the list filters as a .csv file.
+func (h *jobsHandler) ExportApplication(c *fiber.Ctx) error {
+	req, err := parseApplicationFilter(c)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(exportApplicationErr),
+			err.Error(),
+		).Res()
+	}
+
+	data, err := h.jobsUsecase.ExportApplications(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(exportApplicationErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="applications-%d.csv"`, req.JobId))
+	return c.Status(fiber.StatusOK).Send(data)
+}
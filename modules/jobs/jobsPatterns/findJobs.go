@@ -54,7 +54,7 @@ func (b *findJobBuilder) initQuery() {
 		SELECT 
 			*
 		FROM "careers"
-		WHERE 1=1
+		WHERE "deleted_at" IS NULL
 	`
 }
 func (b *findJobBuilder) countQuery() {
@@ -62,12 +62,20 @@ func (b *findJobBuilder) countQuery() {
 	SELECT
 		COUNT(*) AS "count"
 	FROM "careers"
-	WHERE 1 = 1`
+	WHERE "deleted_at" IS NULL`
 }
 func (b *findJobBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
@@ -76,6 +84,22 @@ func (b *findJobBuilder) whereQuery() {
 		AND "id" = ?`)
 	}
 
+	// Status check
+	if b.req.Status != "" {
+		b.values = append(b.values, b.req.Status)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "status" = ?`)
+	}
+
+	// Active on date check, an empty start_date or end_date leaves that side open
+	if b.req.ActiveOn != "" {
+		b.values = append(b.values, b.req.ActiveOn)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND ?::date BETWEEN COALESCE("start_date", '-infinity') AND COALESCE("end_date", 'infinity')`)
+	}
+
 	// Search Check
 	if b.req.Search != "" {
 		b.values = append(b.values,
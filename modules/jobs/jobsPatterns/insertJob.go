@@ -62,9 +62,11 @@ func (b *insertJobBuilder) insertJob() error {
 		"start_date",
 		"end_date",
 		"status",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at"
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::timestamptz, NULLIF($11, '')::timestamptz)
 		RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -79,6 +81,8 @@ func (b *insertJobBuilder) insertJob() error {
 		b.req.EndDate,
 		b.req.Status,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert jobs failed: %v", err)
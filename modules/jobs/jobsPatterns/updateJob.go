@@ -124,6 +124,20 @@ func (b *updateJobBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"display" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
 	b.query += strings.Join(setStatements, ", ")
 }
 
@@ -2,9 +2,14 @@ package jobsRepositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/config"
@@ -18,6 +23,12 @@ type IJobRepository interface {
 	InsertJob(req *jobs.Job) (*jobs.Job, error)
 	UpdateJob(req *jobs.Job) (*jobs.Job, error)
 	DeleteJob(jobId string) error
+	InsertApplication(req *jobs.ApplicationReq) (int, error)
+	FindOneApplication(applicationId int) (*jobs.Application, error)
+	FindApplicationResume(applicationId int) (*jobs.Resume, error)
+	FindApplication(req *jobs.ApplicationFilter) ([]*jobs.Application, int)
+	UpdateApplicationStage(applicationId int, stage string) error
+	InsertApplicationNote(req *jobs.ApplicationNote) error
 }
 
 type jobsRepository struct {
@@ -41,6 +52,7 @@ func (r *jobsRepository) FindOneJob(jobId string) (*jobs.Job, error) {
 		*
 		FROM "careers"  
 		WHERE "id" = $1
+		AND "deleted_at" IS NULL
 		LIMIT 1
 	) AS "t";`
 
@@ -99,10 +111,206 @@ func (r *jobsRepository) UpdateJob(req *jobs.Job) (*jobs.Job, error) {
 }
 
 func (r *jobsRepository) DeleteJob(jobId string) error {
-	query := `DELETE FROM "careers" WHERE "id" = $1;`
+	query := `UPDATE "careers" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, jobId); err != nil {
 		return fmt.Errorf("delete job failed: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+const applicationColumns = `
+		"a"."id",
+		"a"."career_id" AS "job_id",
+		"c"."position",
+		"a"."name",
+		"a"."email",
+		"a"."tel",
+		COALESCE("a"."cover_letter", '') AS "cover_letter",
+		"a"."resume_filename",
+		"a"."stage",
+		"a"."created_at",
+		"a"."updated_at"`
+
+func (r *jobsRepository) InsertApplication(req *jobs.ApplicationReq) (int, error) {
+	query := `
+	INSERT INTO "job_applications" (
+		"career_id",
+		"name",
+		"email",
+		"tel",
+		"cover_letter",
+		"resume_filename",
+		"resume_path",
+		"ip"
+	)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+	RETURNING "id";`
+
+	var applicationId int
+	if err := r.db.QueryRowContext(
+		context.Background(),
+		query,
+		req.JobId,
+		req.Name,
+		req.Email,
+		req.Tel,
+		req.CoverLetter,
+		req.ResumeFilename,
+		req.ResumePath,
+		req.Ip,
+	).Scan(&applicationId); err != nil {
+		return 0, fmt.Errorf("insert application failed: %v", err)
+	}
+	return applicationId, nil
+}
+
+func (r *jobsRepository) FindOneApplication(applicationId int) (*jobs.Application, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT%s,
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("n")), '[]'::json)
+				FROM (
+					SELECT
+						"an"."id",
+						"an"."application_id",
+						"an"."note",
+						"an"."created_by",
+						"nu"."name" AS "created_by_name",
+						"an"."created_at"
+					FROM "job_application_notes" "an"
+					LEFT JOIN "users" "nu" ON "nu"."id" = "an"."created_by"
+					WHERE "an"."application_id" = "a"."id"
+					ORDER BY "an"."id" DESC
+				) AS "n"
+			) AS "notes"
+		FROM "job_applications" "a"
+		JOIN "careers" "c" ON "c"."id" = "a"."career_id"
+		WHERE "a"."id" = $1
+		LIMIT 1
+	) AS "t";`, applicationColumns)
+
+	applicationBytes := make([]byte, 0)
+	application := &jobs.Application{
+		Notes: make([]*jobs.ApplicationNote, 0),
+	}
+
+	if err := r.db.Get(&applicationBytes, query, applicationId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("application %d not found", applicationId)
+		}
+		return nil, fmt.Errorf("get application failed: %v", err)
+	}
+	if err := json.Unmarshal(applicationBytes, application); err != nil {
+		return nil, fmt.Errorf("unmarshal application failed: %v", err)
+	}
+	application.ResumeUrl = jobs.ResumeUrl(r.cfg.App().AppUrl(), application.Id)
+	return application, nil
+}
+
+func (r *jobsRepository) FindApplicationResume(applicationId int) (*jobs.Resume, error) {
+	query := `
+	SELECT
+		"resume_filename",
+		"resume_path"
+	FROM "job_applications"
+	WHERE "id" = $1
+	LIMIT 1;`
+
+	resume := new(jobs.Resume)
+	if err := r.db.Get(resume, query, applicationId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("application %d not found", applicationId)
+		}
+		return nil, fmt.Errorf("get application resume failed: %v", err)
+	}
+	return resume, nil
+}
+
+func (r *jobsRepository) FindApplication(req *jobs.ApplicationFilter) ([]*jobs.Application, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	result := make([]*jobs.Application, 0)
+
+	where := `
+	WHERE "a"."career_id" = $1`
+	values := []any{req.JobId}
+
+	if req.Stage != "" {
+		values = append(values, req.Stage)
+		where += fmt.Sprintf(`
+	AND "a"."stage" = $%d`, len(values))
+	}
+	if req.Search != "" {
+		values = append(values, "%"+strings.ToLower(req.Search)+"%")
+		where += fmt.Sprintf(`
+	AND (LOWER("a"."name") LIKE $%d OR LOWER("a"."email") LIKE $%d OR "a"."tel" LIKE $%d)`, len(values), len(values), len(values))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT%s
+		FROM "job_applications" "a"
+		JOIN "careers" "c" ON "c"."id" = "a"."career_id"%s
+		ORDER BY "a"."id" DESC
+		OFFSET $%d LIMIT $%d
+	) AS "t";`, applicationColumns, where, len(values)+1, len(values)+2)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, append(values, (req.Page-1)*req.Limit, req.Limit)...); err != nil {
+		log.Printf("find applications failed: %v\n", err)
+		return result, 0
+	}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		log.Printf("unmarshal applications failed: %v\n", err)
+		return result, 0
+	}
+	for _, a := range result {
+		a.ResumeUrl = jobs.ResumeUrl(r.cfg.App().AppUrl(), a.Id)
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM "job_applications" "a"%s;`, where), values...); err != nil {
+		log.Printf("count applications failed: %v\n", err)
+		return result, 0
+	}
+	return result, count
+}
+
+func (r *jobsRepository) UpdateApplicationStage(applicationId int, stage string) error {
+	query := `
+	UPDATE "job_applications" SET
+		"stage" = $1
+	WHERE "id" = $2;`
+
+	res, err := r.db.ExecContext(context.Background(), query, stage, applicationId)
+	if err != nil {
+		return fmt.Errorf("update application stage failed: %v", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("application %d not found", applicationId)
+	}
+	return nil
+}
+
+func (r *jobsRepository) InsertApplicationNote(req *jobs.ApplicationNote) error {
+	query := `
+	INSERT INTO "job_application_notes" (
+		"application_id",
+		"note",
+		"created_by"
+	)
+	VALUES ($1, $2, $3);`
+
+	if _, err := r.db.ExecContext(context.Background(), query, req.ApplicationId, req.Note, req.CreatedBy); err != nil {
+		return fmt.Errorf("insert application note failed: %v", err)
+	}
+	return nil
+}
@@ -1,28 +1,49 @@
 package jobsUsecases
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
 	"math"
+	"strconv"
 
 	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/files"
+	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/jobs"
 	"github.com/yporn/sirarom-backend/modules/jobs/jobsRepositories"
 )
 
+// ErrJobClosed is returned when applying to a job that is not published or
+// no longer opening.
+var ErrJobClosed = errors.New("job is not open for applications")
+
 type IJobsUsecase interface {
 	FindOneJob(jobId string) (*jobs.Job, error)
 	FindJob(req *jobs.JobFilter) *entities.PaginateRes
 	AddJob(req *jobs.Job) (*jobs.Job, error)
 	UpdateJob(req *jobs.Job) (*jobs.Job, error)
 	DeleteJob(jobId string) error
+	ApplyJob(req *jobs.ApplicationReq, resume *files.FileReq) (*jobs.Application, error)
+	FindOneApplication(applicationId int) (*jobs.Application, error)
+	DownloadResume(applicationId int) (*jobs.Resume, []byte, error)
+	FindApplication(req *jobs.ApplicationFilter) *entities.PaginateRes
+	UpdateApplicationStage(applicationId int, stage string) (*jobs.Application, error)
+	AddApplicationNote(req *jobs.ApplicationNote) (*jobs.Application, error)
+	ExportApplications(req *jobs.ApplicationFilter) ([]byte, error)
 }
 
 type jobsUsecase struct {
 	jobsRepository jobsRepositories.IJobRepository
+	filesUsecase   filesUsecases.IFilesUsecase
 }
 
-func JobsUsecase(jobsRepository jobsRepositories.IJobRepository) IJobsUsecase {
+func JobsUsecase(jobsRepository jobsRepositories.IJobRepository, filesUsecase filesUsecases.IFilesUsecase) IJobsUsecase {
 	return &jobsUsecase{
 		jobsRepository: jobsRepository,
+		filesUsecase:   filesUsecase,
 	}
 }
 
@@ -68,4 +89,111 @@ func (u *jobsUsecase) DeleteJob(jobId string) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// ApplyJob stores the résumé and the application of an opening job. The file
+// is removed again when the application cannot be saved.
+func (u *jobsUsecase) ApplyJob(req *jobs.ApplicationReq, resume *files.FileReq) (*jobs.Application, error) {
+	job, err := u.jobsRepository.FindOneJob(strconv.Itoa(req.JobId))
+	if err != nil {
+		return nil, err
+	}
+	if job.Display != entities.DisplayPublished || job.Status != jobs.StatusOpening {
+		return nil, ErrJobClosed
+	}
+
+	res, err := u.filesUsecase.UploadPrivate(resume)
+	if err != nil {
+		return nil, fmt.Errorf("upload resume failed: %v", err)
+	}
+	req.ResumeFilename = resume.FileName
+	req.ResumePath = res.Path
+
+	applicationId, err := u.jobsRepository.InsertApplication(req)
+	if err != nil {
+		if deleteErr := u.filesUsecase.DeletePrivate(res.Path); deleteErr != nil {
+			log.Printf("jobs: %v\n", deleteErr)
+		}
+		return nil, err
+	}
+	return u.jobsRepository.FindOneApplication(applicationId)
+}
+
+func (u *jobsUsecase) FindOneApplication(applicationId int) (*jobs.Application, error) {
+	return u.jobsRepository.FindOneApplication(applicationId)
+}
+
+// DownloadResume reads the stored résumé of an application back from storage.
+func (u *jobsUsecase) DownloadResume(applicationId int) (*jobs.Resume, []byte, error) {
+	resume, err := u.jobsRepository.FindApplicationResume(applicationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := u.filesUsecase.DownloadPrivate(resume.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("download resume failed: %v", err)
+	}
+	return resume, data, nil
+}
+
+func (u *jobsUsecase) FindApplication(req *jobs.ApplicationFilter) *entities.PaginateRes {
+	applications, count := u.jobsRepository.FindApplication(req)
+
+	return &entities.PaginateRes{
+		Data:      applications,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *jobsUsecase) UpdateApplicationStage(applicationId int, stage string) (*jobs.Application, error) {
+	if err := u.jobsRepository.UpdateApplicationStage(applicationId, stage); err != nil {
+		return nil, err
+	}
+	return u.jobsRepository.FindOneApplication(applicationId)
+}
+
+func (u *jobsUsecase) AddApplicationNote(req *jobs.ApplicationNote) (*jobs.Application, error) {
+	if _, err := u.jobsRepository.FindOneApplication(req.ApplicationId); err != nil {
+		return nil, err
+	}
+	if err := u.jobsRepository.InsertApplicationNote(req); err != nil {
+		return nil, err
+	}
+	return u.jobsRepository.FindOneApplication(req.ApplicationId)
+}
+
+// ExportApplications writes every applicant matching the filter as csv. The
+// file starts with a byte order mark so Excel opens the Thai text as UTF-8.
+func (u *jobsUsecase) ExportApplications(req *jobs.ApplicationFilter) ([]byte, error) {
+	if _, err := u.jobsRepository.FindOneJob(strconv.Itoa(req.JobId)); err != nil {
+		return nil, err
+	}
+	req.PaginationReq = &entities.PaginationReq{Page: 1, Limit: 10000000}
+	applications, _ := u.jobsRepository.FindApplication(req)
+
+	var b bytes.Buffer
+	b.WriteString("\ufeff")
+	w := csv.NewWriter(&b)
+	w.Write([]string{"id", "position", "name", "email", "tel", "stage", "cover_letter", "resume_url", "created_at"})
+	for _, a := range applications {
+		w.Write([]string{
+			strconv.Itoa(a.Id),
+			a.Position,
+			a.Name,
+			a.Email,
+			a.Tel,
+			a.Stage,
+			a.CoverLetter,
+			a.ResumeUrl,
+			a.CreatedAt,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("write applications csv failed: %v", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+package leads
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
+
+// Pipeline states of a lead.
+const (
+	StatusNew       = "new"
+	StatusContacted = "contacted"
+	StatusVisited   = "visited"
+	StatusWon       = "won"
+	StatusLost      = "lost"
+)
+
+type Lead struct {
+	Id             int         `db:"id" json:"id"`
+	Name           string      `db:"name" json:"name"`
+	Tel            string      `db:"tel" json:"tel"`
+	Email          string      `db:"email" json:"email"`
+	Message        string      `db:"message" json:"message"`
+	ProjectId      *int        `db:"project_id" json:"project_id"`
+	ProjectName    string      `db:"project_name" json:"project_name"`
+	HouseModelId   *int        `db:"house_model_id" json:"house_model_id"`
+	HouseModelName string      `db:"house_model_name" json:"house_model_name"`
+	PromotionId    *int        `db:"promotion_id" json:"promotion_id"`
+	PromotionName  string      `db:"promotion_name" json:"promotion_name"`
+	Status         string      `db:"status" json:"status"`
+	LostReason     string      `db:"lost_reason" json:"lost_reason"`
+	AssignedTo     *int        `db:"assigned_to" json:"assigned_to"`
+	AssignedName   string      `db:"assigned_name" json:"assigned_name"`
+	SlaAlertedAt   *string     `db:"sla_alerted_at" json:"sla_alerted_at"`
+	CreatedAt      string      `db:"created_at" json:"created_at"`
+	UpdatedAt      string      `db:"updated_at" json:"updated_at"`
+	Notes          []*LeadNote `json:"notes,omitempty"`
+	Tasks          []*LeadTask `json:"tasks,omitempty"`
+}
+
+// LeadReq is the public enquiry form. Website is a honeypot field hidden from
+// visitors, so only bots fill it in.
+type LeadReq struct {
+	Name         string `json:"name" form:"name"`
+	Tel          string `json:"tel" form:"tel"`
+	Email        string `json:"email" form:"email"`
+	Message      string `json:"message" form:"message"`
+	ProjectId    *int   `json:"project_id" form:"project_id"`
+	HouseModelId *int   `json:"house_model_id" form:"house_model_id"`
+	PromotionId  *int   `json:"promotion_id" form:"promotion_id"`
+	Website      string `json:"website" form:"website"`
+	Ip           string `json:"-"`
+}
+
+func (r *LeadReq) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Tel = strings.TrimSpace(r.Tel)
+	r.Email = strings.TrimSpace(r.Email)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Tel == "" && r.Email == "" {
+		return fmt.Errorf("tel or email is required")
+	}
+	if r.ProjectId == nil && r.HouseModelId == nil && r.PromotionId == nil {
+		return fmt.Errorf("project_id, house_model_id or promotion_id is required")
+	}
+	return nil
+}
+
+type LeadFilter struct {
+	Search       string `query:"search"` // name, tel & email
+	Status       string `query:"status"`
+	ProjectId    int    `query:"project_id"`
+	HouseModelId int    `query:"house_model_id"`
+	PromotionId  int    `query:"promotion_id"`
+	AssignedTo   int    `query:"assigned_to"`
+	Overdue      bool   `query:"overdue"` // new leads past the SLA
+	SlaHours     int    `query:"-"`
+	*entities.PaginationReq
+	*entities.SortReq
+}
+
+type LeadStatusReq struct {
+	Status     string `json:"status"`
+	LostReason string `json:"lost_reason"`
+}
+
+func ValidateStatus(status string) error {
+	switch status {
+	case StatusNew, StatusContacted, StatusVisited, StatusWon, StatusLost:
+		return nil
+	}
+	return fmt.Errorf("status is invalid: %s", status)
+}
+
+// Validate requires a reason for lost leads and clears it for every other status.
+func (r *LeadStatusReq) Validate() error {
+	if err := ValidateStatus(r.Status); err != nil {
+		return err
+	}
+	r.LostReason = strings.TrimSpace(r.LostReason)
+	if r.Status != StatusLost {
+		r.LostReason = ""
+	} else if r.LostReason == "" {
+		return fmt.Errorf("lost_reason is required when status is lost")
+	}
+	return nil
+}
+
+type LeadAssignReq struct {
+	UserId int `json:"user_id"`
+}
+
+type LeadNote struct {
+	Id            int    `db:"id" json:"id"`
+	LeadId        int    `db:"lead_id" json:"lead_id"`
+	Note          string `db:"note" json:"note"`
+	CreatedBy     *int   `db:"created_by" json:"created_by"`
+	CreatedByName string `db:"created_by_name" json:"created_by_name"`
+	CreatedAt     string `db:"created_at" json:"created_at"`
+}
+
+type LeadTask struct {
+	Id           int     `db:"id" json:"id"`
+	LeadId       int     `db:"lead_id" json:"lead_id"`
+	Title        string  `db:"title" json:"title"`
+	DueAt        string  `db:"due_at" json:"due_at"`
+	DoneAt       *string `db:"done_at" json:"done_at"`
+	AssignedTo   *int    `db:"assigned_to" json:"assigned_to"`
+	AssignedName string  `db:"assigned_name" json:"assigned_name"`
+	CreatedBy    *int    `db:"created_by" json:"created_by"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+	UpdatedAt    string  `db:"updated_at" json:"updated_at"`
+}
+
+func (t *LeadTask) Validate() error {
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if _, err := time.Parse(time.RFC3339, t.DueAt); err != nil {
+		return fmt.Errorf("due_at must be RFC3339, e.g. 2024-01-02T09:00:00+07:00")
+	}
+	return nil
+}
+
+// LeadTaskFilter lists follow-up tasks, open ones only unless Done is set.
+type LeadTaskFilter struct {
+	AssignedTo int  `query:"assigned_to"`
+	Done       bool `query:"done"`
+	Overdue    bool `query:"overdue"`
+	*entities.PaginationReq
+}
+
+// ProjectSales is a sales user in the round-robin pool of a project.
+type ProjectSales struct {
+	ProjectId      int     `db:"project_id" json:"project_id"`
+	UserId         int     `db:"user_id" json:"user_id"`
+	Name           string  `db:"name" json:"name"`
+	LastAssignedAt *string `db:"last_assigned_at" json:"last_assigned_at"`
+}
+
+type ProjectSalesReq struct {
+	UserIds []int `json:"user_ids"`
+}
+
+// SlaBreach is a new lead nobody has touched within the SLA.
+type SlaBreach struct {
+	Id         int    `db:"id" json:"id"`
+	Name       string `db:"name" json:"name"`
+	AssignedTo *int   `db:"assigned_to" json:"assigned_to"`
+}
@@ -0,0 +1,502 @@
+package leadsHandlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/leads"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type leadsHandlersErrCode string
+
+const (
+	insertLeadErr         leadsHandlersErrCode = "leads-001"
+	findOneLeadErr        leadsHandlersErrCode = "leads-002"
+	findLeadErr           leadsHandlersErrCode = "leads-003"
+	updateLeadStatusErr   leadsHandlersErrCode = "leads-004"
+	assignLeadErr         leadsHandlersErrCode = "leads-005"
+	addLeadNoteErr        leadsHandlersErrCode = "leads-006"
+	addLeadTaskErr        leadsHandlersErrCode = "leads-007"
+	doneLeadTaskErr       leadsHandlersErrCode = "leads-008"
+	findLeadTasksErr      leadsHandlersErrCode = "leads-009"
+	findProjectSalesErr   leadsHandlersErrCode = "leads-010"
+	updateProjectSalesErr leadsHandlersErrCode = "leads-011"
+)
+
+type ILeadsHandler interface {
+	InsertLead(c *fiber.Ctx) error
+	FindOneLead(c *fiber.Ctx) error
+	FindLead(c *fiber.Ctx) error
+	UpdateLeadStatus(c *fiber.Ctx) error
+	AssignLead(c *fiber.Ctx) error
+	AddLeadNote(c *fiber.Ctx) error
+	AddLeadTask(c *fiber.Ctx) error
+	DoneLeadTask(c *fiber.Ctx) error
+	FindLeadTasks(c *fiber.Ctx) error
+	FindProjectSales(c *fiber.Ctx) error
+	UpdateProjectSales(c *fiber.Ctx) error
+}
+
+type leadsHandler struct {
+	cfg          config.IConfig
+	leadsUsecase leadsUsecases.ILeadsUsecase
+	db           *sql.DB
+}
+
+func LeadsHandler(cfg config.IConfig, leadsUsecase leadsUsecases.ILeadsUsecase, db *sql.DB) ILeadsHandler {
+	return &leadsHandler{
+		cfg:          cfg,
+		leadsUsecase: leadsUsecase,
+		db:           db,
+	}
+}
+
+// InsertLead is the public enquiry form. Submissions that fill the honeypot get
+// the same answer as real ones but are dropped.
+func (h *leadsHandler) InsertLead(c *fiber.Ctx) error {
+	req := new(leads.LeadReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Website != "" {
+		return entities.NewResponse(c).Success(fiber.StatusCreated, fiber.Map{"status": leads.StatusNew}).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertLeadErr),
+			err.Error(),
+		).Res()
+	}
+	req.Ip = c.IP()
+
+	if _, err := h.leadsUsecase.InsertLead(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	if err := utils.LogSystemActivity(h.db, "created", "ลูกค้าที่สนใจติดต่อเข้ามา : "+req.Name); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			"Failed to log activity",
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, fiber.Map{"status": leads.StatusNew}).Res()
+}
+
+func (h *leadsHandler) FindOneLead(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	lead, err := h.leadsUsecase.FindOneLead(leadId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneLeadErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, lead).Res()
+}
+
+func (h *leadsHandler) FindLead(c *fiber.Ctx) error {
+	req := &leads.LeadFilter{
+		PaginationReq: &entities.PaginationReq{},
+		SortReq:       &entities.SortReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Status != "" {
+		if err := leads.ValidateStatus(req.Status); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(findLeadErr),
+				err.Error(),
+			).Res()
+		}
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.leadsUsecase.FindLead(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+func (h *leadsHandler) UpdateLeadStatus(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLeadStatusErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(leads.LeadStatusReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLeadStatusErr),
+			err.Error(),
+		).Res()
+	}
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLeadStatusErr),
+			err.Error(),
+		).Res()
+	}
+
+	lead, err := h.leadsUsecase.UpdateLeadStatus(leadId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateLeadStatusErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "เปลี่ยนสถานะลูกค้าที่สนใจเป็น "+req.Status+" : "+lead.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, lead).Res()
+}
+
+func (h *leadsHandler) AssignLead(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(assignLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(leads.LeadAssignReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(assignLeadErr),
+			err.Error(),
+		).Res()
+	}
+	if req.UserId < 1 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(assignLeadErr),
+			"user_id is required",
+		).Res()
+	}
+
+	lead, err := h.leadsUsecase.AssignLead(leadId, req.UserId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(assignLeadErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "มอบหมายลูกค้าที่สนใจให้ "+lead.AssignedName+" : "+lead.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, lead).Res()
+}
+
+func (h *leadsHandler) AddLeadNote(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadNoteErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(leads.LeadNote)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadNoteErr),
+			err.Error(),
+		).Res()
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadNoteErr),
+			"note is required",
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	req.LeadId = leadId
+	req.CreatedBy = &userID
+
+	lead, err := h.leadsUsecase.AddLeadNote(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(addLeadNoteErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "เพิ่มบันทึกลูกค้าที่สนใจ : "+lead.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, lead).Res()
+}
+
+func (h *leadsHandler) AddLeadTask(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(leads.LeadTask)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	req.LeadId = leadId
+	req.CreatedBy = &userID
+
+	lead, err := h.leadsUsecase.AddLeadTask(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(addLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "เพิ่มงานติดตามลูกค้าที่สนใจ : "+lead.Name+" - "+req.Title)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, lead).Res()
+}
+
+func (h *leadsHandler) DoneLeadTask(c *fiber.Ctx) error {
+	leadId, err := strconv.Atoi(strings.Trim(c.Params("lead_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(doneLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+	taskId, err := strconv.Atoi(strings.Trim(c.Params("task_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(doneLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+
+	lead, err := h.leadsUsecase.DoneLeadTask(leadId, taskId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(doneLeadTaskErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "ปิดงานติดตามลูกค้าที่สนใจ : "+lead.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, lead).Res()
+}
+
+func (h *leadsHandler) FindLeadTasks(c *fiber.Ctx) error {
+	req := &leads.LeadTaskFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findLeadTasksErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.leadsUsecase.FindLeadTasks(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+func (h *leadsHandler) FindProjectSales(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProjectSalesErr),
+			err.Error(),
+		).Res()
+	}
+
+	sales, err := h.leadsUsecase.FindProjectSales(projectId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findProjectSalesErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, sales).Res()
+}
+
+func (h *leadsHandler) UpdateProjectSales(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProjectSalesErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(leads.ProjectSalesReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProjectSalesErr),
+			err.Error(),
+		).Res()
+	}
+	if req.UserIds == nil {
+		req.UserIds = make([]int, 0)
+	}
+
+	sales, err := h.leadsUsecase.UpdateProjectSales(projectId, req.UserIds)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateProjectSalesErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขพนักงานขายประจำโครงการ : "+strconv.Itoa(projectId))
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, sales).Res()
+}
@@ -0,0 +1,251 @@
+package leadsPatterns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/leads"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+// UntouchedQuery matches new leads without any note or task yet, "l" being the lead.
+const UntouchedQuery = `"l"."status" = 'new'
+		AND NOT EXISTS (SELECT 1 FROM "lead_notes" "n" WHERE "n"."lead_id" = "l"."id")
+		AND NOT EXISTS (SELECT 1 FROM "lead_tasks" "lt" WHERE "lt"."lead_id" = "l"."id")`
+
+type IFindLeadBuilder interface {
+	openJsonQuery()
+	initQuery()
+	countQuery()
+	whereQuery()
+	sort()
+	paginate()
+	closeJsonQuery()
+	resetQuery()
+	Result() []*leads.Lead
+	Count() int
+	PrintQuery()
+}
+
+type findLeadBuilder struct {
+	db             *sqlx.DB
+	req            *leads.LeadFilter
+	query          string
+	lastStackIndex int
+	values         []any
+}
+
+func FindLeadBuilder(db *sqlx.DB, req *leads.LeadFilter) IFindLeadBuilder {
+	return &findLeadBuilder{
+		db:  db,
+		req: req,
+	}
+}
+
+func (b *findLeadBuilder) openJsonQuery() {
+	b.query += `
+	SELECT
+		array_to_json(array_agg("t"))
+	FROM (`
+}
+
+func (b *findLeadBuilder) initQuery() {
+	b.query += `
+		SELECT
+			"l"."id",
+			"l"."name",
+			"l"."tel",
+			"l"."email",
+			"l"."message",
+			"l"."project_id",
+			"p"."name" AS "project_name",
+			"l"."house_model_id",
+			"hm"."name" AS "house_model_name",
+			"l"."promotion_id",
+			"pm"."heading" AS "promotion_name",
+			"l"."status",
+			"l"."lost_reason",
+			"l"."assigned_to",
+			"u"."name" AS "assigned_name",
+			"l"."sla_alerted_at",
+			"l"."created_at",
+			"l"."updated_at"
+		FROM "leads" "l"
+		LEFT JOIN "projects" "p" ON "p"."id" = "l"."project_id"
+		LEFT JOIN "house_models" "hm" ON "hm"."id" = "l"."house_model_id"
+		LEFT JOIN "promotions" "pm" ON "pm"."id" = "l"."promotion_id"
+		LEFT JOIN "users" "u" ON "u"."id" = "l"."assigned_to"
+		WHERE 1 = 1`
+}
+
+func (b *findLeadBuilder) countQuery() {
+	b.query += `
+		SELECT
+			COUNT(*) AS "count"
+		FROM "leads" "l"
+		WHERE 1 = 1`
+}
+
+func (b *findLeadBuilder) whereQuery() {
+	// Search check
+	if b.req.Search != "" {
+		b.values = append(b.values, "%"+strings.ToLower(b.req.Search)+"%")
+		b.query += fmt.Sprintf(`
+		AND (LOWER("l"."name") LIKE $%d OR "l"."tel" LIKE $%d OR LOWER("l"."email") LIKE $%d)`, len(b.values), len(b.values), len(b.values))
+	}
+
+	// Status check
+	if b.req.Status != "" {
+		b.values = append(b.values, b.req.Status)
+		b.query += fmt.Sprintf(`
+		AND "l"."status" = $%d`, len(b.values))
+	}
+
+	// Project check
+	if b.req.ProjectId != 0 {
+		b.values = append(b.values, b.req.ProjectId)
+		b.query += fmt.Sprintf(`
+		AND "l"."project_id" = $%d`, len(b.values))
+	}
+
+	// House model check
+	if b.req.HouseModelId != 0 {
+		b.values = append(b.values, b.req.HouseModelId)
+		b.query += fmt.Sprintf(`
+		AND "l"."house_model_id" = $%d`, len(b.values))
+	}
+
+	// Promotion check
+	if b.req.PromotionId != 0 {
+		b.values = append(b.values, b.req.PromotionId)
+		b.query += fmt.Sprintf(`
+		AND "l"."promotion_id" = $%d`, len(b.values))
+	}
+
+	// Assignee check
+	if b.req.AssignedTo != 0 {
+		b.values = append(b.values, b.req.AssignedTo)
+		b.query += fmt.Sprintf(`
+		AND "l"."assigned_to" = $%d`, len(b.values))
+	}
+
+	// Overdue check
+	if b.req.Overdue {
+		b.values = append(b.values, b.req.SlaHours)
+		b.query += fmt.Sprintf(`
+		AND %s
+		AND "l"."created_at" <= now() - make_interval(hours => $%d)`, UntouchedQuery, len(b.values))
+	}
+
+	// Last stack record
+	b.lastStackIndex = len(b.values)
+}
+
+func (b *findLeadBuilder) sort() {
+	orderByMap := map[string]string{
+		"id":         "\"l\".\"id\"",
+		"name":       "\"l\".\"name\"",
+		"status":     "\"l\".\"status\"",
+		"created_at": "\"l\".\"created_at\"",
+		"updated_at": "\"l\".\"updated_at\"",
+	}
+
+	orderBy := orderByMap[b.req.OrderBy]
+	if orderBy == "" {
+		orderBy = orderByMap["id"]
+	}
+
+	sortOrder := strings.ToUpper(b.req.Sort)
+	if sortOrder != "ASC" {
+		sortOrder = "DESC"
+	}
+
+	b.query += fmt.Sprintf(`
+		ORDER BY %s %s`, orderBy, sortOrder)
+}
+
+func (b *findLeadBuilder) paginate() {
+	// offset (page - 1)*limit
+	b.values = append(b.values, (b.req.Page-1)*b.req.Limit, b.req.Limit)
+
+	b.query += fmt.Sprintf(`	OFFSET $%d LIMIT $%d`, b.lastStackIndex+1, b.lastStackIndex+2)
+	b.lastStackIndex = len(b.values)
+}
+
+func (b *findLeadBuilder) closeJsonQuery() {
+	b.query += `
+	) AS "t";`
+}
+
+func (b *findLeadBuilder) resetQuery() {
+	b.query = ""
+	b.values = make([]any, 0)
+	b.lastStackIndex = 0
+}
+
+func (b *findLeadBuilder) Result() []*leads.Lead {
+	_, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	bytes := make([]byte, 0)
+	leadsData := make([]*leads.Lead, 0)
+
+	if err := b.db.Get(&bytes, b.query, b.values...); err != nil {
+		log.Printf("find leads failed: %v\n", err)
+		return make([]*leads.Lead, 0)
+	}
+
+	if err := json.Unmarshal(bytes, &leadsData); err != nil {
+		log.Printf("unmarshal leads failed: %v\n", err)
+		return make([]*leads.Lead, 0)
+	}
+	b.resetQuery()
+	return leadsData
+}
+
+func (b *findLeadBuilder) Count() int {
+	_, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	var count int
+	if err := b.db.Get(&count, b.query, b.values...); err != nil {
+		log.Printf("count leads failed: %v\n", err)
+		return 0
+	}
+	b.resetQuery()
+	return count
+}
+
+func (b *findLeadBuilder) PrintQuery() {
+	utils.Debug(b.values)
+	fmt.Println(b.query)
+}
+
+type findLeadEngineer struct {
+	builder IFindLeadBuilder
+}
+
+func FindLeadEngineer(builder IFindLeadBuilder) *findLeadEngineer {
+	return &findLeadEngineer{builder: builder}
+}
+
+func (en *findLeadEngineer) FindLead() IFindLeadBuilder {
+	en.builder.openJsonQuery()
+	en.builder.initQuery()
+	en.builder.whereQuery()
+	en.builder.sort()
+	en.builder.paginate()
+	en.builder.closeJsonQuery()
+	return en.builder
+}
+
+func (en *findLeadEngineer) CountLead() IFindLeadBuilder {
+	en.builder.countQuery()
+	en.builder.whereQuery()
+	return en.builder
+}
@@ -0,0 +1,454 @@
+package leadsRepositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/leads"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsPatterns"
+)
+
+type ILeadsRepository interface {
+	InsertLead(req *leads.LeadReq) (int, error)
+	FindOneLead(leadId int) (*leads.Lead, error)
+	FindLead(req *leads.LeadFilter) ([]*leads.Lead, int)
+	UpdateLeadStatus(leadId int, req *leads.LeadStatusReq) error
+	AssignLead(leadId, userId int) error
+	InsertLeadNote(req *leads.LeadNote) error
+	InsertLeadTask(req *leads.LeadTask) error
+	DoneLeadTask(leadId, taskId int) error
+	FindLeadTasks(req *leads.LeadTaskFilter) ([]*leads.LeadTask, int)
+	FindProjectSales(projectId int) ([]*leads.ProjectSales, error)
+	UpdateProjectSales(projectId int, userIds []int) error
+	FlagSlaBreaches(hours int) ([]*leads.SlaBreach, error)
+}
+
+type leadsRepository struct {
+	db *sqlx.DB
+}
+
+func LeadsRepository(db *sqlx.DB) ILeadsRepository {
+	return &leadsRepository{
+		db: db,
+	}
+}
+
+// InsertLead stores an enquiry and hands it to the next sales user of its project.
+// A house model enquiry without a project takes the project of the house model.
+func (r *leadsRepository) InsertLead(req *leads.LeadReq) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if req.HouseModelId != nil {
+		var projectId int
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT "project_id" FROM "house_models" WHERE "id" = $1 AND "deleted_at" IS NULL;`,
+			*req.HouseModelId,
+		).Scan(&projectId); err != nil {
+			tx.Rollback()
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, fmt.Errorf("house model not found")
+			}
+			return 0, fmt.Errorf("get house model failed: %v", err)
+		}
+		if req.ProjectId == nil {
+			req.ProjectId = &projectId
+		} else if *req.ProjectId != projectId {
+			tx.Rollback()
+			return 0, fmt.Errorf("house model does not belong to the project")
+		}
+	}
+
+	if req.ProjectId != nil {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM "projects" WHERE "id" = $1 AND "deleted_at" IS NULL);`, *req.ProjectId); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("get project failed: %v", err)
+		}
+		if !exists {
+			tx.Rollback()
+			return 0, fmt.Errorf("project not found")
+		}
+	}
+
+	if req.PromotionId != nil {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM "promotions" WHERE "id" = $1 AND "deleted_at" IS NULL);`, *req.PromotionId); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("get promotion failed: %v", err)
+		}
+		if !exists {
+			tx.Rollback()
+			return 0, fmt.Errorf("promotion not found")
+		}
+	}
+
+	// Round robin: the sales user assigned least recently takes the lead
+	var assignedTo *int
+	if req.ProjectId != nil {
+		var userId int
+		err := tx.QueryRowContext(ctx, `
+		UPDATE "project_sales" SET
+			"last_assigned_at" = now()
+		WHERE "id" = (
+			SELECT "id"
+			FROM "project_sales"
+			WHERE "project_id" = $1
+			ORDER BY "last_assigned_at" ASC NULLS FIRST, "id" ASC
+			LIMIT 1
+			FOR UPDATE
+		)
+		RETURNING "user_id";`, *req.ProjectId).Scan(&userId)
+		switch {
+		case err == nil:
+			assignedTo = &userId
+		case !errors.Is(err, sql.ErrNoRows):
+			tx.Rollback()
+			return 0, fmt.Errorf("assign lead failed: %v", err)
+		}
+	}
+
+	query := `
+	INSERT INTO "leads" (
+		"name",
+		"tel",
+		"email",
+		"message",
+		"project_id",
+		"house_model_id",
+		"promotion_id",
+		"assigned_to",
+		"ip"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING "id";`
+
+	var leadId int
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		req.Name,
+		req.Tel,
+		req.Email,
+		req.Message,
+		req.ProjectId,
+		req.HouseModelId,
+		req.PromotionId,
+		assignedTo,
+		req.Ip,
+	).Scan(&leadId); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("insert lead failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return leadId, nil
+}
+
+func (r *leadsRepository) FindOneLead(leadId int) (*leads.Lead, error) {
+	query := `
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT
+			"l"."id",
+			"l"."name",
+			"l"."tel",
+			"l"."email",
+			"l"."message",
+			"l"."project_id",
+			"p"."name" AS "project_name",
+			"l"."house_model_id",
+			"hm"."name" AS "house_model_name",
+			"l"."promotion_id",
+			"pm"."heading" AS "promotion_name",
+			"l"."status",
+			"l"."lost_reason",
+			"l"."assigned_to",
+			"u"."name" AS "assigned_name",
+			"l"."sla_alerted_at",
+			"l"."created_at",
+			"l"."updated_at",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("n")), '[]'::json)
+				FROM (
+					SELECT
+						"ln"."id",
+						"ln"."lead_id",
+						"ln"."note",
+						"ln"."created_by",
+						"nu"."name" AS "created_by_name",
+						"ln"."created_at"
+					FROM "lead_notes" "ln"
+					LEFT JOIN "users" "nu" ON "nu"."id" = "ln"."created_by"
+					WHERE "ln"."lead_id" = "l"."id"
+					ORDER BY "ln"."id" DESC
+				) AS "n"
+			) AS "notes",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("lt")), '[]'::json)
+				FROM (
+					SELECT
+						"tk".*,
+						"tu"."name" AS "assigned_name"
+					FROM "lead_tasks" "tk"
+					LEFT JOIN "users" "tu" ON "tu"."id" = "tk"."assigned_to"
+					WHERE "tk"."lead_id" = "l"."id"
+					ORDER BY "tk"."due_at" ASC
+				) AS "lt"
+			) AS "tasks"
+		FROM "leads" "l"
+		LEFT JOIN "projects" "p" ON "p"."id" = "l"."project_id"
+		LEFT JOIN "house_models" "hm" ON "hm"."id" = "l"."house_model_id"
+		LEFT JOIN "promotions" "pm" ON "pm"."id" = "l"."promotion_id"
+		LEFT JOIN "users" "u" ON "u"."id" = "l"."assigned_to"
+		WHERE "l"."id" = $1
+		LIMIT 1
+	) AS "t";`
+
+	leadBytes := make([]byte, 0)
+	lead := &leads.Lead{
+		Notes: make([]*leads.LeadNote, 0),
+		Tasks: make([]*leads.LeadTask, 0),
+	}
+
+	if err := r.db.Get(&leadBytes, query, leadId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead %d not found", leadId)
+		}
+		return nil, fmt.Errorf("get lead failed: %v", err)
+	}
+	if err := json.Unmarshal(leadBytes, &lead); err != nil {
+		return nil, fmt.Errorf("unmarshal lead failed: %v", err)
+	}
+	return lead, nil
+}
+
+func (r *leadsRepository) FindLead(req *leads.LeadFilter) ([]*leads.Lead, int) {
+	builder := leadsPatterns.FindLeadBuilder(r.db, req)
+	engineer := leadsPatterns.FindLeadEngineer(builder)
+
+	result := engineer.FindLead().Result()
+	count := engineer.CountLead().Count()
+
+	return result, count
+}
+
+func (r *leadsRepository) UpdateLeadStatus(leadId int, req *leads.LeadStatusReq) error {
+	query := `
+	UPDATE "leads" SET
+		"status" = $1,
+		"lost_reason" = NULLIF($2, '')
+	WHERE "id" = $3;`
+
+	res, err := r.db.ExecContext(context.Background(), query, req.Status, req.LostReason, leadId)
+	if err != nil {
+		return fmt.Errorf("update lead status failed: %v", err)
+	}
+	return checkAffected(res, fmt.Sprintf("lead %d not found", leadId))
+}
+
+func (r *leadsRepository) AssignLead(leadId, userId int) error {
+	query := `
+	UPDATE "leads" SET
+		"assigned_to" = $1
+	WHERE "id" = $2;`
+
+	res, err := r.db.ExecContext(context.Background(), query, userId, leadId)
+	if err != nil {
+		return fmt.Errorf("assign lead failed: %v", err)
+	}
+	return checkAffected(res, fmt.Sprintf("lead %d not found", leadId))
+}
+
+func (r *leadsRepository) InsertLeadNote(req *leads.LeadNote) error {
+	query := `
+	INSERT INTO "lead_notes" (
+		"lead_id",
+		"note",
+		"created_by"
+	)
+	VALUES ($1, $2, $3);`
+
+	if _, err := r.db.ExecContext(context.Background(), query, req.LeadId, req.Note, req.CreatedBy); err != nil {
+		return fmt.Errorf("insert lead note failed: %v", err)
+	}
+	return nil
+}
+
+func (r *leadsRepository) InsertLeadTask(req *leads.LeadTask) error {
+	query := `
+	INSERT INTO "lead_tasks" (
+		"lead_id",
+		"title",
+		"due_at",
+		"assigned_to",
+		"created_by"
+	)
+	VALUES ($1, $2, $3, $4, $5);`
+
+	if _, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		req.LeadId,
+		req.Title,
+		req.DueAt,
+		req.AssignedTo,
+		req.CreatedBy,
+	); err != nil {
+		return fmt.Errorf("insert lead task failed: %v", err)
+	}
+	return nil
+}
+
+func (r *leadsRepository) DoneLeadTask(leadId, taskId int) error {
+	query := `
+	UPDATE "lead_tasks" SET
+		"done_at" = now()
+	WHERE "id" = $1
+	AND "lead_id" = $2
+	AND "done_at" IS NULL;`
+
+	res, err := r.db.ExecContext(context.Background(), query, taskId, leadId)
+	if err != nil {
+		return fmt.Errorf("update lead task failed: %v", err)
+	}
+	return checkAffected(res, fmt.Sprintf("open task %d not found", taskId))
+}
+
+func (r *leadsRepository) FindLeadTasks(req *leads.LeadTaskFilter) ([]*leads.LeadTask, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tasks := make([]*leads.LeadTask, 0)
+
+	where := `
+		WHERE ("tk"."done_at" IS NOT NULL) = $1
+		AND ($2 = 0 OR "tk"."assigned_to" = $2)
+		AND (NOT $3 OR "tk"."due_at" < now())`
+	args := []any{req.Done, req.AssignedTo, req.Overdue}
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"tk".*,
+			"u"."name" AS "assigned_name"
+		FROM "lead_tasks" "tk"
+		LEFT JOIN "users" "u" ON "u"."id" = "tk"."assigned_to"%s
+		ORDER BY "tk"."due_at" ASC
+		OFFSET $4 LIMIT $5
+	) AS "t";`, where)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, append(args, (req.Page-1)*req.Limit, req.Limit)...); err != nil {
+		log.Printf("find lead tasks failed: %v\n", err)
+		return tasks, 0
+	}
+	if err := json.Unmarshal(bytes, &tasks); err != nil {
+		log.Printf("unmarshal lead tasks failed: %v\n", err)
+		return tasks, 0
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM "lead_tasks" "tk"%s;`, where), args...); err != nil {
+		log.Printf("count lead tasks failed: %v\n", err)
+		return tasks, 0
+	}
+	return tasks, count
+}
+
+func (r *leadsRepository) FindProjectSales(projectId int) ([]*leads.ProjectSales, error) {
+	query := `
+	SELECT
+		"ps"."project_id",
+		"ps"."user_id",
+		COALESCE("u"."name", "u"."username") AS "name",
+		"ps"."last_assigned_at"
+	FROM "project_sales" "ps"
+	JOIN "users" "u" ON "u"."id" = "ps"."user_id"
+	WHERE "ps"."project_id" = $1
+	ORDER BY "ps"."last_assigned_at" ASC NULLS FIRST, "ps"."id" ASC;`
+
+	sales := make([]*leads.ProjectSales, 0)
+	if err := r.db.Select(&sales, query, projectId); err != nil {
+		return nil, fmt.Errorf("find project sales failed: %v", err)
+	}
+	return sales, nil
+}
+
+// UpdateProjectSales replaces the round-robin pool of a project. Users who stay in
+// the pool keep their place in the rotation.
+func (r *leadsRepository) UpdateProjectSales(projectId int, userIds []int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "project_sales" WHERE "project_id" = $1 AND NOT ("user_id" = ANY($2));`, projectId, userIds); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete project sales failed: %v", err)
+	}
+
+	query := `
+	INSERT INTO "project_sales" ("project_id", "user_id")
+	SELECT $1, "u"
+	FROM unnest($2::int[]) AS "u"
+	ON CONFLICT ("project_id", "user_id") DO NOTHING;`
+
+	if _, err := tx.ExecContext(ctx, query, projectId, userIds); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insert project sales failed: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// FlagSlaBreaches marks the untouched new leads older than hours and returns them,
+// each lead is flagged once.
+func (r *leadsRepository) FlagSlaBreaches(hours int) ([]*leads.SlaBreach, error) {
+	query := fmt.Sprintf(`
+	UPDATE "leads" "l" SET
+		"sla_alerted_at" = now()
+	WHERE "l"."sla_alerted_at" IS NULL
+	AND "l"."created_at" <= now() - make_interval(hours => $1)
+	AND %s
+	RETURNING "l"."id", "l"."name", "l"."assigned_to";`, leadsPatterns.UntouchedQuery)
+
+	breaches := make([]*leads.SlaBreach, 0)
+	if err := r.db.Select(&breaches, query, hours); err != nil {
+		return nil, fmt.Errorf("flag sla breaches failed: %v", err)
+	}
+	return breaches, nil
+}
+
+func checkAffected(res sql.Result, notFound string) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New(notFound)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package leadsUsecases
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/leads"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsRepositories"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type ILeadsUsecase interface {
+	InsertLead(req *leads.LeadReq) (int, error)
+	FindOneLead(leadId int) (*leads.Lead, error)
+	FindLead(req *leads.LeadFilter) *entities.PaginateRes
+	UpdateLeadStatus(leadId int, req *leads.LeadStatusReq) (*leads.Lead, error)
+	AssignLead(leadId, userId int) (*leads.Lead, error)
+	AddLeadNote(req *leads.LeadNote) (*leads.Lead, error)
+	AddLeadTask(req *leads.LeadTask) (*leads.Lead, error)
+	DoneLeadTask(leadId, taskId int) (*leads.Lead, error)
+	FindLeadTasks(req *leads.LeadTaskFilter) *entities.PaginateRes
+	FindProjectSales(projectId int) ([]*leads.ProjectSales, error)
+	UpdateProjectSales(projectId int, userIds []int) ([]*leads.ProjectSales, error)
+	RunSlaAlerts()
+}
+
+type leadsUsecase struct {
+	cfg             config.IConfig
+	leadsRepository leadsRepositories.ILeadsRepository
+	db              *sql.DB
+}
+
+func LeadsUsecase(cfg config.IConfig, leadsRepository leadsRepositories.ILeadsRepository, db *sql.DB) ILeadsUsecase {
+	return &leadsUsecase{
+		cfg:             cfg,
+		leadsRepository: leadsRepository,
+		db:              db,
+	}
+}
+
+func (u *leadsUsecase) InsertLead(req *leads.LeadReq) (int, error) {
+	return u.leadsRepository.InsertLead(req)
+}
+
+func (u *leadsUsecase) FindOneLead(leadId int) (*leads.Lead, error) {
+	lead, err := u.leadsRepository.FindOneLead(leadId)
+	if err != nil {
+		return nil, err
+	}
+	return lead, nil
+}
+
+func (u *leadsUsecase) FindLead(req *leads.LeadFilter) *entities.PaginateRes {
+	req.SlaHours = u.cfg.App().LeadSlaHours()
+	leadsData, count := u.leadsRepository.FindLead(req)
+
+	return &entities.PaginateRes{
+		Data:      leadsData,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *leadsUsecase) UpdateLeadStatus(leadId int, req *leads.LeadStatusReq) (*leads.Lead, error) {
+	if err := u.leadsRepository.UpdateLeadStatus(leadId, req); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindOneLead(leadId)
+}
+
+func (u *leadsUsecase) AssignLead(leadId, userId int) (*leads.Lead, error) {
+	if err := u.leadsRepository.AssignLead(leadId, userId); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindOneLead(leadId)
+}
+
+func (u *leadsUsecase) AddLeadNote(req *leads.LeadNote) (*leads.Lead, error) {
+	if _, err := u.leadsRepository.FindOneLead(req.LeadId); err != nil {
+		return nil, err
+	}
+	if err := u.leadsRepository.InsertLeadNote(req); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindOneLead(req.LeadId)
+}
+
+func (u *leadsUsecase) AddLeadTask(req *leads.LeadTask) (*leads.Lead, error) {
+	lead, err := u.leadsRepository.FindOneLead(req.LeadId)
+	if err != nil {
+		return nil, err
+	}
+	// Tasks go to whoever owns the lead unless someone else is named
+	if req.AssignedTo == nil {
+		req.AssignedTo = lead.AssignedTo
+	}
+	if err := u.leadsRepository.InsertLeadTask(req); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindOneLead(req.LeadId)
+}
+
+func (u *leadsUsecase) DoneLeadTask(leadId, taskId int) (*leads.Lead, error) {
+	if err := u.leadsRepository.DoneLeadTask(leadId, taskId); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindOneLead(leadId)
+}
+
+func (u *leadsUsecase) FindLeadTasks(req *leads.LeadTaskFilter) *entities.PaginateRes {
+	tasks, count := u.leadsRepository.FindLeadTasks(req)
+
+	return &entities.PaginateRes{
+		Data:      tasks,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *leadsUsecase) FindProjectSales(projectId int) ([]*leads.ProjectSales, error) {
+	return u.leadsRepository.FindProjectSales(projectId)
+}
+
+func (u *leadsUsecase) UpdateProjectSales(projectId int, userIds []int) ([]*leads.ProjectSales, error) {
+	if err := u.leadsRepository.UpdateProjectSales(projectId, userIds); err != nil {
+		return nil, err
+	}
+	return u.leadsRepository.FindProjectSales(projectId)
+}
+
+// RunSlaAlerts raises an alert in the activity log for every new lead left
+// untouched longer than APP_LEAD_SLA_HOURS.
+func (u *leadsUsecase) RunSlaAlerts() {
+	hours := u.cfg.App().LeadSlaHours()
+	if hours <= 0 {
+		return
+	}
+
+	breaches, err := u.leadsRepository.FlagSlaBreaches(hours)
+	if err != nil {
+		log.Printf("leads: %v\n", err)
+		return
+	}
+	for _, b := range breaches {
+		details := fmt.Sprintf("ลูกค้าที่สนใจไม่ได้รับการติดต่อเกิน %d ชั่วโมง : %s", hours, b.Name)
+		if err := utils.LogSystemActivity(u.db, "alerted", details); err != nil {
+			log.Printf("leads: log activity failed: %v\n", err)
+		}
+	}
+}
@@ -3,16 +3,19 @@ package logos
 import "github.com/yporn/sirarom-backend/modules/entities"
 
 type Logo struct {
-	Id      int               `db:"id" json:"id"`
-	Index   int               `db:"index" json:"index"`
-	Name    string            `db:"name" json:"name"`
-	Display string            `db:"display" json:"display"`
-	Images  []*entities.Image `json:"images"`
+	Id          int               `db:"id" json:"id"`
+	Index       int               `db:"index" json:"index"`
+	Name        string            `db:"name" json:"name"`
+	Display     string            `db:"display" json:"display"`
+	PublishAt   *string           `db:"publish_at" json:"publish_at"`
+	UnpublishAt *string           `db:"unpublish_at" json:"unpublish_at"`
+	Images      []*entities.Image `json:"images"`
 }
 
 type LogoFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // name
+	Id      string `query:"id"`
+	Search  string `query:"search"` // name
+	Display string `query:"display"`
 	*entities.PaginationReq
 	*entities.SortReq
 }
@@ -2,15 +2,14 @@ package logosHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/logos"
 	"github.com/yporn/sirarom-backend/modules/logos/logosUsecases"
@@ -20,19 +19,23 @@ import (
 type logosHandlersErrCode string
 
 const (
-	findOneLogoErr logosHandlersErrCode = "logos-001"
-	findLogoErr    logosHandlersErrCode = "logos-002"
-	insertLogoErr  logosHandlersErrCode = "logos-003"
-	deleteLogoErr  logosHandlersErrCode = "logos-004"
-	updateLogoErr  logosHandlersErrCode = "logos-005"
+	findOneLogoErr     logosHandlersErrCode = "logos-001"
+	findLogoErr        logosHandlersErrCode = "logos-002"
+	insertLogoErr      logosHandlersErrCode = "logos-003"
+	deleteLogoErr      logosHandlersErrCode = "logos-004"
+	updateLogoErr      logosHandlersErrCode = "logos-005"
+	updateLogoOrderErr logosHandlersErrCode = "logos-006"
 )
 
 type ILogosHandler interface {
 	FindOneLogo(c *fiber.Ctx) error
+	FindOneAdminLogo(c *fiber.Ctx) error
 	FindLogo(c *fiber.Ctx) error
+	FindAdminLogo(c *fiber.Ctx) error
 	AddLogo(c *fiber.Ctx) error
 	UpdateLogo(c *fiber.Ctx) error
 	DeleteLogo(c *fiber.Ctx) error
+	UpdateLogoOrder(c *fiber.Ctx) error
 }
 
 type logosHandler struct {
@@ -52,6 +55,14 @@ func LogosHandler(cfg config.IConfig, logosUsecase logosUsecases.ILogosUsecase,
 }
 
 func (h *logosHandler) FindOneLogo(c *fiber.Ctx) error {
+	return h.findOneLogo(c, true)
+}
+
+func (h *logosHandler) FindOneAdminLogo(c *fiber.Ctx) error {
+	return h.findOneLogo(c, false)
+}
+
+func (h *logosHandler) findOneLogo(c *fiber.Ctx, public bool) error {
 	logoId := strings.Trim(c.Params("brand_id"), " ")
 
 	logo, err := h.logosUsecase.FindOneLogo(logoId)
@@ -62,10 +73,26 @@ func (h *logosHandler) FindOneLogo(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && logo.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneLogoErr),
+			"brand not found",
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, logo).Res()
 }
 
 func (h *logosHandler) FindLogo(c *fiber.Ctx) error {
+	return h.findLogo(c, true)
+}
+
+// FindAdminLogo returns rows in every display state, optionally filtered by ?display=.
+func (h *logosHandler) FindAdminLogo(c *fiber.Ctx) error {
+	return h.findLogo(c, false)
+}
+
+func (h *logosHandler) findLogo(c *fiber.Ctx, public bool) error {
 	req := &logos.LogoFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -79,6 +106,16 @@ func (h *logosHandler) FindLogo(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findLogoErr),
+			err.Error(),
+		).Res()
+	}
+
 	if req.Page < 1 {
 		req.Page = 1
 	}
@@ -109,6 +146,14 @@ func (h *logosHandler) AddLogo(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertLogoErr),
+			err.Error(),
+		).Res()
+	}
+
 	logo, err := h.logosUsecase.AddLogo(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -155,6 +200,14 @@ func (h *logosHandler) UpdateLogo(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLogoErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = logoId
 
 	logo, err := h.logosUsecase.UpdateLogo(req)
@@ -193,32 +246,68 @@ func (h *logosHandler) DeleteLogo(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range logo.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("brands/%s", path.Base(p.Url)),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.logosUsecase.DeleteLogo(logoId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteLogoErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบรนด์ในเครือ : "+logo.Name)
+	if err != nil {
+		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteLogoErr),
+			fmt.Sprintf("Failed to log activity %v", userID),
 			err.Error(),
 		).Res()
 	}
 
-	if err := h.logosUsecase.DeleteLogo(logoId); err != nil {
+
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UpdateLogoOrder rewrites the display order from the ids in the body, first id first.
+func (h *logosHandler) UpdateLogoOrder(c *fiber.Ctx) error {
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLogoOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateLogoOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.logosUsecase.UpdateLogoOrder(req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateLogoOrderErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteLogoErr),
+			string(updateLogoOrderErr),
 			err.Error(),
 		).Res()
 	}
 
 	// Log activity
 	userID := utils.GetUserIDFromContext(c)
-	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลแบรนด์ในเครือ : "+logo.Name)
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลแบรนด์ในเครือ")
 	if err != nil {
 		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
@@ -228,6 +317,5 @@ func (h *logosHandler) DeleteLogo(c *fiber.Ctx) error {
 		).Res()
 	}
 
-
-	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
 }
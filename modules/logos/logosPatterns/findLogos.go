@@ -67,7 +67,7 @@ func (b *findLogoBuilder) initQuery() {
 				) AS "it"
 			) AS "images"
 		FROM "logos" "l"
-		WHERE 1 = 1`
+		WHERE "l"."deleted_at" IS NULL`
 }
 
 func (b *findLogoBuilder) countQuery() {
@@ -75,13 +75,21 @@ func (b *findLogoBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "logos" "l"
-		WHERE 1 = 1`
+		WHERE "l"."deleted_at" IS NULL`
 }
 
 func (b *findLogoBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "l"."display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
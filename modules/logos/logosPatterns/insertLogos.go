@@ -52,9 +52,11 @@ func (b *insertLogoBuilder) insertLogo() error {
 	INSERT INTO "logos" (
 		"index",
 		"name",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at"
 	)
-	VALUES ($1, $2, $3)
+	VALUES ($1, $2, $3, NULLIF($4, '')::timestamptz, NULLIF($5, '')::timestamptz)
 		RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -63,6 +65,8 @@ func (b *insertLogoBuilder) insertLogo() error {
 		b.req.Index,
 		b.req.Name,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert logo failed: %v", err)
@@ -12,6 +12,7 @@ import (
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/logos"
 	"github.com/yporn/sirarom-backend/modules/logos/logosPatterns"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type ILogosRepository interface {
@@ -20,6 +21,7 @@ type ILogosRepository interface {
 	InsertLogo(req *logos.Logo) (*logos.Logo, error)
 	UpdateLogo(req *logos.Logo) (*logos.Logo, error)
 	DeleteLogo(logoId string) error
+	UpdateLogoOrder(ids []int) error
 }
 
 type logosRepository struct {
@@ -57,6 +59,7 @@ func (r *logosRepository) FindOneLogo(logoId string) (*logos.Logo, error) {
 			) AS "images"
 		FROM "logos" "l"
 		WHERE "l"."id" = $1
+		AND "l"."deleted_at" IS NULL
 		LIMIT 1
 	) AS "t";`
 
@@ -115,10 +118,14 @@ func (r *logosRepository) UpdateLogo(req *logos.Logo) (*logos.Logo, error) {
 }
 
 func (r *logosRepository) DeleteLogo(logoId string) error {
-	query := `DELETE FROM "logos" WHERE "id" = $1;`
+	query := `UPDATE "logos" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, logoId); err != nil {
 		return fmt.Errorf("delete logo failed: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *logosRepository) UpdateLogoOrder(ids []int) error {
+	return utils.Reorder(r.db, "logos", ids, "", nil)
+}
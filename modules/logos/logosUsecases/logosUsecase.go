@@ -14,6 +14,7 @@ type ILogosUsecase interface {
 	AddLogo(req *logos.Logo) (*logos.Logo, error) 
 	UpdateLogo(req *logos.Logo) (*logos.Logo, error)
 	DeleteLogo(logoId string) error
+	UpdateLogoOrder(ids []int) error
 }
 
 type logosUsecase struct {
@@ -69,3 +70,7 @@ func (u *logosUsecase) DeleteLogo(logoId string) error {
 	}
 	return nil
 }
+
+func (u *logosUsecase) UpdateLogoOrder(ids []int) error {
+	return u.logosRepository.UpdateLogoOrder(ids)
+}
@@ -0,0 +1,43 @@
+package media
+
+import "github.com/yporn/sirarom-backend/modules/entities"
+
+type Media struct {
+	Id         int             `db:"id" json:"id"`
+	FileName   string          `db:"filename" json:"filename"`
+	Url        string          `db:"url" json:"url"`
+	Path       string          `db:"path" json:"path"`
+	Hash       string          `db:"hash" json:"hash"`
+	Size       int             `db:"size" json:"size"`
+	Width      int             `db:"width" json:"width"`
+	Height     int             `db:"height" json:"height"`
+	MimeType   string          `db:"mime_type" json:"mime_type"`
+	SrcSet     entities.SrcSet `db:"srcset" json:"srcset"`
+	UploadedBy int             `db:"uploaded_by" json:"uploaded_by"`
+	References int             `db:"references" json:"references"`
+	CreatedAt  string          `db:"created_at" json:"created_at"`
+	UpdatedAt  string          `db:"updated_at" json:"updated_at"`
+}
+
+type MediaFilter struct {
+	Search     string `query:"search"`
+	MimeType   string `query:"mime_type"`
+	Hash       string `query:"hash"`
+	UploadedBy string `query:"uploaded_by"`
+	Unused     bool   `query:"unused"`
+	*entities.PaginationReq
+	*entities.SortReq
+}
+
+type MediaSweepReq struct {
+	Remove     bool
+	GraceHours int `query:"grace_hours"`
+}
+
+// MediaSweep lists what nobody points at: media rows without references and
+// files on storage without a media row.
+type MediaSweep struct {
+	Unreferenced []*Media `json:"unreferenced"`
+	Untracked    []string `json:"untracked"`
+	Removed      bool     `json:"removed"`
+}
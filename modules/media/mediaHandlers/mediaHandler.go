@@ -0,0 +1,133 @@
+package mediaHandlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/media"
+	"github.com/yporn/sirarom-backend/modules/media/mediaUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type mediaHandlersErrCode string
+
+const (
+	findOneMediaErr mediaHandlersErrCode = "media-001"
+	findMediaErr    mediaHandlersErrCode = "media-002"
+	sweepMediaErr   mediaHandlersErrCode = "media-003"
+)
+
+type IMediaHandler interface {
+	FindOneMedia(c *fiber.Ctx) error
+	FindMedia(c *fiber.Ctx) error
+	SweepMedia(c *fiber.Ctx) error
+}
+
+type mediaHandler struct {
+	cfg          config.IConfig
+	mediaUsecase mediaUsecases.IMediaUsecase
+	db           *sql.DB
+}
+
+func MediaHandler(cfg config.IConfig, mediaUsecase mediaUsecases.IMediaUsecase, db *sql.DB) IMediaHandler {
+	return &mediaHandler{
+		cfg:          cfg,
+		mediaUsecase: mediaUsecase,
+		db:           db,
+	}
+}
+
+func (h *mediaHandler) FindOneMedia(c *fiber.Ctx) error {
+	mediaId := strings.Trim(c.Params("media_id"), " ")
+
+	mediaData, err := h.mediaUsecase.FindOneMedia(mediaId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneMediaErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, mediaData).Res()
+}
+
+func (h *mediaHandler) FindMedia(c *fiber.Ctx) error {
+	req := &media.MediaFilter{
+		PaginationReq: &entities.PaginationReq{},
+		SortReq:       &entities.SortReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findMediaErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	if req.OrderBy == "" {
+		req.OrderBy = "created_at"
+	}
+
+	if req.Sort == "" {
+		req.Sort = "desc"
+	}
+
+	mediaData := h.mediaUsecase.FindMedia(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, mediaData).Res()
+}
+
+// SweepMedia reports unused files on GET and removes them on DELETE.
+func (h *mediaHandler) SweepMedia(c *fiber.Ctx) error {
+	req := &media.MediaSweepReq{
+		GraceHours: 24,
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(sweepMediaErr),
+			err.Error(),
+		).Res()
+	}
+	if req.GraceHours < 0 {
+		req.GraceHours = 0
+	}
+	req.Remove = c.Method() == fiber.MethodDelete
+
+	res, err := h.mediaUsecase.SweepMedia(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(sweepMediaErr),
+			err.Error(),
+		).Res()
+	}
+
+	if res.Removed {
+		// Log activity
+		userID := utils.GetUserIDFromContext(c)
+		err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", fmt.Sprintf("ล้างไฟล์ที่ไม่ได้ใช้งาน : %d ไฟล์", len(res.Unreferenced)+len(res.Untracked)))
+		if err != nil {
+			// Handle error if logging fails
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				fmt.Sprintf("Failed to log activity %v", userID),
+				err.Error(),
+			).Res()
+		}
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
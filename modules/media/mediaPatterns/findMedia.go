@@ -0,0 +1,214 @@
+package mediaPatterns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/media"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type IFindMediaBuilder interface {
+	openJsonQuery()
+	initQuery()
+	countQuery()
+	whereQuery()
+	sort()
+	paginate()
+	closeJsonQuery()
+	resetQuery()
+	Result() []*media.Media
+	Count() int
+	PrintQuery()
+}
+
+type findMediaBuilder struct {
+	db             *sqlx.DB
+	req            *media.MediaFilter
+	query          string
+	lastStackIndex int
+	values         []any
+}
+
+func FindMediaBuilder(db *sqlx.DB, req *media.MediaFilter) IFindMediaBuilder {
+	return &findMediaBuilder{
+		db:  db,
+		req: req,
+	}
+}
+
+func (b *findMediaBuilder) openJsonQuery() {
+	b.query += `
+	SELECT
+		array_to_json(array_agg("t"))
+	FROM (`
+}
+
+func (b *findMediaBuilder) initQuery() {
+	b.query += `
+		SELECT
+			"m".*,
+			(
+				SELECT
+					COUNT(*)
+				FROM "media_references" "r"
+				WHERE "r"."media_id" = "m"."id"
+			) AS "references"
+		FROM "media" "m"
+		WHERE 1 = 1`
+}
+
+func (b *findMediaBuilder) countQuery() {
+	b.query += `
+		SELECT
+			COUNT(*) AS "count"
+		FROM "media" "m"
+		WHERE 1 = 1`
+}
+
+func (b *findMediaBuilder) whereQuery() {
+	// Search check
+	if b.req.Search != "" {
+		b.values = append(b.values, "%"+strings.ToLower(b.req.Search)+"%")
+		b.query += fmt.Sprintf(`
+		AND (LOWER("m"."filename") LIKE $%d OR LOWER("m"."path") LIKE $%d)`, len(b.values), len(b.values))
+	}
+
+	// Mime type check
+	if b.req.MimeType != "" {
+		b.values = append(b.values, b.req.MimeType)
+		b.query += fmt.Sprintf(`
+		AND "m"."mime_type" = $%d`, len(b.values))
+	}
+
+	// Hash check
+	if b.req.Hash != "" {
+		b.values = append(b.values, b.req.Hash)
+		b.query += fmt.Sprintf(`
+		AND "m"."hash" = $%d`, len(b.values))
+	}
+
+	// Uploader check
+	if b.req.UploadedBy != "" {
+		b.values = append(b.values, b.req.UploadedBy)
+		b.query += fmt.Sprintf(`
+		AND "m"."uploaded_by" = $%d`, len(b.values))
+	}
+
+	// Unused check
+	if b.req.Unused {
+		b.query += `
+		AND NOT EXISTS (SELECT 1 FROM "media_references" "r" WHERE "r"."media_id" = "m"."id")`
+	}
+
+	// Last stack record
+	b.lastStackIndex = len(b.values)
+}
+
+func (b *findMediaBuilder) sort() {
+	orderByMap := map[string]string{
+		"id":         "\"m\".\"id\"",
+		"filename":   "\"m\".\"filename\"",
+		"size":       "\"m\".\"size\"",
+		"created_at": "\"m\".\"created_at\"",
+	}
+
+	orderBy := orderByMap[b.req.OrderBy]
+	if orderBy == "" {
+		orderBy = orderByMap["id"]
+	}
+
+	sortOrder := strings.ToUpper(b.req.Sort)
+	if sortOrder != "ASC" {
+		sortOrder = "DESC"
+	}
+
+	b.query += fmt.Sprintf(`
+		ORDER BY %s %s`, orderBy, sortOrder)
+}
+
+func (b *findMediaBuilder) paginate() {
+	// offset (page - 1)*limit
+	b.values = append(b.values, (b.req.Page-1)*b.req.Limit, b.req.Limit)
+
+	b.query += fmt.Sprintf(`	OFFSET $%d LIMIT $%d`, b.lastStackIndex+1, b.lastStackIndex+2)
+	b.lastStackIndex = len(b.values)
+}
+
+func (b *findMediaBuilder) closeJsonQuery() {
+	b.query += `
+	) AS "t";`
+}
+
+func (b *findMediaBuilder) resetQuery() {
+	b.query = ""
+	b.values = make([]any, 0)
+	b.lastStackIndex = 0
+}
+
+func (b *findMediaBuilder) Result() []*media.Media {
+	_, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	bytes := make([]byte, 0)
+	mediaData := make([]*media.Media, 0)
+
+	if err := b.db.Get(&bytes, b.query, b.values...); err != nil {
+		log.Printf("find media failed: %v\n", err)
+		return make([]*media.Media, 0)
+	}
+
+	if err := json.Unmarshal(bytes, &mediaData); err != nil {
+		log.Printf("unmarshal media failed: %v\n", err)
+		return make([]*media.Media, 0)
+	}
+	b.resetQuery()
+	return mediaData
+}
+
+func (b *findMediaBuilder) Count() int {
+	_, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	var count int
+	if err := b.db.Get(&count, b.query, b.values...); err != nil {
+		log.Printf("count media failed: %v\n", err)
+		return 0
+	}
+	b.resetQuery()
+	return count
+}
+
+func (b *findMediaBuilder) PrintQuery() {
+	utils.Debug(b.values)
+	fmt.Println(b.query)
+}
+
+type findMediaEngineer struct {
+	builder IFindMediaBuilder
+}
+
+func FindMediaEngineer(builder IFindMediaBuilder) *findMediaEngineer {
+	return &findMediaEngineer{builder: builder}
+}
+
+func (en *findMediaEngineer) FindMedia() IFindMediaBuilder {
+	en.builder.openJsonQuery()
+	en.builder.initQuery()
+	en.builder.whereQuery()
+	en.builder.sort()
+	en.builder.paginate()
+	en.builder.closeJsonQuery()
+	return en.builder
+}
+
+func (en *findMediaEngineer) CountMedia() IFindMediaBuilder {
+	en.builder.countQuery()
+	en.builder.whereQuery()
+	return en.builder
+}
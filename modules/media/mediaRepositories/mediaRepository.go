@@ -0,0 +1,169 @@
+package mediaRepositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/media"
+	"github.com/yporn/sirarom-backend/modules/media/mediaPatterns"
+)
+
+type IMediaRepository interface {
+	FindOneMedia(mediaId string) (*media.Media, error)
+	FindMedia(req *media.MediaFilter) ([]*media.Media, int)
+	InsertMedia(req *media.Media) (*media.Media, error)
+	FindUnreferencedMedia(graceHours int) ([]*media.Media, error)
+	FindMediaPaths() (map[string]bool, error)
+	DeleteMedia(mediaId int) error
+}
+
+type mediaRepository struct {
+	db  *sqlx.DB
+	cfg config.IConfig
+}
+
+func MediaRepository(db *sqlx.DB, cfg config.IConfig) IMediaRepository {
+	return &mediaRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (r *mediaRepository) FindOneMedia(mediaId string) (*media.Media, error) {
+	query := `
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT
+			"m".*,
+			(
+				SELECT
+					COUNT(*)
+				FROM "media_references" "r"
+				WHERE "r"."media_id" = "m"."id"
+			) AS "references"
+		FROM "media" "m"
+		WHERE "m"."id" = $1
+		LIMIT 1
+	) AS "t";`
+
+	mediaBytes := make([]byte, 0)
+	mediaData := &media.Media{}
+
+	if err := r.db.Get(&mediaBytes, query, mediaId); err != nil {
+		return nil, fmt.Errorf("get media failed: %v", err)
+	}
+	if err := json.Unmarshal(mediaBytes, mediaData); err != nil {
+		return nil, fmt.Errorf("unmarshal media failed: %v", err)
+	}
+	return mediaData, nil
+}
+
+func (r *mediaRepository) FindMedia(req *media.MediaFilter) ([]*media.Media, int) {
+	builder := mediaPatterns.FindMediaBuilder(r.db, req)
+	engineer := mediaPatterns.FindMediaEngineer(builder)
+
+	result := engineer.FindMedia().Result()
+	count := engineer.CountMedia().Count()
+
+	return result, count
+}
+
+func (r *mediaRepository) InsertMedia(req *media.Media) (*media.Media, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	INSERT INTO "media" (
+		"filename",
+		"url",
+		"path",
+		"hash",
+		"size",
+		"width",
+		"height",
+		"mime_type",
+		"srcset",
+		"uploaded_by"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0))
+	ON CONFLICT ("path") DO UPDATE SET
+		"url" = EXCLUDED."url",
+		"hash" = EXCLUDED."hash",
+		"size" = EXCLUDED."size",
+		"width" = EXCLUDED."width",
+		"height" = EXCLUDED."height",
+		"mime_type" = EXCLUDED."mime_type",
+		"srcset" = EXCLUDED."srcset"
+	RETURNING "id";`
+
+	if err := r.db.QueryRowContext(
+		ctx,
+		query,
+		req.FileName,
+		req.Url,
+		req.Path,
+		req.Hash,
+		req.Size,
+		req.Width,
+		req.Height,
+		req.MimeType,
+		req.SrcSet,
+		req.UploadedBy,
+	).Scan(&req.Id); err != nil {
+		return nil, fmt.Errorf("insert media failed: %v", err)
+	}
+	return req, nil
+}
+
+func (r *mediaRepository) FindUnreferencedMedia(graceHours int) ([]*media.Media, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"m".*,
+			0 AS "references"
+		FROM "media" "m"
+		WHERE NOT EXISTS (SELECT 1 FROM "media_references" "r" WHERE "r"."media_id" = "m"."id")
+		AND "m"."created_at" < now() - make_interval(hours => $1)
+		ORDER BY "m"."id"
+	) AS "t";`
+
+	bytes := make([]byte, 0)
+	mediaData := make([]*media.Media, 0)
+
+	if err := r.db.Get(&bytes, query, graceHours); err != nil {
+		return nil, fmt.Errorf("get unreferenced media failed: %v", err)
+	}
+	if err := json.Unmarshal(bytes, &mediaData); err != nil {
+		return nil, fmt.Errorf("unmarshal media failed: %v", err)
+	}
+	return mediaData, nil
+}
+
+func (r *mediaRepository) FindMediaPaths() (map[string]bool, error) {
+	paths := make([]string, 0)
+	if err := r.db.Select(&paths, `SELECT "path" FROM "media";`); err != nil {
+		return nil, fmt.Errorf("get media paths failed: %v", err)
+	}
+
+	res := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		res[p] = true
+	}
+	return res, nil
+}
+
+func (r *mediaRepository) DeleteMedia(mediaId int) error {
+	query := `DELETE FROM "media" WHERE "id" = $1;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, mediaId); err != nil {
+		return fmt.Errorf("delete media failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package mediaUsecases
+
+import (
+	"log"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/files"
+	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/modules/media"
+	"github.com/yporn/sirarom-backend/modules/media/mediaRepositories"
+)
+
+// variantSuffix matches the width variants written next to an upload, e.g. "a_640w.webp".
+var variantSuffix = regexp.MustCompile(`_\d+w(\.[A-Za-z0-9]+)$`)
+
+type IMediaUsecase interface {
+	FindOneMedia(mediaId string) (*media.Media, error)
+	FindMedia(req *media.MediaFilter) *entities.PaginateRes
+	AddMedia(req []*files.FileRes, userId int) error
+	SweepMedia(req *media.MediaSweepReq) (*media.MediaSweep, error)
+}
+
+type mediaUsecase struct {
+	mediaRepository mediaRepositories.IMediaRepository
+	filesUsecase    filesUsecases.IFilesUsecase
+}
+
+func MediaUsecase(mediaRepository mediaRepositories.IMediaRepository, filesUsecase filesUsecases.IFilesUsecase) IMediaUsecase {
+	return &mediaUsecase{
+		mediaRepository: mediaRepository,
+		filesUsecase:    filesUsecase,
+	}
+}
+
+func (u *mediaUsecase) FindOneMedia(mediaId string) (*media.Media, error) {
+	mediaData, err := u.mediaRepository.FindOneMedia(mediaId)
+	if err != nil {
+		return nil, err
+	}
+	return mediaData, nil
+}
+
+func (u *mediaUsecase) FindMedia(req *media.MediaFilter) *entities.PaginateRes {
+	mediaData, count := u.mediaRepository.FindMedia(req)
+
+	return &entities.PaginateRes{
+		Data:      mediaData,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *mediaUsecase) AddMedia(req []*files.FileRes, userId int) error {
+	for _, f := range req {
+		mediaData, err := u.mediaRepository.InsertMedia(&media.Media{
+			FileName:   f.FileName,
+			Url:        f.Url,
+			Path:       f.Path,
+			Hash:       f.Hash,
+			Size:       f.Size,
+			Width:      f.Width,
+			Height:     f.Height,
+			MimeType:   f.MimeType,
+			SrcSet:     f.SrcSet,
+			UploadedBy: userId,
+		})
+		if err != nil {
+			return err
+		}
+		f.MediaId = mediaData.Id
+	}
+	return nil
+}
+
+func (u *mediaUsecase) SweepMedia(req *media.MediaSweepReq) (*media.MediaSweep, error) {
+	unreferenced, err := u.mediaRepository.FindUnreferencedMedia(req.GraceHours)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := u.mediaRepository.FindMediaPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := u.filesUsecase.ListFileOnStorage("")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked := make([]string, 0)
+	for _, dest := range stored {
+		// Résumés stored before they moved under the private prefix have no
+		// media row, they belong to job applications
+		if strings.HasPrefix(dest, "resumes/") {
+			continue
+		}
+		key := "assets/images/" + dest
+		if paths[key] || paths[variantSuffix.ReplaceAllString(key, "$1")] {
+			continue
+		}
+		untracked = append(untracked, dest)
+	}
+
+	res := &media.MediaSweep{
+		Unreferenced: unreferenced,
+		Untracked:    untracked,
+	}
+	if !req.Remove {
+		return res, nil
+	}
+
+	for _, m := range unreferenced {
+		if strings.HasPrefix(m.Path, "assets/images/") {
+			if err := u.filesUsecase.DeleteFileOnStorage([]*files.DeleteFileReq{
+				{Destination: strings.TrimPrefix(m.Path, "assets/images/")},
+			}); err != nil {
+				// The file may already be gone, the row is removed either way
+				log.Printf("sweep media: %v\n", err)
+			}
+		}
+		if err := u.mediaRepository.DeleteMedia(m.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dest := range untracked {
+		if err := u.filesUsecase.DeleteFileOnStorage([]*files.DeleteFileReq{
+			{Destination: dest},
+		}); err != nil {
+			log.Printf("sweep media: %v\n", err)
+		}
+	}
+	res.Removed = true
+
+	return res, nil
+}
@@ -3,11 +3,13 @@ package middlewaresHandlers
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
@@ -22,6 +24,7 @@ const (
 	jwtAuthErr     middlewareHandlersErrCode = "middleware-002"
 	authorizeErr   middlewareHandlersErrCode = "middleware-003"
 	apiKeyErr      middlewareHandlersErrCode = "middleware-004"
+	rateLimitErr   middlewareHandlersErrCode = "middleware-005"
 )
 
 type IMiddlewaresHandler interface {
@@ -31,6 +34,8 @@ type IMiddlewaresHandler interface {
 	JwtAuth() fiber.Handler
 	Authorize(expectRoleIDs ...int) fiber.Handler
 	ApiKeyAuth() fiber.Handler
+	PublicAuth() fiber.Handler
+	RateLimit(max int, expiration time.Duration) fiber.Handler
 }
 
 type middlewaresHandler struct {
@@ -92,7 +97,8 @@ func (h *middlewaresHandler) JwtAuth() fiber.Handler {
 		claims := result.Claims
 		// fmt.Println("Claims:", claims) // ปริ้นค่า claims หลังจาก Parse Token
 
-		if !h.middlewaresUsecase.FindAccessToken(claims.Id, token) {
+		sessionId, ok := h.middlewaresUsecase.FindAccessToken(claims.Id, token)
+		if !ok {
 			return entities.NewResponse(c).Error(
 				fiber.ErrUnauthorized.Code,
 				string(jwtAuthErr),
@@ -103,6 +109,7 @@ func (h *middlewaresHandler) JwtAuth() fiber.Handler {
 		// Set UserId
 		c.Locals("userId", claims.Id)
 		c.Locals("userRoleId", claims.UserRole)
+		c.Locals("sessionId", sessionId)
 		return c.Next()
 	}
 }
@@ -163,3 +170,31 @@ func (h *middlewaresHandler) ApiKeyAuth() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// PublicAuth guards the public read routes, which only need an api key when APP_REQUIRE_API_KEY is set.
+func (h *middlewaresHandler) PublicAuth() fiber.Handler {
+	if h.cfg.App().RequireApiKey() {
+		return h.ApiKeyAuth()
+	}
+	return func(c *fiber.Ctx) error {
+		return c.Next()
+	}
+}
+
+// RateLimit allows max requests per client ip within expiration.
+func (h *middlewaresHandler) RateLimit(max int, expiration time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: expiration,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return entities.NewResponse(c).Error(
+				fiber.ErrTooManyRequests.Code,
+				string(rateLimitErr),
+				"too many requests, please try again later",
+			).Res()
+		},
+	})
+}
@@ -8,7 +8,7 @@ import (
 )
 
 type IMiddlewaresRepository interface {
-	FindAccessToken(userId, accessToken string) bool
+	FindAccessToken(userId, accessToken string) (string, bool)
 	FindRole() ([]*middlewares.Role, error)
 	GetUserRoles(userID int) ([]*middlewares.Role, error) 
 }
@@ -23,19 +23,33 @@ func MiddlewaresRepository(db *sqlx.DB) IMiddlewaresRepository {
 	}
 }
 
-func (r *middlewaresRepository) FindAccessToken(userId, accessToken string) bool {
+// FindAccessToken returns the id of the unexpired session holding the access
+// token and marks it as used, at most once a minute.
+func (r *middlewaresRepository) FindAccessToken(userId, accessToken string) (string, bool) {
 	query := `
-	SELECT
-		(CASE WHEN COUNT(*) = 1 THEN TRUE ELSE FALSE END)
-	FROM "oauth"
-	WHERE "user_id" = $1
-	AND "access_token" = $2;
+	WITH "session" AS (
+		SELECT
+			"id",
+			"last_used_at"
+		FROM "oauth"
+		WHERE "user_id" = $1
+		AND "access_token" = $2
+		AND "expires_at" > now()
+	), "touch" AS (
+		UPDATE "oauth" SET
+			"last_used_at" = now()
+		WHERE "id" IN (
+			SELECT "id" FROM "session"
+			WHERE "last_used_at" < now() - INTERVAL '1 minute'
+		)
+	)
+	SELECT "id" FROM "session";
 	`
-	var check bool
-	if err := r.db.Get(&check, query, userId, accessToken); err != nil {
-		return false
+	var sessionId string
+	if err := r.db.Get(&sessionId, query, userId, accessToken); err != nil {
+		return "", false
 	}
-	return true
+	return sessionId, true
 }
 
 func (r *middlewaresRepository) FindRole() ([]*middlewares.Role, error) {
@@ -7,7 +7,7 @@ import (
 )
 
 type IMiddlewaresUsecase interface {
-	FindAccessToken(userId, accessToken string) bool
+	FindAccessToken(userId, accessToken string) (string, bool)
 	FindRole() ([]*middlewares.Role, error)
 	GetUserRoles(userID int) ([]*middlewares.Role, error)
 }
@@ -22,7 +22,7 @@ func MiddlewaresUsecase(middlewaresRepository middlewaresRepositories.IMiddlewar
 	}
 }
 
-func (u *middlewaresUsecase) FindAccessToken(userId, accessToken string) bool {
+func (u *middlewaresUsecase) FindAccessToken(userId, accessToken string) (string, bool) {
 	return u.middlewaresRepository.FindAccessToken(userId, accessToken)
 }
 
@@ -2,6 +2,9 @@ package projects
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/houseModels"
@@ -10,6 +13,7 @@ import (
 type Project struct {
 	Id            int                       `db:"id" json:"id"`
 	Name          string                    `db:"name" json:"name"`
+	Slug          string                    `db:"slug" json:"slug"` // empty on create is made from the name, on update keeps the stored one
 	Index         int                       `db:"index" json:"index"`
 	Heading       string                    `db:"heading" json:"heading"`
 	Text          string                    `db:"text" json:"text"`
@@ -24,6 +28,8 @@ type Project struct {
 	Address       string                    `db:"address" json:"address"`
 	LinkLocation  string                    `db:"link_location" json:"link_location"`
 	Display       string                    `db:"display" json:"display"`
+	PublishAt     *string                   `db:"publish_at" json:"publish_at"`
+	UnpublishAt   *string                   `db:"unpublish_at" json:"unpublish_at"`
 	CreatedAt     string                    `db:"created_at" json:"created_at"`
 	UpdatedAt     string                    `db:"updated_at" json:"updated_at"`
 	Images        []*entities.Image         `json:"images"`
@@ -31,6 +37,78 @@ type Project struct {
 	DescAreaItem  []*ProjectDescAreaItem    `json:"area_items"`
 	FacilityItem  []*ProjectFacilityItem    `json:"facilities_items"`
 	HouseModel    []*houseModels.HouseModel `json:"house_models"`
+	Phases        []*ProjectPhase           `json:"phases"`
+}
+
+// GroupByPhase moves each house model under its phase. Only the house models
+// without a phase are left in HouseModel.
+func (p *Project) GroupByPhase() {
+	phases := make(map[int]*ProjectPhase, len(p.Phases))
+	for _, phase := range p.Phases {
+		phase.HouseModel = make([]*houseModels.HouseModel, 0)
+		phases[phase.Id] = phase
+	}
+
+	unphased := make([]*houseModels.HouseModel, 0)
+	for _, houseModel := range p.HouseModel {
+		if houseModel.PhaseId != nil && phases[*houseModel.PhaseId] != nil {
+			phase := phases[*houseModel.PhaseId]
+			phase.HouseModel = append(phase.HouseModel, houseModel)
+			continue
+		}
+		unphased = append(unphased, houseModel)
+	}
+	p.HouseModel = unphased
+}
+
+const (
+	PhaseUpcoming = "upcoming"
+	PhaseSelling  = "selling"
+	PhaseSoldOut  = "sold_out"
+)
+
+// ProjectPhase is a phase or zone of a project that is launched on its own.
+type ProjectPhase struct {
+	Id           int                       `db:"id" json:"id"`
+	ProjectId    int                       `db:"project_id" json:"project_id"`
+	Name         string                    `db:"name" json:"name"`
+	Index        int                       `db:"index" json:"index"`
+	Status       string                    `db:"status" json:"status"`
+	LaunchDate   *string                   `db:"launch_date" json:"launch_date"`
+	PriceMin     *float64                  `db:"price_min" json:"price_min"`
+	PriceMax     *float64                  `db:"price_max" json:"price_max"`
+	Description  string                    `db:"description" json:"description"`
+	CreatedAt    string                    `db:"created_at" json:"created_at"`
+	UpdatedAt    string                    `db:"updated_at" json:"updated_at"`
+	Images       []*entities.Image         `json:"images"`
+	DescAreaItem []*ProjectDescAreaItem    `json:"area_items"`
+	HouseModel   []*houseModels.HouseModel `json:"house_models,omitempty"`
+}
+
+func (p *ProjectPhase) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch p.Status {
+	case "":
+		p.Status = PhaseUpcoming
+	case PhaseUpcoming, PhaseSelling, PhaseSoldOut:
+	default:
+		return fmt.Errorf("status is invalid: %s", p.Status)
+	}
+	if p.LaunchDate != nil && *p.LaunchDate != "" {
+		if _, err := time.Parse("2006-01-02", *p.LaunchDate); err != nil {
+			return fmt.Errorf("launch_date must be YYYY-MM-DD")
+		}
+	}
+	if (p.PriceMin != nil && *p.PriceMin < 0) || (p.PriceMax != nil && *p.PriceMax < 0) {
+		return fmt.Errorf("price must not be negative")
+	}
+	if p.PriceMin != nil && p.PriceMax != nil && *p.PriceMax < *p.PriceMin {
+		return fmt.Errorf("price_max must not be less than price_min")
+	}
+	return nil
 }
 
 type ProjectHouseTypeItem struct {
@@ -54,6 +132,7 @@ type ProjectFacilityItem struct {
 type ProjectFilter struct {
 	Search        string `query:"search"` // name,status_project,type_project,location
 	StatusProject string `query:"status_project"`
+	Display       string `query:"display"`
 	*entities.PaginationReq
 	*entities.SortReq
 }
@@ -2,38 +2,61 @@ package projectsHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/modules/houseModels"
 	"github.com/yporn/sirarom-backend/modules/projects"
 	"github.com/yporn/sirarom-backend/modules/projects/projectsUsecases"
+	"github.com/yporn/sirarom-backend/modules/revisions"
 	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type projectsHandlersErrCode string
 
 const (
-	findOneProjectErr projectsHandlersErrCode = "projects-001"
-	findProjectErr    projectsHandlersErrCode = "projects-002"
-	insertProjectErr  projectsHandlersErrCode = "projects-003"
-	deleteProjectErr  projectsHandlersErrCode = "projects-004"
-	updateProjectErr  projectsHandlersErrCode = "projects-005"
+	findOneProjectErr         projectsHandlersErrCode = "projects-001"
+	findProjectErr            projectsHandlersErrCode = "projects-002"
+	insertProjectErr          projectsHandlersErrCode = "projects-003"
+	deleteProjectErr          projectsHandlersErrCode = "projects-004"
+	updateProjectErr          projectsHandlersErrCode = "projects-005"
+	findProjectRevisionsErr   projectsHandlersErrCode = "projects-006"
+	findOneProjectRevisionErr projectsHandlersErrCode = "projects-007"
+	diffProjectRevisionsErr   projectsHandlersErrCode = "projects-008"
+	restoreProjectRevisionErr projectsHandlersErrCode = "projects-009"
+	updateProjectOrderErr     projectsHandlersErrCode = "projects-010"
+	findPhasesErr             projectsHandlersErrCode = "projects-011"
+	insertPhaseErr            projectsHandlersErrCode = "projects-012"
+	updatePhaseErr            projectsHandlersErrCode = "projects-013"
+	deletePhaseErr            projectsHandlersErrCode = "projects-014"
+	findOneProjectBySlugErr   projectsHandlersErrCode = "projects-015"
 )
 
 type IProjectsHandler interface {
 	FindOneProject(c *fiber.Ctx) error
+	FindOneProjectBySlug(c *fiber.Ctx) error
+	FindOneAdminProject(c *fiber.Ctx) error
 	FindProject(c *fiber.Ctx) error
+	FindAdminProject(c *fiber.Ctx) error
 	AddProject(c *fiber.Ctx) error
 	UpdateProject(c *fiber.Ctx) error
 	DeleteProject(c *fiber.Ctx) error
 	FindProjectHouseModel(c *fiber.Ctx) error
+	FindProjectRevisions(c *fiber.Ctx) error
+	FindOneProjectRevision(c *fiber.Ctx) error
+	DiffProjectRevisions(c *fiber.Ctx) error
+	RestoreProjectRevision(c *fiber.Ctx) error
+	UpdateProjectOrder(c *fiber.Ctx) error
+	FindProjectPhases(c *fiber.Ctx) error
+	AddProjectPhase(c *fiber.Ctx) error
+	UpdateProjectPhase(c *fiber.Ctx) error
+	DeleteProjectPhase(c *fiber.Ctx) error
 }
 
 type projectsHandler struct {
@@ -53,8 +76,39 @@ func ProjectsHandler(cfg config.IConfig, projectsUsecase projectsUsecases.IProje
 }
 
 func (h *projectsHandler) FindOneProject(c *fiber.Ctx) error {
-	projectId := strings.Trim(c.Params("project_id"), " ")
+	return h.findOneProject(c, strings.Trim(c.Params("project_id"), " "), true)
+}
+
+func (h *projectsHandler) FindOneAdminProject(c *fiber.Ctx) error {
+	return h.findOneProject(c, strings.Trim(c.Params("project_id"), " "), false)
+}
+
+// FindOneProjectBySlug answers an old slug with a redirect to the current one.
+func (h *projectsHandler) FindOneProjectBySlug(c *fiber.Ctx) error {
+	slug := strings.Trim(c.Params("slug"), " ")
+
+	projectId, current, err := h.projectsUsecases.FindProjectSlug(utils.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, utils.ErrSlugNotFound) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(findOneProjectBySlugErr),
+				"project not found",
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneProjectBySlugErr),
+			err.Error(),
+		).Res()
+	}
+	if current != slug {
+		return c.Redirect(utils.SlugPath(c.Path(), current), fiber.StatusMovedPermanently)
+	}
+	return h.findOneProject(c, strconv.Itoa(projectId), true)
+}
 
+func (h *projectsHandler) findOneProject(c *fiber.Ctx, projectId string, public bool) error {
 	project, err := h.projectsUsecases.FindOneProject(projectId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -63,6 +117,24 @@ func (h *projectsHandler) FindOneProject(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && project.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneProjectErr),
+			"project not found",
+		).Res()
+	}
+	if public {
+		// Only published house models are shown under a published project
+		houseModelsData := make([]*houseModels.HouseModel, 0)
+		for _, houseModel := range project.HouseModel {
+			if houseModel.Display == entities.DisplayPublished {
+				houseModelsData = append(houseModelsData, houseModel)
+			}
+		}
+		project.HouseModel = houseModelsData
+	}
+	project.GroupByPhase()
 	return entities.NewResponse(c).Success(fiber.StatusOK, project).Res()
 }
 
@@ -77,10 +149,27 @@ func (h *projectsHandler) FindProjectHouseModel(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if project.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOneProjectErr),
+			"project not found",
+		).Res()
+	}
+	project.GroupByPhase()
 	return entities.NewResponse(c).Success(fiber.StatusOK, project).Res()
 }
 
 func (h *projectsHandler) FindProject(c *fiber.Ctx) error {
+	return h.findProject(c, true)
+}
+
+// FindAdminProject returns rows in every display state, optionally filtered by ?display=.
+func (h *projectsHandler) FindAdminProject(c *fiber.Ctx) error {
+	return h.findProject(c, false)
+}
+
+func (h *projectsHandler) findProject(c *fiber.Ctx, public bool) error {
 	req := &projects.ProjectFilter{
 		SortReq:       &entities.SortReq{},
 		PaginationReq: &entities.PaginationReq{},
@@ -93,6 +182,16 @@ func (h *projectsHandler) FindProject(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProjectErr),
+			err.Error(),
+		).Res()
+	}
+
 	// Paginate
 	if req.Page < 1 {
 		req.Page = 1
@@ -140,6 +239,14 @@ func (h *projectsHandler) AddProject(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertProjectErr),
+			err.Error(),
+		).Res()
+	}
+
 	if len(req.HouseTypeItem) == 0 {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
@@ -167,6 +274,13 @@ func (h *projectsHandler) AddProject(c *fiber.Ctx) error {
 
 	project, err := h.projectsUsecases.AddProject(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(insertProjectErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(insertProjectErr),
@@ -214,10 +328,26 @@ func (h *projectsHandler) UpdateProject(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProjectErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = projectId
 
-	project, err := h.projectsUsecases.UpdateProject(req)
+	userID := utils.GetUserIDFromContext(c)
+	project, err := h.projectsUsecases.UpdateProject(req, userID)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(updateProjectErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(updateProjectErr),
@@ -226,7 +356,6 @@ func (h *projectsHandler) UpdateProject(c *fiber.Ctx) error {
 	}
 
 	// Log activity
-	userID := utils.GetUserIDFromContext(c)
 	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขข้อมูลโครงการ : "+project.Name)
 	if err != nil {
 		// Handle error if logging fails
@@ -252,32 +381,385 @@ func (h *projectsHandler) DeleteProject(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range project.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("projects/%s", path.Base(p.Url)),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.projectsUsecases.DeleteProject(projectId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteProjectErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลโครงการ : "+project.Name)
+	if err != nil {
+		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteProjectErr),
+			fmt.Sprintf("Failed to log activity %v", userID),
 			err.Error(),
 		).Res()
 	}
 
-	if err := h.projectsUsecases.DeleteProject(projectId); err != nil {
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *projectsHandler) FindProjectRevisions(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProjectRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	revisionsData, err := h.projectsUsecases.FindProjectRevisions(projectId)
+	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deleteProjectErr),
+			string(findProjectRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, revisionsData).Res()
+}
+
+func (h *projectsHandler) FindOneProjectRevision(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+	revisionId, err := strconv.Atoi(strings.Trim(c.Params("revision_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	revision, err := h.projectsUsecases.FindOneProjectRevision(projectId, revisionId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, revision).Res()
+}
+
+func (h *projectsHandler) DiffProjectRevisions(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffProjectRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(revisions.RevisionDiffReq)
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffProjectRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+	if req.From < 1 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(diffProjectRevisionsErr),
+			"from is required",
+		).Res()
+	}
+
+	diff, err := h.projectsUsecases.DiffProjectRevisions(projectId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(diffProjectRevisionsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, diff).Res()
+}
+
+func (h *projectsHandler) RestoreProjectRevision(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(restoreProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+	revisionId, err := strconv.Atoi(strings.Trim(c.Params("revision_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(restoreProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	project, err := h.projectsUsecases.RestoreProjectRevision(projectId, revisionId, userID)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(restoreProjectRevisionErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "กู้คืนข้อมูลโครงการ : "+project.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, project).Res()
+}
+
+// UpdateProjectOrder rewrites the display order from the ids in the body, first id first.
+func (h *projectsHandler) UpdateProjectOrder(c *fiber.Ctx) error {
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProjectOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProjectOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.projectsUsecases.UpdateProjectOrder(req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateProjectOrderErr),
+				err.Error(),
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateProjectOrderErr),
 			err.Error(),
 		).Res()
 	}
 
 	// Log activity
 	userID := utils.GetUserIDFromContext(c)
-	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลโครงการ : "+project.Name)
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลโครงการ")
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
+}
+
+
+func (h *projectsHandler) FindProjectPhases(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findPhasesErr),
+			err.Error(),
+		).Res()
+	}
+
+	phases, err := h.projectsUsecases.FindPhases(projectId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findPhasesErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, phases).Res()
+}
+
+func (h *projectsHandler) AddProjectPhase(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertPhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := &projects.ProjectPhase{
+		Images:       make([]*entities.Image, 0),
+		DescAreaItem: make([]*projects.ProjectDescAreaItem, 0),
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertPhaseErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertPhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	phase, err := h.projectsUsecases.AddPhase(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(insertPhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "เพิ่มเฟสโครงการ : "+phase.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, phase).Res()
+}
+
+// UpdateProjectPhase applies the body over the stored phase, images and area
+// items given in the body replace the old ones.
+func (h *projectsHandler) UpdateProjectPhase(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+	phaseId, err := strconv.Atoi(strings.Trim(c.Params("phase_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	req, err := h.projectsUsecases.FindOnePhase(projectId, phaseId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+	req.Id = phaseId
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	phase, err := h.projectsUsecases.UpdatePhase(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updatePhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขเฟสโครงการ : "+phase.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, phase).Res()
+}
+
+func (h *projectsHandler) DeleteProjectPhase(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deletePhaseErr),
+			err.Error(),
+		).Res()
+	}
+	phaseId, err := strconv.Atoi(strings.Trim(c.Params("phase_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deletePhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	phase, err := h.projectsUsecases.DeletePhase(projectId, phaseId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deletePhaseErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบเฟสโครงการ : "+phase.Name)
 	if err != nil {
 		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
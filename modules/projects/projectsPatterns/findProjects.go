@@ -17,6 +17,7 @@ type IFindProjectBuilder interface {
 	initCountQuery()
 	buildWhereSearch()
 	buildWhereStatus()
+	buildWhereDisplay()
 	// buildWhereDate()
 	buildSort()
 	buildPaginate()
@@ -101,13 +102,14 @@ func (b *findProjectBuilder) initQuery() {
 					SELECT
 						"i"."id",
 						"i"."filename",
-						"i"."url"
+						"i"."url",
+						"i"."srcset"
 					FROM "project_images" "i"
 					WHERE "i"."project_id" = "p"."id"
 				) AS "it"
 			) AS "images"
 			FROM "projects" "p"
-			WHERE 1 = 1
+			WHERE "p"."deleted_at" IS NULL
 	`
 }
 
@@ -116,7 +118,7 @@ func (b *findProjectBuilder) initCountQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "projects" "p"
-		WHERE 1 = 1`
+		WHERE "p"."deleted_at" IS NULL`
 }
 
 func (b *findProjectBuilder) buildWhereSearch() {
@@ -165,6 +167,25 @@ func (b *findProjectBuilder) buildWhereStatus() {
 	}
 }
 
+func (b *findProjectBuilder) buildWhereDisplay() {
+	if b.req.Display != "" {
+		b.values = append(
+			b.values,
+			b.req.Display,
+		)
+
+		query := fmt.Sprintf(`
+		AND "p"."display" = $%d`,
+			b.lastIndex+1,
+		)
+		temp := b.getQuery()
+		temp += query
+		b.setQuery(temp)
+
+		b.lastIndex = len(b.values)
+	}
+}
+
 func (b *findProjectBuilder) buildSort() {
 	b.values = append(b.values, b.req.OrderBy)
 
@@ -219,6 +240,7 @@ func (en *findProjectEngineer) FindProject() []*projects.Project {
 	en.builder.initQuery()
 	en.builder.buildWhereSearch()
 	en.builder.buildWhereStatus()
+	en.builder.buildWhereDisplay()
 	// en.builder.buildWhereDate()
 	en.builder.buildSort()
 	en.builder.buildPaginate()
@@ -248,6 +270,7 @@ func (en *findProjectEngineer) CountOrder() int {
 	en.builder.initCountQuery()
 	en.builder.buildWhereSearch()
 	en.builder.buildWhereStatus()
+	en.builder.buildWhereDisplay()
 	// en.builder.buildWhereDate()
 
 	var count int
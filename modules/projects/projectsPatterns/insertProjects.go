@@ -67,9 +67,12 @@ func (b *insertProjectBuilder) insertProject() error {
 		"tel",
 		"address",
 		"link_location",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at",
+		"slug"
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, '')::timestamptz, NULLIF($17, '')::timestamptz, $18)
 		RETURNING "id";
 	`
 	if err := b.tx.QueryRowContext(
@@ -90,6 +93,9 @@ func (b *insertProjectBuilder) insertProject() error {
 		b.req.Address,
 		b.req.LinkLocation,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
+		b.req.Slug,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert project failed: %v", err)
@@ -189,6 +195,7 @@ func (b *insertProjectBuilder) insertAttachment() error {
 	INSERT INTO "project_images" (
 		"filename",
 		"url",
+		"srcset",
 		"project_id"
 	)
 	VALUES`
@@ -199,17 +206,18 @@ func (b *insertProjectBuilder) insertAttachment() error {
 		valueStack = append(valueStack,
 			b.req.Images[i].FileName,
 			b.req.Images[i].Url,
+			b.req.Images[i].SrcSet,
 			b.req.Id,
 		)
 
 		if i != len(b.req.Images)-1 {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d),`, index+1, index+2, index+3, index+4)
 		} else {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d);`, index+1, index+2, index+3, index+4)
 		}
-		index += 3
+		index += 4
 	}
 
 	if _, err := b.tx.ExecContext(
@@ -8,7 +8,6 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/projects"
 )
@@ -36,6 +35,7 @@ type IUpdateProjectBuilder interface {
 	getQuery() string
 	setQuery(query string)
 	getImagesLen() int
+	beforeCommit() error
 	getHouseTypeItemLen() int
 	getDescAreaItemLen() int
 	getFacilityItemLen() int
@@ -51,15 +51,25 @@ type updateProjectBuilder struct {
 	queryFields    []string
 	lastStackIndex int
 	values         []any
+	hook           *UpdateProjectHook
 }
 
-func UpdateProjectBuilder(db *sqlx.DB, req *projects.Project, filesUsecases filesUsecases.IFilesUsecase) IUpdateProjectBuilder {
+// UpdateProjectHook runs inside the transaction of the update: Begin before
+// anything is written, Commit right before it is committed. Either one failing
+// rolls the update back.
+type UpdateProjectHook struct {
+	Begin  func(tx *sqlx.Tx) error
+	Commit func(tx *sqlx.Tx) error
+}
+
+func UpdateProjectBuilder(db *sqlx.DB, req *projects.Project, filesUsecases filesUsecases.IFilesUsecase, hook *UpdateProjectHook) IUpdateProjectBuilder {
 	return &updateProjectBuilder{
 		db:            db,
 		req:           req,
 		filesUsecases: filesUsecases,
 		queryFields:   make([]string, 0),
 		values:        make([]any, 0),
+		hook:          hook,
 	}
 }
 
@@ -73,6 +83,13 @@ func (b *updateProjectBuilder) initTransaction() error {
 		return err
 	}
 	b.tx = tx
+
+	if b.hook != nil && b.hook.Begin != nil {
+		if err := b.hook.Begin(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 	return nil
 }
 
@@ -90,6 +107,13 @@ func (b *updateProjectBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"name" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.Slug != "" {
+		b.values = append(b.values, b.req.Slug)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"slug" = $%d`, b.lastStackIndex))
+	}
+
 	if b.req.Index != 0 {
 		b.values = append(b.values, b.req.Index)
 		b.lastStackIndex = len(b.values)
@@ -188,6 +212,20 @@ func (b *updateProjectBuilder) updateQuery() {
 		setStatements = append(setStatements, fmt.Sprintf(`"display" = $%d`, b.lastStackIndex))
 	}
 
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"publish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		setStatements = append(setStatements, fmt.Sprintf(`"unpublish_at" = NULLIF($%d, '')::timestamptz`, b.lastStackIndex))
+	}
+
 	b.query += strings.Join(setStatements, ", ")
 }
 
@@ -389,6 +427,7 @@ func (b *updateProjectBuilder) insertImages() error {
 	INSERT INTO "project_images" (
 		"filename",
 		"url",
+		"srcset",
 		"project_id"
 	)
 	VALUES`
@@ -399,17 +438,18 @@ func (b *updateProjectBuilder) insertImages() error {
 		valueStack = append(valueStack,
 			b.req.Images[i].FileName,
 			b.req.Images[i].Url,
+			b.req.Images[i].SrcSet,
 			b.req.Id,
 		)
 
 		if i != len(b.req.Images)-1 {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d),`, index+1, index+2, index+3, index+4)
 		} else {
 			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			($%d, $%d, $%d, $%d);`, index+1, index+2, index+3, index+4)
 		}
-		index += 3
+		index += 4
 	}
 
 	if _, err := b.tx.ExecContext(
@@ -448,17 +488,8 @@ func (b *updateProjectBuilder) deleteOldImages() error {
 	DELETE FROM "project_images"
 	WHERE "project_id" = $1;`
 
-	images := b.getOldImages()
-	if len(images) > 0 {
-		deleteFileReq := make([]*files.DeleteFileReq, 0)
-		for _, img := range images {
-			deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-				Destination: fmt.Sprintf("images/projects/%s", img.FileName),
-			})
-		}
-		b.filesUsecases.DeleteFileOnStorage(deleteFileReq)
-	}
-
+	// Files are kept on storage so older revisions can still be restored,
+	// unused ones are removed by the media sweep
 	if _, err := b.tx.ExecContext(
 		context.Background(),
 		query,
@@ -494,6 +525,15 @@ func (b *updateProjectBuilder) getImagesLen() int        { return len(b.req.Imag
 func (b *updateProjectBuilder) getHouseTypeItemLen() int { return len(b.req.HouseTypeItem) }
 func (b *updateProjectBuilder) getDescAreaItemLen() int { return len(b.req.DescAreaItem) }
 func (b *updateProjectBuilder) getFacilityItemLen() int { return len(b.req.FacilityItem) }
+func (b *updateProjectBuilder) beforeCommit() error {
+	if b.hook != nil && b.hook.Commit != nil {
+		if err := b.hook.Commit(b.tx); err != nil {
+			b.tx.Rollback()
+			return err
+		}
+	}
+	return nil
+}
 func (b *updateProjectBuilder) commit() error {
 	if err := b.tx.Commit(); err != nil {
 		return err
@@ -521,7 +561,9 @@ func (en *updateProjectEngineer) sumQueryFields() {
 }
 
 func (en *updateProjectEngineer) UpdateProject() error {
-	en.builder.initTransaction()
+	if err := en.builder.initTransaction(); err != nil {
+		return err
+	}
 
 	en.builder.initQuery()
 	en.sumQueryFields()
@@ -569,6 +611,10 @@ func (en *updateProjectEngineer) UpdateProject() error {
 		}
 	}
 
+	if err := en.builder.beforeCommit(); err != nil {
+		return err
+	}
+
 	// Commit
 	if err := en.builder.commit(); err != nil {
 		return err
@@ -2,9 +2,12 @@ package projectsRepositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/config"
@@ -12,17 +15,81 @@ import (
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/projects"
 	"github.com/yporn/sirarom-backend/modules/projects/projectsPatterns"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type IProjectRepository interface {
 	FindOneProject(projectId string) (*projects.Project, error)
 	FindProject(req *projects.ProjectFilter) ([]*projects.Project, int)
 	InsertProject(req *projects.Project) (*projects.Project, error)
-	UpdateProject(req *projects.Project) (*projects.Project, error)
+	UpdateProject(req *projects.Project, revise revisions.Revise) (*projects.Project, error)
+	FindProjectSlug(slug string) (int, string, error)
 	DeleteProject(projectId string) error
 	FindProjectHouseModel(projectID string) (*projects.Project, error)
+	RestoreProject(req *projects.Project, revise revisions.Revise) (*projects.Project, error)
+	UpdateProjectOrder(ids []int) error
+	FindPhases(projectId int) ([]*projects.ProjectPhase, error)
+	FindOnePhase(projectId, phaseId int) (*projects.ProjectPhase, error)
+	InsertPhase(req *projects.ProjectPhase) error
+	UpdatePhase(req *projects.ProjectPhase) error
+	DeletePhase(projectId, phaseId int) error
 }
 
+// phasesQuery selects the phases of project "p" with their images and area items.
+const phasesQuery = `
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("pht")), '[]'::json)
+				FROM (` + phaseColumns + `
+					FROM "project_phases" "ph"
+					WHERE "ph"."project_id" = "p"."id"
+					ORDER BY "ph"."index" ASC, "ph"."id" ASC
+				) AS "pht"
+			) AS "phases"`
+
+const phaseColumns = `
+					SELECT
+						"ph"."id",
+						"ph"."project_id",
+						"ph"."name",
+						"ph"."index",
+						"ph"."status",
+						"ph"."launch_date",
+						"ph"."price_min",
+						"ph"."price_max",
+						COALESCE("ph"."description", '') AS "description",
+						"ph"."created_at",
+						"ph"."updated_at",
+						(
+							SELECT
+								COALESCE(array_to_json(array_agg("phi")), '[]'::json)
+							FROM (
+								SELECT
+									"i"."id",
+									"i"."filename",
+									"i"."url",
+									"i"."srcset"
+								FROM "project_phase_images" "i"
+								WHERE "i"."phase_id" = "ph"."id"
+								ORDER BY "i"."id" ASC
+							) AS "phi"
+						) AS "images",
+						(
+							SELECT
+								COALESCE(array_to_json(array_agg("pha")), '[]'::json)
+							FROM (
+								SELECT
+									"a"."id",
+									"a"."item",
+									"a"."amount",
+									"a"."unit"
+								FROM "project_phase_area_items" "a"
+								WHERE "a"."phase_id" = "ph"."id"
+								ORDER BY "a"."id" ASC
+							) AS "pha"
+						) AS "area_items"`
+
 type projectsRepository struct {
 	db           *sqlx.DB
 	cfg          config.IConfig
@@ -38,6 +105,11 @@ func ProjectsRepository(db *sqlx.DB, cfg config.IConfig, filesUsecase filesUseca
 }
 
 func (r *projectsRepository) FindOneProject(projectId string) (*projects.Project, error) {
+	return findOneProject(r.db, projectId)
+}
+
+// findOneProject reads the project on q, the db or the transaction of an edit.
+func findOneProject(q sqlx.Queryer, projectId string) (*projects.Project, error) {
 	query := `
 	SELECT
 		to_jsonb("t")
@@ -84,7 +156,8 @@ func (r *projectsRepository) FindOneProject(projectId string) (*projects.Project
 					SELECT
 						"i"."id",
 						"i"."filename",
-						"i"."url"
+						"i"."url",
+						"i"."srcset"
 					FROM "project_images" "i"
 					WHERE "i"."project_id" = "p"."id"
 				) AS "it"
@@ -117,10 +190,12 @@ func (r *projectsRepository) FindOneProject(projectId string) (*projects.Project
 						) AS "house_images"
 					FROM "house_models" "hm"
 					WHERE "hm"."project_id" = "p"."id"
+					AND "hm"."deleted_at" IS NULL
 				) AS "hm"
-			) AS "house_models"
+			) AS "house_models",`+phasesQuery+`
 			FROM "projects" "p"
 		WHERE "p"."id" = $1
+		AND "p"."deleted_at" IS NULL
 	) AS "t";
 	`
 	projectBytes := make([]byte, 0)
@@ -131,7 +206,7 @@ func (r *projectsRepository) FindOneProject(projectId string) (*projects.Project
 		FacilityItem:  make([]*projects.ProjectFacilityItem, 0),
 	}
 
-	if err := r.db.Get(&projectBytes, query, projectId); err != nil {
+	if err := sqlx.Get(q, &projectBytes, query, projectId); err != nil {
 		return nil, fmt.Errorf("get project failed: %v", err)
 	}
 	if err := json.Unmarshal(projectBytes, &project); err != nil {
@@ -153,6 +228,19 @@ func (r *projectsRepository) FindProjectHouseModel(projectId string) (*projects.
 				FROM (
 					SELECT
 						"hm".*,
+						(
+							SELECT
+								COUNT(*)
+							FROM "project_units" "u"
+							WHERE "u"."house_model_id" = "hm"."id"
+							AND "u"."status" = 'available'
+						) AS "available_units",
+						(
+							SELECT
+								COUNT(*)
+							FROM "project_units" "u"
+							WHERE "u"."house_model_id" = "hm"."id"
+						) AS "total_units",
 						(
 							SELECT
 								COALESCE(array_to_json(array_agg("hmti")), '[]'::json)
@@ -200,6 +288,8 @@ func (r *projectsRepository) FindProjectHouseModel(projectId string) (*projects.
 												) AS "free_items"
 											FROM "promotions" "pt"
 											WHERE  "ptm"."promotion_id" = "pt"."id"
+											AND "pt"."display" = 'published'
+											AND "pt"."deleted_at" IS NULL
 										) AS "pt"
 									) AS "promotions"
 								FROM "promotion_house_models" "ptm"
@@ -208,10 +298,13 @@ func (r *projectsRepository) FindProjectHouseModel(projectId string) (*projects.
 						) AS "houseModel_promotions"
 					FROM "house_models" "hm"
 					WHERE "hm"."project_id" = "p"."id"
+					AND "hm"."display" = 'published'
+					AND "hm"."deleted_at" IS NULL
 				) AS "hm"
-			) AS "house_models"
+			) AS "house_models",`+phasesQuery+`
 			FROM "projects" "p"
 		WHERE "p"."id" = $1
+		AND "p"."deleted_at" IS NULL
 	) AS "t";
 	`
 	projectBytes := make([]byte, 0)
@@ -238,6 +331,12 @@ func (r *projectsRepository) FindProject(req *projects.ProjectFilter) ([]*projec
 }
 
 func (r *projectsRepository) InsertProject(req *projects.Project) (*projects.Project, error) {
+	slug, err := utils.UniqueSlug(r.db, "projects", 0, req.Name, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Slug = slug
+
 	builder := projectsPatterns.InsertProjectBuilder(r.db, req)
 	projectId, err := projectsPatterns.InsertProjectEngineer(builder).InsertProject()
 	if err != nil {
@@ -251,26 +350,410 @@ func (r *projectsRepository) InsertProject(req *projects.Project) (*projects.Pro
 	return project, nil
 }
 
-func (r *projectsRepository) UpdateProject(req *projects.Project) (*projects.Project, error) {
-	builder := projectsPatterns.UpdateProjectBuilder(r.db, req, r.filesUsecase)
+// UpdateProject writes the edit and its revision in one transaction. The state
+// before is read there too, with the row locked, so it is the one replaced.
+func (r *projectsRepository) UpdateProject(req *projects.Project, revise revisions.Revise) (*projects.Project, error) {
+	// The slug only changes when one is given, so renaming keeps the links
+	if req.Slug != "" {
+		slug, err := utils.UniqueSlug(r.db, "projects", req.Id, "", req.Slug)
+		if err != nil {
+			return nil, err
+		}
+		req.Slug = slug
+	}
+
+	projectId := strconv.Itoa(req.Id)
+	var before, after *projects.Project
+	builder := projectsPatterns.UpdateProjectBuilder(r.db, req, r.filesUsecase, &projectsPatterns.UpdateProjectHook{
+		Begin: func(tx *sqlx.Tx) error {
+			if err := lockProject(tx, req.Id); err != nil {
+				return err
+			}
+			project, err := findOneProject(tx, projectId)
+			if err != nil {
+				return err
+			}
+			before = project
+			return nil
+		},
+		Commit: func(tx *sqlx.Tx) error {
+			project, err := findOneProject(tx, projectId)
+			if err != nil {
+				return err
+			}
+			after = project
+			return revise(tx, before, after)
+		},
+	})
 	engineer := projectsPatterns.UpdateProjectEngineer(builder)
 
 	if err := engineer.UpdateProject(); err != nil {
 		return nil, err
 	}
+	return after, nil
+}
 
-	project, err := r.FindOneProject(strconv.Itoa(req.Id))
-	if err != nil {
-		return nil, err
+// lockProject locks the row of a project that is not in the trash for the rest of tx.
+func lockProject(tx *sqlx.Tx, projectId int) error {
+	var id int
+	if err := tx.Get(&id, `SELECT "id" FROM "projects" WHERE "id" = $1 AND "deleted_at" IS NULL FOR UPDATE;`, projectId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("project %d not found", projectId)
+		}
+		return fmt.Errorf("lock project failed: %v", err)
 	}
-	return project, nil
+	return nil
 }
 
 func (r *projectsRepository) DeleteProject(projectId string) error {
-	query := `DELETE FROM "projects" WHERE "id" = $1;`
+	query := `UPDATE "projects" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, projectId); err != nil {
 		return fmt.Errorf("delete project failed: %v", err)
 	}
 	return nil
 }
+
+// RestoreProject overwrites a project and its child items with a revision snapshot,
+// and stores the restore as a new revision in the same transaction. A project in
+// the trash is refused. House models keep their own revisions and are not touched.
+func (r *projectsRepository) RestoreProject(req *projects.Project, revise revisions.Revise) (*projects.Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockProject(tx, req.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query := `
+	UPDATE "projects" SET
+		"name" = $1,
+		"index" = $2,
+		"heading" = $3,
+		"text" = $4,
+		"location" = $5,
+		"price" = $6,
+		"status_project" = $7,
+		"type_project" = $8,
+		"description" = $9,
+		"name_facebook" = $10,
+		"link_facebook" = $11,
+		"tel" = $12,
+		"address" = $13,
+		"link_location" = $14,
+		"display" = $15,
+		"publish_at" = $16::timestamptz,
+		"unpublish_at" = $17::timestamptz
+	WHERE "id" = $18;`
+
+	if _, err := tx.ExecContext(
+		ctx,
+		query,
+		req.Name,
+		req.Index,
+		req.Heading,
+		req.Text,
+		req.Location,
+		req.Price,
+		req.StatusProject,
+		req.TypeProject,
+		req.Description,
+		req.NameFacebook,
+		req.LinkFacebook,
+		req.Tel,
+		req.Address,
+		req.LinkLocation,
+		req.Display,
+		req.PublishAt,
+		req.UnpublishAt,
+		req.Id,
+	); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("restore project failed: %v", err)
+	}
+
+	for _, table := range []string{"project_house_type_items", "project_desc_area_items", "project_facility_items", "project_images"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE "project_id" = $1;`, table), req.Id); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("delete %s failed: %v", table, err)
+		}
+	}
+
+	for _, item := range req.HouseTypeItem {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_house_type_items" ("project_id", "name") VALUES ($1, $2);`,
+			req.Id,
+			item.Name,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert project house type item failed: %v", err)
+		}
+	}
+
+	for _, item := range req.DescAreaItem {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_desc_area_items" ("project_id", "item", "amount", "unit") VALUES ($1, $2, $3, $4);`,
+			req.Id,
+			item.ItemArea,
+			item.Amount,
+			item.Unit,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert project desc area item failed: %v", err)
+		}
+	}
+
+	for _, item := range req.FacilityItem {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_facility_items" ("project_id", "item") VALUES ($1, $2);`,
+			req.Id,
+			item.Item,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert facilities failed: %v", err)
+		}
+	}
+
+	for _, img := range req.Images {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_images" ("filename", "url", "srcset", "project_id") VALUES ($1, $2, $3, $4);`,
+			img.FileName,
+			img.Url,
+			img.SrcSet,
+			req.Id,
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("insert images failed: %v", err)
+		}
+	}
+
+	project, err := findOneProject(tx, strconv.Itoa(req.Id))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := revise(tx, nil, project); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *projectsRepository) UpdateProjectOrder(ids []int) error {
+	return utils.Reorder(r.db, "projects", ids, "", nil)
+}
+
+func (r *projectsRepository) FindPhases(projectId int) ([]*projects.ProjectPhase, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("pht")), '[]'::json)
+	FROM (` + phaseColumns + `
+		FROM "project_phases" "ph"
+		WHERE "ph"."project_id" = $1
+		ORDER BY "ph"."index" ASC, "ph"."id" ASC
+	) AS "pht";`
+
+	phasesBytes := make([]byte, 0)
+	phases := make([]*projects.ProjectPhase, 0)
+
+	if err := r.db.Get(&phasesBytes, query, projectId); err != nil {
+		return nil, fmt.Errorf("get phases failed: %v", err)
+	}
+	if err := json.Unmarshal(phasesBytes, &phases); err != nil {
+		return nil, fmt.Errorf("unmarshal phases failed: %v", err)
+	}
+	return phases, nil
+}
+
+func (r *projectsRepository) FindOnePhase(projectId, phaseId int) (*projects.ProjectPhase, error) {
+	query := `
+	SELECT
+		to_jsonb("pht")
+	FROM (` + phaseColumns + `
+		FROM "project_phases" "ph"
+		WHERE "ph"."id" = $1
+		AND "ph"."project_id" = $2
+	) AS "pht";`
+
+	phaseBytes := make([]byte, 0)
+	phase := new(projects.ProjectPhase)
+
+	if err := r.db.Get(&phaseBytes, query, phaseId, projectId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("phase %d not found", phaseId)
+		}
+		return nil, fmt.Errorf("get phase failed: %v", err)
+	}
+	if err := json.Unmarshal(phaseBytes, phase); err != nil {
+		return nil, fmt.Errorf("unmarshal phase failed: %v", err)
+	}
+	return phase, nil
+}
+
+// insertPhaseItems writes the images and area items of a phase.
+func insertPhaseItems(ctx context.Context, tx *sqlx.Tx, req *projects.ProjectPhase) error {
+	for _, image := range req.Images {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_phase_images" ("phase_id", "filename", "url", "srcset") VALUES ($1, $2, $3, $4);`,
+			req.Id,
+			image.FileName,
+			image.Url,
+			image.SrcSet,
+		); err != nil {
+			return fmt.Errorf("insert phase images failed: %v", err)
+		}
+	}
+	for _, item := range req.DescAreaItem {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "project_phase_area_items" ("phase_id", "item", "amount", "unit") VALUES ($1, $2, $3, $4);`,
+			req.Id,
+			item.ItemArea,
+			item.Amount,
+			item.Unit,
+		); err != nil {
+			return fmt.Errorf("insert phase area items failed: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *projectsRepository) InsertPhase(req *projects.ProjectPhase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO "project_phases" (
+		"project_id",
+		"name",
+		"index",
+		"status",
+		"launch_date",
+		"price_min",
+		"price_max",
+		"description"
+	)
+	VALUES ($1, $2, $3, $4, NULLIF($5, '')::date, $6, $7, $8)
+	RETURNING "id";`
+
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		req.ProjectId,
+		req.Name,
+		req.Index,
+		req.Status,
+		req.LaunchDate,
+		req.PriceMin,
+		req.PriceMax,
+		req.Description,
+	).Scan(&req.Id); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insert phase failed: %v", err)
+	}
+
+	if err := insertPhaseItems(ctx, tx, req); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdatePhase writes every field of the phase and replaces its images and
+// area items.
+func (r *projectsRepository) UpdatePhase(req *projects.ProjectPhase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE "project_phases" SET
+		"name" = $3,
+		"index" = $4,
+		"status" = $5,
+		"launch_date" = NULLIF($6, '')::date,
+		"price_min" = $7,
+		"price_max" = $8,
+		"description" = $9
+	WHERE "id" = $1
+	AND "project_id" = $2;`
+
+	res, err := tx.ExecContext(
+		ctx,
+		query,
+		req.Id,
+		req.ProjectId,
+		req.Name,
+		req.Index,
+		req.Status,
+		req.LaunchDate,
+		req.PriceMin,
+		req.PriceMax,
+		req.Description,
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update phase failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("phase %d not found", req.Id)
+	}
+
+	// Files are kept on storage, unused ones are removed by the media sweep
+	for _, table := range []string{"project_phase_images", "project_phase_area_items"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE "phase_id" = $1;`, table), req.Id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("delete %s failed: %v", table, err)
+		}
+	}
+	if err := insertPhaseItems(ctx, tx, req); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeletePhase removes a phase, its house models stay in the project without a phase.
+func (r *projectsRepository) DeletePhase(projectId, phaseId int) error {
+	query := `DELETE FROM "project_phases" WHERE "id" = $1 AND "project_id" = $2;`
+
+	res, err := r.db.ExecContext(context.Background(), query, phaseId, projectId)
+	if err != nil {
+		return fmt.Errorf("delete phase failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("phase %d not found", phaseId)
+	}
+	return nil
+}
+
+func (r *projectsRepository) FindProjectSlug(slug string) (int, string, error) {
+	return utils.FindSlug(r.db, "projects", slug)
+}
@@ -1,29 +1,48 @@
 package projectsUsecases
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"strconv"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/projects"
 	"github.com/yporn/sirarom-backend/modules/projects/projectsRepositories"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+	"github.com/yporn/sirarom-backend/modules/revisions/revisionsUsecases"
 )
 
 type IProjectsUsecase interface {
 	FindOneProject(projectId string) (*projects.Project, error)
 	FindProject(req *projects.ProjectFilter) *entities.PaginateRes
 	AddProject(req *projects.Project) (*projects.Project, error)
-	UpdateProject(req *projects.Project) (*projects.Project, error)
+	UpdateProject(req *projects.Project, userId int) (*projects.Project, error)
+	FindProjectSlug(slug string) (int, string, error)
 	DeleteProject(projectId string) error
 	FindProjectHouseModel(projectID string) (*projects.Project, error)
+	FindProjectRevisions(projectId int) ([]*revisions.Revision, error)
+	FindOneProjectRevision(projectId, revisionId int) (*revisions.Revision, error)
+	DiffProjectRevisions(projectId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error)
+	RestoreProjectRevision(projectId, revisionId, userId int) (*projects.Project, error)
+	UpdateProjectOrder(ids []int) error
+	FindPhases(projectId int) ([]*projects.ProjectPhase, error)
+	FindOnePhase(projectId, phaseId int) (*projects.ProjectPhase, error)
+	AddPhase(req *projects.ProjectPhase) (*projects.ProjectPhase, error)
+	UpdatePhase(req *projects.ProjectPhase) (*projects.ProjectPhase, error)
+	DeletePhase(projectId, phaseId int) (*projects.ProjectPhase, error)
 }
 
 type projectsUsecase struct {
 	projectsRepository projectsRepositories.IProjectRepository
+	revisionsUsecase   revisionsUsecases.IRevisionsUsecase
 }
 
-func ProjectsUsecase(projectsRepository projectsRepositories.IProjectRepository) IProjectsUsecase {
+func ProjectsUsecase(projectsRepository projectsRepositories.IProjectRepository, revisionsUsecase revisionsUsecases.IRevisionsUsecase) IProjectsUsecase {
 	return &projectsUsecase{
 		projectsRepository: projectsRepository,
+		revisionsUsecase:   revisionsUsecase,
 	}
 }
 
@@ -62,12 +81,15 @@ func (u *projectsUsecase) FindProject(req *projects.ProjectFilter) *entities.Pag
 	}
 }
 
-func (u *projectsUsecase) UpdateProject(req *projects.Project) (*projects.Project, error) {
-	project, err := u.projectsRepository.UpdateProject(req)
-	if err != nil {
-		return nil, err
+func (u *projectsUsecase) UpdateProject(req *projects.Project, userId int) (*projects.Project, error) {
+	return u.projectsRepository.UpdateProject(req, u.revise(req.Id, userId))
+}
+
+// revise stores a revision of the project in the transaction of its edit.
+func (u *projectsUsecase) revise(projectId, userId int) revisions.Revise {
+	return func(tx *sqlx.Tx, before, after any) error {
+		return u.revisionsUsecase.AddRevision(tx, revisions.ProjectEntity, projectId, before, after, userId)
 	}
-	return project, nil
 }
 
 func (u *projectsUsecase) DeleteProject(projectId string) error {
@@ -75,4 +97,78 @@ func (u *projectsUsecase) DeleteProject(projectId string) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (u *projectsUsecase) FindProjectRevisions(projectId int) ([]*revisions.Revision, error) {
+	return u.revisionsUsecase.FindRevisions(revisions.ProjectEntity, projectId)
+}
+
+func (u *projectsUsecase) FindOneProjectRevision(projectId, revisionId int) (*revisions.Revision, error) {
+	return u.revisionsUsecase.FindOneRevision(revisions.ProjectEntity, projectId, revisionId)
+}
+
+func (u *projectsUsecase) DiffProjectRevisions(projectId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error) {
+	return u.revisionsUsecase.DiffRevisions(revisions.ProjectEntity, projectId, req)
+}
+
+func (u *projectsUsecase) RestoreProjectRevision(projectId, revisionId, userId int) (*projects.Project, error) {
+	revision, err := u.revisionsUsecase.FindOneRevision(revisions.ProjectEntity, projectId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	req := new(projects.Project)
+	if err := json.Unmarshal(revision.Snapshot, req); err != nil {
+		return nil, fmt.Errorf("unmarshal revision failed: %v", err)
+	}
+	req.Id = projectId
+
+	// The restore is itself a new revision so it can be undone
+	return u.projectsRepository.RestoreProject(req, u.revise(projectId, userId))
+}
+
+func (u *projectsUsecase) UpdateProjectOrder(ids []int) error {
+	return u.projectsRepository.UpdateProjectOrder(ids)
+}
+
+func (u *projectsUsecase) FindPhases(projectId int) ([]*projects.ProjectPhase, error) {
+	return u.projectsRepository.FindPhases(projectId)
+}
+
+func (u *projectsUsecase) FindOnePhase(projectId, phaseId int) (*projects.ProjectPhase, error) {
+	return u.projectsRepository.FindOnePhase(projectId, phaseId)
+}
+
+func (u *projectsUsecase) AddPhase(req *projects.ProjectPhase) (*projects.ProjectPhase, error) {
+	if _, err := u.projectsRepository.FindOneProject(strconv.Itoa(req.ProjectId)); err != nil {
+		return nil, err
+	}
+	if err := u.projectsRepository.InsertPhase(req); err != nil {
+		return nil, err
+	}
+	return u.projectsRepository.FindOnePhase(req.ProjectId, req.Id)
+}
+
+func (u *projectsUsecase) UpdatePhase(req *projects.ProjectPhase) (*projects.ProjectPhase, error) {
+	if err := u.projectsRepository.UpdatePhase(req); err != nil {
+		return nil, err
+	}
+	return u.projectsRepository.FindOnePhase(req.ProjectId, req.Id)
+}
+
+func (u *projectsUsecase) DeletePhase(projectId, phaseId int) (*projects.ProjectPhase, error) {
+	phase, err := u.projectsRepository.FindOnePhase(projectId, phaseId)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.projectsRepository.DeletePhase(projectId, phaseId); err != nil {
+		return nil, err
+	}
+	return phase, nil
+}
+
+// FindProjectSlug returns the id and current slug of the project with the slug
+// or with an old slug of it.
+func (u *projectsUsecase) FindProjectSlug(slug string) (int, string, error) {
+	return u.projectsRepository.FindProjectSlug(slug)
+}
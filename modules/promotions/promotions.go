@@ -8,10 +8,13 @@ type Promotion struct {
 	Id          int                    `db:"id" json:"id" form:"id"`
 	Index       int                    `db:"index" json:"index" form:"index" `
 	Heading     string                 `db:"heading" json:"heading" form:"heading"`
+	Slug        string                 `db:"slug" json:"slug" form:"slug"` // empty on create is made from the heading, on update keeps the stored one
 	Description string                 `db:"description" json:"description" form:"description"`
 	StartDate   string                 `db:"start_date" json:"start_date" form:"start_date"`
 	EndDate     string                 `db:"end_date" json:"end_date" form:"end_date"`
 	Display     string                 `db:"display" json:"display" form:"display"`
+	PublishAt   *string                `db:"publish_at" json:"publish_at" form:"publish_at"`
+	UnpublishAt *string                `db:"unpublish_at" json:"unpublish_at" form:"unpublish_at"`
 	Images      []*entities.Image      `json:"promotion_images"`
 	HouseModel  []*PromotionHouseModel `json:"house_models"`
 	FreeItem    []*PromotionFreeItem   `json:"free_items"`
@@ -45,8 +48,9 @@ type HouseModelType struct {
 }
 
 type PromotionFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // Heading
+	Id      string `query:"id"`
+	Search  string `query:"search"` // Heading
+	Display string `query:"display"`
 	*entities.PaginationReq
 	*entities.SortReq
 }
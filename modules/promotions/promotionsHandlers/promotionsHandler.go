@@ -2,15 +2,14 @@ package promotionsHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
-	"github.com/yporn/sirarom-backend/modules/files"
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/promotions"
 	"github.com/yporn/sirarom-backend/modules/promotions/promotionsUsecases"
@@ -20,19 +19,25 @@ import (
 type promotionsHandlersErrCode string
 
 const (
-	findOnePromotionErr promotionsHandlersErrCode = "promotions-001"
-	findPromotionErr    promotionsHandlersErrCode = "promotions-002"
-	insertPromotionErr  promotionsHandlersErrCode = "promotions-003"
-	deletePromotionErr  promotionsHandlersErrCode = "promotions-004"
-	updatePromotionErr  promotionsHandlersErrCode = "promotions-005"
+	findOnePromotionErr       promotionsHandlersErrCode = "promotions-001"
+	findPromotionErr          promotionsHandlersErrCode = "promotions-002"
+	insertPromotionErr        promotionsHandlersErrCode = "promotions-003"
+	deletePromotionErr        promotionsHandlersErrCode = "promotions-004"
+	updatePromotionErr        promotionsHandlersErrCode = "promotions-005"
+	updatePromotionOrderErr   promotionsHandlersErrCode = "promotions-006"
+	findOnePromotionBySlugErr promotionsHandlersErrCode = "promotions-007"
 )
 
 type IPromotionsHandler interface {
 	FindOnePromotion(c *fiber.Ctx) error
+	FindOnePromotionBySlug(c *fiber.Ctx) error
+	FindOneAdminPromotion(c *fiber.Ctx) error
 	FindPromotion(c *fiber.Ctx) error
+	FindAdminPromotion(c *fiber.Ctx) error
 	AddPromotion(c *fiber.Ctx) error
 	UpdatePromotion(c *fiber.Ctx) error
 	DeletePromotion(c *fiber.Ctx) error
+	UpdatePromotionOrder(c *fiber.Ctx) error
 }
 
 type promotionsHandlers struct {
@@ -52,8 +57,39 @@ func PromotionsHandler(cfg config.IConfig, promotionsUsecase promotionsUsecases.
 }
 
 func (h *promotionsHandlers) FindOnePromotion(c *fiber.Ctx) error {
-	promotionId := strings.Trim(c.Params("promotion_id"), " ")
+	return h.findOnePromotion(c, strings.Trim(c.Params("promotion_id"), " "), true)
+}
+
+func (h *promotionsHandlers) FindOneAdminPromotion(c *fiber.Ctx) error {
+	return h.findOnePromotion(c, strings.Trim(c.Params("promotion_id"), " "), false)
+}
+
+// FindOnePromotionBySlug answers an old slug with a redirect to the current one.
+func (h *promotionsHandlers) FindOnePromotionBySlug(c *fiber.Ctx) error {
+	slug := strings.Trim(c.Params("slug"), " ")
 
+	promotionId, current, err := h.promotionsUsecase.FindPromotionSlug(utils.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, utils.ErrSlugNotFound) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(findOnePromotionBySlugErr),
+				"promotion not found",
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOnePromotionBySlugErr),
+			err.Error(),
+		).Res()
+	}
+	if current != slug {
+		return c.Redirect(utils.SlugPath(c.Path(), current), fiber.StatusMovedPermanently)
+	}
+	return h.findOnePromotion(c, strconv.Itoa(promotionId), true)
+}
+
+func (h *promotionsHandlers) findOnePromotion(c *fiber.Ctx, promotionId string, public bool) error {
 	house, err := h.promotionsUsecase.FindOnePromotion(promotionId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -62,10 +98,26 @@ func (h *promotionsHandlers) FindOnePromotion(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if public && house.Display != entities.DisplayPublished {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findOnePromotionErr),
+			"promotion not found",
+		).Res()
+	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, house).Res()
 }
 
 func (h *promotionsHandlers) FindPromotion(c *fiber.Ctx) error {
+	return h.findPromotion(c, true)
+}
+
+// FindAdminPromotion returns rows in every display state, optionally filtered by ?display=.
+func (h *promotionsHandlers) FindAdminPromotion(c *fiber.Ctx) error {
+	return h.findPromotion(c, false)
+}
+
+func (h *promotionsHandlers) findPromotion(c *fiber.Ctx, public bool) error {
 	req := &promotions.PromotionFilter{
 		PaginationReq: &entities.PaginationReq{},
 		SortReq:       &entities.SortReq{},
@@ -79,6 +131,15 @@ func (h *promotionsHandlers) FindPromotion(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if public {
+		req.Display = entities.DisplayPublished
+	} else if err := entities.ValidatePublishing(req.Display, nil, nil); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findPromotionErr),
+			err.Error(),
+		).Res()
+	}
 
 	if req.Page < 1 {
 		req.Page = 1
@@ -111,6 +172,14 @@ func (h *promotionsHandlers) AddPromotion(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertPromotionErr),
+			err.Error(),
+		).Res()
+	}
+
 	if len(req.HouseModel) == 0 {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
@@ -121,6 +190,13 @@ func (h *promotionsHandlers) AddPromotion(c *fiber.Ctx) error {
 
 	promotion, err := h.promotionsUsecase.AddPromotion(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(insertPromotionErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(insertPromotionErr),
@@ -167,10 +243,25 @@ func (h *promotionsHandlers) UpdatePromotion(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	if err := entities.ValidatePublishing(req.Display, req.PublishAt, req.UnpublishAt); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePromotionErr),
+			err.Error(),
+		).Res()
+	}
 	req.Id = promotionId
 
 	promotion, err := h.promotionsUsecase.UpdatePromotion(req)
 	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(updatePromotionErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
 			string(updatePromotionErr),
@@ -205,32 +296,67 @@ func (h *promotionsHandlers) DeletePromotion(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	deleteFileReq := make([]*files.DeleteFileReq, 0)
-	for _, p := range promotion.Images {
-		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
-			Destination: fmt.Sprintf("promotions/%s", path.Base(p.Url)),
-		})
+	// Files are kept until the item is purged from the trash
+	if err := h.promotionsUsecase.DeletePromotion(promotionId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deletePromotionErr),
+			err.Error(),
+		).Res()
 	}
 
-	if err := h.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลโปรโมชัน : "+promotion.Heading)
+	if err != nil {
+		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deletePromotionErr),
+			fmt.Sprintf("Failed to log activity %v", userID),
 			err.Error(),
 		).Res()
 	}
 
-	if err := h.promotionsUsecase.DeletePromotion(promotionId); err != nil {
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UpdatePromotionOrder rewrites the display order from the ids in the body, first id first.
+func (h *promotionsHandlers) UpdatePromotionOrder(c *fiber.Ctx) error {
+	req := new(entities.OrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePromotionOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePromotionOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.promotionsUsecase.UpdatePromotionOrder(req.Ids); err != nil {
+		if errors.Is(err, utils.ErrUnknownOrderId) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updatePromotionOrderErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
-			string(deletePromotionErr),
+			string(updatePromotionOrderErr),
 			err.Error(),
 		).Res()
 	}
 
 	// Log activity
 	userID := utils.GetUserIDFromContext(c)
-	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูลโปรโมชัน : "+promotion.Heading)
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "จัดลำดับข้อมูลโปรโมชัน")
 	if err != nil {
 		// Handle error if logging fails
 		return entities.NewResponse(c).Error(
@@ -240,5 +366,5 @@ func (h *promotionsHandlers) DeletePromotion(c *fiber.Ctx) error {
 		).Res()
 	}
 
-	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
 }
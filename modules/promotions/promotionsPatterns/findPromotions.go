@@ -69,7 +69,7 @@ func (b *findPromotionBuilder) initQuery() {
 				) AS "it"
 			) AS "promotion_images"
 		FROM "promotions" "p"
-		WHERE 1 = 1`
+		WHERE "p"."deleted_at" IS NULL`
 }
 
 func (b *findPromotionBuilder) countQuery() {
@@ -77,13 +77,21 @@ func (b *findPromotionBuilder) countQuery() {
 		SELECT
 			COUNT(*) AS "count"
 		FROM "promotions" "p"
-		WHERE 1 = 1`
+		WHERE "p"."deleted_at" IS NULL`
 }
 
 func (b *findPromotionBuilder) whereQuery() {
 	var queryWhere string
 	queryWhereStack := make([]string, 0)
 
+	// Display check
+	if b.req.Display != "" {
+		b.values = append(b.values, b.req.Display)
+
+		queryWhereStack = append(queryWhereStack, `
+		AND "p"."display" = ?`)
+	}
+
 	// Id check
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
@@ -53,9 +53,12 @@ func (b *insertPromotionBuilder) insertPromotion() error {
 		"description",
 		"start_date",
 		"end_date",
-		"display"
+		"display",
+		"publish_at",
+		"unpublish_at",
+		"slug"
 	)
-	VALUES ($1, $2, $3, $4, $5, $6)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::timestamptz, NULLIF($8, '')::timestamptz, $9)
 	RETURNING "id";`
 
 	if err := b.tx.QueryRowxContext(
@@ -67,6 +70,9 @@ func (b *insertPromotionBuilder) insertPromotion() error {
 		b.req.StartDate,
 		b.req.EndDate,
 		b.req.Display,
+		b.req.PublishAt,
+		b.req.UnpublishAt,
+		b.req.Slug,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert promotion failed: %v", err)
@@ -11,6 +11,7 @@ import (
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/promotions"
 	"github.com/yporn/sirarom-backend/modules/promotions/promotionsPatterns"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type IPromotionsRepository interface {
@@ -18,7 +19,9 @@ type IPromotionsRepository interface {
 	FindPromotion(req *promotions.PromotionFilter) ([]*promotions.Promotion, int)
 	InsertPromotion(req *promotions.Promotion) (*promotions.Promotion, error)
 	UpdatePromotion(req *promotions.Promotion) (*promotions.Promotion, error) 
+	FindPromotionSlug(slug string) (int, string, error)
 	DeletePromotion(promotionId string) error
+	UpdatePromotionOrder(ids []int) error
 }
 
 type promotionsRepository struct {
@@ -100,6 +103,7 @@ func (r *promotionsRepository) FindOnePromotion(promotionId string) (*promotions
 									) AS "house_images"
 								FROM "house_models" "hm"
 								WHERE "hm"."id" = "phm"."house_model_id"
+								AND "hm"."deleted_at" IS NULL
 							) AS "hm"
 						) AS "house_model_name"
 					FROM "promotion_house_models" "phm"
@@ -108,6 +112,7 @@ func (r *promotionsRepository) FindOnePromotion(promotionId string) (*promotions
 			) AS "house_models"
 			FROM "promotions" "p"
 		WHERE "p"."id" = $1
+		AND "p"."deleted_at" IS NULL
 	) AS "t";
 	`
 	var promotionJSON []byte
@@ -136,6 +141,12 @@ func (r *promotionsRepository) FindPromotion(req *promotions.PromotionFilter) ([
 }
 
 func (r *promotionsRepository) InsertPromotion(req *promotions.Promotion) (*promotions.Promotion, error) {
+	slug, err := utils.UniqueSlug(r.db, "promotions", 0, req.Heading, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Slug = slug
+
 	builder := promotionsPatterns.InsertPromotionBuilder(r.db, req)
 	promotionId, err := promotionsPatterns.InsertPromotionEngineer(builder).InsertPromotion()
 	if err != nil {
@@ -150,6 +161,15 @@ func (r *promotionsRepository) InsertPromotion(req *promotions.Promotion) (*prom
 }
 
 func (r *promotionsRepository) UpdatePromotion(req *promotions.Promotion) (*promotions.Promotion, error) {
+	// The slug only changes when one is given, so renaming keeps the links
+	if req.Slug != "" {
+		slug, err := utils.UniqueSlug(r.db, "promotions", req.Id, "", req.Slug)
+		if err != nil {
+			return nil, err
+		}
+		req.Slug = slug
+	}
+
     // Initialize the update builder
     builder := promotionsPatterns.UpdatePromotionBuilder(r.db, req, r.filesUsecase)
     engineer := promotionsPatterns.UpdatePromotionEngineer(builder)
@@ -169,10 +189,18 @@ func (r *promotionsRepository) UpdatePromotion(req *promotions.Promotion) (*prom
 
 
 func (r *promotionsRepository) DeletePromotion(promotionId string) error {
-	query := `DELETE FROM "promotions" WHERE "id" = $1;`
+	query := `UPDATE "promotions" SET "deleted_at" = now() WHERE "id" = $1 AND "deleted_at" IS NULL;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, promotionId); err != nil {
 		return fmt.Errorf("delete promotion failed: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *promotionsRepository) UpdatePromotionOrder(ids []int) error {
+	return utils.Reorder(r.db, "promotions", ids, "", nil)
+}
+
+func (r *promotionsRepository) FindPromotionSlug(slug string) (int, string, error) {
+	return utils.FindSlug(r.db, "promotions", slug)
+}
@@ -13,7 +13,9 @@ type IPromotionsUsecase interface {
 	FindPromotion(req *promotions.PromotionFilter) *entities.PaginateRes 
 	AddPromotion(req *promotions.Promotion) (*promotions.Promotion, error)
 	UpdatePromotion(req *promotions.Promotion) (*promotions.Promotion, error)
+	FindPromotionSlug(slug string) (int, string, error)
 	DeletePromotion(promotionId string) error 
+	UpdatePromotionOrder(ids []int) error
 }
 
 type promotionsUsecase struct {
@@ -67,4 +69,14 @@ func (u *promotionsUsecase) DeletePromotion(promotionId string) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (u *promotionsUsecase) UpdatePromotionOrder(ids []int) error {
+	return u.promotionsRepository.UpdatePromotionOrder(ids)
+}
+
+// FindPromotionSlug returns the id and current slug of the promotion with the slug
+// or with an old slug of it.
+func (u *promotionsUsecase) FindPromotionSlug(slug string) (int, string, error) {
+	return u.promotionsRepository.FindPromotionSlug(slug)
+}
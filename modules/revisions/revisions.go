@@ -0,0 +1,45 @@
+package revisions
+
+import (
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Entities that keep a revision history.
+const (
+	ProjectEntity    = "projects"
+	HouseModelEntity = "house_models"
+)
+
+// Revise stores the revision of an edit inside the transaction of the edit, so
+// the edit and its revision are committed together or not at all. before is
+// nil when the edit does not need the original kept, such as a restore.
+type Revise func(tx *sqlx.Tx, before, after any) error
+
+type Revision struct {
+	Id            int             `db:"id" json:"id"`
+	Entity        string          `db:"entity" json:"entity"`
+	EntityId      int             `db:"entity_id" json:"entity_id"`
+	Snapshot      json.RawMessage `db:"snapshot" json:"snapshot,omitempty"`
+	CreatedBy     int             `db:"created_by" json:"created_by"`
+	CreatedByName string          `db:"created_by_name" json:"created_by_name"`
+	CreatedAt     string          `db:"created_at" json:"created_at"`
+}
+
+type RevisionDiffReq struct {
+	From int `query:"from"`
+	To   int `query:"to"` // latest revision when empty
+}
+
+type RevisionDiff struct {
+	From    int               `json:"from"`
+	To      int               `json:"to"`
+	Changes []*RevisionChange `json:"changes"`
+}
+
+type RevisionChange struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
@@ -0,0 +1,167 @@
+package revisionsRepositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+)
+
+type IRevisionsRepository interface {
+	WithTx(tx *sqlx.Tx) IRevisionsRepository
+	InsertRevision(req *revisions.Revision) error
+	CountRevisions(entity string, entityId int) (int, error)
+	FindRevisions(entity string, entityId int) ([]*revisions.Revision, error)
+	FindOneRevision(entity string, entityId, revisionId int) (*revisions.Revision, error)
+	FindLatestRevision(entity string, entityId int) (*revisions.Revision, error)
+}
+
+// queryer is the db, or the transaction of the edit a revision is stored with.
+type queryer interface {
+	Get(dest any, query string, args ...any) error
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type revisionsRepository struct {
+	db queryer
+}
+
+func RevisionsRepository(db *sqlx.DB) IRevisionsRepository {
+	return &revisionsRepository{
+		db: db,
+	}
+}
+
+// WithTx runs the queries of the returned repository in tx.
+func (r *revisionsRepository) WithTx(tx *sqlx.Tx) IRevisionsRepository {
+	return &revisionsRepository{
+		db: tx,
+	}
+}
+
+func (r *revisionsRepository) InsertRevision(req *revisions.Revision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	INSERT INTO "revisions" (
+		"entity",
+		"entity_id",
+		"snapshot",
+		"created_by"
+	)
+	VALUES ($1, $2, $3, NULLIF($4, 0))
+	RETURNING "id";`
+
+	if err := r.db.QueryRowContext(
+		ctx,
+		query,
+		req.Entity,
+		req.EntityId,
+		string(req.Snapshot),
+		req.CreatedBy,
+	).Scan(&req.Id); err != nil {
+		return fmt.Errorf("insert revision failed: %v", err)
+	}
+	return nil
+}
+
+func (r *revisionsRepository) CountRevisions(entity string, entityId int) (int, error) {
+	query := `
+	SELECT
+		COUNT(*)
+	FROM "revisions"
+	WHERE "entity" = $1
+	AND "entity_id" = $2;`
+
+	var count int
+	if err := r.db.Get(&count, query, entity, entityId); err != nil {
+		return 0, fmt.Errorf("count revisions failed: %v", err)
+	}
+	return count, nil
+}
+
+func (r *revisionsRepository) FindRevisions(entity string, entityId int) ([]*revisions.Revision, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"r"."id",
+			"r"."entity",
+			"r"."entity_id",
+			COALESCE("r"."created_by", 0) AS "created_by",
+			COALESCE("u"."name", '') AS "created_by_name",
+			"r"."created_at"
+		FROM "revisions" "r"
+		LEFT JOIN "users" "u" ON "u"."id" = "r"."created_by"
+		WHERE "r"."entity" = $1
+		AND "r"."entity_id" = $2
+		ORDER BY "r"."id" DESC
+	) AS "t";`
+
+	bytes := make([]byte, 0)
+	revisionsData := make([]*revisions.Revision, 0)
+
+	if err := r.db.Get(&bytes, query, entity, entityId); err != nil {
+		return nil, fmt.Errorf("get revisions failed: %v", err)
+	}
+	if err := json.Unmarshal(bytes, &revisionsData); err != nil {
+		return nil, fmt.Errorf("unmarshal revisions failed: %v", err)
+	}
+	return revisionsData, nil
+}
+
+func (r *revisionsRepository) FindOneRevision(entity string, entityId, revisionId int) (*revisions.Revision, error) {
+	query := `
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT
+			"r"."id",
+			"r"."entity",
+			"r"."entity_id",
+			"r"."snapshot",
+			COALESCE("r"."created_by", 0) AS "created_by",
+			COALESCE("u"."name", '') AS "created_by_name",
+			"r"."created_at"
+		FROM "revisions" "r"
+		LEFT JOIN "users" "u" ON "u"."id" = "r"."created_by"
+		WHERE "r"."entity" = $1
+		AND "r"."entity_id" = $2
+		AND "r"."id" = $3
+		LIMIT 1
+	) AS "t";`
+
+	bytes := make([]byte, 0)
+	revision := &revisions.Revision{}
+
+	if err := r.db.Get(&bytes, query, entity, entityId, revisionId); err != nil {
+		return nil, fmt.Errorf("get revision failed: %v", err)
+	}
+	if err := json.Unmarshal(bytes, revision); err != nil {
+		return nil, fmt.Errorf("unmarshal revision failed: %v", err)
+	}
+	return revision, nil
+}
+
+func (r *revisionsRepository) FindLatestRevision(entity string, entityId int) (*revisions.Revision, error) {
+	query := `
+	SELECT
+		"id"
+	FROM "revisions"
+	WHERE "entity" = $1
+	AND "entity_id" = $2
+	ORDER BY "id" DESC
+	LIMIT 1;`
+
+	var revisionId int
+	if err := r.db.Get(&revisionId, query, entity, entityId); err != nil {
+		return nil, fmt.Errorf("get latest revision failed: %v", err)
+	}
+	return r.FindOneRevision(entity, entityId, revisionId)
+}
@@ -0,0 +1,163 @@
+package revisionsUsecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/revisions"
+	"github.com/yporn/sirarom-backend/modules/revisions/revisionsRepositories"
+)
+
+type IRevisionsUsecase interface {
+	AddRevision(tx *sqlx.Tx, entity string, entityId int, before, after any, userId int) error
+	FindRevisions(entity string, entityId int) ([]*revisions.Revision, error)
+	FindOneRevision(entity string, entityId, revisionId int) (*revisions.Revision, error)
+	DiffRevisions(entity string, entityId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error)
+}
+
+type revisionsUsecase struct {
+	revisionsRepository revisionsRepositories.IRevisionsRepository
+}
+
+func RevisionsUsecase(revisionsRepository revisionsRepositories.IRevisionsRepository) IRevisionsUsecase {
+	return &revisionsUsecase{
+		revisionsRepository: revisionsRepository,
+	}
+}
+
+// AddRevision stores the state after an edit, in the transaction of the edit. The
+// first edit of a record also stores the state before it, so the original
+// version can be restored as well.
+func (u *revisionsUsecase) AddRevision(tx *sqlx.Tx, entity string, entityId int, before, after any, userId int) error {
+	repository := u.revisionsRepository.WithTx(tx)
+
+	count, err := repository.CountRevisions(entity, entityId)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 && before != nil {
+		if err := insertRevision(repository, entity, entityId, before, 0); err != nil {
+			return err
+		}
+	}
+	return insertRevision(repository, entity, entityId, after, userId)
+}
+
+func insertRevision(repository revisionsRepositories.IRevisionsRepository, entity string, entityId int, snapshot any, userId int) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal revision failed: %v", err)
+	}
+	return repository.InsertRevision(&revisions.Revision{
+		Entity:    entity,
+		EntityId:  entityId,
+		Snapshot:  data,
+		CreatedBy: userId,
+	})
+}
+
+func (u *revisionsUsecase) FindRevisions(entity string, entityId int) ([]*revisions.Revision, error) {
+	revisionsData, err := u.revisionsRepository.FindRevisions(entity, entityId)
+	if err != nil {
+		return nil, err
+	}
+	return revisionsData, nil
+}
+
+func (u *revisionsUsecase) FindOneRevision(entity string, entityId, revisionId int) (*revisions.Revision, error) {
+	revision, err := u.revisionsRepository.FindOneRevision(entity, entityId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+func (u *revisionsUsecase) DiffRevisions(entity string, entityId int, req *revisions.RevisionDiffReq) (*revisions.RevisionDiff, error) {
+	from, err := u.revisionsRepository.FindOneRevision(entity, entityId, req.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *revisions.Revision
+	if req.To == 0 {
+		to, err = u.revisionsRepository.FindLatestRevision(entity, entityId)
+	} else {
+		to, err = u.revisionsRepository.FindOneRevision(entity, entityId, req.To)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fromData, toData any
+	if err := json.Unmarshal(from.Snapshot, &fromData); err != nil {
+		return nil, fmt.Errorf("unmarshal revision failed: %v", err)
+	}
+	if err := json.Unmarshal(to.Snapshot, &toData); err != nil {
+		return nil, fmt.Errorf("unmarshal revision failed: %v", err)
+	}
+
+	changes := make([]*revisions.RevisionChange, 0)
+	diffValue("", fromData, toData, &changes)
+
+	return &revisions.RevisionDiff{
+		From:    from.Id,
+		To:      to.Id,
+		Changes: changes,
+	}, nil
+}
+
+// diffValue walks both decoded snapshots and records every leaf that differs,
+// e.g. "house_type_items[1].name".
+func diffValue(path string, from, to any, changes *[]*revisions.RevisionChange) {
+	fromMap, fromIsMap := from.(map[string]any)
+	toMap, toIsMap := to.(map[string]any)
+	if fromIsMap && toIsMap {
+		keys := make([]string, 0, len(fromMap)+len(toMap))
+		for k := range fromMap {
+			keys = append(keys, k)
+		}
+		for k := range toMap {
+			if _, ok := fromMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			key := k
+			if path != "" {
+				key = path + "." + k
+			}
+			diffValue(key, fromMap[k], toMap[k], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]any)
+	toList, toIsList := to.([]any)
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			var f, t any
+			if i < len(fromList) {
+				f = fromList[i]
+			}
+			if i < len(toList) {
+				t = toList[i]
+			}
+			diffValue(fmt.Sprintf("%s[%d]", path, i), f, t, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, &revisions.RevisionChange{
+			Path: path,
+			From: from,
+			To:   to,
+		})
+	}
+}
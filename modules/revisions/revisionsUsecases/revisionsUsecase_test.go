@@ -0,0 +1,78 @@
+package revisionsUsecases
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/yporn/sirarom-backend/modules/revisions"
+)
+
+func TestDiffValue(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []*revisions.RevisionChange
+	}{
+		{
+			name: "same",
+			from: `{"name":"A","items":[{"name":"x"}]}`,
+			to:   `{"name":"A","items":[{"name":"x"}]}`,
+			want: []*revisions.RevisionChange{},
+		},
+		{
+			name: "changed field",
+			from: `{"name":"A","price":1}`,
+			to:   `{"name":"B","price":1}`,
+			want: []*revisions.RevisionChange{{Path: "name", From: "A", To: "B"}},
+		},
+		{
+			name: "added and removed fields in key order",
+			from: `{"b":1,"c":true}`,
+			to:   `{"a":"new","b":1}`,
+			want: []*revisions.RevisionChange{
+				{Path: "a", From: nil, To: "new"},
+				{Path: "c", From: true, To: nil},
+			},
+		},
+		{
+			name: "nested list item",
+			from: `{"house_type_items":[{"name":"x"},{"name":"y"}]}`,
+			to:   `{"house_type_items":[{"name":"x"},{"name":"z"}]}`,
+			want: []*revisions.RevisionChange{{Path: "house_type_items[1].name", From: "y", To: "z"}},
+		},
+		{
+			name: "list grows",
+			from: `{"tags":["a"]}`,
+			to:   `{"tags":["a","b"]}`,
+			want: []*revisions.RevisionChange{{Path: "tags[1]", From: nil, To: "b"}},
+		},
+		{
+			name: "object replaced by a value",
+			from: `{"seo":{"title":"t"}}`,
+			to:   `{"seo":null}`,
+			want: []*revisions.RevisionChange{{Path: "seo", From: map[string]any{"title": "t"}, To: nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to any
+			if err := json.Unmarshal([]byte(tt.from), &from); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.to), &to); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]*revisions.RevisionChange, 0)
+			diffValue("", from, to, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJson, _ := json.Marshal(got)
+				wantJson, _ := json.Marshal(tt.want)
+				t.Errorf("diffValue = %s, want %s", gotJson, wantJson)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+// Publishable is a content table handled by the publishing scheduler.
+type Publishable struct {
+	Table string
+	Name  string // column used to describe the row in the activity log
+	Label string
+}
+
+var Publishables = []*Publishable{
+	{Table: "projects", Name: `"name"`, Label: "โครงการ"},
+	{Table: "house_models", Name: `"name"`, Label: "แบบบ้าน"},
+	{Table: "promotions", Name: `"heading"`, Label: "โปรโมชัน"},
+	{Table: "activities", Name: `"heading"`, Label: "กิจกรรม"},
+	{Table: "banners", Name: `'#' || "id"`, Label: "แบนเนอร์"},
+	{Table: "logos", Name: `"name"`, Label: "แบรนด์ในเครือ"},
+	{Table: "interests", Name: `"bank_name"`, Label: "ดอกเบี้ย"},
+	{Table: "careers", Name: `"position"`, Label: "ตำแหน่งงาน"},
+}
+
+// Task is a periodic job of another module, run by the scheduler alongside its own.
+type Task func()
+
+// LockKey is the postgres advisory lock held during a run, so that only one
+// replica of the api runs the tasks of a tick.
+const LockKey int64 = 240401
+
+// Change is a row whose state was flipped by the scheduler.
+type Change struct {
+	Id   int    `db:"id"`
+	Name string `db:"name"`
+}
@@ -0,0 +1,213 @@
+package schedulerRepositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/scheduler"
+)
+
+type ISchedulerRepository interface {
+	PublishDue(table *scheduler.Publishable) ([]*scheduler.Change, error)
+	ArchiveDue(table *scheduler.Publishable) ([]*scheduler.Change, error)
+	SwitchInterestRates() ([]*scheduler.Change, error)
+	CloseExpiredJobs(today string) ([]*scheduler.Change, error)
+	OpenStartedJobs(today string) ([]*scheduler.Change, error)
+	PruneSessions() (int64, error)
+	PruneLoginThrottles() (int64, error)
+	TryLock(key int64) (func(), bool, error)
+}
+
+type schedulerRepository struct {
+	db *sqlx.DB
+}
+
+func SchedulerRepository(db *sqlx.DB) ISchedulerRepository {
+	return &schedulerRepository{
+		db: db,
+	}
+}
+
+// PublishDue publishes scheduled rows whose publish_at has passed and that are not already expired.
+func (r *schedulerRepository) PublishDue(table *scheduler.Publishable) ([]*scheduler.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	UPDATE "%s" SET
+		"display" = 'published'
+	WHERE "display" = 'scheduled'
+	AND "publish_at" <= now()
+	AND ("unpublish_at" IS NULL OR "unpublish_at" > now())
+	AND "deleted_at" IS NULL
+	RETURNING "id", (%s)::text AS "name";`, table.Table, table.Name)
+
+	changes := make([]*scheduler.Change, 0)
+	if err := r.db.SelectContext(ctx, &changes, query); err != nil {
+		return nil, fmt.Errorf("publish %s failed: %v", table.Table, err)
+	}
+	return changes, nil
+}
+
+// ArchiveDue archives published or scheduled rows whose unpublish_at has passed.
+func (r *schedulerRepository) ArchiveDue(table *scheduler.Publishable) ([]*scheduler.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	UPDATE "%s" SET
+		"display" = 'archived'
+	WHERE "display" IN ('published', 'scheduled')
+	AND "unpublish_at" <= now()
+	AND "deleted_at" IS NULL
+	RETURNING "id", (%s)::text AS "name";`, table.Table, table.Name)
+
+	changes := make([]*scheduler.Change, 0)
+	if err := r.db.SelectContext(ctx, &changes, query); err != nil {
+		return nil, fmt.Errorf("archive %s failed: %v", table.Table, err)
+	}
+	return changes, nil
+}
+
+// SwitchInterestRates moves every bank to the latest rate of its history that has
+// started, which brings in future-dated rates once their date arrives.
+func (r *schedulerRepository) SwitchInterestRates() ([]*scheduler.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	UPDATE "interests" "i" SET
+		"interest_rate" = "h"."rate"
+	FROM (
+		SELECT DISTINCT ON ("interest_id")
+			"interest_id",
+			"rate"
+		FROM "interest_rate_history"
+		WHERE "effective_from" <= now()
+		ORDER BY "interest_id", "effective_from" DESC
+	) AS "h"
+	WHERE "i"."id" = "h"."interest_id"
+	AND "i"."interest_rate" IS DISTINCT FROM "h"."rate"
+	AND "i"."deleted_at" IS NULL
+	RETURNING "i"."id", "i"."bank_name" || ' ' || "h"."rate" || '%' AS "name";`
+
+	changes := make([]*scheduler.Change, 0)
+	if err := r.db.SelectContext(ctx, &changes, query); err != nil {
+		return nil, fmt.Errorf("switch interest rates failed: %v", err)
+	}
+	return changes, nil
+}
+
+// CloseExpiredJobs closes opening jobs whose end_date is before today.
+func (r *schedulerRepository) CloseExpiredJobs(today string) ([]*scheduler.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	UPDATE "careers" SET
+		"status" = 'closed'
+	WHERE "status" = 'opening'
+	AND "end_date" < $1::date
+	AND "deleted_at" IS NULL
+	RETURNING "id", "position" AS "name";`
+
+	changes := make([]*scheduler.Change, 0)
+	if err := r.db.SelectContext(ctx, &changes, query, today); err != nil {
+		return nil, fmt.Errorf("close expired jobs failed: %v", err)
+	}
+	return changes, nil
+}
+
+// OpenStartedJobs opens closed jobs once their start_date arrives, as long as
+// the end_date has not passed. A job edited on or after its start date keeps
+// its status, so one closed by hand is not reopened.
+func (r *schedulerRepository) OpenStartedJobs(today string) ([]*scheduler.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	UPDATE "careers" SET
+		"status" = 'opening'
+	WHERE "status" = 'closed'
+	AND "start_date" <= $1::date
+	AND ("end_date" IS NULL OR "end_date" >= $1::date)
+	AND "updated_at" < "start_date"
+	AND "deleted_at" IS NULL
+	RETURNING "id", "position" AS "name";`
+
+	changes := make([]*scheduler.Change, 0)
+	if err := r.db.SelectContext(ctx, &changes, query, today); err != nil {
+		return nil, fmt.Errorf("open started jobs failed: %v", err)
+	}
+	return changes, nil
+}
+
+// PruneSessions deletes the oauth rows whose refresh token has expired, along
+// with the expired used refresh tokens.
+func (r *schedulerRepository) PruneSessions() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM "used_refresh_tokens" WHERE "expires_at" <= now();`); err != nil {
+		return 0, fmt.Errorf("prune used refresh tokens failed: %v", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM "oauth" WHERE "expires_at" <= now();`)
+	if err != nil {
+		return 0, fmt.Errorf("prune sessions failed: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// PruneLoginThrottles deletes the unlocked failed sign in counts older than a day.
+func (r *schedulerRepository) PruneLoginThrottles() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	DELETE FROM "login_throttles"
+	WHERE ("locked_until" IS NULL OR "locked_until" <= now())
+	AND "last_failed_at" < now() - INTERVAL '1 day';`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("prune login throttles failed: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// TryLock takes the session advisory lock key on a connection of its own and
+// reports false when another session holds it. The returned func releases the
+// lock and the connection; a crashed replica loses both with its connection.
+func (r *schedulerRepository) TryLock(key int64) (func(), bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("get scheduler connection failed: %v", err)
+	}
+
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock($1);`, key); err != nil || !locked {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("lock scheduler failed: %v", err)
+		}
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+		defer cancel()
+
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, key); err != nil {
+			log.Printf("scheduler: unlock failed: %v\n", err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
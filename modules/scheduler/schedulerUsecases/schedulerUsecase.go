@@ -0,0 +1,148 @@
+package schedulerUsecases
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/scheduler"
+	"github.com/yporn/sirarom-backend/modules/scheduler/schedulerRepositories"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type ISchedulerUsecase interface {
+	Start(ctx context.Context, interval time.Duration)
+	RunPublishing()
+	RunInterestRates()
+	RunJobStatus()
+	RunSessionPrune()
+}
+
+type schedulerUsecase struct {
+	cfg                 config.IConfig
+	schedulerRepository schedulerRepositories.ISchedulerRepository
+	db                  *sql.DB
+	tasks               []scheduler.Task
+}
+
+// SchedulerUsecase runs its own jobs followed by tasks, the periodic jobs of other modules.
+func SchedulerUsecase(cfg config.IConfig, schedulerRepository schedulerRepositories.ISchedulerRepository, db *sql.DB, tasks ...scheduler.Task) ISchedulerUsecase {
+	return &schedulerUsecase{
+		cfg:                 cfg,
+		schedulerRepository: schedulerRepository,
+		db:                  db,
+		tasks:               tasks,
+	}
+}
+
+// Start runs every task once and then on each tick until ctx is done.
+func (u *schedulerUsecase) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		u.run()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs every task under the scheduler lock. A tick is skipped when another
+// replica holds the lock, as that one is already running the same tasks.
+func (u *schedulerUsecase) run() {
+	unlock, locked, err := u.schedulerRepository.TryLock(scheduler.LockKey)
+	if err != nil {
+		log.Printf("scheduler: %v\n", err)
+		return
+	}
+	if !locked {
+		return
+	}
+	defer unlock()
+
+	u.RunPublishing()
+	u.RunInterestRates()
+	u.RunJobStatus()
+	u.RunSessionPrune()
+	for _, task := range u.tasks {
+		task()
+	}
+}
+
+// RunPublishing flips scheduled content to published and expired content to archived.
+// A failing table is logged and skipped so the others still run.
+func (u *schedulerUsecase) RunPublishing() {
+	for _, table := range scheduler.Publishables {
+		published, err := u.schedulerRepository.PublishDue(table)
+		if err != nil {
+			log.Printf("scheduler: %v\n", err)
+		}
+		for _, c := range published {
+			u.logChange("published", "เผยแพร่ข้อมูล"+table.Label+" : "+c.Name)
+		}
+
+		archived, err := u.schedulerRepository.ArchiveDue(table)
+		if err != nil {
+			log.Printf("scheduler: %v\n", err)
+		}
+		for _, c := range archived {
+			u.logChange("archived", "เก็บถาวรข้อมูล"+table.Label+" : "+c.Name)
+		}
+	}
+}
+
+// RunInterestRates brings in the interest rates whose effective date has arrived.
+func (u *schedulerUsecase) RunInterestRates() {
+	changes, err := u.schedulerRepository.SwitchInterestRates()
+	if err != nil {
+		log.Printf("scheduler: %v\n", err)
+	}
+	for _, c := range changes {
+		u.logChange("updated", "ปรับอัตราดอกเบี้ยตามวันที่มีผล : "+c.Name)
+	}
+}
+
+// RunJobStatus closes jobs past their end_date and opens jobs whose start_date
+// has arrived. Dates are taken in APP_TIMEZONE.
+func (u *schedulerUsecase) RunJobStatus() {
+	today := time.Now().In(u.cfg.App().Timezone()).Format("2006-01-02")
+
+	closed, err := u.schedulerRepository.CloseExpiredJobs(today)
+	if err != nil {
+		log.Printf("scheduler: %v\n", err)
+	}
+	for _, c := range closed {
+		u.logChange("updated", "ปิดรับสมัครตำแหน่งงานที่หมดเขต : "+c.Name)
+	}
+
+	opened, err := u.schedulerRepository.OpenStartedJobs(today)
+	if err != nil {
+		log.Printf("scheduler: %v\n", err)
+	}
+	for _, c := range opened {
+		u.logChange("updated", "เปิดรับสมัครตำแหน่งงานตามวันที่เริ่ม : "+c.Name)
+	}
+}
+
+// RunSessionPrune clears the expired logins out of oauth and the old failed
+// sign in counts out of login_throttles.
+func (u *schedulerUsecase) RunSessionPrune() {
+	if _, err := u.schedulerRepository.PruneSessions(); err != nil {
+		log.Printf("scheduler: %v\n", err)
+	}
+	if _, err := u.schedulerRepository.PruneLoginThrottles(); err != nil {
+		log.Printf("scheduler: %v\n", err)
+	}
+}
+
+func (u *schedulerUsecase) logChange(action, details string) {
+	if err := utils.LogSystemActivity(u.db, action, details); err != nil {
+		log.Printf("scheduler: log activity failed: %v\n", err)
+	}
+}
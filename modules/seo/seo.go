@@ -1,5 +1,11 @@
 package seo
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
 type Seo struct {
 	Id          int    `db:"id" json:"id"`
 	Title       string `db:"title" json:"title"`
@@ -8,3 +14,96 @@ type Seo struct {
 	Robot       string `db:"robot" json:"robot"`
 	GoogleBot   string `db:"google_bot" json:"google_bot"`
 }
+
+// Sources of the landing page pages.
+const (
+	PageProject    = "projects"
+	PageHouseModel = "house_models"
+	PagePromotion  = "promotions"
+	PageActivity   = "activities"
+	PageJob        = "jobs"
+)
+
+// PagePath is the path of a content page on the landing page, key is the slug
+// of the page or the id for pages without one.
+func PagePath(page string, key any) string {
+	return fmt.Sprintf("/%s/%v", page, key)
+}
+
+// SitemapEntry is a published row listed in sitemap.xml.
+type SitemapEntry struct {
+	Page    string `db:"page"`
+	Id      int    `db:"id"`
+	Slug    string `db:"slug"`    // the id for pages without a slug
+	Lastmod string `db:"lastmod"` // YYYY-MM-DD of updated_at
+}
+
+// Pageable is a content table whose pages can carry their own meta tags.
+type Pageable struct {
+	Table      string
+	Name       string // column used as the page title
+	ImageTable string
+	ImageKey   string // column of ImageTable pointing at the row
+	Published  string // extra condition for the row to be on the landing page, "x" being the row
+}
+
+var Pageables = map[string]*Pageable{
+	PageProject: {Table: "projects", Name: `"name"`, ImageTable: "project_images", ImageKey: "project_id"},
+	PageHouseModel: {Table: "house_models", Name: `"name"`, ImageTable: "house_model_images", ImageKey: "house_model_id", Published: `EXISTS (
+		SELECT 1 FROM "projects" "p"
+		WHERE "p"."id" = "x"."project_id" AND "p"."display" = 'published' AND "p"."deleted_at" IS NULL
+	)`},
+	PagePromotion: {Table: "promotions", Name: `"heading"`, ImageTable: "promotion_images", ImageKey: "promotion_id"},
+	PageActivity:  {Table: "activities", Name: `"heading"`, ImageTable: "activities_images", ImageKey: "activity_id"},
+}
+
+// Page is the content row behind a page, Published is false when the row or,
+// for a house model, its project is not on the landing page.
+type Page struct {
+	Name      string   `db:"name" json:"name"`
+	Slug      string   `db:"slug" json:"slug"`
+	Published bool     `db:"published" json:"published"`
+	Images    []string `json:"images"`
+}
+
+// Meta is the meta tags set on a single page. Empty fields fall back to the
+// page itself and then to the global seo record.
+type Meta struct {
+	Id           int    `db:"id" json:"id"`
+	Page         string `db:"page" json:"page"`
+	EntityId     int    `db:"entity_id" json:"entity_id"`
+	Title        string `db:"title" json:"title"`
+	Description  string `db:"description" json:"description"`
+	OgImage      string `db:"og_image" json:"og_image"` // one of the images of the page
+	CanonicalUrl string `db:"canonical_url" json:"canonical_url"`
+	Noindex      bool   `db:"noindex" json:"noindex"`
+	CreatedAt    string `db:"created_at" json:"created_at"`
+	UpdatedAt    string `db:"updated_at" json:"updated_at"`
+}
+
+func (m *Meta) Validate() error {
+	m.Title = strings.TrimSpace(m.Title)
+	m.Description = strings.TrimSpace(m.Description)
+	m.OgImage = strings.TrimSpace(m.OgImage)
+	m.CanonicalUrl = strings.TrimSpace(m.CanonicalUrl)
+	if m.CanonicalUrl != "" {
+		u, err := url.Parse(m.CanonicalUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("canonical_url must be an absolute http(s) url")
+		}
+	}
+	return nil
+}
+
+// PageSeo is the effective meta tags of a route.
+type PageSeo struct {
+	Path         string `json:"path"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Keyword      string `json:"keyword"`
+	Robot        string `json:"robot"`
+	GoogleBot    string `json:"google_bot"`
+	OgImage      string `json:"og_image"`
+	CanonicalUrl string `json:"canonical_url"`
+	Noindex      bool   `json:"noindex"`
+}
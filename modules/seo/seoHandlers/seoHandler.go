@@ -2,6 +2,7 @@ package seoHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -19,11 +20,23 @@ type seoHandlersErrCode string
 const (
 	findOneSeoErr seoHandlersErrCode = "seo-001"
 	updateSeoErr  seoHandlersErrCode = "seo-002"
+	sitemapErr    seoHandlersErrCode = "seo-003"
+	robotsErr     seoHandlersErrCode = "seo-004"
+	findPageSeoErr   seoHandlersErrCode = "seo-005"
+	updatePageSeoErr seoHandlersErrCode = "seo-006"
+	deletePageSeoErr seoHandlersErrCode = "seo-007"
+	resolveSeoErr    seoHandlersErrCode = "seo-008"
 )
 
 type ISeoHandler interface {
 	FindOneSeo(c *fiber.Ctx) error
 	UpdateSeo(c *fiber.Ctx) error
+	Sitemap(c *fiber.Ctx) error
+	Robots(c *fiber.Ctx) error
+	FindPageSeo(c *fiber.Ctx) error
+	UpdatePageSeo(c *fiber.Ctx) error
+	DeletePageSeo(c *fiber.Ctx) error
+	ResolvePage(c *fiber.Ctx) error
 }
 
 type seoHandler struct {
@@ -101,3 +114,171 @@ func (h *seoHandler) UpdateSeo(c *fiber.Ctx) error {
 	return entities.NewResponse(c).Success(fiber.StatusOK, job).Res()
 }
 
+func (h *seoHandler) Sitemap(c *fiber.Ctx) error {
+	sitemap, err := h.seoUsecase.Sitemap()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(sitemapErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(sitemap)
+}
+
+func (h *seoHandler) Robots(c *fiber.Ctx) error {
+	robots, err := h.seoUsecase.Robots()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(robotsErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, "text/plain; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(robots)
+}
+
+// pageErrStatus maps the errors of the page meta usecases to a status code.
+func pageErrStatus(err error) int {
+	switch {
+	case errors.Is(err, seoUsecases.ErrNotFound):
+		return fiber.ErrNotFound.Code
+	case errors.Is(err, seoUsecases.ErrOgImage):
+		return fiber.ErrBadRequest.Code
+	}
+	return fiber.ErrInternalServerError.Code
+}
+
+func (h *seoHandler) FindPageSeo(c *fiber.Ctx) error {
+	page := strings.Trim(c.Params("page"), " ")
+	entityId, err := strconv.Atoi(strings.Trim(c.Params("entity_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findPageSeoErr),
+			"Invalid entity ID",
+		).Res()
+	}
+
+	meta, err := h.seoUsecase.FindPageMeta(page, entityId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			pageErrStatus(err),
+			string(findPageSeoErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, meta).Res()
+}
+
+func (h *seoHandler) UpdatePageSeo(c *fiber.Ctx) error {
+	page := strings.Trim(c.Params("page"), " ")
+	entityId, err := strconv.Atoi(strings.Trim(c.Params("entity_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePageSeoErr),
+			"Invalid entity ID",
+		).Res()
+	}
+
+	req := &seo.Meta{}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePageSeoErr),
+			err.Error(),
+		).Res()
+	}
+	req.Page = page
+	req.EntityId = entityId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updatePageSeoErr),
+			err.Error(),
+		).Res()
+	}
+
+	meta, err := h.seoUsecase.UpdatePageMeta(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			pageErrStatus(err),
+			string(updatePageSeoErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", fmt.Sprintf("อัพเดตข้อมูล SEO หน้า %s", seo.PagePath(page, entityId)))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, meta).Res()
+}
+
+func (h *seoHandler) DeletePageSeo(c *fiber.Ctx) error {
+	page := strings.Trim(c.Params("page"), " ")
+	entityId, err := strconv.Atoi(strings.Trim(c.Params("entity_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deletePageSeoErr),
+			"Invalid entity ID",
+		).Res()
+	}
+
+	if err := h.seoUsecase.DeletePageMeta(page, entityId); err != nil {
+		return entities.NewResponse(c).Error(
+			pageErrStatus(err),
+			string(deletePageSeoErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", fmt.Sprintf("ลบข้อมูล SEO หน้า %s", seo.PagePath(page, entityId)))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, nil).Res()
+}
+
+// ResolvePage returns the effective meta tags of the route in ?path=.
+func (h *seoHandler) ResolvePage(c *fiber.Ctx) error {
+	path := strings.Trim(c.Query("path"), " ")
+	if path == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(resolveSeoErr),
+			"path is required",
+		).Res()
+	}
+
+	res, err := h.seoUsecase.ResolvePage(path)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			pageErrStatus(err),
+			string(resolveSeoErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
@@ -1,7 +1,10 @@
 package seoRepositories
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -9,11 +12,19 @@ import (
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/seo"
 	"github.com/yporn/sirarom-backend/modules/seo/seoPatterns"
+	"github.com/yporn/sirarom-backend/pkg/utils"
 )
 
 type ISeoRepository interface {
 	FindOneSeo(seoId string) (*seo.Seo, error)
 	UpdateSeo(req *seo.Seo) (*seo.Seo, error)
+	FindSitemapEntries() ([]*seo.SitemapEntry, error)
+	SitemapVersion() (string, error)
+	FindPage(page string, entityId int) (*seo.Page, error)
+	FindMeta(page string, entityId int) (*seo.Meta, error)
+	UpsertMeta(req *seo.Meta) error
+	DeleteMeta(page string, entityId int) error
+	FindPageSlug(page, slug string) (int, string, error)
 }
 
 type seoRepository struct {
@@ -69,3 +80,190 @@ func (r *seoRepository) UpdateSeo(req *seo.Seo) (*seo.Seo, error) {
 
 	return seo, nil
 }
+
+// FindSitemapEntries lists every published row that has a page of its own,
+// except pages marked noindex. House models are only listed while their
+// project is published too.
+func (r *seoRepository) FindSitemapEntries() ([]*seo.SitemapEntry, error) {
+	query := `
+	SELECT
+		"e".*
+	FROM (
+		SELECT 'projects' AS "page", "id", "slug", to_char("updated_at", 'YYYY-MM-DD') AS "lastmod"
+		FROM "projects"
+		WHERE "display" = 'published' AND "deleted_at" IS NULL
+		UNION ALL
+		SELECT 'house_models', "hm"."id", "hm"."slug", to_char("hm"."updated_at", 'YYYY-MM-DD')
+		FROM "house_models" "hm"
+		JOIN "projects" "p" ON "p"."id" = "hm"."project_id"
+		WHERE "hm"."display" = 'published' AND "hm"."deleted_at" IS NULL
+		AND "p"."display" = 'published' AND "p"."deleted_at" IS NULL
+		UNION ALL
+		SELECT 'promotions', "id", "slug", to_char("updated_at", 'YYYY-MM-DD')
+		FROM "promotions"
+		WHERE "display" = 'published' AND "deleted_at" IS NULL
+		UNION ALL
+		SELECT 'activities', "id", "slug", to_char("updated_at", 'YYYY-MM-DD')
+		FROM "activities"
+		WHERE "display" = 'published' AND "deleted_at" IS NULL
+		UNION ALL
+		SELECT 'jobs', "id", "id"::text, to_char("updated_at", 'YYYY-MM-DD')
+		FROM "careers"
+		WHERE "display" = 'published' AND "deleted_at" IS NULL
+	) AS "e"
+	WHERE NOT EXISTS (
+		SELECT 1 FROM "seo_meta" "m"
+		WHERE "m"."page" = "e"."page" AND "m"."entity_id" = "e"."id" AND "m"."noindex"
+	)
+	ORDER BY "e"."page", "e"."id";`
+
+	entries := make([]*seo.SitemapEntry, 0)
+	if err := r.db.Select(&entries, query); err != nil {
+		return nil, fmt.Errorf("find sitemap entries failed: %v", err)
+	}
+	return entries, nil
+}
+
+// SitemapVersion changes whenever a row of the sitemap sources, a page meta,
+// the seo record or the company settings change. Every update bumps updated_at,
+// soft deletes included, and a purge lowers the row count.
+func (r *seoRepository) SitemapVersion() (string, error) {
+	query := `
+	SELECT concat_ws('|',
+		(SELECT max("updated_at") || '/' || count(*) FROM "projects"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "house_models"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "promotions"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "activities"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "careers"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "seo_meta"),
+		(SELECT md5(string_agg(row_to_json("s")::text, ',' ORDER BY "s"."id")) FROM "seo" "s"),
+		(SELECT md5(string_agg(row_to_json("d")::text, ',' ORDER BY "d"."id")) FROM "data_settings" "d"),
+		(SELECT max("updated_at") || '/' || count(*) FROM "data_setting_images")
+	);`
+
+	var version string
+	if err := r.db.Get(&version, query); err != nil {
+		return "", fmt.Errorf("get sitemap version failed: %v", err)
+	}
+	return version, nil
+}
+
+// FindPage returns nil when the row does not exist.
+func (r *seoRepository) FindPage(page string, entityId int) (*seo.Page, error) {
+	t := seo.Pageables[page]
+	published := ""
+	if t.Published != "" {
+		published = "\n\t\t\tAND " + t.Published
+	}
+
+	query := fmt.Sprintf(`
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT
+			("x".%s)::text AS "name",
+			"x"."slug",
+			("x"."display" = 'published' AND "x"."deleted_at" IS NULL%s) AS "published",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("i"."url" ORDER BY "i"."id")), '[]'::json)
+				FROM "%s" "i"
+				WHERE "i"."%s" = "x"."id"
+			) AS "images"
+		FROM "%s" "x"
+		WHERE "x"."id" = $1
+		LIMIT 1
+	) AS "t";`, t.Name, published, t.ImageTable, t.ImageKey, t.Table)
+
+	pageBytes := make([]byte, 0)
+	result := &seo.Page{
+		Images: make([]string, 0),
+	}
+
+	if err := r.db.Get(&pageBytes, query, entityId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get %s failed: %v", page, err)
+	}
+	if err := json.Unmarshal(pageBytes, result); err != nil {
+		return nil, fmt.Errorf("unmarshal %s failed: %v", page, err)
+	}
+	return result, nil
+}
+
+// FindMeta returns nil when the page has no meta of its own.
+func (r *seoRepository) FindMeta(page string, entityId int) (*seo.Meta, error) {
+	query := `
+	SELECT
+		"id",
+		"page",
+		"entity_id",
+		COALESCE("title", '') AS "title",
+		COALESCE("description", '') AS "description",
+		COALESCE("og_image", '') AS "og_image",
+		COALESCE("canonical_url", '') AS "canonical_url",
+		"noindex",
+		"created_at",
+		"updated_at"
+	FROM "seo_meta"
+	WHERE "page" = $1
+	AND "entity_id" = $2;`
+
+	meta := new(seo.Meta)
+	if err := r.db.Get(meta, query, page, entityId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get seo meta failed: %v", err)
+	}
+	return meta, nil
+}
+
+func (r *seoRepository) UpsertMeta(req *seo.Meta) error {
+	query := `
+	INSERT INTO "seo_meta" (
+		"page",
+		"entity_id",
+		"title",
+		"description",
+		"og_image",
+		"canonical_url",
+		"noindex"
+	)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7)
+	ON CONFLICT ("page", "entity_id") DO UPDATE SET
+		"title" = EXCLUDED."title",
+		"description" = EXCLUDED."description",
+		"og_image" = EXCLUDED."og_image",
+		"canonical_url" = EXCLUDED."canonical_url",
+		"noindex" = EXCLUDED."noindex";`
+
+	if _, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		req.Page,
+		req.EntityId,
+		req.Title,
+		req.Description,
+		req.OgImage,
+		req.CanonicalUrl,
+		req.Noindex,
+	); err != nil {
+		return fmt.Errorf("upsert seo meta failed: %v", err)
+	}
+	return nil
+}
+
+func (r *seoRepository) DeleteMeta(page string, entityId int) error {
+	query := `DELETE FROM "seo_meta" WHERE "page" = $1 AND "entity_id" = $2;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, page, entityId); err != nil {
+		return fmt.Errorf("delete seo meta failed: %v", err)
+	}
+	return nil
+}
+
+func (r *seoRepository) FindPageSlug(page, slug string) (int, string, error) {
+	return utils.FindSlug(r.db, seo.Pageables[page].Table, slug)
+}
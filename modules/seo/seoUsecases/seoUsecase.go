@@ -1,21 +1,57 @@
 package seoUsecases
 
 import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/seo"
 	"github.com/yporn/sirarom-backend/modules/seo/seoRepositories"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+// The robots rules come from the first row of seo.
+const siteSeoId = "1"
+
+// Robots meta of a page marked noindex.
+const noindexRobot = "noindex, follow"
+
+var (
+	// ErrNotFound is returned for pages that do not exist or are not shown on
+	// the landing page.
+	ErrNotFound = errors.New("page not found")
+	ErrOgImage  = errors.New("og_image must be one of the images of the page")
 )
 
 type ISeoUsecase interface {
 	FindOneSeo(seoId string) (*seo.Seo, error)
 	UpdateSeo(req *seo.Seo) (*seo.Seo, error)
+	Sitemap() ([]byte, error)
+	Robots() ([]byte, error)
+	Version() (string, error)
+	FindPageMeta(page string, entityId int) (*seo.Meta, error)
+	UpdatePageMeta(req *seo.Meta) (*seo.Meta, error)
+	DeletePageMeta(page string, entityId int) error
+	ResolvePage(path string) (*seo.PageSeo, error)
 }
 
 type seoUsecase struct {
+	cfg           config.IConfig
 	seoRepository seoRepositories.ISeoRepository
+
+	// sitemap.xml and robots.txt, built again once the version changes
+	mu      sync.Mutex
+	version string
+	sitemap []byte
+	robots  []byte
 }
 
-func SeoUsecase(seoRepository seoRepositories.ISeoRepository) ISeoUsecase {
+func SeoUsecase(cfg config.IConfig, seoRepository seoRepositories.ISeoRepository) ISeoUsecase {
 	return &seoUsecase{
+		cfg:           cfg,
 		seoRepository: seoRepository,
 	}
 }
@@ -34,4 +70,262 @@ func (u *seoUsecase) UpdateSeo(req *seo.Seo) (*seo.Seo, error) {
 		return nil, err
 	}
 	return seo, nil
-}
\ No newline at end of file
+}
+
+func (u *seoUsecase) findPage(page string, entityId int) (*seo.Page, error) {
+	if seo.Pageables[page] == nil {
+		return nil, ErrNotFound
+	}
+	p, err := u.seoRepository.FindPage(page, entityId)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	return p, nil
+}
+
+func hasImage(page *seo.Page, url string) bool {
+	for _, image := range page.Images {
+		if image == url {
+			return true
+		}
+	}
+	return false
+}
+
+// FindPageMeta returns an empty meta when the page has none of its own yet.
+func (u *seoUsecase) FindPageMeta(page string, entityId int) (*seo.Meta, error) {
+	if _, err := u.findPage(page, entityId); err != nil {
+		return nil, err
+	}
+
+	meta, err := u.seoRepository.FindMeta(page, entityId)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &seo.Meta{
+			Page:     page,
+			EntityId: entityId,
+		}
+	}
+	return meta, nil
+}
+
+func (u *seoUsecase) UpdatePageMeta(req *seo.Meta) (*seo.Meta, error) {
+	page, err := u.findPage(req.Page, req.EntityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.OgImage != "" && !hasImage(page, req.OgImage) {
+		return nil, ErrOgImage
+	}
+
+	if err := u.seoRepository.UpsertMeta(req); err != nil {
+		return nil, err
+	}
+	return u.seoRepository.FindMeta(req.Page, req.EntityId)
+}
+
+func (u *seoUsecase) DeletePageMeta(page string, entityId int) error {
+	if seo.Pageables[page] == nil {
+		return ErrNotFound
+	}
+	return u.seoRepository.DeleteMeta(page, entityId)
+}
+
+// ResolvePage returns the meta tags of a landing page route, e.g.
+// /projects/baan-suan or /projects/12. The meta of the page wins, then the page
+// itself and then the global seo record. Routes without a page of their own get
+// the global record. The canonical url of a page always uses its current slug.
+func (u *seoUsecase) ResolvePage(path string) (*seo.PageSeo, error) {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	path = "/" + strings.Trim(path, "/")
+
+	global, err := u.seoRepository.FindOneSeo(siteSeoId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &seo.PageSeo{
+		Path:         path,
+		Title:        global.Title,
+		Description:  global.Description,
+		Keyword:      global.Keyword,
+		Robot:        global.Robot,
+		GoogleBot:    global.GoogleBot,
+		CanonicalUrl: u.cfg.App().SiteUrl() + path,
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 || seo.Pageables[parts[0]] == nil {
+		return res, nil
+	}
+	entityId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		entityId, _, err = u.seoRepository.FindPageSlug(parts[0], utils.Slugify(parts[1]))
+		if errors.Is(err, utils.ErrSlugNotFound) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := u.findPage(parts[0], entityId)
+	if err != nil {
+		return nil, err
+	}
+	if !page.Published {
+		return nil, ErrNotFound
+	}
+
+	if global.Title != "" {
+		res.Title = page.Name + " | " + global.Title
+	} else {
+		res.Title = page.Name
+	}
+	if len(page.Images) > 0 {
+		res.OgImage = page.Images[0]
+	}
+	res.CanonicalUrl = u.cfg.App().SiteUrl() + seo.PagePath(parts[0], page.Slug)
+
+	meta, err := u.seoRepository.FindMeta(parts[0], entityId)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return res, nil
+	}
+
+	if meta.Title != "" {
+		res.Title = meta.Title
+	}
+	if meta.Description != "" {
+		res.Description = meta.Description
+	}
+	// The chosen image may have been removed from the page since
+	if meta.OgImage != "" && hasImage(page, meta.OgImage) {
+		res.OgImage = meta.OgImage
+	}
+	if meta.CanonicalUrl != "" {
+		res.CanonicalUrl = meta.CanonicalUrl
+	}
+	if meta.Noindex {
+		res.Noindex = true
+		res.Robot = noindexRobot
+		res.GoogleBot = noindexRobot
+	}
+	return res, nil
+}
+
+func (u *seoUsecase) Sitemap() ([]byte, error) {
+	if err := u.refresh(); err != nil {
+		return nil, err
+	}
+	return u.sitemap, nil
+}
+
+func (u *seoUsecase) Robots() ([]byte, error) {
+	if err := u.refresh(); err != nil {
+		return nil, err
+	}
+	return u.robots, nil
+}
+
+// Version changes with the published content, so other caches of it can
+// share the key of the sitemap.
+func (u *seoUsecase) Version() (string, error) {
+	return u.seoRepository.SitemapVersion()
+}
+
+// refresh rebuilds both files when the content changed since they were built.
+func (u *seoUsecase) refresh() error {
+	version, err := u.seoRepository.SitemapVersion()
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if version == u.version && u.sitemap != nil {
+		return nil
+	}
+
+	sitemap, err := u.buildSitemap()
+	if err != nil {
+		return err
+	}
+	robots, err := u.buildRobots()
+	if err != nil {
+		return err
+	}
+	u.version, u.sitemap, u.robots = version, sitemap, robots
+	return nil
+}
+
+type sitemapUrlset struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+type sitemapUrl struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+func (u *seoUsecase) buildSitemap() ([]byte, error) {
+	entries, err := u.seoRepository.FindSitemapEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	site := u.cfg.App().SiteUrl()
+	urlset := &sitemapUrlset{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Urls:  []sitemapUrl{{Loc: site + "/"}},
+	}
+	for _, e := range entries {
+		urlset.Urls = append(urlset.Urls, sitemapUrl{
+			Loc:     site + seo.PagePath(e.Page, e.Slug),
+			Lastmod: e.Lastmod,
+		})
+	}
+
+	data, err := xml.MarshalIndent(urlset, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// buildRobots turns the robots meta values of the seo record into crawl rules,
+// "noindex" or "none" keeps the crawler out of the whole site.
+func (u *seoUsecase) buildRobots() ([]byte, error) {
+	record, err := u.seoRepository.FindOneSeo(siteSeoId)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := func(meta string) string {
+		meta = strings.ToLower(meta)
+		if strings.Contains(meta, "noindex") || strings.Contains(meta, "none") {
+			return "Disallow: /"
+		}
+		return "Allow: /"
+	}
+
+	var b strings.Builder
+	b.WriteString("User-agent: *\n" + rule(record.Robot) + "\n")
+	if strings.TrimSpace(record.GoogleBot) != "" {
+		b.WriteString("\nUser-agent: Googlebot\n" + rule(record.GoogleBot) + "\n")
+	}
+	b.WriteString("\nSitemap: " + u.cfg.App().SiteUrl() + "/sitemap.xml\n")
+	return []byte(b.String()), nil
+}
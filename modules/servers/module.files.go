@@ -3,6 +3,8 @@ package servers
 import (
 	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
 	"github.com/yporn/sirarom-backend/modules/files/filesHandlers"
+	"github.com/yporn/sirarom-backend/modules/media/mediaRepositories"
+	"github.com/yporn/sirarom-backend/modules/media/mediaUsecases"
 )
 
 type IFilesModule interface {
@@ -19,7 +21,8 @@ type filesModule struct {
 
 func (m *moduleFactory) FilesModule() IFilesModule {
 	usecase := filesUsecases.FilesUsecase(m.s.cfg)
-	handler := filesHandlers.FilesHandler(m.s.cfg, usecase)
+	mediaUsecase := mediaUsecases.MediaUsecase(mediaRepositories.MediaRepository(m.s.db, m.s.cfg), usecase)
+	handler := filesHandlers.FilesHandler(m.s.cfg, usecase, mediaUsecase)
 
 	return &filesModule{
 		moduleFactory: m,
@@ -3,6 +3,8 @@ package servers
 import (
 	"context"
 	"io/ioutil"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"google.golang.org/api/analyticsreporting/v4"
@@ -35,9 +37,15 @@ import (
 	"github.com/yporn/sirarom-backend/modules/jobs/jobsHandlers"
 	"github.com/yporn/sirarom-backend/modules/jobs/jobsRepositories"
 	"github.com/yporn/sirarom-backend/modules/jobs/jobsUsecases"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsHandlers"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsRepositories"
+	"github.com/yporn/sirarom-backend/modules/leads/leadsUsecases"
 	"github.com/yporn/sirarom-backend/modules/logos/logosHandlers"
 	"github.com/yporn/sirarom-backend/modules/logos/logosRepositories"
 	"github.com/yporn/sirarom-backend/modules/logos/logosUsecases"
+	"github.com/yporn/sirarom-backend/modules/media/mediaHandlers"
+	"github.com/yporn/sirarom-backend/modules/media/mediaRepositories"
+	"github.com/yporn/sirarom-backend/modules/media/mediaUsecases"
 	"github.com/yporn/sirarom-backend/modules/middlewares/middlewaresHandlers"
 	"github.com/yporn/sirarom-backend/modules/middlewares/middlewaresRepositories"
 	"github.com/yporn/sirarom-backend/modules/middlewares/middlewaresUsecases"
@@ -48,12 +56,30 @@ import (
 	"github.com/yporn/sirarom-backend/modules/promotions/promotionsHandlers"
 	"github.com/yporn/sirarom-backend/modules/promotions/promotionsRepositories"
 	"github.com/yporn/sirarom-backend/modules/promotions/promotionsUsecases"
+	"github.com/yporn/sirarom-backend/modules/revisions/revisionsRepositories"
+	"github.com/yporn/sirarom-backend/modules/revisions/revisionsUsecases"
+	"github.com/yporn/sirarom-backend/modules/scheduler"
+	"github.com/yporn/sirarom-backend/modules/scheduler/schedulerRepositories"
+	"github.com/yporn/sirarom-backend/modules/scheduler/schedulerUsecases"
 	"github.com/yporn/sirarom-backend/modules/seo/seoHandlers"
 	"github.com/yporn/sirarom-backend/modules/seo/seoRepositories"
 	"github.com/yporn/sirarom-backend/modules/seo/seoUsecases"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsHandlers"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsRepositories"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsUsecases"
+	"github.com/yporn/sirarom-backend/modules/structuredData/structuredDataHandlers"
+	"github.com/yporn/sirarom-backend/modules/structuredData/structuredDataUsecases"
+	"github.com/yporn/sirarom-backend/modules/trash/trashHandlers"
+	"github.com/yporn/sirarom-backend/modules/trash/trashRepositories"
+	"github.com/yporn/sirarom-backend/modules/trash/trashUsecases"
+	"github.com/yporn/sirarom-backend/modules/units/unitsHandlers"
+	"github.com/yporn/sirarom-backend/modules/units/unitsRepositories"
+	"github.com/yporn/sirarom-backend/modules/units/unitsUsecases"
 	"github.com/yporn/sirarom-backend/modules/users/usersHandlers"
 	"github.com/yporn/sirarom-backend/modules/users/usersRepositories"
 	"github.com/yporn/sirarom-backend/modules/users/usersUsecases"
+	"github.com/yporn/sirarom-backend/pkg/mailer"
+	"github.com/yporn/sirarom-backend/pkg/payments"
 )
 
 type IModuleFactory interface {
@@ -62,6 +88,7 @@ type IModuleFactory interface {
 	AppinfoModule()
 	JobModule()
 	FilesModule() IFilesModule
+	MediaModule()
 	GeneralModule()
 	InterestModule()
 	BannerModule()
@@ -73,12 +100,19 @@ type IModuleFactory interface {
 	ActivityLogModule()
 	SeoModule()
 	AnalyticModule()
+	SchedulerModule()
+	TrashModule()
+	LeadModule()
+	SiteVisitModule()
+	UnitModule()
+	StructuredDataModule()
 }
 
 type moduleFactory struct {
-	r   fiber.Router
-	s   *server
-	mid middlewaresHandlers.IMiddlewaresHandler
+	r     fiber.Router
+	s     *server
+	mid   middlewaresHandlers.IMiddlewaresHandler
+	tasks []scheduler.Task // periodic jobs registered for SchedulerModule
 }
 
 func InitModule(r fiber.Router, s *server, mid middlewaresHandlers.IMiddlewaresHandler) IModuleFactory {
@@ -105,20 +139,44 @@ func (m *moduleFactory) MonitorModule() {
 func (m *moduleFactory) UserModule() {
 	db := m.s.db.DB
 	repository := usersRepositories.UsersRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase())
-	usecase := usersUsecases.UsersUsecase(m.s.cfg, repository)
+	mail, err := mailer.NewMailer(m.s.cfg.Mail(), m.s.cfg.App().Env())
+	if err != nil {
+		log.Fatalf("load mailer failed: %v", err)
+	}
+	usecase := usersUsecases.UsersUsecase(m.s.cfg, repository, mail)
 	handler := usersHandlers.UsersHandler(m.s.cfg, usecase, m.FilesModule().Usecase(), db)
 
 	// route
 	router := m.r.Group("/users")
 
+	router.Get("/me/sessions", m.mid.JwtAuth(), handler.FindMySessions)
+	router.Delete("/me/sessions", m.mid.JwtAuth(), handler.DeleteMySessions)
+	router.Delete("/me/sessions/:session_id", m.mid.JwtAuth(), handler.DeleteMySession)
+	router.Get("/:user_id/sessions", m.mid.JwtAuth(), m.mid.Authorize(1), handler.FindUserSessions)
+	router.Delete("/:user_id/sessions", m.mid.JwtAuth(), m.mid.Authorize(1), handler.DeleteUserSessions)
+	router.Get("/me/2fa", m.mid.JwtAuth(), handler.FindTwoFactor)
+	router.Post("/me/2fa/enroll", m.mid.JwtAuth(), handler.EnrollTwoFactor)
+	router.Post("/me/2fa/activate", m.mid.JwtAuth(), handler.ActivateTwoFactor)
+	router.Post("/me/2fa/disable", m.mid.JwtAuth(), handler.DisableTwoFactor)
+	router.Post("/me/2fa/recovery-codes", m.mid.JwtAuth(), handler.RegenerateRecoveryCodes)
+	router.Delete("/:user_id/2fa", m.mid.JwtAuth(), m.mid.Authorize(1), handler.ResetTwoFactor)
+	router.Patch("/roles/:role_id/2fa", m.mid.JwtAuth(), m.mid.Authorize(1), handler.UpdateRoleTwoFactor)
+	router.Get("/login-locks", m.mid.JwtAuth(), m.mid.Authorize(1), handler.FindLoginLocks)
+	router.Delete("/login-locks/ip/:ip", m.mid.JwtAuth(), m.mid.Authorize(1), handler.UnlockIpLogin)
+	router.Delete("/:user_id/login-lock", m.mid.JwtAuth(), m.mid.Authorize(1), handler.UnlockUserLogin)
+
 	router.Get("/:user_id", handler.FindOneUser)
 	router.Get("/", handler.FindUser)
 	router.Post("/signup", m.mid.JwtAuth(), handler.SignUp)
 	router.Post("/signin", func(c *fiber.Ctx) error {
 		return handler.SignIn(c, db)
 	})
-	router.Post("/refresh", m.mid.JwtAuth(), handler.RefreshPassport)
+	router.Post("/refresh", handler.RefreshPassport)
 	router.Post("/signout", handler.SignOut)
+	router.Post("/password/forgot", m.mid.RateLimit(m.s.cfg.App().AuthRateLimit(), time.Minute), handler.ForgotPassword)
+	router.Post("/password/reset", m.mid.RateLimit(m.s.cfg.App().AuthRateLimit(), time.Minute), handler.ResetPassword)
+	router.Post("/signin/verify", m.mid.RateLimit(m.s.cfg.App().AuthRateLimit(), time.Minute), handler.VerifyTwoFactor)
+	router.Post("/signin/enroll", m.mid.RateLimit(m.s.cfg.App().AuthRateLimit(), time.Minute), handler.EnrollTwoFactorChallenge)
 	router.Patch("/update/:user_id", m.mid.JwtAuth(), m.mid.Authorize(1), handler.UpdateUser)
 	router.Delete("/:user_id", m.mid.JwtAuth(), m.mid.Authorize(1), handler.DeleteUser)
 	router.Get("/admin/secret", m.mid.JwtAuth(), m.mid.Authorize(1), handler.GenerateAdminToken)
@@ -137,16 +195,40 @@ func (m *moduleFactory) AppinfoModule() {
 func (m *moduleFactory) JobModule() {
 	db := m.s.db.DB
 	repository := jobsRepositories.JobsRepository(m.s.db, m.s.cfg)
-	usecase := jobsUsecases.JobsUsecase(repository)
+	usecase := jobsUsecases.JobsUsecase(repository, m.FilesModule().Usecase())
 	handler := jobsHandlers.JobsHandler(m.s.cfg, usecase, db)
 
 	router := m.r.Group("/jobs")
 
-	router.Get("/:job_id", handler.FindOneJob)
-	router.Get("/", handler.FindJob)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.FindAdminJob)
+	router.Get("/admin/:job_id", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.FindOneAdminJob)
+	router.Get("/:job_id", m.mid.PublicAuth(), handler.FindOneJob)
+	router.Get("/", m.mid.PublicAuth(), handler.FindJob)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.AddJob)
 	router.Patch("/update/:job_id", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.UpdateJob)
 	router.Delete("/:job_id", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.DeleteJob)
+	router.Post("/:job_id/apply", m.mid.PublicAuth(), m.mid.RateLimit(m.s.cfg.App().LeadRateLimit(), time.Minute), handler.ApplyJob)
+	router.Get("/admin/:job_id/applications", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.FindApplication)
+	router.Get("/admin/:job_id/applications/export", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.ExportApplication)
+	router.Get("/admin/applications/:application_id", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.FindOneApplication)
+	router.Get("/admin/applications/:application_id/resume", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.DownloadResume)
+	router.Patch("/admin/applications/:application_id/stage", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.UpdateApplicationStage)
+	router.Post("/admin/applications/:application_id/notes", m.mid.JwtAuth(), m.mid.Authorize(1, 6), handler.AddApplicationNote)
+}
+
+// MediaModule serves the media library to the content admins, roles 1 to 6.
+func (m *moduleFactory) MediaModule() {
+	db := m.s.db.DB
+	repository := mediaRepositories.MediaRepository(m.s.db, m.s.cfg)
+	usecase := mediaUsecases.MediaUsecase(repository, m.FilesModule().Usecase())
+	handler := mediaHandlers.MediaHandler(m.s.cfg, usecase, db)
+
+	router := m.r.Group("/media")
+
+	router.Get("/", m.mid.JwtAuth(), m.mid.Authorize(1, 2, 3, 4, 5, 6), handler.FindMedia)
+	router.Get("/sweep", m.mid.JwtAuth(), m.mid.Authorize(1), handler.SweepMedia)
+	router.Delete("/sweep", m.mid.JwtAuth(), m.mid.Authorize(1), handler.SweepMedia)
+	router.Get("/:media_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2, 3, 4, 5, 6), handler.FindOneMedia)
 }
 
 func (m *moduleFactory) GeneralModule() {
@@ -169,8 +251,11 @@ func (m *moduleFactory) InterestModule() {
 
 	router := m.r.Group("/interests")
 
-	router.Get("/:interest_id", handler.FindOneInterest)
-	router.Get("/", handler.FindInterest)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindAdminInterest)
+	router.Get("/admin/:interest_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindOneAdminInterest)
+	router.Get("/:interest_id", m.mid.PublicAuth(), handler.FindOneInterest)
+	router.Get("/", m.mid.PublicAuth(), handler.FindInterest)
+	router.Post("/calculate", m.mid.PublicAuth(), handler.CalculateMortgage)
 
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.AddInterest)
 	router.Patch("/update/:interest_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateInterest)
@@ -185,11 +270,14 @@ func (m *moduleFactory) BannerModule() {
 
 	router := m.r.Group("/banners")
 
-	router.Get("/:banner_id", handler.FindOneBanner)
-	router.Get("/", handler.FindBanner)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.FindAdminBanner)
+	router.Get("/admin/:banner_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.FindOneAdminBanner)
+	router.Get("/:banner_id", m.mid.PublicAuth(), handler.FindOneBanner)
+	router.Get("/", m.mid.PublicAuth(), handler.FindBanner)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.AddBanner)
 	router.Patch("/update/:banner_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdateBanner)
 	router.Delete("/:banner_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.DeleteBanner)
+	router.Put("/order", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdateBannerOrder)
 }
 
 func (m *moduleFactory) ActivityModule() {
@@ -200,43 +288,70 @@ func (m *moduleFactory) ActivityModule() {
 
 	router := m.r.Group("/activities")
 
-	router.Get("/:activity_id", handler.FindOneActivity)
-	router.Get("/", handler.FindActivity)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.FindAdminActivity)
+	router.Get("/admin/:activity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.FindOneAdminActivity)
+	router.Get("/slug/:slug", m.mid.PublicAuth(), handler.FindOneActivityBySlug)
+	router.Get("/:activity_id", m.mid.PublicAuth(), handler.FindOneActivity)
+	router.Get("/", m.mid.PublicAuth(), handler.FindActivity)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.AddActivity)
 	router.Patch("/update/:activity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.UpdateActivity)
 	router.Delete("/:activity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.DeleteActivity)
+	router.Put("/order", m.mid.JwtAuth(), m.mid.Authorize(1, 5), handler.UpdateActivityOrder)
 }
 
 func (m *moduleFactory) ProjectModule() {
 	db := m.s.db.DB
 	repository := projectsRepositories.ProjectsRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase())
-	usecase := projectsUsecases.ProjectsUsecase(repository)
+	revisionsUsecase := revisionsUsecases.RevisionsUsecase(revisionsRepositories.RevisionsRepository(m.s.db))
+	usecase := projectsUsecases.ProjectsUsecase(repository, revisionsUsecase)
 	handler := projectsHandlers.ProjectsHandler(m.s.cfg, usecase, m.FilesModule().Usecase(), db)
 
 	router := m.r.Group("/projects")
 
-	router.Get("/:project_id", handler.FindOneProject)
-	router.Get("/", handler.FindProject)
-	router.Get("/:project_id/house_models", handler.FindProjectHouseModel)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindAdminProject)
+	router.Get("/admin/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindOneAdminProject)
+	router.Get("/slug/:slug", m.mid.PublicAuth(), handler.FindOneProjectBySlug)
+	router.Get("/:project_id", m.mid.PublicAuth(), handler.FindOneProject)
+	router.Get("/", m.mid.PublicAuth(), handler.FindProject)
+	router.Get("/:project_id/house_models", m.mid.PublicAuth(), handler.FindProjectHouseModel)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.AddProject)
 	router.Patch("/update/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateProject)
 	router.Delete("/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DeleteProject)
+	router.Put("/order", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateProjectOrder)
+	router.Get("/:project_id/revisions", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindProjectRevisions)
+	router.Get("/:project_id/revisions/diff", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DiffProjectRevisions)
+	router.Get("/:project_id/revisions/:revision_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindOneProjectRevision)
+	router.Post("/:project_id/revisions/:revision_id/restore", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.RestoreProjectRevision)
+	router.Get("/:project_id/phases", m.mid.PublicAuth(), handler.FindProjectPhases)
+	router.Post("/:project_id/phases/create", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.AddProjectPhase)
+	router.Patch("/:project_id/phases/update/:phase_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateProjectPhase)
+	router.Delete("/:project_id/phases/:phase_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DeleteProjectPhase)
 }
 
 func (m *moduleFactory) HouseModelModule() {
 	db := m.s.db.DB
 	repository := houseModelsRepositories.HouseModelsRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase())
-	usecase := houseModelsUsecases.HouseModelsUsecases(repository)
+	revisionsUsecase := revisionsUsecases.RevisionsUsecase(revisionsRepositories.RevisionsRepository(m.s.db))
+	usecase := houseModelsUsecases.HouseModelsUsecases(repository, revisionsUsecase)
 	handler := houseModelsHandlers.HouseModelsHandler(m.s.cfg, usecase, m.FilesModule().Usecase(), db)
 
 	router := m.r.Group("/house_models")
 
-	router.Get("/all", handler.FindAllHouseModel)
-	router.Get("/:house_model_id", handler.FindOneHouseModel)
-	router.Get("/projects/:project_id", handler.FindHouseModel)
+	router.Get("/admin/all", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindAllAdminHouseModel)
+	router.Get("/admin/projects/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindAdminHouseModel)
+	router.Get("/admin/:house_model_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindOneAdminHouseModel)
+	router.Get("/all", m.mid.PublicAuth(), handler.FindAllHouseModel)
+	router.Get("/slug/:slug", m.mid.PublicAuth(), handler.FindOneHouseModelBySlug)
+	router.Get("/:house_model_id", m.mid.PublicAuth(), handler.FindOneHouseModel)
+	router.Get("/projects/:project_id", m.mid.PublicAuth(), handler.FindHouseModel)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.AddHouseModel)
 	router.Patch("/update/:house_model_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateHouseModel)
 	router.Delete("/:house_model_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DeleteHouseModel)
+	router.Put("/projects/:project_id/order", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateHouseModelOrder)
+	router.Get("/:house_model_id/revisions", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindHouseModelRevisions)
+	router.Get("/:house_model_id/revisions/diff", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DiffHouseModelRevisions)
+	router.Get("/:house_model_id/revisions/:revision_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.FindOneHouseModelRevision)
+	router.Post("/:house_model_id/revisions/:revision_id/restore", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.RestoreHouseModelRevision)
 }
 
 func (m *moduleFactory) PromotionModule() {
@@ -247,11 +362,15 @@ func (m *moduleFactory) PromotionModule() {
 
 	router := m.r.Group("/promotions")
 
-	router.Get("/", handler.FindPromotion)
-	router.Get("/:promotion_id", handler.FindOnePromotion)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.FindAdminPromotion)
+	router.Get("/admin/:promotion_id", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.FindOneAdminPromotion)
+	router.Get("/", m.mid.PublicAuth(), handler.FindPromotion)
+	router.Get("/slug/:slug", m.mid.PublicAuth(), handler.FindOnePromotionBySlug)
+	router.Get("/:promotion_id", m.mid.PublicAuth(), handler.FindOnePromotion)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.AddPromotion)
 	router.Patch("/update/:promotion_id", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.UpdatePromotion)
 	router.Delete("/:promotion_id", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.DeletePromotion)
+	router.Put("/order", m.mid.JwtAuth(), m.mid.Authorize(1, 4), handler.UpdatePromotionOrder)
 }
 
 func (m *moduleFactory) LogoModule() {
@@ -262,11 +381,14 @@ func (m *moduleFactory) LogoModule() {
 
 	router := m.r.Group("/brands")
 
-	router.Get("/", handler.FindLogo)
-	router.Get("/:brand_id", handler.FindOneLogo)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.FindAdminLogo)
+	router.Get("/admin/:brand_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.FindOneAdminLogo)
+	router.Get("/", m.mid.PublicAuth(), handler.FindLogo)
+	router.Get("/:brand_id", m.mid.PublicAuth(), handler.FindOneLogo)
 	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.AddLogo)
 	router.Patch("/update/:brand_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdateLogo)
 	router.Delete("/:brand_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.DeleteLogo)
+	router.Put("/order", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdateLogoOrder)
 }
 
 func (m *moduleFactory) ActivityLogModule() {
@@ -281,16 +403,149 @@ func (m *moduleFactory) ActivityLogModule() {
 
 }
 
+// SchedulerModule runs the periodic jobs, including the tasks registered by the
+// modules set up before it, on one replica at a time.
+func (m *moduleFactory) SchedulerModule() {
+	repository := schedulerRepositories.SchedulerRepository(m.s.db)
+	usecase := schedulerUsecases.SchedulerUsecase(m.s.cfg, repository, m.s.db.DB, m.tasks...)
+
+	go usecase.Start(context.Background(), time.Minute)
+}
+
+func (m *moduleFactory) TrashModule() {
+	db := m.s.db.DB
+	repository := trashRepositories.TrashRepository(m.s.db)
+	usecase := trashUsecases.TrashUsecase(repository, m.FilesModule().Usecase())
+	handler := trashHandlers.TrashHandler(m.s.cfg, usecase, db)
+
+	router := m.r.Group("/trash")
+
+	router.Get("/", m.mid.JwtAuth(), m.mid.Authorize(1), handler.FindTrash)
+	router.Post("/:type/:id/restore", m.mid.JwtAuth(), m.mid.Authorize(1), handler.RestoreTrash)
+	router.Delete("/:type/:id", m.mid.JwtAuth(), m.mid.Authorize(1), handler.PurgeTrash)
+}
+
+// LeadModule serves the public enquiry form and the sales pipeline. Role 7 is sales.
+func (m *moduleFactory) LeadModule() {
+	db := m.s.db.DB
+	repository := leadsRepositories.LeadsRepository(m.s.db)
+	usecase := leadsUsecases.LeadsUsecase(m.s.cfg, repository, db)
+	handler := leadsHandlers.LeadsHandler(m.s.cfg, usecase, db)
+
+	m.tasks = append(m.tasks, usecase.RunSlaAlerts)
+
+	router := m.r.Group("/leads")
+
+	router.Post("/", m.mid.PublicAuth(), m.mid.RateLimit(m.s.cfg.App().LeadRateLimit(), time.Minute), handler.InsertLead)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.FindLead)
+	router.Get("/admin/tasks", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.FindLeadTasks)
+	router.Get("/admin/sales/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.FindProjectSales)
+	router.Put("/admin/sales/:project_id", m.mid.JwtAuth(), m.mid.Authorize(1), handler.UpdateProjectSales)
+	router.Get("/admin/:lead_id", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.FindOneLead)
+	router.Patch("/admin/:lead_id/status", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.UpdateLeadStatus)
+	router.Patch("/admin/:lead_id/assign", m.mid.JwtAuth(), m.mid.Authorize(1), handler.AssignLead)
+	router.Post("/admin/:lead_id/notes", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.AddLeadNote)
+	router.Post("/admin/:lead_id/tasks", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.AddLeadTask)
+	router.Patch("/admin/:lead_id/tasks/:task_id/done", m.mid.JwtAuth(), m.mid.Authorize(1, 7), handler.DoneLeadTask)
+}
+
+func (m *moduleFactory) SiteVisitModule() {
+	db := m.s.db.DB
+	repository := siteVisitsRepositories.SiteVisitsRepository(m.s.db)
+	usecase := siteVisitsUsecases.SiteVisitsUsecase(m.s.cfg, repository)
+	handler := siteVisitsHandlers.SiteVisitsHandler(m.s.cfg, usecase, db)
+
+	router := m.r.Group("/site_visits")
+
+	router.Get("/projects/:project_id/slots", m.mid.PublicAuth(), handler.FindSlots)
+	router.Post("/projects/:project_id/book", m.mid.PublicAuth(), m.mid.RateLimit(m.s.cfg.App().LeadRateLimit(), time.Minute), handler.BookSiteVisit)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindSiteVisit)
+	router.Get("/admin/export.ics", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.ExportIcs)
+	router.Patch("/admin/:visit_id/cancel", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.CancelSiteVisit)
+	router.Get("/admin/projects/:project_id/hours", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindVisitHours)
+	router.Put("/admin/projects/:project_id/hours", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateVisitHours)
+	router.Get("/admin/projects/:project_id/blackouts", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindBlackouts)
+	router.Post("/admin/projects/:project_id/blackouts", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.InsertBlackout)
+	router.Delete("/admin/projects/:project_id/blackouts/:blackout_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DeleteBlackout)
+}
+
+func (m *moduleFactory) UnitModule() {
+	db := m.s.db.DB
+	repository := unitsRepositories.UnitsRepository(m.s.db)
+	gateway, err := payments.NewGateway(m.s.cfg.App().PaymentGateway(), m.s.cfg.App().PaymentSecret(), m.s.cfg.App().Env())
+	if err != nil {
+		log.Fatalf("load payment gateway failed: %v", err)
+	}
+	usecase := unitsUsecases.UnitsUsecase(m.s.cfg, repository, gateway, db)
+	handler := unitsHandlers.UnitsHandler(m.s.cfg, usecase, db)
+
+	m.tasks = append(m.tasks, usecase.RunReleaseExpired)
+
+	router := m.r.Group("/projects/:project_id/units")
+
+	router.Get("/", m.mid.PublicAuth(), handler.FindUnit)
+	router.Get("/availability", m.mid.PublicAuth(), handler.FindAvailability)
+	router.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindAdminUnit)
+	router.Get("/admin/:unit_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindOneUnit)
+	router.Post("/create", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.AddUnit)
+	router.Post("/import", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.ImportUnits)
+	router.Patch("/update/:unit_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.UpdateUnit)
+	router.Delete("/:unit_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3), handler.DeleteUnit)
+
+	reservations := m.r.Group("/reservations")
+
+	// Online reservations only exist with a payment gateway configured
+	if gateway != nil {
+		router.Post("/:unit_id/reserve", m.mid.PublicAuth(), m.mid.RateLimit(m.s.cfg.App().LeadRateLimit(), time.Minute), handler.ReserveUnit)
+		// called by the payment gateway, authenticated by the callback signature
+		reservations.Post("/payments/callback", handler.PaymentCallback)
+	} else {
+		log.Println("units: no APP_PAYMENT_GATEWAY set, online reservations are off")
+	}
+	reservations.Get("/admin", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindReservation)
+	reservations.Get("/admin/:reservation_id", m.mid.JwtAuth(), m.mid.Authorize(1, 3, 7), handler.FindOneReservation)
+}
+
 func (m *moduleFactory) SeoModule() {
 	db := m.s.db.DB
 	repository := seoRepositories.SeoRepository(m.s.db, m.s.cfg)
-	usecase := seoUsecases.SeoUsecase(repository)
+	usecase := seoUsecases.SeoUsecase(m.s.cfg, repository)
 	handler := seoHandlers.SeoHandler(m.s.cfg, usecase, db)
 
 	router := m.r.Group("/seo")
 
-	router.Get("/:seo_id", m.mid.JwtAuth(), handler.FindOneSeo)
+	router.Get("/resolve", m.mid.PublicAuth(), handler.ResolvePage)
+	router.Get("/pages/:page/:entity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.FindPageSeo)
+	router.Put("/pages/:page/:entity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdatePageSeo)
+	router.Delete("/pages/:page/:entity_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.DeletePageSeo)
+
+	router.Get("/:seo_id", m.mid.PublicAuth(), handler.FindOneSeo)
 	router.Patch("/update/:seo_id", m.mid.JwtAuth(), m.mid.Authorize(1, 2), handler.UpdateSeo)
+
+	m.r.Get("/sitemap.xml", handler.Sitemap)
+	m.r.Get("/robots.txt", handler.Robots)
+}
+
+// StructuredDataModule renders schema.org JSON-LD of the landing page content
+// for the front end to embed.
+func (m *moduleFactory) StructuredDataModule() {
+	revisionsUsecase := revisionsUsecases.RevisionsUsecase(revisionsRepositories.RevisionsRepository(m.s.db))
+	usecase := structuredDataUsecases.StructuredDataUsecase(
+		m.s.cfg,
+		jobsUsecases.JobsUsecase(jobsRepositories.JobsRepository(m.s.db, m.s.cfg), m.FilesModule().Usecase()),
+		projectsUsecases.ProjectsUsecase(projectsRepositories.ProjectsRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase()), revisionsUsecase),
+		promotionsUsecases.PromotionsUsecase(promotionsRepositories.PromotionsRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase())),
+		generalUsecases.GeneralUsecase(generalRepositories.GeneralRepository(m.s.db, m.s.cfg, m.FilesModule().Usecase())),
+		seoUsecases.SeoUsecase(m.s.cfg, seoRepositories.SeoRepository(m.s.db, m.s.cfg)),
+	)
+	handler := structuredDataHandlers.StructuredDataHandler(m.s.cfg, usecase)
+
+	router := m.r.Group("/structured_data")
+
+	router.Get("/", m.mid.PublicAuth(), handler.Feed)
+	router.Get("/jobs/:job_id", m.mid.PublicAuth(), handler.JobPosting)
+	router.Get("/projects/:project_id", m.mid.PublicAuth(), handler.Residence)
+	router.Get("/promotions/:promotion_id", m.mid.PublicAuth(), handler.Offer)
 }
 
 
@@ -32,6 +32,12 @@ func NewServer(cfg config.IConfig, db *sqlx.DB) IServer {
 			WriteTimeout: cfg.App().WriteTimeout(),
 			JSONEncoder:  json.Marshal,
 			JSONDecoder:  json.Unmarshal,
+			// c.IP() is the client behind the load balancer, for rate limits,
+			// sign in throttles and the ip kept with leads and reservations
+			ProxyHeader:             cfg.App().ProxyHeader(),
+			EnableTrustedProxyCheck: cfg.App().ProxyHeader() != "",
+			TrustedProxies:          cfg.App().TrustedProxies(),
+			EnableIPValidation:      true,
 		}),
 	}
 }
@@ -51,6 +57,7 @@ func (s *server) Start() {
 	modules.AppinfoModule()
 	modules.JobModule()
 	modules.FilesModule().Init()
+	modules.MediaModule()
 	modules.GeneralModule()
 	modules.InterestModule()
 	modules.BannerModule()
@@ -62,6 +69,12 @@ func (s *server) Start() {
 	modules.ActivityLogModule()
 	modules.AnalyticModule()
 	modules.SeoModule()
+	modules.TrashModule()
+	modules.LeadModule()
+	modules.SiteVisitModule()
+	modules.UnitModule()
+	modules.StructuredDataModule()
+	modules.SchedulerModule()
 	
 	s.app.Use(middlewares.RouterCheck())
 	//Graceful Shutdown
@@ -0,0 +1,135 @@
+package siteVisits
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
+
+const (
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+)
+
+// VisitHour is one weekly opening window of a project's show home, cut into
+// slots of SlotMinutes. Weekday 0 is Sunday.
+type VisitHour struct {
+	Id          int    `db:"id" json:"id"`
+	ProjectId   int    `db:"project_id" json:"project_id"`
+	Weekday     int    `db:"weekday" json:"weekday"`
+	StartTime   string `db:"start_time" json:"start_time"`
+	EndTime     string `db:"end_time" json:"end_time"`
+	SlotMinutes int    `db:"slot_minutes" json:"slot_minutes"`
+}
+
+func (h *VisitHour) Validate() error {
+	if h.Weekday < 0 || h.Weekday > 6 {
+		return fmt.Errorf("weekday must be 0 (sunday) to 6 (saturday)")
+	}
+	start, err := time.Parse(TimeLayout, h.StartTime)
+	if err != nil {
+		return fmt.Errorf("start_time must be HH:MM")
+	}
+	end, err := time.Parse(TimeLayout, h.EndTime)
+	if err != nil {
+		return fmt.Errorf("end_time must be HH:MM")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	if h.SlotMinutes == 0 {
+		h.SlotMinutes = 60
+	}
+	if h.SlotMinutes < 0 || time.Duration(h.SlotMinutes)*time.Minute > end.Sub(start) {
+		return fmt.Errorf("slot_minutes must fit between start_time and end_time")
+	}
+	return nil
+}
+
+type VisitHoursReq struct {
+	Hours []*VisitHour `json:"hours"`
+}
+
+type Blackout struct {
+	Id        int    `db:"id" json:"id"`
+	ProjectId int    `db:"project_id" json:"project_id"`
+	Date      string `db:"date" json:"date"`
+	Reason    string `db:"reason" json:"reason"`
+}
+
+func (b *Blackout) Validate() error {
+	if _, err := time.Parse(DateLayout, b.Date); err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	return nil
+}
+
+type Slot struct {
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// SlotFilter is the date range of the free slots, both ends included.
+type SlotFilter struct {
+	From string `query:"from"`
+	To   string `query:"to"`
+}
+
+type SiteVisit struct {
+	Id          int    `db:"id" json:"id"`
+	ProjectId   int    `db:"project_id" json:"project_id"`
+	ProjectName string `db:"project_name" json:"project_name"`
+	VisitDate   string `db:"visit_date" json:"visit_date"`
+	StartTime   string `db:"start_time" json:"start_time"`
+	EndTime     string `db:"end_time" json:"end_time"`
+	Name        string `db:"name" json:"name"`
+	Tel         string `db:"tel" json:"tel"`
+	Email       string `db:"email" json:"email"`
+	Note        string `db:"note" json:"note"`
+	Status      string `db:"status" json:"status"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+	UpdatedAt   string `db:"updated_at" json:"updated_at"`
+}
+
+type SiteVisitReq struct {
+	ProjectId int    `json:"-"`
+	Date      string `json:"date" form:"date"`
+	StartTime string `json:"start_time" form:"start_time"`
+	Name      string `json:"name" form:"name"`
+	Tel       string `json:"tel" form:"tel"`
+	Email     string `json:"email" form:"email"`
+	Note      string `json:"note" form:"note"`
+}
+
+func (r *SiteVisitReq) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Tel = strings.TrimSpace(r.Tel)
+	r.Email = strings.TrimSpace(r.Email)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Tel == "" && r.Email == "" {
+		return fmt.Errorf("tel or email is required")
+	}
+	if _, err := time.Parse(DateLayout, r.Date); err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	if _, err := time.Parse(TimeLayout, r.StartTime); err != nil {
+		return fmt.Errorf("start_time must be HH:MM")
+	}
+	return nil
+}
+
+type SiteVisitFilter struct {
+	ProjectId int    `query:"project_id"`
+	Status    string `query:"status"`
+	From      string `query:"from"` // visit_date range, YYYY-MM-DD
+	To        string `query:"to"`
+	*entities.PaginationReq
+}
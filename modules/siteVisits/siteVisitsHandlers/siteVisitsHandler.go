@@ -0,0 +1,451 @@
+package siteVisitsHandlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/siteVisits"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsRepositories"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type siteVisitsHandlersErrCode string
+
+const (
+	findSlotsErr        siteVisitsHandlersErrCode = "site-visits-001"
+	bookSiteVisitErr    siteVisitsHandlersErrCode = "site-visits-002"
+	findSiteVisitErr    siteVisitsHandlersErrCode = "site-visits-003"
+	cancelSiteVisitErr  siteVisitsHandlersErrCode = "site-visits-004"
+	exportIcsErr        siteVisitsHandlersErrCode = "site-visits-005"
+	findVisitHoursErr   siteVisitsHandlersErrCode = "site-visits-006"
+	updateVisitHoursErr siteVisitsHandlersErrCode = "site-visits-007"
+	findBlackoutsErr    siteVisitsHandlersErrCode = "site-visits-008"
+	insertBlackoutErr   siteVisitsHandlersErrCode = "site-visits-009"
+	deleteBlackoutErr   siteVisitsHandlersErrCode = "site-visits-010"
+)
+
+type ISiteVisitsHandler interface {
+	FindSlots(c *fiber.Ctx) error
+	BookSiteVisit(c *fiber.Ctx) error
+	FindSiteVisit(c *fiber.Ctx) error
+	CancelSiteVisit(c *fiber.Ctx) error
+	ExportIcs(c *fiber.Ctx) error
+	FindVisitHours(c *fiber.Ctx) error
+	UpdateVisitHours(c *fiber.Ctx) error
+	FindBlackouts(c *fiber.Ctx) error
+	InsertBlackout(c *fiber.Ctx) error
+	DeleteBlackout(c *fiber.Ctx) error
+}
+
+type siteVisitsHandler struct {
+	cfg               config.IConfig
+	siteVisitsUsecase siteVisitsUsecases.ISiteVisitsUsecase
+	db                *sql.DB
+}
+
+func SiteVisitsHandler(cfg config.IConfig, siteVisitsUsecase siteVisitsUsecases.ISiteVisitsUsecase, db *sql.DB) ISiteVisitsHandler {
+	return &siteVisitsHandler{
+		cfg:               cfg,
+		siteVisitsUsecase: siteVisitsUsecase,
+		db:                db,
+	}
+}
+
+func (h *siteVisitsHandler) FindSlots(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findSlotsErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(siteVisits.SlotFilter)
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findSlotsErr),
+			err.Error(),
+		).Res()
+	}
+
+	slots, err := h.siteVisitsUsecase.FindSlots(projectId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findSlotsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, slots).Res()
+}
+
+func (h *siteVisitsHandler) BookSiteVisit(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bookSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(siteVisits.SiteVisitReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bookSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bookSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	visit, err := h.siteVisitsUsecase.BookSiteVisit(req)
+	if err != nil {
+		if errors.Is(err, siteVisitsRepositories.ErrSlotUnavailable) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(bookSiteVisitErr),
+				err.Error(),
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bookSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogSystemActivity(h.db, "created", "จองเยี่ยมชมโครงการ "+visit.ProjectName+" วันที่ "+visit.VisitDate+" "+visit.StartTime+" : "+visit.Name)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			"Failed to log activity",
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, visit).Res()
+}
+
+func (h *siteVisitsHandler) FindSiteVisit(c *fiber.Ctx) error {
+	req := &siteVisits.SiteVisitFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+	if err := validateSiteVisitFilter(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.siteVisitsUsecase.FindSiteVisit(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+func validateSiteVisitFilter(req *siteVisits.SiteVisitFilter) error {
+	switch req.Status {
+	case "", siteVisits.StatusConfirmed, siteVisits.StatusCancelled:
+	default:
+		return fmt.Errorf("status is invalid: %s", req.Status)
+	}
+	for field, value := range map[string]string{"from": req.From, "to": req.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(siteVisits.DateLayout, value); err != nil {
+			return fmt.Errorf("%s must be YYYY-MM-DD", field)
+		}
+	}
+	return nil
+}
+
+func (h *siteVisitsHandler) CancelSiteVisit(c *fiber.Ctx) error {
+	visitId, err := strconv.Atoi(strings.Trim(c.Params("visit_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(cancelSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	visit, err := h.siteVisitsUsecase.CancelSiteVisit(visitId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(cancelSiteVisitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "ยกเลิกการจองเยี่ยมชมโครงการ "+visit.ProjectName+" วันที่ "+visit.VisitDate+" "+visit.StartTime+" : "+visit.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, visit).Res()
+}
+
+// ExportIcs downloads the confirmed bookings matching the list filters as a .ics file.
+func (h *siteVisitsHandler) ExportIcs(c *fiber.Ctx) error {
+	req := &siteVisits.SiteVisitFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(exportIcsErr),
+			err.Error(),
+		).Res()
+	}
+	if err := validateSiteVisitFilter(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(exportIcsErr),
+			err.Error(),
+		).Res()
+	}
+
+	ics, err := h.siteVisitsUsecase.ExportIcs(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(exportIcsErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="site-visits.ics"`)
+	return c.Status(fiber.StatusOK).Send(ics)
+}
+
+func (h *siteVisitsHandler) FindVisitHours(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findVisitHoursErr),
+			err.Error(),
+		).Res()
+	}
+
+	hours, err := h.siteVisitsUsecase.FindVisitHours(projectId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findVisitHoursErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, hours).Res()
+}
+
+func (h *siteVisitsHandler) UpdateVisitHours(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVisitHoursErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(siteVisits.VisitHoursReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVisitHoursErr),
+			err.Error(),
+		).Res()
+	}
+	for _, hour := range req.Hours {
+		if err := hour.Validate(); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateVisitHoursErr),
+				err.Error(),
+			).Res()
+		}
+	}
+
+	hours, err := h.siteVisitsUsecase.UpdateVisitHours(projectId, req.Hours)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateVisitHoursErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขเวลาเปิดให้เยี่ยมชมโครงการ : "+strconv.Itoa(projectId))
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, hours).Res()
+}
+
+func (h *siteVisitsHandler) FindBlackouts(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findBlackoutsErr),
+			err.Error(),
+		).Res()
+	}
+
+	blackouts, err := h.siteVisitsUsecase.FindBlackouts(projectId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findBlackoutsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, blackouts).Res()
+}
+
+func (h *siteVisitsHandler) InsertBlackout(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(siteVisits.Blackout)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.siteVisitsUsecase.InsertBlackout(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(insertBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "ปิดรับเยี่ยมชมโครงการ "+strconv.Itoa(projectId)+" วันที่ : "+req.Date)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, req).Res()
+}
+
+func (h *siteVisitsHandler) DeleteBlackout(c *fiber.Ctx) error {
+	projectId, err := strconv.Atoi(strings.Trim(c.Params("project_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+	blackoutId, err := strconv.Atoi(strings.Trim(c.Params("blackout_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.siteVisitsUsecase.DeleteBlackout(projectId, blackoutId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteBlackoutErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ยกเลิกวันปิดรับเยี่ยมชมโครงการ : "+strconv.Itoa(projectId))
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
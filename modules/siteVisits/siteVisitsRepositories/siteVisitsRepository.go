@@ -0,0 +1,352 @@
+package siteVisitsRepositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/siteVisits"
+)
+
+// ErrSlotUnavailable is returned for a slot that is not offered or already booked.
+var ErrSlotUnavailable = errors.New("this slot is not available, please pick another one")
+
+type ISiteVisitsRepository interface {
+	FindProjectName(projectId int) (string, error)
+	FindVisitHours(projectId int) ([]*siteVisits.VisitHour, error)
+	UpdateVisitHours(projectId int, hours []*siteVisits.VisitHour) error
+	FindBlackouts(projectId int, from, to string) ([]*siteVisits.Blackout, error)
+	InsertBlackout(req *siteVisits.Blackout) error
+	DeleteBlackout(projectId, blackoutId int) error
+	FindBookedSlots(projectId int, from, to string) (map[string]bool, error)
+	InsertSiteVisit(req *siteVisits.SiteVisitReq, endTime string) (int, error)
+	FindOneSiteVisit(visitId int) (*siteVisits.SiteVisit, error)
+	FindSiteVisit(req *siteVisits.SiteVisitFilter) ([]*siteVisits.SiteVisit, int)
+	FindConfirmedSiteVisits(req *siteVisits.SiteVisitFilter) ([]*siteVisits.SiteVisit, error)
+	CancelSiteVisit(visitId int) error
+}
+
+type siteVisitsRepository struct {
+	db *sqlx.DB
+}
+
+func SiteVisitsRepository(db *sqlx.DB) ISiteVisitsRepository {
+	return &siteVisitsRepository{
+		db: db,
+	}
+}
+
+// BookedKey identifies a booked slot in the map of FindBookedSlots.
+func BookedKey(date, startTime string) string {
+	return date + " " + startTime
+}
+
+func (r *siteVisitsRepository) FindProjectName(projectId int) (string, error) {
+	var name sql.NullString
+	if err := r.db.Get(&name, `SELECT "name" FROM "projects" WHERE "id" = $1 AND "deleted_at" IS NULL;`, projectId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("project not found")
+		}
+		return "", fmt.Errorf("get project failed: %v", err)
+	}
+	return name.String, nil
+}
+
+func (r *siteVisitsRepository) FindVisitHours(projectId int) ([]*siteVisits.VisitHour, error) {
+	query := `
+	SELECT
+		"id",
+		"project_id",
+		"weekday",
+		to_char("start_time", 'HH24:MI') AS "start_time",
+		to_char("end_time", 'HH24:MI') AS "end_time",
+		"slot_minutes"
+	FROM "project_visit_hours"
+	WHERE "project_id" = $1
+	ORDER BY "weekday" ASC, "start_time" ASC;`
+
+	hours := make([]*siteVisits.VisitHour, 0)
+	if err := r.db.Select(&hours, query, projectId); err != nil {
+		return nil, fmt.Errorf("find visit hours failed: %v", err)
+	}
+	return hours, nil
+}
+
+// UpdateVisitHours replaces the weekly opening hours of a project.
+func (r *siteVisitsRepository) UpdateVisitHours(projectId int, hours []*siteVisits.VisitHour) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "project_visit_hours" WHERE "project_id" = $1;`, projectId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete visit hours failed: %v", err)
+	}
+
+	query := `
+	INSERT INTO "project_visit_hours" (
+		"project_id",
+		"weekday",
+		"start_time",
+		"end_time",
+		"slot_minutes"
+	)
+	VALUES ($1, $2, $3, $4, $5);`
+
+	for _, h := range hours {
+		if _, err := tx.ExecContext(ctx, query, projectId, h.Weekday, h.StartTime, h.EndTime, h.SlotMinutes); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert visit hours failed: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *siteVisitsRepository) FindBlackouts(projectId int, from, to string) ([]*siteVisits.Blackout, error) {
+	query := `
+	SELECT
+		"id",
+		"project_id",
+		to_char("date", 'YYYY-MM-DD') AS "date",
+		COALESCE("reason", '') AS "reason"
+	FROM "project_visit_blackouts"
+	WHERE "project_id" = $1
+	AND "date" BETWEEN $2 AND $3
+	ORDER BY "date" ASC;`
+
+	blackouts := make([]*siteVisits.Blackout, 0)
+	if err := r.db.Select(&blackouts, query, projectId, from, to); err != nil {
+		return nil, fmt.Errorf("find blackouts failed: %v", err)
+	}
+	return blackouts, nil
+}
+
+func (r *siteVisitsRepository) InsertBlackout(req *siteVisits.Blackout) error {
+	query := `
+	INSERT INTO "project_visit_blackouts" (
+		"project_id",
+		"date",
+		"reason"
+	)
+	VALUES ($1, $2, $3)
+	ON CONFLICT ("project_id", "date") DO UPDATE SET
+		"reason" = EXCLUDED."reason"
+	RETURNING "id";`
+
+	if err := r.db.QueryRowContext(context.Background(), query, req.ProjectId, req.Date, req.Reason).Scan(&req.Id); err != nil {
+		return fmt.Errorf("insert blackout failed: %v", err)
+	}
+	return nil
+}
+
+func (r *siteVisitsRepository) DeleteBlackout(projectId, blackoutId int) error {
+	query := `DELETE FROM "project_visit_blackouts" WHERE "id" = $1 AND "project_id" = $2;`
+
+	res, err := r.db.ExecContext(context.Background(), query, blackoutId, projectId)
+	if err != nil {
+		return fmt.Errorf("delete blackout failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("blackout %d not found", blackoutId)
+	}
+	return nil
+}
+
+func (r *siteVisitsRepository) FindBookedSlots(projectId int, from, to string) (map[string]bool, error) {
+	query := `
+	SELECT
+		to_char("visit_date", 'YYYY-MM-DD') AS "visit_date",
+		to_char("start_time", 'HH24:MI') AS "start_time"
+	FROM "site_visits"
+	WHERE "project_id" = $1
+	AND "status" = 'confirmed'
+	AND "visit_date" BETWEEN $2 AND $3;`
+
+	rows := make([]*siteVisits.SiteVisit, 0)
+	if err := r.db.Select(&rows, query, projectId, from, to); err != nil {
+		return nil, fmt.Errorf("find booked slots failed: %v", err)
+	}
+
+	booked := make(map[string]bool, len(rows))
+	for _, v := range rows {
+		booked[BookedKey(v.VisitDate, v.StartTime)] = true
+	}
+	return booked, nil
+}
+
+func (r *siteVisitsRepository) InsertSiteVisit(req *siteVisits.SiteVisitReq, endTime string) (int, error) {
+	query := `
+	INSERT INTO "site_visits" (
+		"project_id",
+		"visit_date",
+		"start_time",
+		"end_time",
+		"name",
+		"tel",
+		"email",
+		"note"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING "id";`
+
+	var visitId int
+	if err := r.db.QueryRowContext(
+		context.Background(),
+		query,
+		req.ProjectId,
+		req.Date,
+		req.StartTime,
+		endTime,
+		req.Name,
+		req.Tel,
+		req.Email,
+		req.Note,
+	).Scan(&visitId); err != nil {
+		if strings.Contains(err.Error(), "site_visits_slot_key") {
+			return 0, ErrSlotUnavailable
+		}
+		return 0, fmt.Errorf("insert site visit failed: %v", err)
+	}
+	return visitId, nil
+}
+
+const siteVisitColumns = `
+		"v"."id",
+		"v"."project_id",
+		COALESCE("p"."name", '') AS "project_name",
+		to_char("v"."visit_date", 'YYYY-MM-DD') AS "visit_date",
+		to_char("v"."start_time", 'HH24:MI') AS "start_time",
+		to_char("v"."end_time", 'HH24:MI') AS "end_time",
+		"v"."name",
+		COALESCE("v"."tel", '') AS "tel",
+		COALESCE("v"."email", '') AS "email",
+		COALESCE("v"."note", '') AS "note",
+		"v"."status",
+		"v"."created_at",
+		"v"."updated_at"`
+
+func (r *siteVisitsRepository) FindOneSiteVisit(visitId int) (*siteVisits.SiteVisit, error) {
+	query := fmt.Sprintf(`
+	SELECT%s
+	FROM "site_visits" "v"
+	LEFT JOIN "projects" "p" ON "p"."id" = "v"."project_id"
+	WHERE "v"."id" = $1;`, siteVisitColumns)
+
+	visit := new(siteVisits.SiteVisit)
+	if err := r.db.Get(visit, query, visitId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("site visit %d not found", visitId)
+		}
+		return nil, fmt.Errorf("get site visit failed: %v", err)
+	}
+	return visit, nil
+}
+
+func siteVisitWhere(req *siteVisits.SiteVisitFilter) (string, []any) {
+	where := `
+	WHERE 1 = 1`
+	values := make([]any, 0)
+
+	if req.ProjectId != 0 {
+		values = append(values, req.ProjectId)
+		where += fmt.Sprintf(`
+	AND "v"."project_id" = $%d`, len(values))
+	}
+	if req.Status != "" {
+		values = append(values, req.Status)
+		where += fmt.Sprintf(`
+	AND "v"."status" = $%d`, len(values))
+	}
+	if req.From != "" {
+		values = append(values, req.From)
+		where += fmt.Sprintf(`
+	AND "v"."visit_date" >= $%d`, len(values))
+	}
+	if req.To != "" {
+		values = append(values, req.To)
+		where += fmt.Sprintf(`
+	AND "v"."visit_date" <= $%d`, len(values))
+	}
+	return where, values
+}
+
+func (r *siteVisitsRepository) FindSiteVisit(req *siteVisits.SiteVisitFilter) ([]*siteVisits.SiteVisit, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	visits := make([]*siteVisits.SiteVisit, 0)
+	where, values := siteVisitWhere(req)
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT%s
+		FROM "site_visits" "v"
+		LEFT JOIN "projects" "p" ON "p"."id" = "v"."project_id"%s
+		ORDER BY "v"."visit_date" ASC, "v"."start_time" ASC
+		OFFSET $%d LIMIT $%d
+	) AS "t";`, siteVisitColumns, where, len(values)+1, len(values)+2)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, append(values, (req.Page-1)*req.Limit, req.Limit)...); err != nil {
+		log.Printf("find site visits failed: %v\n", err)
+		return visits, 0
+	}
+	if err := json.Unmarshal(bytes, &visits); err != nil {
+		log.Printf("unmarshal site visits failed: %v\n", err)
+		return visits, 0
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM "site_visits" "v"%s;`, where), values...); err != nil {
+		log.Printf("count site visits failed: %v\n", err)
+		return visits, 0
+	}
+	return visits, count
+}
+
+func (r *siteVisitsRepository) FindConfirmedSiteVisits(req *siteVisits.SiteVisitFilter) ([]*siteVisits.SiteVisit, error) {
+	req.Status = siteVisits.StatusConfirmed
+	where, values := siteVisitWhere(req)
+
+	query := fmt.Sprintf(`
+	SELECT%s
+	FROM "site_visits" "v"
+	LEFT JOIN "projects" "p" ON "p"."id" = "v"."project_id"%s
+	ORDER BY "v"."visit_date" ASC, "v"."start_time" ASC;`, siteVisitColumns, where)
+
+	visits := make([]*siteVisits.SiteVisit, 0)
+	if err := r.db.Select(&visits, query, values...); err != nil {
+		return nil, fmt.Errorf("find site visits failed: %v", err)
+	}
+	return visits, nil
+}
+
+func (r *siteVisitsRepository) CancelSiteVisit(visitId int) error {
+	query := `
+	UPDATE "site_visits" SET
+		"status" = 'cancelled'
+	WHERE "id" = $1
+	AND "status" = 'confirmed';`
+
+	res, err := r.db.ExecContext(context.Background(), query, visitId)
+	if err != nil {
+		return fmt.Errorf("cancel site visit failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("confirmed site visit %d not found", visitId)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package siteVisitsUsecases
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/siteVisits"
+	"github.com/yporn/sirarom-backend/modules/siteVisits/siteVisitsRepositories"
+)
+
+// maxSlotDays caps the range of a free slot query.
+const maxSlotDays = 62
+
+type ISiteVisitsUsecase interface {
+	FindVisitHours(projectId int) ([]*siteVisits.VisitHour, error)
+	UpdateVisitHours(projectId int, hours []*siteVisits.VisitHour) ([]*siteVisits.VisitHour, error)
+	FindBlackouts(projectId int) ([]*siteVisits.Blackout, error)
+	InsertBlackout(req *siteVisits.Blackout) error
+	DeleteBlackout(projectId, blackoutId int) error
+	FindSlots(projectId int, req *siteVisits.SlotFilter) ([]*siteVisits.Slot, error)
+	BookSiteVisit(req *siteVisits.SiteVisitReq) (*siteVisits.SiteVisit, error)
+	FindSiteVisit(req *siteVisits.SiteVisitFilter) *entities.PaginateRes
+	CancelSiteVisit(visitId int) (*siteVisits.SiteVisit, error)
+	ExportIcs(req *siteVisits.SiteVisitFilter) ([]byte, error)
+}
+
+type siteVisitsUsecase struct {
+	cfg                  config.IConfig
+	siteVisitsRepository siteVisitsRepositories.ISiteVisitsRepository
+}
+
+func SiteVisitsUsecase(cfg config.IConfig, siteVisitsRepository siteVisitsRepositories.ISiteVisitsRepository) ISiteVisitsUsecase {
+	return &siteVisitsUsecase{
+		cfg:                  cfg,
+		siteVisitsRepository: siteVisitsRepository,
+	}
+}
+
+func (u *siteVisitsUsecase) FindVisitHours(projectId int) ([]*siteVisits.VisitHour, error) {
+	if _, err := u.siteVisitsRepository.FindProjectName(projectId); err != nil {
+		return nil, err
+	}
+	return u.siteVisitsRepository.FindVisitHours(projectId)
+}
+
+func (u *siteVisitsUsecase) UpdateVisitHours(projectId int, hours []*siteVisits.VisitHour) ([]*siteVisits.VisitHour, error) {
+	if _, err := u.siteVisitsRepository.FindProjectName(projectId); err != nil {
+		return nil, err
+	}
+	if err := u.siteVisitsRepository.UpdateVisitHours(projectId, hours); err != nil {
+		return nil, err
+	}
+	return u.siteVisitsRepository.FindVisitHours(projectId)
+}
+
+// FindBlackouts lists the blackout dates from today on.
+func (u *siteVisitsUsecase) FindBlackouts(projectId int) ([]*siteVisits.Blackout, error) {
+	today := time.Now().In(u.cfg.App().Timezone()).Format(siteVisits.DateLayout)
+	return u.siteVisitsRepository.FindBlackouts(projectId, today, "9999-12-31")
+}
+
+func (u *siteVisitsUsecase) InsertBlackout(req *siteVisits.Blackout) error {
+	if _, err := u.siteVisitsRepository.FindProjectName(req.ProjectId); err != nil {
+		return err
+	}
+	return u.siteVisitsRepository.InsertBlackout(req)
+}
+
+func (u *siteVisitsUsecase) DeleteBlackout(projectId, blackoutId int) error {
+	return u.siteVisitsRepository.DeleteBlackout(projectId, blackoutId)
+}
+
+// FindSlots returns the free slots of a project between from and to, defaulting
+// to the next 14 days. Past, blacked-out and booked slots are left out.
+func (u *siteVisitsUsecase) FindSlots(projectId int, req *siteVisits.SlotFilter) ([]*siteVisits.Slot, error) {
+	loc := u.cfg.App().Timezone()
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	from, err := parseDate(req.From, today, loc)
+	if err != nil {
+		return nil, fmt.Errorf("from must be YYYY-MM-DD")
+	}
+	to, err := parseDate(req.To, from.AddDate(0, 0, 13), loc)
+	if err != nil {
+		return nil, fmt.Errorf("to must be YYYY-MM-DD")
+	}
+	if from.Before(today) {
+		from = today
+	}
+	if to.Before(from) {
+		return make([]*siteVisits.Slot, 0), nil
+	}
+	if to.Sub(from) > maxSlotDays*24*time.Hour {
+		return nil, fmt.Errorf("range must not be longer than %d days", maxSlotDays)
+	}
+
+	if _, err := u.siteVisitsRepository.FindProjectName(projectId); err != nil {
+		return nil, err
+	}
+	hours, err := u.siteVisitsRepository.FindVisitHours(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	fromStr, toStr := from.Format(siteVisits.DateLayout), to.Format(siteVisits.DateLayout)
+	blackouts, err := u.siteVisitsRepository.FindBlackouts(projectId, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+	closed := make(map[string]bool, len(blackouts))
+	for _, b := range blackouts {
+		closed[b.Date] = true
+	}
+
+	booked, err := u.siteVisitsRepository.FindBookedSlots(projectId, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]*siteVisits.Slot, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(siteVisits.DateLayout)
+		if closed[date] {
+			continue
+		}
+		for _, h := range hours {
+			if h.Weekday != int(day.Weekday()) {
+				continue
+			}
+			for _, slot := range daySlots(day, h, loc) {
+				start, _ := time.ParseInLocation(siteVisits.DateLayout+" "+siteVisits.TimeLayout, date+" "+slot.StartTime, loc)
+				if !start.After(now) || booked[siteVisitsRepositories.BookedKey(date, slot.StartTime)] {
+					continue
+				}
+				slots = append(slots, slot)
+			}
+		}
+	}
+	return slots, nil
+}
+
+// daySlots cuts an opening window into slots, the last one has to end by EndTime.
+func daySlots(day time.Time, h *siteVisits.VisitHour, loc *time.Location) []*siteVisits.Slot {
+	date := day.Format(siteVisits.DateLayout)
+	start, err := time.ParseInLocation(siteVisits.DateLayout+" "+siteVisits.TimeLayout, date+" "+h.StartTime, loc)
+	if err != nil {
+		return nil
+	}
+	end, err := time.ParseInLocation(siteVisits.DateLayout+" "+siteVisits.TimeLayout, date+" "+h.EndTime, loc)
+	if err != nil {
+		return nil
+	}
+	step := time.Duration(h.SlotMinutes) * time.Minute
+
+	slots := make([]*siteVisits.Slot, 0)
+	for t := start; !t.Add(step).After(end); t = t.Add(step) {
+		slots = append(slots, &siteVisits.Slot{
+			Date:      date,
+			StartTime: t.Format(siteVisits.TimeLayout),
+			EndTime:   t.Add(step).Format(siteVisits.TimeLayout),
+		})
+	}
+	return slots
+}
+
+func parseDate(value string, fallback time.Time, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseInLocation(siteVisits.DateLayout, value, loc)
+}
+
+// BookSiteVisit books one of the free slots. Two visitors racing for the same
+// slot are settled by the unique index, the loser gets ErrSlotUnavailable.
+func (u *siteVisitsUsecase) BookSiteVisit(req *siteVisits.SiteVisitReq) (*siteVisits.SiteVisit, error) {
+	slots, err := u.FindSlots(req.ProjectId, &siteVisits.SlotFilter{From: req.Date, To: req.Date})
+	if err != nil {
+		return nil, err
+	}
+
+	var slot *siteVisits.Slot
+	for _, s := range slots {
+		if s.StartTime == req.StartTime {
+			slot = s
+			break
+		}
+	}
+	if slot == nil {
+		return nil, siteVisitsRepositories.ErrSlotUnavailable
+	}
+
+	visitId, err := u.siteVisitsRepository.InsertSiteVisit(req, slot.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	return u.siteVisitsRepository.FindOneSiteVisit(visitId)
+}
+
+func (u *siteVisitsUsecase) FindSiteVisit(req *siteVisits.SiteVisitFilter) *entities.PaginateRes {
+	visits, count := u.siteVisitsRepository.FindSiteVisit(req)
+
+	return &entities.PaginateRes{
+		Data:      visits,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *siteVisitsUsecase) CancelSiteVisit(visitId int) (*siteVisits.SiteVisit, error) {
+	if err := u.siteVisitsRepository.CancelSiteVisit(visitId); err != nil {
+		return nil, err
+	}
+	return u.siteVisitsRepository.FindOneSiteVisit(visitId)
+}
+
+// ExportIcs renders the confirmed bookings as an iCalendar feed. Times are
+// written in UTC so no VTIMEZONE block is needed.
+func (u *siteVisitsUsecase) ExportIcs(req *siteVisits.SiteVisitFilter) ([]byte, error) {
+	visits, err := u.siteVisitsRepository.FindConfirmedSiteVisits(req)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := u.cfg.App().Timezone()
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	host := u.cfg.App().Host()
+	if host == "" {
+		host = "localhost"
+	}
+
+	var b strings.Builder
+	writeIcsLine(&b, "BEGIN:VCALENDAR")
+	writeIcsLine(&b, "VERSION:2.0")
+	writeIcsLine(&b, "PRODID:-//"+u.cfg.App().Name()+"//Site visits//TH")
+	writeIcsLine(&b, "CALSCALE:GREGORIAN")
+	writeIcsLine(&b, "METHOD:PUBLISH")
+	for _, v := range visits {
+		start, err := time.ParseInLocation(siteVisits.DateLayout+" "+siteVisits.TimeLayout, v.VisitDate+" "+v.StartTime, loc)
+		if err != nil {
+			return nil, fmt.Errorf("parse site visit %d failed: %v", v.Id, err)
+		}
+		end, err := time.ParseInLocation(siteVisits.DateLayout+" "+siteVisits.TimeLayout, v.VisitDate+" "+v.EndTime, loc)
+		if err != nil {
+			return nil, fmt.Errorf("parse site visit %d failed: %v", v.Id, err)
+		}
+
+		description := "โทร: " + v.Tel + "\nอีเมล: " + v.Email
+		if v.Note != "" {
+			description += "\n" + v.Note
+		}
+
+		writeIcsLine(&b, "BEGIN:VEVENT")
+		writeIcsLine(&b, fmt.Sprintf("UID:site-visit-%d@%s", v.Id, host))
+		writeIcsLine(&b, "DTSTAMP:"+stamp)
+		writeIcsLine(&b, "DTSTART:"+start.UTC().Format("20060102T150405Z"))
+		writeIcsLine(&b, "DTEND:"+end.UTC().Format("20060102T150405Z"))
+		writeIcsLine(&b, "SUMMARY:"+escapeIcs("เยี่ยมชมโครงการ "+v.ProjectName+" - "+v.Name))
+		writeIcsLine(&b, "DESCRIPTION:"+escapeIcs(description))
+		writeIcsLine(&b, "LOCATION:"+escapeIcs(v.ProjectName))
+		writeIcsLine(&b, "STATUS:CONFIRMED")
+		writeIcsLine(&b, "END:VEVENT")
+	}
+	writeIcsLine(&b, "END:VCALENDAR")
+	return []byte(b.String()), nil
+}
+
+func escapeIcs(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeIcsLine folds lines longer than 75 octets as RFC 5545 asks, without
+// splitting a multi-byte character.
+func writeIcsLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // the leading space counts
+	}
+	b.WriteString(line + "\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
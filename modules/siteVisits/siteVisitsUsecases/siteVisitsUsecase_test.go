@@ -0,0 +1,121 @@
+package siteVisitsUsecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yporn/sirarom-backend/modules/siteVisits"
+)
+
+func TestDaySlots(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*60*60)
+	day := time.Date(2024, 5, 4, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name string
+		hour *siteVisits.VisitHour
+		want []string // start-end of every slot
+	}{
+		{
+			name: "even window",
+			hour: &siteVisits.VisitHour{StartTime: "09:00", EndTime: "11:00", SlotMinutes: 60},
+			want: []string{"09:00-10:00", "10:00-11:00"},
+		},
+		{
+			name: "last slot must end by the close",
+			hour: &siteVisits.VisitHour{StartTime: "09:00", EndTime: "10:40", SlotMinutes: 30},
+			want: []string{"09:00-09:30", "09:30-10:00", "10:00-10:30"},
+		},
+		{
+			name: "window shorter than a slot",
+			hour: &siteVisits.VisitHour{StartTime: "09:00", EndTime: "09:20", SlotMinutes: 30},
+			want: []string{},
+		},
+		{
+			name: "bad time",
+			hour: &siteVisits.VisitHour{StartTime: "9am", EndTime: "10:00", SlotMinutes: 30},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := daySlots(day, tt.hour, loc)
+			if tt.want == nil {
+				if slots != nil {
+					t.Fatalf("daySlots = %d slots, want nil", len(slots))
+				}
+				return
+			}
+			if len(slots) != len(tt.want) {
+				t.Fatalf("daySlots = %d slots, want %d", len(slots), len(tt.want))
+			}
+			for n, slot := range slots {
+				if slot.Date != "2024-05-04" {
+					t.Errorf("slot %d date = %s, want 2024-05-04", n, slot.Date)
+				}
+				if got := slot.StartTime + "-" + slot.EndTime; got != tt.want[n] {
+					t.Errorf("slot %d = %s, want %s", n, got, tt.want[n])
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeIcs(t *testing.T) {
+	got := escapeIcs("a\\b; c, d\r\ne\nf")
+	want := `a\\b\; c\, d\ne\nf`
+	if got != want {
+		t.Errorf("escapeIcs = %q, want %q", got, want)
+	}
+}
+
+func TestWriteIcsLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short", line: "SUMMARY:visit"},
+		{name: "exactly 75 octets", line: "SUMMARY:" + strings.Repeat("a", 67)},
+		{name: "long ascii", line: "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{name: "long thai", line: "SUMMARY:" + strings.Repeat("เยี่ยมชมโครงการ", 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeIcsLine(&b, tt.line)
+			out := b.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("line does not end with CRLF: %q", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(tt.line) <= 75 && len(lines) != 1 {
+				t.Errorf("line of %d octets folded into %d", len(tt.line), len(lines))
+			}
+
+			var unfolded strings.Builder
+			for n, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("line %d has %d octets, want at most 75", n, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a character: %q", n, l)
+				}
+				if n > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Errorf("folded line %d does not start with a space: %q", n, l)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded = %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
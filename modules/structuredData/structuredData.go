@@ -0,0 +1,90 @@
+package structuredData
+
+// Context is the @context of every JSON-LD document.
+const Context = "https://schema.org"
+
+type Organization struct {
+	Context   string   `json:"@context,omitempty"`
+	Type      string   `json:"@type"`
+	Name      string   `json:"name"`
+	Url       string   `json:"url,omitempty"`
+	Logo      string   `json:"logo,omitempty"`
+	Telephone string   `json:"telephone,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	SameAs    []string `json:"sameAs,omitempty"`
+}
+
+type PostalAddress struct {
+	Type            string `json:"@type"`
+	StreetAddress   string `json:"streetAddress,omitempty"`
+	AddressLocality string `json:"addressLocality,omitempty"`
+	AddressCountry  string `json:"addressCountry"`
+}
+
+type Place struct {
+	Type    string         `json:"@type"`
+	Address *PostalAddress `json:"address"`
+}
+
+type JobPosting struct {
+	Context            string        `json:"@context,omitempty"`
+	Type               string        `json:"@type"`
+	Title              string        `json:"title"`
+	Description        string        `json:"description"`
+	DatePosted         string        `json:"datePosted"`
+	ValidThrough       string        `json:"validThrough,omitempty"`
+	TotalJobOpenings   int           `json:"totalJobOpenings,omitempty"`
+	DirectApply        bool          `json:"directApply"`
+	Url                string        `json:"url,omitempty"`
+	HiringOrganization *Organization `json:"hiringOrganization"`
+	JobLocation        *Place        `json:"jobLocation,omitempty"`
+}
+
+type LocationFeature struct {
+	Type  string `json:"@type"`
+	Name  string `json:"name"`
+	Value bool   `json:"value"`
+}
+
+// Residence is typed as both Residence and Product, a place on its own cannot
+// carry offers.
+type Residence struct {
+	Context        string             `json:"@context,omitempty"`
+	Type           []string           `json:"@type"`
+	Name           string             `json:"name"`
+	Description    string             `json:"description,omitempty"`
+	Url            string             `json:"url,omitempty"`
+	Image          []string           `json:"image,omitempty"`
+	Address        *PostalAddress     `json:"address,omitempty"`
+	Telephone      string             `json:"telephone,omitempty"`
+	HasMap         string             `json:"hasMap,omitempty"`
+	AmenityFeature []*LocationFeature `json:"amenityFeature,omitempty"`
+	Offers         *Offer             `json:"offers,omitempty"`
+}
+
+type Offer struct {
+	Context       string        `json:"@context,omitempty"`
+	Type          string        `json:"@type"`
+	Name          string        `json:"name,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	Url           string        `json:"url,omitempty"`
+	Image         []string      `json:"image,omitempty"`
+	Price         *float64      `json:"price,omitempty"`
+	PriceCurrency string        `json:"priceCurrency,omitempty"`
+	ValidFrom     string        `json:"validFrom,omitempty"`
+	ValidThrough  string        `json:"validThrough,omitempty"`
+	Seller        *Organization `json:"seller,omitempty"`
+	ItemOffered   []*Thing      `json:"itemOffered,omitempty"`
+}
+
+type Thing struct {
+	Type  string   `json:"@type"`
+	Name  string   `json:"name"`
+	Image []string `json:"image,omitempty"`
+}
+
+// Graph is the aggregated feed, every node shares the context of the graph.
+type Graph struct {
+	Context string `json:"@context"`
+	Graph   []any  `json:"@graph"`
+}
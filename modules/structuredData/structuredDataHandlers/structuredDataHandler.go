@@ -0,0 +1,88 @@
+package structuredDataHandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/structuredData/structuredDataUsecases"
+)
+
+type structuredDataHandlersErrCode string
+
+const (
+	jobPostingErr structuredDataHandlersErrCode = "structuredData-001"
+	residenceErr  structuredDataHandlersErrCode = "structuredData-002"
+	offerErr      structuredDataHandlersErrCode = "structuredData-003"
+	feedErr       structuredDataHandlersErrCode = "structuredData-004"
+)
+
+type IStructuredDataHandler interface {
+	JobPosting(c *fiber.Ctx) error
+	Residence(c *fiber.Ctx) error
+	Offer(c *fiber.Ctx) error
+	Feed(c *fiber.Ctx) error
+}
+
+type structuredDataHandler struct {
+	cfg                   config.IConfig
+	structuredDataUsecase structuredDataUsecases.IStructuredDataUsecase
+}
+
+func StructuredDataHandler(cfg config.IConfig, structuredDataUsecase structuredDataUsecases.IStructuredDataUsecase) IStructuredDataHandler {
+	return &structuredDataHandler{
+		cfg:                   cfg,
+		structuredDataUsecase: structuredDataUsecase,
+	}
+}
+
+// send writes the document as is, ready to be embedded in a
+// <script type="application/ld+json"> tag.
+func send(c *fiber.Ctx, code structuredDataHandlersErrCode, doc any, err error) error {
+	if err != nil {
+		status := fiber.ErrInternalServerError.Code
+		if errors.Is(err, structuredDataUsecases.ErrNotFound) {
+			status = fiber.ErrNotFound.Code
+		}
+		return entities.NewResponse(c).Error(
+			status,
+			string(code),
+			err.Error(),
+		).Res()
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(code),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, "application/ld+json; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(data)
+}
+
+func (h *structuredDataHandler) JobPosting(c *fiber.Ctx) error {
+	doc, err := h.structuredDataUsecase.JobPosting(strings.Trim(c.Params("job_id"), " "))
+	return send(c, jobPostingErr, doc, err)
+}
+
+func (h *structuredDataHandler) Residence(c *fiber.Ctx) error {
+	doc, err := h.structuredDataUsecase.Residence(strings.Trim(c.Params("project_id"), " "))
+	return send(c, residenceErr, doc, err)
+}
+
+func (h *structuredDataHandler) Offer(c *fiber.Ctx) error {
+	doc, err := h.structuredDataUsecase.Offer(strings.Trim(c.Params("promotion_id"), " "))
+	return send(c, offerErr, doc, err)
+}
+
+func (h *structuredDataHandler) Feed(c *fiber.Ctx) error {
+	doc, err := h.structuredDataUsecase.Feed()
+	return send(c, feedErr, doc, err)
+}
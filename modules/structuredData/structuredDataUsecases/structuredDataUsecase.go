@@ -0,0 +1,332 @@
+package structuredDataUsecases
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/general/generalUsecases"
+	"github.com/yporn/sirarom-backend/modules/jobs"
+	"github.com/yporn/sirarom-backend/modules/jobs/jobsUsecases"
+	"github.com/yporn/sirarom-backend/modules/projects"
+	"github.com/yporn/sirarom-backend/modules/projects/projectsUsecases"
+	"github.com/yporn/sirarom-backend/modules/promotions"
+	"github.com/yporn/sirarom-backend/modules/promotions/promotionsUsecases"
+	"github.com/yporn/sirarom-backend/modules/seo"
+	"github.com/yporn/sirarom-backend/modules/seo/seoUsecases"
+	"github.com/yporn/sirarom-backend/modules/structuredData"
+)
+
+// ErrNotFound is returned for rows that are not shown on the landing page.
+var ErrNotFound = errors.New("not found")
+
+// The company details and site title are the first rows of data_settings and seo.
+const settingId = "1"
+
+type IStructuredDataUsecase interface {
+	JobPosting(jobId string) (*structuredData.JobPosting, error)
+	Residence(projectId string) (*structuredData.Residence, error)
+	Offer(promotionId string) (*structuredData.Offer, error)
+	Feed() (*structuredData.Graph, error)
+}
+
+type structuredDataUsecase struct {
+	cfg               config.IConfig
+	jobsUsecase       jobsUsecases.IJobsUsecase
+	projectsUsecase   projectsUsecases.IProjectsUsecase
+	promotionsUsecase promotionsUsecases.IPromotionsUsecase
+	generalUsecase    generalUsecases.IGeneralUsecase
+	seoUsecase        seoUsecases.ISeoUsecase
+
+	// the feed, built again once the sitemap version changes
+	mu      sync.Mutex
+	version string
+	feed    *structuredData.Graph
+}
+
+func StructuredDataUsecase(
+	cfg config.IConfig,
+	jobsUsecase jobsUsecases.IJobsUsecase,
+	projectsUsecase projectsUsecases.IProjectsUsecase,
+	promotionsUsecase promotionsUsecases.IPromotionsUsecase,
+	generalUsecase generalUsecases.IGeneralUsecase,
+	seoUsecase seoUsecases.ISeoUsecase,
+) IStructuredDataUsecase {
+	return &structuredDataUsecase{
+		cfg:               cfg,
+		jobsUsecase:       jobsUsecase,
+		projectsUsecase:   projectsUsecase,
+		promotionsUsecase: promotionsUsecase,
+		generalUsecase:    generalUsecase,
+		seoUsecase:        seoUsecase,
+	}
+}
+
+// organization describes the company from the site settings.
+func (u *structuredDataUsecase) organization() (*structuredData.Organization, error) {
+	setting, err := u.generalUsecase.FindOneGeneral(settingId)
+	if err != nil {
+		return nil, err
+	}
+	site, err := u.seoUsecase.FindOneSeo(settingId)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &structuredData.Organization{
+		Type:      "Organization",
+		Name:      site.Title,
+		Url:       u.cfg.App().SiteUrl(),
+		Telephone: setting.Tel,
+		Email:     setting.Email,
+		SameAs:    make([]string, 0),
+	}
+	if len(setting.Images) > 0 {
+		org.Logo = setting.Images[0].Url
+	}
+	for _, link := range []string{setting.LinkFacebook, setting.LinkInstagram, setting.LinkTwitter, setting.LinkTikTok, setting.LinkLine} {
+		if link != "" {
+			org.SameAs = append(org.SameAs, link)
+		}
+	}
+	return org, nil
+}
+
+func imageUrls(images []*entities.Image) []string {
+	urls := make([]string, 0, len(images))
+	for _, image := range images {
+		urls = append(urls, image.Url)
+	}
+	return urls
+}
+
+func jobPosting(org *structuredData.Organization, job *jobs.Job) *structuredData.JobPosting {
+	description := job.Description
+	if job.Qualification != "" {
+		description += "\n\n" + job.Qualification
+	}
+	datePosted := job.StartDate
+	if datePosted == "" && len(job.CreatedAt) >= 10 {
+		datePosted = job.CreatedAt[:10]
+	}
+
+	posting := &structuredData.JobPosting{
+		Type:               "JobPosting",
+		Title:              job.Position,
+		Description:        description,
+		DatePosted:         datePosted,
+		ValidThrough:       job.EndDate,
+		TotalJobOpenings:   job.Amount,
+		DirectApply:        true,
+		Url:                org.Url + seo.PagePath(seo.PageJob, job.Id),
+		HiringOrganization: org,
+	}
+	if job.Location != "" {
+		posting.JobLocation = &structuredData.Place{
+			Type: "Place",
+			Address: &structuredData.PostalAddress{
+				Type:            "PostalAddress",
+				AddressLocality: job.Location,
+				AddressCountry:  "TH",
+			},
+		}
+	}
+	return posting
+}
+
+func residence(org *structuredData.Organization, project *projects.Project) *structuredData.Residence {
+	res := &structuredData.Residence{
+		Type:        []string{"Residence", "Product"},
+		Name:        project.Name,
+		Description: project.Description,
+		Url:         org.Url + seo.PagePath(seo.PageProject, project.Slug),
+		Image:       imageUrls(project.Images),
+		Address: &structuredData.PostalAddress{
+			Type:            "PostalAddress",
+			StreetAddress:   project.Address,
+			AddressLocality: project.Location,
+			AddressCountry:  "TH",
+		},
+		Telephone:      project.Tel,
+		HasMap:         project.LinkLocation,
+		AmenityFeature: make([]*structuredData.LocationFeature, 0),
+	}
+	for _, facility := range project.FacilityItem {
+		res.AmenityFeature = append(res.AmenityFeature, &structuredData.LocationFeature{
+			Type:  "LocationFeatureSpecification",
+			Name:  facility.Item,
+			Value: true,
+		})
+	}
+	if project.Price > 0 {
+		price := float64(project.Price)
+		res.Offers = &structuredData.Offer{
+			Type:          "Offer",
+			Price:         &price,
+			PriceCurrency: "THB",
+			Url:           res.Url,
+		}
+	}
+	return res
+}
+
+func offer(org *structuredData.Organization, promotion *promotions.Promotion) *structuredData.Offer {
+	res := &structuredData.Offer{
+		Type:         "Offer",
+		Name:         promotion.Heading,
+		Description:  promotion.Description,
+		Url:          org.Url + seo.PagePath(seo.PagePromotion, promotion.Slug),
+		Image:        imageUrls(promotion.Images),
+		ValidFrom:    promotion.StartDate,
+		ValidThrough: promotion.EndDate,
+		Seller:       org,
+		ItemOffered:  make([]*structuredData.Thing, 0),
+	}
+	for _, item := range promotion.HouseModel {
+		for _, houseModel := range item.HouseModel {
+			res.ItemOffered = append(res.ItemOffered, &structuredData.Thing{
+				Type:  "SingleFamilyResidence",
+				Name:  houseModel.Name,
+				Image: imageUrls(houseModel.Images),
+			})
+		}
+	}
+	return res
+}
+
+// JobPosting renders a published job that is still opening.
+func (u *structuredDataUsecase) JobPosting(jobId string) (*structuredData.JobPosting, error) {
+	job, err := u.jobsUsecase.FindOneJob(jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job.Display != entities.DisplayPublished || job.Status != jobs.StatusOpening {
+		return nil, ErrNotFound
+	}
+
+	org, err := u.organization()
+	if err != nil {
+		return nil, err
+	}
+	posting := jobPosting(org, job)
+	posting.Context = structuredData.Context
+	return posting, nil
+}
+
+func (u *structuredDataUsecase) Residence(projectId string) (*structuredData.Residence, error) {
+	project, err := u.projectsUsecase.FindOneProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+	if project.Display != entities.DisplayPublished {
+		return nil, ErrNotFound
+	}
+
+	org, err := u.organization()
+	if err != nil {
+		return nil, err
+	}
+	res := residence(org, project)
+	res.Context = structuredData.Context
+	return res, nil
+}
+
+func (u *structuredDataUsecase) Offer(promotionId string) (*structuredData.Offer, error) {
+	promotion, err := u.promotionsUsecase.FindOnePromotion(promotionId)
+	if err != nil {
+		return nil, err
+	}
+	if promotion.Display != entities.DisplayPublished {
+		return nil, ErrNotFound
+	}
+
+	org, err := u.organization()
+	if err != nil {
+		return nil, err
+	}
+	res := offer(org, promotion)
+	res.Context = structuredData.Context
+	return res, nil
+}
+
+// Feed puts the company, every opening job, every project and every promotion
+// on the landing page into one @graph document. The document is cached until
+// the content changes, as told by the version of the sitemap.
+func (u *structuredDataUsecase) Feed() (*structuredData.Graph, error) {
+	version, err := u.seoUsecase.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if version == u.version && u.feed != nil {
+		return u.feed, nil
+	}
+
+	feed, err := u.buildFeed()
+	if err != nil {
+		return nil, err
+	}
+	u.version, u.feed = version, feed
+	return feed, nil
+}
+
+func (u *structuredDataUsecase) buildFeed() (*structuredData.Graph, error) {
+	org, err := u.organization()
+	if err != nil {
+		return nil, err
+	}
+	graph := &structuredData.Graph{
+		Context: structuredData.Context,
+		Graph:   []any{org},
+	}
+
+	all := func() *entities.PaginationReq {
+		return &entities.PaginationReq{Page: 1, Limit: 10000000}
+	}
+	sortReq := &entities.SortReq{OrderBy: "id", Sort: "asc"}
+
+	jobsRes := u.jobsUsecase.FindJob(&jobs.JobFilter{
+		Display:       entities.DisplayPublished,
+		Status:        jobs.StatusOpening,
+		PaginationReq: all(),
+		SortReq:       sortReq,
+	})
+	jobsData, ok := jobsRes.Data.([]*jobs.Job)
+	if !ok {
+		return nil, fmt.Errorf("find jobs failed: unexpected %T", jobsRes.Data)
+	}
+	for _, job := range jobsData {
+		graph.Graph = append(graph.Graph, jobPosting(org, job))
+	}
+
+	projectsRes := u.projectsUsecase.FindProject(&projects.ProjectFilter{
+		Display:       entities.DisplayPublished,
+		PaginationReq: all(),
+		SortReq:       sortReq,
+	})
+	projectsData, ok := projectsRes.Data.([]*projects.Project)
+	if !ok {
+		return nil, fmt.Errorf("find projects failed: unexpected %T", projectsRes.Data)
+	}
+	for _, project := range projectsData {
+		graph.Graph = append(graph.Graph, residence(org, project))
+	}
+
+	promotionsRes := u.promotionsUsecase.FindPromotion(&promotions.PromotionFilter{
+		Display:       entities.DisplayPublished,
+		PaginationReq: all(),
+		SortReq:       sortReq,
+	})
+	promotionsData, ok := promotionsRes.Data.([]*promotions.Promotion)
+	if !ok {
+		return nil, fmt.Errorf("find promotions failed: unexpected %T", promotionsRes.Data)
+	}
+	for _, promotion := range promotionsData {
+		graph.Graph = append(graph.Graph, offer(org, promotion))
+	}
+	return graph, nil
+}
@@ -0,0 +1,128 @@
+package trash
+
+import (
+	"errors"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
+
+// ErrTrashInUse is returned when a trashed row still has records that must be kept.
+var ErrTrashInUse = errors.New("trash item still has records that must be kept")
+
+// Trashable is a content table whose rows are soft deleted into the trash.
+type Trashable struct {
+	Table string
+	Name  string // column used to describe the row
+	Label string
+	// Images selects the urls of every image removed together with row $1,
+	// including the ones of child rows dropped by ON DELETE CASCADE.
+	Images string
+	// Revisions deletes the stored revisions of row $1 and its children.
+	Revisions string
+	// Keep reports whether row $1 still has records that must outlive it,
+	// such as reservations and visit bookings, so it can't be purged.
+	Keep string
+	Kept string // the records Keep looks for, used in the error
+}
+
+var Trashables = map[string]*Trashable{
+	"projects": {
+		Table: "projects",
+		Name:  `"name"`,
+		Label: "โครงการ",
+		Images: `
+		SELECT "url" FROM "project_images" WHERE "project_id" = $1
+		UNION ALL
+		SELECT "i"."url" FROM "house_model_images" "i"
+		JOIN "house_models" "hm" ON "hm"."id" = "i"."house_model_id"
+		WHERE "hm"."project_id" = $1
+		UNION ALL
+		SELECT "i"."url" FROM "house_model_plan_images" "i"
+		JOIN "house_model_plans" "hp" ON "hp"."id" = "i"."house_model_plan_id"
+		JOIN "house_models" "hm" ON "hm"."id" = "hp"."house_model_id"
+		WHERE "hm"."project_id" = $1
+		UNION ALL
+		SELECT "i"."url" FROM "project_phase_images" "i"
+		JOIN "project_phases" "ph" ON "ph"."id" = "i"."phase_id"
+		WHERE "ph"."project_id" = $1`,
+		Revisions: `
+		DELETE FROM "revisions"
+		WHERE ("entity" = 'projects' AND "entity_id" = $1)
+		OR ("entity" = 'house_models' AND "entity_id" IN (SELECT "id" FROM "house_models" WHERE "project_id" = $1))`,
+		Keep: `
+		SELECT
+			EXISTS (SELECT 1 FROM "unit_reservations" WHERE "project_id" = $1)
+			OR EXISTS (SELECT 1 FROM "site_visits" WHERE "project_id" = $1)`,
+		Kept: "reservations or visit bookings",
+	},
+	"house_models": {
+		Table: "house_models",
+		Name:  `"name"`,
+		Label: "แบบบ้าน",
+		Images: `
+		SELECT "url" FROM "house_model_images" WHERE "house_model_id" = $1
+		UNION ALL
+		SELECT "i"."url" FROM "house_model_plan_images" "i"
+		JOIN "house_model_plans" "hp" ON "hp"."id" = "i"."house_model_plan_id"
+		WHERE "hp"."house_model_id" = $1`,
+		Revisions: `DELETE FROM "revisions" WHERE "entity" = 'house_models' AND "entity_id" = $1`,
+	},
+	"promotions": {
+		Table:  "promotions",
+		Name:   `"heading"`,
+		Label:  "โปรโมชัน",
+		Images: `SELECT "url" FROM "promotion_images" WHERE "promotion_id" = $1`,
+	},
+	"activities": {
+		Table:  "activities",
+		Name:   `"heading"`,
+		Label:  "กิจกรรม",
+		Images: `SELECT "url" FROM "activities_images" WHERE "activity_id" = $1`,
+	},
+	"banners": {
+		Table:  "banners",
+		Name:   `'#' || "id"`,
+		Label:  "แบนเนอร์",
+		Images: `SELECT "url" FROM "banner_images" WHERE "banner_id" = $1`,
+	},
+	"logos": {
+		Table:  "logos",
+		Name:   `"name"`,
+		Label:  "แบรนด์ในเครือ",
+		Images: `SELECT "url" FROM "logo_images" WHERE "logo_id" = $1`,
+	},
+	"interests": {
+		Table:  "interests",
+		Name:   `"bank_name"`,
+		Label:  "ดอกเบี้ย",
+		Images: `SELECT "url" FROM "interest_images" WHERE "interest_id" = $1`,
+	},
+	"careers": {
+		Table: "careers",
+		Name:  `"position"`,
+		Label: "ตำแหน่งงาน",
+		Keep:  `SELECT EXISTS (SELECT 1 FROM "job_applications" WHERE "career_id" = $1)`,
+		Kept:  "job applications",
+	},
+}
+
+type Trash struct {
+	Type      string `db:"type" json:"type"`
+	Id        int    `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Label     string `db:"label" json:"label"`
+	DeletedAt string `db:"deleted_at" json:"deleted_at"`
+}
+
+type TrashFilter struct {
+	Type string `query:"type"`
+	*entities.PaginationReq
+}
+
+// TrashPurge is a row removed for good together with the files nobody else uses.
+type TrashPurge struct {
+	Type  string   `json:"type"`
+	Id    int      `json:"id"`
+	Name  string   `json:"name"`
+	Files []string `json:"files"`
+}
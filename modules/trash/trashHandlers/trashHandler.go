@@ -0,0 +1,167 @@
+package trashHandlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/trash"
+	"github.com/yporn/sirarom-backend/modules/trash/trashUsecases"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type trashHandlersErrCode string
+
+const (
+	findTrashErr    trashHandlersErrCode = "trash-001"
+	restoreTrashErr trashHandlersErrCode = "trash-002"
+	purgeTrashErr   trashHandlersErrCode = "trash-003"
+)
+
+type ITrashHandler interface {
+	FindTrash(c *fiber.Ctx) error
+	RestoreTrash(c *fiber.Ctx) error
+	PurgeTrash(c *fiber.Ctx) error
+}
+
+type trashHandler struct {
+	cfg          config.IConfig
+	trashUsecase trashUsecases.ITrashUsecase
+	db           *sql.DB
+}
+
+func TrashHandler(cfg config.IConfig, trashUsecase trashUsecases.ITrashUsecase, db *sql.DB) ITrashHandler {
+	return &trashHandler{
+		cfg:          cfg,
+		trashUsecase: trashUsecase,
+		db:           db,
+	}
+}
+
+func (h *trashHandler) FindTrash(c *fiber.Ctx) error {
+	req := &trash.TrashFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findTrashErr),
+			err.Error(),
+		).Res()
+	}
+
+	if _, ok := trash.Trashables[req.Type]; req.Type != "" && !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findTrashErr),
+			"type is invalid",
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.trashUsecase.FindTrash(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+// trashParams reads the ":type/:id" route params shared by restore and purge.
+func trashParams(c *fiber.Ctx) (*trash.Trashable, int, error) {
+	t, ok := trash.Trashables[strings.Trim(c.Params("type"), " ")]
+	if !ok {
+		return nil, 0, fmt.Errorf("type is invalid")
+	}
+	id, err := strconv.Atoi(strings.Trim(c.Params("id"), " "))
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, id, nil
+}
+
+func (h *trashHandler) RestoreTrash(c *fiber.Ctx) error {
+	t, id, err := trashParams(c)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(restoreTrashErr),
+			err.Error(),
+		).Res()
+	}
+
+	name, err := h.trashUsecase.RestoreTrash(t, id)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(restoreTrashErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "กู้คืนข้อมูล"+t.Label+"จากถังขยะ : "+name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, &trash.Trash{
+		Type:  t.Table,
+		Id:    id,
+		Name:  name,
+		Label: t.Label,
+	}).Res()
+}
+
+func (h *trashHandler) PurgeTrash(c *fiber.Ctx) error {
+	t, id, err := trashParams(c)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(purgeTrashErr),
+			err.Error(),
+		).Res()
+	}
+
+	res, err := h.trashUsecase.PurgeTrash(t, id)
+	if err != nil {
+		code := fiber.ErrInternalServerError.Code
+		if errors.Is(err, trash.ErrTrashInUse) {
+			code = fiber.ErrConflict.Code
+		}
+		return entities.NewResponse(c).Error(
+			code,
+			string(purgeTrashErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบข้อมูล"+t.Label+"ถาวร : "+res.Name)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
@@ -0,0 +1,205 @@
+package trashRepositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/trash"
+)
+
+type ITrashRepository interface {
+	FindTrash(req *trash.TrashFilter) ([]*trash.Trash, int)
+	RestoreTrash(t *trash.Trashable, id int) (string, error)
+	PurgeTrash(t *trash.Trashable, id int) (string, []string, error)
+	FindReferencedUrls(urls []string) (map[string]bool, error)
+	DeleteMediaByUrls(urls []string) error
+}
+
+type trashRepository struct {
+	db *sqlx.DB
+}
+
+func TrashRepository(db *sqlx.DB) ITrashRepository {
+	return &trashRepository{
+		db: db,
+	}
+}
+
+func (r *trashRepository) trashQuery(kind string) string {
+	keys := make([]string, 0, len(trash.Trashables))
+	for k := range trash.Trashables {
+		if kind == "" || kind == k {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	unions := make([]string, 0, len(keys))
+	for _, k := range keys {
+		t := trash.Trashables[k]
+		unions = append(unions, fmt.Sprintf(`
+		SELECT '%s' AS "type", "id", (%s)::text AS "name", "deleted_at"
+		FROM "%s"
+		WHERE "deleted_at" IS NOT NULL`, k, t.Name, t.Table))
+	}
+	return strings.Join(unions, `
+		UNION ALL`)
+}
+
+func (r *trashRepository) FindTrash(req *trash.TrashFilter) ([]*trash.Trash, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	items := make([]*trash.Trash, 0)
+	union := r.trashQuery(req.Type)
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT *
+		FROM (%s
+		) AS "trash"
+		ORDER BY "deleted_at" DESC
+		OFFSET $1 LIMIT $2
+	) AS "t";`, union)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, (req.Page-1)*req.Limit, req.Limit); err != nil {
+		log.Printf("find trash failed: %v\n", err)
+		return items, 0
+	}
+	if err := json.Unmarshal(bytes, &items); err != nil {
+		log.Printf("unmarshal trash failed: %v\n", err)
+		return items, 0
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM (%s
+	) AS "trash";`, union)); err != nil {
+		log.Printf("count trash failed: %v\n", err)
+		return items, 0
+	}
+	return items, count
+}
+
+func (r *trashRepository) RestoreTrash(t *trash.Trashable, id int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	UPDATE "%s" SET
+		"deleted_at" = NULL
+	WHERE "id" = $1
+	AND "deleted_at" IS NOT NULL
+	RETURNING (%s)::text;`, t.Table, t.Name)
+
+	var name string
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s %d not found in trash", t.Table, id)
+		}
+		return "", fmt.Errorf("restore %s failed: %v", t.Table, err)
+	}
+	return name, nil
+}
+
+// PurgeTrash deletes a trashed row for good and returns its name and the image
+// urls that were attached to it, so the caller can remove the files.
+func (r *trashRepository) PurgeTrash(t *trash.Trashable, id int) (string, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var name string
+	if err := tx.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT (%s)::text FROM "%s" WHERE "id" = $1 AND "deleted_at" IS NOT NULL FOR UPDATE;`, t.Name, t.Table),
+		id,
+	).Scan(&name); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, fmt.Errorf("%s %d not found in trash", t.Table, id)
+		}
+		return "", nil, fmt.Errorf("get %s failed: %v", t.Table, err)
+	}
+
+	if t.Keep != "" {
+		var keep bool
+		if err := tx.GetContext(ctx, &keep, t.Keep, id); err != nil {
+			tx.Rollback()
+			return "", nil, fmt.Errorf("get %s records failed: %v", t.Table, err)
+		}
+		if keep {
+			tx.Rollback()
+			return "", nil, fmt.Errorf("%s %d has %s: %w", t.Table, id, t.Kept, trash.ErrTrashInUse)
+		}
+	}
+
+	urls := make([]string, 0)
+	if t.Images != "" {
+		if err := tx.SelectContext(ctx, &urls, t.Images, id); err != nil {
+			tx.Rollback()
+			return "", nil, fmt.Errorf("get %s images failed: %v", t.Table, err)
+		}
+	}
+
+	if t.Revisions != "" {
+		if _, err := tx.ExecContext(ctx, t.Revisions, id); err != nil {
+			tx.Rollback()
+			return "", nil, fmt.Errorf("delete %s revisions failed: %v", t.Table, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE "id" = $1;`, t.Table), id); err != nil {
+		tx.Rollback()
+		return "", nil, fmt.Errorf("purge %s failed: %v", t.Table, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", nil, err
+	}
+	return name, urls, nil
+}
+
+// FindReferencedUrls reports which of the urls are still used by another row or revision.
+func (r *trashRepository) FindReferencedUrls(urls []string) (map[string]bool, error) {
+	query := `
+	SELECT
+		"m"."url"
+	FROM "media" "m"
+	WHERE "m"."url" = ANY($1)
+	AND EXISTS (SELECT 1 FROM "media_references" "r" WHERE "r"."media_id" = "m"."id");`
+
+	rows := make([]string, 0)
+	if err := r.db.Select(&rows, query, urls); err != nil {
+		return nil, fmt.Errorf("find referenced urls failed: %v", err)
+	}
+
+	referenced := make(map[string]bool)
+	for _, url := range rows {
+		referenced[url] = true
+	}
+	return referenced, nil
+}
+
+func (r *trashRepository) DeleteMediaByUrls(urls []string) error {
+	query := `DELETE FROM "media" WHERE "url" = ANY($1);`
+
+	if _, err := r.db.ExecContext(context.Background(), query, urls); err != nil {
+		return fmt.Errorf("delete media failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package trashUsecases
+
+import (
+	"log"
+	"math"
+	"strings"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/files"
+	"github.com/yporn/sirarom-backend/modules/files/filesUsecases"
+	"github.com/yporn/sirarom-backend/modules/trash"
+	"github.com/yporn/sirarom-backend/modules/trash/trashRepositories"
+)
+
+type ITrashUsecase interface {
+	FindTrash(req *trash.TrashFilter) *entities.PaginateRes
+	RestoreTrash(t *trash.Trashable, id int) (string, error)
+	PurgeTrash(t *trash.Trashable, id int) (*trash.TrashPurge, error)
+}
+
+type trashUsecase struct {
+	trashRepository trashRepositories.ITrashRepository
+	filesUsecase    filesUsecases.IFilesUsecase
+}
+
+func TrashUsecase(trashRepository trashRepositories.ITrashRepository, filesUsecase filesUsecases.IFilesUsecase) ITrashUsecase {
+	return &trashUsecase{
+		trashRepository: trashRepository,
+		filesUsecase:    filesUsecase,
+	}
+}
+
+func (u *trashUsecase) FindTrash(req *trash.TrashFilter) *entities.PaginateRes {
+	items, count := u.trashRepository.FindTrash(req)
+	for _, item := range items {
+		if t, ok := trash.Trashables[item.Type]; ok {
+			item.Label = t.Label
+		}
+	}
+
+	return &entities.PaginateRes{
+		Data:      items,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *trashUsecase) RestoreTrash(t *trash.Trashable, id int) (string, error) {
+	return u.trashRepository.RestoreTrash(t, id)
+}
+
+// PurgeTrash removes the row for good, then deletes the files that no other row
+// or revision still points at.
+func (u *trashUsecase) PurgeTrash(t *trash.Trashable, id int) (*trash.TrashPurge, error) {
+	name, urls, err := u.trashRepository.PurgeTrash(t, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &trash.TrashPurge{
+		Type:  t.Table,
+		Id:    id,
+		Name:  name,
+		Files: make([]string, 0),
+	}
+	if len(urls) == 0 {
+		return res, nil
+	}
+
+	referenced, err := u.trashRepository.FindReferencedUrls(urls)
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make([]string, 0)
+	deleteFileReq := make([]*files.DeleteFileReq, 0)
+	for _, url := range urls {
+		if strings.HasPrefix(url, filesUsecases.PrivatePrefix) {
+			if err := u.filesUsecase.DeletePrivate(url); err != nil {
+				log.Printf("purge trash: %v\n", err)
+			}
+			res.Files = append(res.Files, url)
+			continue
+		}
+		i := strings.Index(url, "assets/images/")
+		if referenced[url] || i < 0 {
+			continue
+		}
+		unused = append(unused, url)
+		deleteFileReq = append(deleteFileReq, &files.DeleteFileReq{
+			Destination: url[i+len("assets/images/"):],
+		})
+		res.Files = append(res.Files, url)
+	}
+	if len(deleteFileReq) == 0 {
+		return res, nil
+	}
+
+	if err := u.filesUsecase.DeleteFileOnStorage(deleteFileReq); err != nil {
+		// The row is already gone, leftovers are picked up by the media sweep
+		log.Printf("purge trash: %v\n", err)
+	}
+	if err := u.trashRepository.DeleteMediaByUrls(unused); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
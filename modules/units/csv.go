@@ -0,0 +1,79 @@
+package units
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseCsv reads an import file into units. The header row names the columns,
+// in any order, out of plot_no, house_model_id, land_size, price, deposit,
+// status and note. Only plot_no is required.
+func ParseCsv(r io.Reader) ([]*Unit, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header failed: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["plot_no"]; !ok {
+		return nil, fmt.Errorf("csv must have a plot_no column")
+	}
+
+	result := make([]*Unit, 0)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv row %d failed: %v", row, err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		unit := &Unit{
+			PlotNo: field("plot_no"),
+			Status: field("status"),
+			Note:   field("note"),
+		}
+		if v := field("house_model_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("csv row %d: house_model_id is invalid: %s", row, v)
+			}
+			unit.HouseModelId = &id
+		}
+		for name, dest := range map[string]*float64{"land_size": &unit.LandSize, "price": &unit.Price} {
+			if v := field(name); v != "" {
+				n, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+				if err != nil {
+					return nil, fmt.Errorf("csv row %d: %s is invalid: %s", row, name, v)
+				}
+				*dest = n
+			}
+		}
+		if v := field("deposit"); v != "" {
+			n, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("csv row %d: deposit is invalid: %s", row, v)
+			}
+			unit.Deposit = &n
+		}
+		result = append(result, unit)
+	}
+	return result, nil
+}
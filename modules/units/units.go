@@ -0,0 +1,138 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yporn/sirarom-backend/modules/entities"
+)
+
+const (
+	StatusAvailable = "available"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+
+	ReservationPending   = "pending"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+)
+
+// Unit is one plot of a project.
+type Unit struct {
+	Id             int      `db:"id" json:"id"`
+	ProjectId      int      `db:"project_id" json:"project_id"`
+	HouseModelId   *int     `db:"house_model_id" json:"house_model_id"`
+	HouseModelName string   `db:"house_model_name" json:"house_model_name"`
+	PlotNo         string   `db:"plot_no" json:"plot_no"`
+	LandSize       float64  `db:"land_size" json:"land_size"`
+	Price          float64  `db:"price" json:"price"`
+	Deposit        *float64 `db:"deposit" json:"deposit"`
+	Status         string   `db:"status" json:"status"`
+	Note           string   `db:"note" json:"note,omitempty"`
+	CreatedAt      string   `db:"created_at" json:"created_at"`
+	UpdatedAt      string   `db:"updated_at" json:"updated_at"`
+}
+
+func ValidateStatus(status string) error {
+	switch status {
+	case StatusAvailable, StatusReserved, StatusSold:
+		return nil
+	}
+	return fmt.Errorf("status is invalid: %s", status)
+}
+
+func (u *Unit) Validate() error {
+	u.PlotNo = strings.TrimSpace(u.PlotNo)
+	if u.PlotNo == "" {
+		return fmt.Errorf("plot_no is required")
+	}
+	if u.LandSize < 0 {
+		return fmt.Errorf("land_size must not be negative")
+	}
+	if u.Price < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+	if u.Deposit != nil && *u.Deposit <= 0 {
+		return fmt.Errorf("deposit must be more than 0")
+	}
+	if u.Status == "" {
+		u.Status = StatusAvailable
+	}
+	return ValidateStatus(u.Status)
+}
+
+type UnitFilter struct {
+	ProjectId    int    `query:"-"`
+	HouseModelId int    `query:"house_model_id"`
+	Status       string `query:"status"`
+	Search       string `query:"search"` // plot_no
+	*entities.PaginationReq
+}
+
+type ImportReq struct {
+	Units []*Unit `json:"units"`
+}
+
+type ImportRes struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+}
+
+// Availability summarises the plots of one house model.
+type Availability struct {
+	HouseModelId   *int     `db:"house_model_id" json:"house_model_id"`
+	HouseModelName string   `db:"house_model_name" json:"house_model_name"`
+	Available      int      `db:"available" json:"available"`
+	Total          int      `db:"total" json:"total"`
+	MinPrice       *float64 `db:"min_price" json:"min_price"`
+}
+
+type Reservation struct {
+	Id          int     `db:"id" json:"id"`
+	UnitId      *int    `db:"unit_id" json:"unit_id"`
+	ProjectId   int     `db:"project_id" json:"project_id"`
+	ProjectName string  `db:"project_name" json:"project_name"`
+	PlotNo      string  `db:"plot_no" json:"plot_no"`
+	Name        string  `db:"name" json:"name"`
+	Tel         string  `db:"tel" json:"tel"`
+	Email       string  `db:"email" json:"email"`
+	Note        string  `db:"note" json:"note"`
+	Deposit     float64 `db:"deposit" json:"deposit"`
+	Status      string  `db:"status" json:"status"`
+	PaymentRef  string  `db:"payment_ref" json:"payment_ref"`
+	CheckoutUrl string  `db:"checkout_url" json:"checkout_url"`
+	ExpiresAt   string  `db:"expires_at" json:"expires_at"`
+	PaidAt      *string `db:"paid_at" json:"paid_at"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
+	UpdatedAt   string  `db:"updated_at" json:"updated_at"`
+}
+
+type ReservationReq struct {
+	ProjectId int    `json:"-"`
+	UnitId    int    `json:"-"`
+	Ip        string `json:"-"`
+	Name      string `json:"name" form:"name"`
+	Tel       string `json:"tel" form:"tel"`
+	Email     string `json:"email" form:"email"`
+	Note      string `json:"note" form:"note"`
+}
+
+func (r *ReservationReq) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Tel = strings.TrimSpace(r.Tel)
+	r.Email = strings.TrimSpace(r.Email)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Tel == "" {
+		return fmt.Errorf("tel is required")
+	}
+	return nil
+}
+
+type ReservationFilter struct {
+	ProjectId int    `query:"project_id"`
+	Status    string `query:"status"`
+	Search    string `query:"search"` // name,tel,email,plot_no
+	*entities.PaginationReq
+}
@@ -0,0 +1,575 @@
+package unitsHandlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/units"
+	"github.com/yporn/sirarom-backend/modules/units/unitsRepositories"
+	"github.com/yporn/sirarom-backend/modules/units/unitsUsecases"
+	"github.com/yporn/sirarom-backend/pkg/payments"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type unitsHandlersErrCode string
+
+const (
+	findUnitErr           unitsHandlersErrCode = "units-001"
+	findOneUnitErr        unitsHandlersErrCode = "units-002"
+	insertUnitErr         unitsHandlersErrCode = "units-003"
+	updateUnitErr         unitsHandlersErrCode = "units-004"
+	deleteUnitErr         unitsHandlersErrCode = "units-005"
+	importUnitsErr        unitsHandlersErrCode = "units-006"
+	findAvailabilityErr   unitsHandlersErrCode = "units-007"
+	reserveUnitErr        unitsHandlersErrCode = "units-008"
+	paymentCallbackErr    unitsHandlersErrCode = "units-009"
+	findReservationErr    unitsHandlersErrCode = "units-010"
+	findOneReservationErr unitsHandlersErrCode = "units-011"
+)
+
+type IUnitsHandler interface {
+	FindUnit(c *fiber.Ctx) error
+	FindAdminUnit(c *fiber.Ctx) error
+	FindOneUnit(c *fiber.Ctx) error
+	AddUnit(c *fiber.Ctx) error
+	UpdateUnit(c *fiber.Ctx) error
+	DeleteUnit(c *fiber.Ctx) error
+	ImportUnits(c *fiber.Ctx) error
+	FindAvailability(c *fiber.Ctx) error
+	ReserveUnit(c *fiber.Ctx) error
+	PaymentCallback(c *fiber.Ctx) error
+	FindReservation(c *fiber.Ctx) error
+	FindOneReservation(c *fiber.Ctx) error
+}
+
+type unitsHandler struct {
+	cfg          config.IConfig
+	unitsUsecase unitsUsecases.IUnitsUsecase
+	db           *sql.DB
+}
+
+func UnitsHandler(cfg config.IConfig, unitsUsecase unitsUsecases.IUnitsUsecase, db *sql.DB) IUnitsHandler {
+	return &unitsHandler{
+		cfg:          cfg,
+		unitsUsecase: unitsUsecase,
+		db:           db,
+	}
+}
+
+func paramId(c *fiber.Ctx, key string) (int, error) {
+	return strconv.Atoi(strings.Trim(c.Params(key), " "))
+}
+
+func (h *unitsHandler) findUnit(c *fiber.Ctx, public bool) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := &units.UnitFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findUnitErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+
+	if req.Status != "" {
+		if err := units.ValidateStatus(req.Status); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(findUnitErr),
+				err.Error(),
+			).Res()
+		}
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.unitsUsecase.FindUnit(req)
+	if public {
+		for _, unit := range res.Data.([]*units.Unit) {
+			unit.Note = ""
+		}
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+// FindUnit lists the plots of a project for visitors, without the sales notes.
+func (h *unitsHandler) FindUnit(c *fiber.Ctx) error {
+	return h.findUnit(c, true)
+}
+
+func (h *unitsHandler) FindAdminUnit(c *fiber.Ctx) error {
+	return h.findUnit(c, false)
+}
+
+func (h *unitsHandler) FindOneUnit(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneUnitErr),
+			err.Error(),
+		).Res()
+	}
+	unitId, err := paramId(c, "unit_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	unit, err := h.unitsUsecase.FindOneUnit(projectId, unitId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneUnitErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, unit).Res()
+}
+
+func (h *unitsHandler) AddUnit(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(units.Unit)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertUnitErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(insertUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	unit, err := h.unitsUsecase.InsertUnit(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(insertUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", "เพิ่มแปลง : "+unit.PlotNo)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, unit).Res()
+}
+
+func (h *unitsHandler) UpdateUnit(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+	unitId, err := paramId(c, "unit_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	req, err := h.unitsUsecase.FindOneUnit(projectId, unitId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+	req.Id = unitId
+	req.ProjectId = projectId
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	unit, err := h.unitsUsecase.UpdateUnit(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "แก้ไขแปลง : "+unit.PlotNo)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, unit).Res()
+}
+
+func (h *unitsHandler) DeleteUnit(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteUnitErr),
+			err.Error(),
+		).Res()
+	}
+	unitId, err := paramId(c, "unit_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	unit, err := h.unitsUsecase.DeleteUnit(projectId, unitId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ลบแปลง : "+unit.PlotNo)
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// ImportUnits takes either a JSON body of units or a csv upload in the file
+// field, see units.ParseCsv for its columns.
+func (h *unitsHandler) ImportUnits(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importUnitsErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(units.ImportReq)
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(importUnitsErr),
+				err.Error(),
+			).Res()
+		}
+		if file.Size > int64(h.cfg.App().FileLimit()) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(importUnitsErr),
+				"file is too large",
+			).Res()
+		}
+		f, err := file.Open()
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(importUnitsErr),
+				err.Error(),
+			).Res()
+		}
+		defer f.Close()
+
+		if req.Units, err = units.ParseCsv(f); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(importUnitsErr),
+				err.Error(),
+			).Res()
+		}
+	} else if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importUnitsErr),
+			err.Error(),
+		).Res()
+	}
+
+	res, err := h.unitsUsecase.ImportUnits(projectId, req.Units)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importUnitsErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "created", fmt.Sprintf("นำเข้าแปลงของโครงการ %d : เพิ่ม %d แก้ไข %d", projectId, res.Inserted, res.Updated))
+	if err != nil {
+		// Handle error if logging fails
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+func (h *unitsHandler) FindAvailability(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findAvailabilityErr),
+			err.Error(),
+		).Res()
+	}
+
+	availability, err := h.unitsUsecase.FindAvailability(projectId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findAvailabilityErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, availability).Res()
+}
+
+func (h *unitsHandler) ReserveUnit(c *fiber.Ctx) error {
+	projectId, err := paramId(c, "project_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(reserveUnitErr),
+			err.Error(),
+		).Res()
+	}
+	unitId, err := paramId(c, "unit_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(reserveUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	req := new(units.ReservationReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(reserveUnitErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProjectId = projectId
+	req.UnitId = unitId
+	req.Ip = c.IP()
+
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(reserveUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	reservation, err := h.unitsUsecase.ReserveUnit(req)
+	if err != nil {
+		if errors.Is(err, unitsRepositories.ErrUnitUnavailable) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrConflict.Code,
+				string(reserveUnitErr),
+				err.Error(),
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(reserveUnitErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogSystemActivity(h.db, "created", "จองแปลง "+reservation.PlotNo+" โครงการ "+reservation.ProjectName+" : "+reservation.Name)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			"Failed to log activity",
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusCreated, reservation).Res()
+}
+
+// PaymentCallback is called by the payment gateway when a deposit is settled.
+// A deposit paid too late is answered with 200 so the gateway stops retrying,
+// the sales team picks it up from the activity log for a refund.
+func (h *unitsHandler) PaymentCallback(c *fiber.Ctx) error {
+	reservation, err := h.unitsUsecase.HandlePaymentCallback(c.Body(), c.Get(payments.SignatureHeader))
+	if err != nil {
+		if errors.Is(err, unitsRepositories.ErrReservationLate) {
+			err = utils.LogSystemActivity(h.db, "alerted", "ได้รับเงินมัดจำหลังหมดเวลาจอง แปลงถูกจองไปแล้ว ต้องคืนเงิน : "+reservation.PaymentRef+" "+reservation.Name)
+			if err != nil {
+				return entities.NewResponse(c).Error(
+					fiber.ErrInternalServerError.Code,
+					"Failed to log activity",
+					err.Error(),
+				).Res()
+			}
+			return entities.NewResponse(c).Success(fiber.StatusOK, reservation).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(paymentCallbackErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Log activity
+	err = utils.LogSystemActivity(h.db, "updated", "ชำระเงินมัดจำแปลง "+reservation.PlotNo+" โครงการ "+reservation.ProjectName+" : "+reservation.Status)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			"Failed to log activity",
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, reservation).Res()
+}
+
+func (h *unitsHandler) FindReservation(c *fiber.Ctx) error {
+	req := &units.ReservationFilter{
+		PaginationReq: &entities.PaginationReq{},
+	}
+
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findReservationErr),
+			err.Error(),
+		).Res()
+	}
+
+	switch req.Status {
+	case "", units.ReservationPending, units.ReservationConfirmed, units.ReservationReleased:
+	default:
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findReservationErr),
+			fmt.Sprintf("status is invalid: %s", req.Status),
+		).Res()
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	if req.Limit < 5 {
+		req.Limit = 20
+	}
+
+	res := h.unitsUsecase.FindReservation(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, res).Res()
+}
+
+func (h *unitsHandler) FindOneReservation(c *fiber.Ctx) error {
+	reservationId, err := paramId(c, "reservation_id")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneReservationErr),
+			err.Error(),
+		).Res()
+	}
+
+	reservation, err := h.unitsUsecase.FindOneReservation(reservationId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneReservationErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, reservation).Res()
+}
@@ -0,0 +1,655 @@
+package unitsRepositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yporn/sirarom-backend/modules/units"
+)
+
+var (
+	// ErrUnitUnavailable is returned when the plot is already held, reserved or sold.
+	ErrUnitUnavailable = errors.New("this plot is not available, please pick another one")
+	// ErrReservationLate is returned for a deposit paid after the hold was released
+	// and the plot was taken by someone else.
+	ErrReservationLate = errors.New("the hold of this reservation has been released")
+)
+
+type IUnitsRepository interface {
+	FindProjectName(projectId int) (string, error)
+	HouseModelInProject(projectId, houseModelId int) (bool, error)
+	FindOneUnit(projectId, unitId int) (*units.Unit, error)
+	FindUnit(req *units.UnitFilter) ([]*units.Unit, int)
+	InsertUnit(req *units.Unit) error
+	UpdateUnit(req *units.Unit) error
+	DeleteUnit(projectId, unitId int) error
+	ImportUnits(projectId int, req []*units.Unit) (*units.ImportRes, error)
+	FindAvailability(projectId int) ([]*units.Availability, error)
+	InsertReservation(req *units.ReservationReq, defaultDeposit float64, holdMinutes int) (int, error)
+	UpdateReservationCharge(reservationId int, ref, checkoutUrl string) error
+	FindOneReservation(reservationId int) (*units.Reservation, error)
+	FindReservationByRef(ref string) (*units.Reservation, error)
+	FindReservation(req *units.ReservationFilter) ([]*units.Reservation, int)
+	ConfirmReservation(reservationId int) error
+	ReleaseReservation(reservationId int) (bool, error)
+	ReleaseExpiredReservations() ([]*units.Reservation, error)
+}
+
+type unitsRepository struct {
+	db *sqlx.DB
+}
+
+func UnitsRepository(db *sqlx.DB) IUnitsRepository {
+	return &unitsRepository{
+		db: db,
+	}
+}
+
+func (r *unitsRepository) FindProjectName(projectId int) (string, error) {
+	var name sql.NullString
+	if err := r.db.Get(&name, `SELECT "name" FROM "projects" WHERE "id" = $1 AND "deleted_at" IS NULL;`, projectId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("project not found")
+		}
+		return "", fmt.Errorf("get project failed: %v", err)
+	}
+	return name.String, nil
+}
+
+func (r *unitsRepository) HouseModelInProject(projectId, houseModelId int) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM "house_models"
+		WHERE "id" = $1
+		AND "project_id" = $2
+		AND "deleted_at" IS NULL
+	);`
+
+	var ok bool
+	if err := r.db.Get(&ok, query, houseModelId, projectId); err != nil {
+		return false, fmt.Errorf("check house model failed: %v", err)
+	}
+	return ok, nil
+}
+
+const unitColumns = `
+		"u"."id",
+		"u"."project_id",
+		"u"."house_model_id",
+		COALESCE("hm"."name", '') AS "house_model_name",
+		"u"."plot_no",
+		"u"."land_size",
+		"u"."price",
+		"u"."deposit",
+		"u"."status",
+		COALESCE("u"."note", '') AS "note",
+		"u"."created_at",
+		"u"."updated_at"`
+
+func (r *unitsRepository) FindOneUnit(projectId, unitId int) (*units.Unit, error) {
+	query := fmt.Sprintf(`
+	SELECT%s
+	FROM "project_units" "u"
+	LEFT JOIN "house_models" "hm" ON "hm"."id" = "u"."house_model_id"
+	WHERE "u"."id" = $1
+	AND "u"."project_id" = $2;`, unitColumns)
+
+	unit := new(units.Unit)
+	if err := r.db.Get(unit, query, unitId, projectId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("unit %d not found", unitId)
+		}
+		return nil, fmt.Errorf("get unit failed: %v", err)
+	}
+	return unit, nil
+}
+
+func (r *unitsRepository) FindUnit(req *units.UnitFilter) ([]*units.Unit, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	result := make([]*units.Unit, 0)
+
+	where := `
+	WHERE "u"."project_id" = $1`
+	values := []any{req.ProjectId}
+
+	if req.HouseModelId != 0 {
+		values = append(values, req.HouseModelId)
+		where += fmt.Sprintf(`
+	AND "u"."house_model_id" = $%d`, len(values))
+	}
+	if req.Status != "" {
+		values = append(values, req.Status)
+		where += fmt.Sprintf(`
+	AND "u"."status" = $%d`, len(values))
+	}
+	if req.Search != "" {
+		values = append(values, "%"+strings.ToLower(req.Search)+"%")
+		where += fmt.Sprintf(`
+	AND LOWER("u"."plot_no") LIKE $%d`, len(values))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT%s
+		FROM "project_units" "u"
+		LEFT JOIN "house_models" "hm" ON "hm"."id" = "u"."house_model_id"%s
+		ORDER BY length("u"."plot_no") ASC, "u"."plot_no" ASC
+		OFFSET $%d LIMIT $%d
+	) AS "t";`, unitColumns, where, len(values)+1, len(values)+2)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, append(values, (req.Page-1)*req.Limit, req.Limit)...); err != nil {
+		log.Printf("find units failed: %v\n", err)
+		return result, 0
+	}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		log.Printf("unmarshal units failed: %v\n", err)
+		return result, 0
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM "project_units" "u"%s;`, where), values...); err != nil {
+		log.Printf("count units failed: %v\n", err)
+		return result, 0
+	}
+	return result, count
+}
+
+func (r *unitsRepository) InsertUnit(req *units.Unit) error {
+	query := `
+	INSERT INTO "project_units" (
+		"project_id",
+		"house_model_id",
+		"plot_no",
+		"land_size",
+		"price",
+		"deposit",
+		"status",
+		"note"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING "id";`
+
+	if err := r.db.QueryRowContext(
+		context.Background(),
+		query,
+		req.ProjectId,
+		req.HouseModelId,
+		req.PlotNo,
+		req.LandSize,
+		req.Price,
+		req.Deposit,
+		req.Status,
+		req.Note,
+	).Scan(&req.Id); err != nil {
+		if strings.Contains(err.Error(), "project_units_project_id_plot_no_key") {
+			return fmt.Errorf("plot_no %s already exists", req.PlotNo)
+		}
+		return fmt.Errorf("insert unit failed: %v", err)
+	}
+	return nil
+}
+
+func (r *unitsRepository) UpdateUnit(req *units.Unit) error {
+	query := `
+	UPDATE "project_units" SET
+		"house_model_id" = $3,
+		"plot_no" = $4,
+		"land_size" = $5,
+		"price" = $6,
+		"deposit" = $7,
+		"status" = $8,
+		"note" = $9
+	WHERE "id" = $1
+	AND "project_id" = $2;`
+
+	res, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		req.Id,
+		req.ProjectId,
+		req.HouseModelId,
+		req.PlotNo,
+		req.LandSize,
+		req.Price,
+		req.Deposit,
+		req.Status,
+		req.Note,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "project_units_project_id_plot_no_key") {
+			return fmt.Errorf("plot_no %s already exists", req.PlotNo)
+		}
+		return fmt.Errorf("update unit failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("unit %d not found", req.Id)
+	}
+	return nil
+}
+
+func (r *unitsRepository) DeleteUnit(projectId, unitId int) error {
+	query := `DELETE FROM "project_units" WHERE "id" = $1 AND "project_id" = $2;`
+
+	res, err := r.db.ExecContext(context.Background(), query, unitId, projectId)
+	if err != nil {
+		return fmt.Errorf("delete unit failed: %v", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("unit %d not found", unitId)
+	}
+	return nil
+}
+
+// ImportUnits inserts the plots of a project, or updates them when the plot
+// number already exists, all or nothing. An empty status keeps the current one.
+func (r *unitsRepository) ImportUnits(projectId int, req []*units.Unit) (*units.ImportRes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO "project_units" (
+		"project_id",
+		"house_model_id",
+		"plot_no",
+		"land_size",
+		"price",
+		"deposit",
+		"status",
+		"note"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, '')::unit_status, 'available'), $8)
+	ON CONFLICT ("project_id", "plot_no") DO UPDATE SET
+		"house_model_id" = EXCLUDED."house_model_id",
+		"land_size" = EXCLUDED."land_size",
+		"price" = EXCLUDED."price",
+		"deposit" = EXCLUDED."deposit",
+		"status" = COALESCE(NULLIF($7, '')::unit_status, "project_units"."status"),
+		"note" = EXCLUDED."note"
+	RETURNING ("xmax" = 0) AS "inserted";`
+
+	res := new(units.ImportRes)
+	for i, u := range req {
+		var inserted bool
+		if err := tx.QueryRowContext(
+			ctx,
+			query,
+			projectId,
+			u.HouseModelId,
+			u.PlotNo,
+			u.LandSize,
+			u.Price,
+			u.Deposit,
+			u.Status,
+			u.Note,
+		).Scan(&inserted); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("import unit %d (%s) failed: %v", i+1, u.PlotNo, err)
+		}
+		if inserted {
+			res.Inserted++
+		} else {
+			res.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// FindAvailability counts the plots of each published house model of a
+// project. Plots without a house model are grouped under a null id.
+func (r *unitsRepository) FindAvailability(projectId int) ([]*units.Availability, error) {
+	query := `
+	SELECT
+		"u"."house_model_id",
+		COALESCE("hm"."name", '') AS "house_model_name",
+		COUNT(*) FILTER (WHERE "u"."status" = 'available') AS "available",
+		COUNT(*) AS "total",
+		MIN("u"."price") FILTER (WHERE "u"."status" = 'available') AS "min_price"
+	FROM "project_units" "u"
+	LEFT JOIN "house_models" "hm" ON "hm"."id" = "u"."house_model_id"
+	WHERE "u"."project_id" = $1
+	AND ("u"."house_model_id" IS NULL OR ("hm"."display" = 'published' AND "hm"."deleted_at" IS NULL))
+	GROUP BY "u"."house_model_id", "hm"."name", "hm"."index"
+	ORDER BY "hm"."index" ASC NULLS LAST;`
+
+	availability := make([]*units.Availability, 0)
+	if err := r.db.Select(&availability, query, projectId); err != nil {
+		return nil, fmt.Errorf("find unit availability failed: %v", err)
+	}
+	return availability, nil
+}
+
+// InsertReservation holds an available plot for holdMinutes and records the
+// customer. The deposit is the plot's own or defaultDeposit.
+func (r *unitsRepository) InsertReservation(req *units.ReservationReq, defaultDeposit float64, holdMinutes int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		plotNo  string
+		deposit float64
+	)
+	if err := tx.QueryRowContext(
+		ctx,
+		`
+	UPDATE "project_units" SET
+		"status" = 'reserved'
+	WHERE "id" = $1
+	AND "project_id" = $2
+	AND "status" = 'available'
+	RETURNING "plot_no", COALESCE("deposit", $3);`,
+		req.UnitId,
+		req.ProjectId,
+		defaultDeposit,
+	).Scan(&plotNo, &deposit); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUnitUnavailable
+		}
+		return 0, fmt.Errorf("hold unit failed: %v", err)
+	}
+
+	query := `
+	INSERT INTO "unit_reservations" (
+		"unit_id",
+		"project_id",
+		"plot_no",
+		"name",
+		"tel",
+		"email",
+		"note",
+		"deposit",
+		"expires_at",
+		"ip"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now() + make_interval(mins => $9), $10)
+	RETURNING "id";`
+
+	var reservationId int
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		req.UnitId,
+		req.ProjectId,
+		plotNo,
+		req.Name,
+		req.Tel,
+		req.Email,
+		req.Note,
+		deposit,
+		holdMinutes,
+		req.Ip,
+	).Scan(&reservationId); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("insert reservation failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return reservationId, nil
+}
+
+func (r *unitsRepository) UpdateReservationCharge(reservationId int, ref, checkoutUrl string) error {
+	query := `
+	UPDATE "unit_reservations" SET
+		"payment_ref" = $2,
+		"checkout_url" = $3
+	WHERE "id" = $1;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, reservationId, ref, checkoutUrl); err != nil {
+		return fmt.Errorf("update reservation charge failed: %v", err)
+	}
+	return nil
+}
+
+const reservationColumns = `
+		"r"."id",
+		"r"."unit_id",
+		"r"."project_id",
+		COALESCE("p"."name", '') AS "project_name",
+		"r"."plot_no",
+		"r"."name",
+		"r"."tel",
+		COALESCE("r"."email", '') AS "email",
+		COALESCE("r"."note", '') AS "note",
+		"r"."deposit",
+		"r"."status",
+		COALESCE("r"."payment_ref", '') AS "payment_ref",
+		COALESCE("r"."checkout_url", '') AS "checkout_url",
+		"r"."expires_at",
+		"r"."paid_at",
+		"r"."created_at",
+		"r"."updated_at"`
+
+func (r *unitsRepository) findOneReservation(where string, arg any) (*units.Reservation, error) {
+	query := fmt.Sprintf(`
+	SELECT%s
+	FROM "unit_reservations" "r"
+	LEFT JOIN "projects" "p" ON "p"."id" = "r"."project_id"
+	WHERE %s = $1;`, reservationColumns, where)
+
+	reservation := new(units.Reservation)
+	if err := r.db.Get(reservation, query, arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("reservation %v not found", arg)
+		}
+		return nil, fmt.Errorf("get reservation failed: %v", err)
+	}
+	return reservation, nil
+}
+
+func (r *unitsRepository) FindOneReservation(reservationId int) (*units.Reservation, error) {
+	return r.findOneReservation(`"r"."id"`, reservationId)
+}
+
+func (r *unitsRepository) FindReservationByRef(ref string) (*units.Reservation, error) {
+	return r.findOneReservation(`"r"."payment_ref"`, ref)
+}
+
+func (r *unitsRepository) FindReservation(req *units.ReservationFilter) ([]*units.Reservation, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	result := make([]*units.Reservation, 0)
+
+	where := `
+	WHERE 1 = 1`
+	values := make([]any, 0)
+
+	if req.ProjectId != 0 {
+		values = append(values, req.ProjectId)
+		where += fmt.Sprintf(`
+	AND "r"."project_id" = $%d`, len(values))
+	}
+	if req.Status != "" {
+		values = append(values, req.Status)
+		where += fmt.Sprintf(`
+	AND "r"."status" = $%d`, len(values))
+	}
+	if req.Search != "" {
+		values = append(values, "%"+strings.ToLower(req.Search)+"%")
+		where += fmt.Sprintf(`
+	AND (
+		LOWER("r"."name") LIKE $%d OR
+		LOWER("r"."tel") LIKE $%d OR
+		LOWER("r"."email") LIKE $%d OR
+		LOWER("r"."plot_no") LIKE $%d
+	)`, len(values), len(values), len(values), len(values))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT%s
+		FROM "unit_reservations" "r"
+		LEFT JOIN "projects" "p" ON "p"."id" = "r"."project_id"%s
+		ORDER BY "r"."id" DESC
+		OFFSET $%d LIMIT $%d
+	) AS "t";`, reservationColumns, where, len(values)+1, len(values)+2)
+
+	bytes := make([]byte, 0)
+	if err := r.db.GetContext(ctx, &bytes, query, append(values, (req.Page-1)*req.Limit, req.Limit)...); err != nil {
+		log.Printf("find reservations failed: %v\n", err)
+		return result, 0
+	}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		log.Printf("unmarshal reservations failed: %v\n", err)
+		return result, 0
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM "unit_reservations" "r"%s;`, where), values...); err != nil {
+		log.Printf("count reservations failed: %v\n", err)
+		return result, 0
+	}
+	return result, count
+}
+
+// ConfirmReservation marks the deposit as paid. A deposit paid after the hold
+// was released still takes the plot if nobody else has, otherwise it fails with
+// ErrReservationLate. Confirming twice is a no-op.
+func (r *unitsRepository) ConfirmReservation(reservationId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var (
+		status string
+		unitId sql.NullInt64
+	)
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT "status", "unit_id" FROM "unit_reservations" WHERE "id" = $1 FOR UPDATE;`,
+		reservationId,
+	).Scan(&status, &unitId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("get reservation failed: %v", err)
+	}
+
+	switch status {
+	case units.ReservationConfirmed:
+		tx.Rollback()
+		return nil
+	case units.ReservationReleased:
+		if !unitId.Valid {
+			tx.Rollback()
+			return ErrReservationLate
+		}
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE "project_units" SET "status" = 'reserved' WHERE "id" = $1 AND "status" = 'available';`,
+			unitId.Int64,
+		)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("hold unit failed: %v", err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			tx.Rollback()
+			return ErrReservationLate
+		}
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE "unit_reservations" SET "status" = 'confirmed', "paid_at" = now() WHERE "id" = $1;`,
+		reservationId,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("confirm reservation failed: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseReservation ends a pending hold and frees its plot. It reports false
+// when the reservation was no longer pending.
+func (r *unitsRepository) ReleaseReservation(reservationId int) (bool, error) {
+	query := `
+	WITH "released" AS (
+		UPDATE "unit_reservations" SET
+			"status" = 'released'
+		WHERE "id" = $1
+		AND "status" = 'pending'
+		RETURNING "unit_id"
+	), "freed" AS (
+		UPDATE "project_units" "u" SET
+			"status" = 'available'
+		FROM "released" "r"
+		WHERE "u"."id" = "r"."unit_id"
+		AND "u"."status" = 'reserved'
+	)
+	SELECT COUNT(*) FROM "released";`
+
+	var count int
+	if err := r.db.Get(&count, query, reservationId); err != nil {
+		return false, fmt.Errorf("release reservation failed: %v", err)
+	}
+	return count > 0, nil
+}
+
+// ReleaseExpiredReservations ends the pending holds that ran out of time.
+func (r *unitsRepository) ReleaseExpiredReservations() ([]*units.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	query := `
+	WITH "released" AS (
+		UPDATE "unit_reservations" SET
+			"status" = 'released'
+		WHERE "status" = 'pending'
+		AND "expires_at" <= now()
+		RETURNING "id", "unit_id", "project_id", "plot_no", "name"
+	), "freed" AS (
+		UPDATE "project_units" "u" SET
+			"status" = 'available'
+		FROM "released" "r"
+		WHERE "u"."id" = "r"."unit_id"
+		AND "u"."status" = 'reserved'
+	)
+	SELECT
+		"r"."id",
+		"r"."plot_no",
+		"r"."name",
+		COALESCE("p"."name", '') AS "project_name"
+	FROM "released" "r"
+	LEFT JOIN "projects" "p" ON "p"."id" = "r"."project_id";`
+
+	released := make([]*units.Reservation, 0)
+	if err := r.db.SelectContext(ctx, &released, query); err != nil {
+		return nil, fmt.Errorf("release expired reservations failed: %v", err)
+	}
+	return released, nil
+}
@@ -0,0 +1,255 @@
+package unitsUsecases
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/entities"
+	"github.com/yporn/sirarom-backend/modules/units"
+	"github.com/yporn/sirarom-backend/modules/units/unitsRepositories"
+	"github.com/yporn/sirarom-backend/pkg/payments"
+	"github.com/yporn/sirarom-backend/pkg/utils"
+)
+
+type IUnitsUsecase interface {
+	FindOneUnit(projectId, unitId int) (*units.Unit, error)
+	FindUnit(req *units.UnitFilter) *entities.PaginateRes
+	InsertUnit(req *units.Unit) (*units.Unit, error)
+	UpdateUnit(req *units.Unit) (*units.Unit, error)
+	DeleteUnit(projectId, unitId int) (*units.Unit, error)
+	ImportUnits(projectId int, req []*units.Unit) (*units.ImportRes, error)
+	FindAvailability(projectId int) ([]*units.Availability, error)
+	ReserveUnit(req *units.ReservationReq) (*units.Reservation, error)
+	HandlePaymentCallback(body []byte, signature string) (*units.Reservation, error)
+	FindOneReservation(reservationId int) (*units.Reservation, error)
+	FindReservation(req *units.ReservationFilter) *entities.PaginateRes
+	RunReleaseExpired()
+}
+
+type unitsUsecase struct {
+	cfg             config.IConfig
+	unitsRepository unitsRepositories.IUnitsRepository
+	gateway         payments.IPaymentGateway
+	db              *sql.DB
+}
+
+func UnitsUsecase(cfg config.IConfig, unitsRepository unitsRepositories.IUnitsRepository, gateway payments.IPaymentGateway, db *sql.DB) IUnitsUsecase {
+	return &unitsUsecase{
+		cfg:             cfg,
+		unitsRepository: unitsRepository,
+		gateway:         gateway,
+		db:              db,
+	}
+}
+
+func (u *unitsUsecase) FindOneUnit(projectId, unitId int) (*units.Unit, error) {
+	return u.unitsRepository.FindOneUnit(projectId, unitId)
+}
+
+func (u *unitsUsecase) FindUnit(req *units.UnitFilter) *entities.PaginateRes {
+	unitsData, count := u.unitsRepository.FindUnit(req)
+
+	return &entities.PaginateRes{
+		Data:      unitsData,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+func (u *unitsUsecase) checkHouseModel(projectId int, houseModelId *int) error {
+	if houseModelId == nil {
+		return nil
+	}
+	ok, err := u.unitsRepository.HouseModelInProject(projectId, *houseModelId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("house model %d is not in this project", *houseModelId)
+	}
+	return nil
+}
+
+func (u *unitsUsecase) InsertUnit(req *units.Unit) (*units.Unit, error) {
+	if _, err := u.unitsRepository.FindProjectName(req.ProjectId); err != nil {
+		return nil, err
+	}
+	if err := u.checkHouseModel(req.ProjectId, req.HouseModelId); err != nil {
+		return nil, err
+	}
+	if err := u.unitsRepository.InsertUnit(req); err != nil {
+		return nil, err
+	}
+	return u.unitsRepository.FindOneUnit(req.ProjectId, req.Id)
+}
+
+func (u *unitsUsecase) UpdateUnit(req *units.Unit) (*units.Unit, error) {
+	if err := u.checkHouseModel(req.ProjectId, req.HouseModelId); err != nil {
+		return nil, err
+	}
+	if err := u.unitsRepository.UpdateUnit(req); err != nil {
+		return nil, err
+	}
+	return u.unitsRepository.FindOneUnit(req.ProjectId, req.Id)
+}
+
+// DeleteUnit removes a plot that is still available. Reserved and sold plots
+// are kept for the sales record.
+func (u *unitsUsecase) DeleteUnit(projectId, unitId int) (*units.Unit, error) {
+	unit, err := u.unitsRepository.FindOneUnit(projectId, unitId)
+	if err != nil {
+		return nil, err
+	}
+	if unit.Status != units.StatusAvailable {
+		return nil, fmt.Errorf("plot %s is %s and cannot be deleted", unit.PlotNo, unit.Status)
+	}
+	if err := u.unitsRepository.DeleteUnit(projectId, unitId); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// ImportUnits checks every row before anything is written, so a bad row
+// rejects the whole import.
+func (u *unitsUsecase) ImportUnits(projectId int, req []*units.Unit) (*units.ImportRes, error) {
+	if _, err := u.unitsRepository.FindProjectName(projectId); err != nil {
+		return nil, err
+	}
+	if len(req) == 0 {
+		return nil, fmt.Errorf("units are required")
+	}
+
+	plots := make(map[string]bool, len(req))
+	houseModels := make(map[int]bool)
+	for i, unit := range req {
+		status := unit.Status
+		if err := unit.Validate(); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		// an empty status keeps the status of an existing plot
+		unit.Status = status
+
+		if plots[unit.PlotNo] {
+			return nil, fmt.Errorf("row %d: plot_no %s is duplicated", i+1, unit.PlotNo)
+		}
+		plots[unit.PlotNo] = true
+
+		if unit.HouseModelId != nil && !houseModels[*unit.HouseModelId] {
+			if err := u.checkHouseModel(projectId, unit.HouseModelId); err != nil {
+				return nil, fmt.Errorf("row %d: %v", i+1, err)
+			}
+			houseModels[*unit.HouseModelId] = true
+		}
+	}
+
+	return u.unitsRepository.ImportUnits(projectId, req)
+}
+
+func (u *unitsUsecase) FindAvailability(projectId int) ([]*units.Availability, error) {
+	if _, err := u.unitsRepository.FindProjectName(projectId); err != nil {
+		return nil, err
+	}
+	return u.unitsRepository.FindAvailability(projectId)
+}
+
+// ReserveUnit holds the plot for APP_RESERVATION_HOLD_MINUTES and opens a
+// deposit charge. The hold is let go again when the charge cannot be made.
+func (u *unitsUsecase) ReserveUnit(req *units.ReservationReq) (*units.Reservation, error) {
+	// free plots whose hold ran out since the last sweep
+	u.RunReleaseExpired()
+
+	if _, err := u.unitsRepository.FindProjectName(req.ProjectId); err != nil {
+		return nil, err
+	}
+
+	reservationId, err := u.unitsRepository.InsertReservation(req, u.cfg.App().ReservationDeposit(), u.cfg.App().ReservationHoldMinutes())
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := u.unitsRepository.FindOneReservation(reservationId)
+	if err != nil {
+		return nil, err
+	}
+
+	charge, err := u.gateway.CreateCharge(&payments.ChargeReq{
+		Ref:         "reservation-" + strconv.Itoa(reservationId),
+		Amount:      reservation.Deposit,
+		Description: fmt.Sprintf("%s แปลง %s", reservation.ProjectName, reservation.PlotNo),
+		Email:       reservation.Email,
+	})
+	if err != nil {
+		if _, releaseErr := u.unitsRepository.ReleaseReservation(reservationId); releaseErr != nil {
+			log.Printf("units: %v\n", releaseErr)
+		}
+		return nil, fmt.Errorf("create deposit charge failed: %v", err)
+	}
+
+	if err := u.unitsRepository.UpdateReservationCharge(reservationId, charge.Ref, charge.CheckoutUrl); err != nil {
+		return nil, err
+	}
+	return u.unitsRepository.FindOneReservation(reservationId)
+}
+
+// HandlePaymentCallback confirms the reservation of a paid deposit and lets go
+// of the hold of a failed one.
+func (u *unitsUsecase) HandlePaymentCallback(body []byte, signature string) (*units.Reservation, error) {
+	callback, err := u.gateway.ParseCallback(body, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := u.unitsRepository.FindReservationByRef(callback.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	switch callback.Status {
+	case payments.StatusPaid:
+		if err := u.unitsRepository.ConfirmReservation(reservation.Id); err != nil {
+			return reservation, err
+		}
+	case payments.StatusFailed:
+		if _, err := u.unitsRepository.ReleaseReservation(reservation.Id); err != nil {
+			return nil, err
+		}
+	}
+	return u.unitsRepository.FindOneReservation(reservation.Id)
+}
+
+func (u *unitsUsecase) FindOneReservation(reservationId int) (*units.Reservation, error) {
+	return u.unitsRepository.FindOneReservation(reservationId)
+}
+
+func (u *unitsUsecase) FindReservation(req *units.ReservationFilter) *entities.PaginateRes {
+	reservations, count := u.unitsRepository.FindReservation(req)
+
+	return &entities.PaginateRes{
+		Data:      reservations,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}
+}
+
+// RunReleaseExpired frees the plots whose deposit was not paid in time.
+func (u *unitsUsecase) RunReleaseExpired() {
+	released, err := u.unitsRepository.ReleaseExpiredReservations()
+	if err != nil {
+		log.Printf("units: %v\n", err)
+		return
+	}
+	for _, r := range released {
+		details := fmt.Sprintf("หมดเวลาชำระเงินมัดจำ ยกเลิกการจองแปลง %s โครงการ %s : %s", r.PlotNo, r.ProjectName, r.Name)
+		if err := utils.LogSystemActivity(u.db, "updated", details); err != nil {
+			log.Printf("units: log activity failed: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package unitsUsecases
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/units"
+	"github.com/yporn/sirarom-backend/modules/units/unitsRepositories"
+	"github.com/yporn/sirarom-backend/pkg/payments"
+)
+
+const secret = "payment-secret"
+
+type fakeConfig struct {
+	config.IConfig
+}
+
+func (c *fakeConfig) App() config.IAppConfig { return &fakeApp{} }
+
+type fakeApp struct {
+	config.IAppConfig
+}
+
+func (a *fakeApp) ReservationDeposit() float64 { return 5000 }
+func (a *fakeApp) ReservationHoldMinutes() int { return 30 }
+
+type reservation struct {
+	unitId  int
+	status  string
+	ref     string
+	expired bool
+}
+
+// fakeUnits holds plots and their reservations in memory, changing their status
+// the way the queries of unitsRepository do.
+type fakeUnits struct {
+	unitsRepositories.IUnitsRepository
+	units        map[int]string // status by unit id
+	reservations map[int]*reservation
+}
+
+func newFakeUnits() *fakeUnits {
+	return &fakeUnits{
+		units:        map[int]string{1: units.StatusAvailable, 2: units.StatusAvailable},
+		reservations: make(map[int]*reservation),
+	}
+}
+
+func (r *fakeUnits) FindProjectName(projectId int) (string, error) {
+	return "Sirarom Village", nil
+}
+
+func (r *fakeUnits) InsertReservation(req *units.ReservationReq, defaultDeposit float64, holdMinutes int) (int, error) {
+	if r.units[req.UnitId] != units.StatusAvailable {
+		return 0, unitsRepositories.ErrUnitUnavailable
+	}
+	r.units[req.UnitId] = units.StatusReserved
+	id := len(r.reservations) + 1
+	r.reservations[id] = &reservation{unitId: req.UnitId, status: units.ReservationPending}
+	return id, nil
+}
+
+func (r *fakeUnits) UpdateReservationCharge(reservationId int, ref, checkoutUrl string) error {
+	r.reservations[reservationId].ref = ref
+	return nil
+}
+
+func (r *fakeUnits) FindOneReservation(reservationId int) (*units.Reservation, error) {
+	res, ok := r.reservations[reservationId]
+	if !ok {
+		return nil, fmt.Errorf("reservation not found")
+	}
+	unitId := res.unitId
+	return &units.Reservation{Id: reservationId, UnitId: &unitId, Deposit: 5000, Status: res.status, PaymentRef: res.ref}, nil
+}
+
+func (r *fakeUnits) FindReservationByRef(ref string) (*units.Reservation, error) {
+	for id, res := range r.reservations {
+		if res.ref == ref {
+			return r.FindOneReservation(id)
+		}
+	}
+	return nil, fmt.Errorf("reservation not found")
+}
+
+func (r *fakeUnits) ConfirmReservation(reservationId int) error {
+	res := r.reservations[reservationId]
+	switch res.status {
+	case units.ReservationConfirmed:
+		return nil
+	case units.ReservationReleased:
+		if r.units[res.unitId] != units.StatusAvailable {
+			return unitsRepositories.ErrReservationLate
+		}
+		r.units[res.unitId] = units.StatusReserved
+	}
+	res.status = units.ReservationConfirmed
+	return nil
+}
+
+func (r *fakeUnits) ReleaseReservation(reservationId int) (bool, error) {
+	res := r.reservations[reservationId]
+	if res.status != units.ReservationPending {
+		return false, nil
+	}
+	res.status = units.ReservationReleased
+	if r.units[res.unitId] == units.StatusReserved {
+		r.units[res.unitId] = units.StatusAvailable
+	}
+	return true, nil
+}
+
+func (r *fakeUnits) ReleaseExpiredReservations() ([]*units.Reservation, error) {
+	released := make([]*units.Reservation, 0)
+	for id, res := range r.reservations {
+		if res.status == units.ReservationPending && res.expired {
+			r.ReleaseReservation(id)
+			released = append(released, &units.Reservation{Id: id})
+		}
+	}
+	return released, nil
+}
+
+// reserve holds unit 1 and returns the usecase, its store and the payment ref.
+func reserve(t *testing.T) (IUnitsUsecase, *fakeUnits, string) {
+	t.Helper()
+
+	repository := newFakeUnits()
+	usecase := UnitsUsecase(&fakeConfig{}, repository, payments.FakeGateway(secret), nil)
+	res, err := usecase.ReserveUnit(&units.ReservationReq{ProjectId: 1, UnitId: 1, Name: "Somchai"})
+	if err != nil {
+		t.Fatalf("ReserveUnit failed: %v", err)
+	}
+	if res.Status != units.ReservationPending || repository.units[1] != units.StatusReserved {
+		t.Fatalf("reservation is %s and the plot %s, want pending and reserved", res.Status, repository.units[1])
+	}
+	return usecase, repository, res.PaymentRef
+}
+
+func callback(ref, status string) ([]byte, string) {
+	body := []byte(fmt.Sprintf(`{"ref":%q,"status":%q}`, ref, status))
+	return body, payments.Sign(secret, body)
+}
+
+func TestPaymentCallbackPaid(t *testing.T) {
+	usecase, repository, ref := reserve(t)
+
+	body, signature := callback(ref, payments.StatusPaid)
+	res, err := usecase.HandlePaymentCallback(body, signature)
+	if err != nil {
+		t.Fatalf("HandlePaymentCallback failed: %v", err)
+	}
+	if res.Status != units.ReservationConfirmed || repository.units[1] != units.StatusReserved {
+		t.Errorf("reservation is %s and the plot %s, want confirmed and reserved", res.Status, repository.units[1])
+	}
+
+	// the gateway may post the same callback again
+	if res, err := usecase.HandlePaymentCallback(body, signature); err != nil || res.Status != units.ReservationConfirmed {
+		t.Errorf("repeated callback = %v, %v, want confirmed", res, err)
+	}
+}
+
+func TestPaymentCallbackBadSignature(t *testing.T) {
+	usecase, repository, ref := reserve(t)
+
+	body, _ := callback(ref, payments.StatusPaid)
+	for _, signature := range []string{"", payments.Sign("other-secret", body)} {
+		if _, err := usecase.HandlePaymentCallback(body, signature); err == nil {
+			t.Errorf("callback signed %q was accepted", signature)
+		}
+	}
+	if status := repository.reservations[1].status; status != units.ReservationPending {
+		t.Errorf("reservation is %s after forged callbacks, want pending", status)
+	}
+}
+
+func TestPaymentCallbackFailedReleasesHold(t *testing.T) {
+	usecase, repository, ref := reserve(t)
+
+	body, signature := callback(ref, payments.StatusFailed)
+	res, err := usecase.HandlePaymentCallback(body, signature)
+	if err != nil {
+		t.Fatalf("HandlePaymentCallback failed: %v", err)
+	}
+	if res.Status != units.ReservationReleased || repository.units[1] != units.StatusAvailable {
+		t.Errorf("reservation is %s and the plot %s, want released and available", res.Status, repository.units[1])
+	}
+}
+
+func TestPaymentCallbackPaidAfterRelease(t *testing.T) {
+	t.Run("plot taken by someone else", func(t *testing.T) {
+		usecase, repository, ref := reserve(t)
+		repository.reservations[1].expired = true
+		repository.ReleaseExpiredReservations()
+		if _, err := usecase.ReserveUnit(&units.ReservationReq{ProjectId: 1, UnitId: 1, Name: "Somsri"}); err != nil {
+			t.Fatalf("second ReserveUnit failed: %v", err)
+		}
+
+		body, signature := callback(ref, payments.StatusPaid)
+		if _, err := usecase.HandlePaymentCallback(body, signature); !errors.Is(err, unitsRepositories.ErrReservationLate) {
+			t.Fatalf("late payment gave %v, want ErrReservationLate", err)
+		}
+		if status := repository.reservations[1].status; status != units.ReservationReleased {
+			t.Errorf("late reservation is %s, want released", status)
+		}
+		if status := repository.reservations[2].status; status != units.ReservationPending {
+			t.Errorf("hold of the other buyer is %s, want pending", status)
+		}
+	})
+
+	t.Run("plot still free", func(t *testing.T) {
+		usecase, repository, ref := reserve(t)
+		repository.reservations[1].expired = true
+		repository.ReleaseExpiredReservations()
+		if repository.units[1] != units.StatusAvailable {
+			t.Fatalf("expired hold left the plot %s", repository.units[1])
+		}
+
+		body, signature := callback(ref, payments.StatusPaid)
+		res, err := usecase.HandlePaymentCallback(body, signature)
+		if err != nil {
+			t.Fatalf("HandlePaymentCallback failed: %v", err)
+		}
+		if res.Status != units.ReservationConfirmed || repository.units[1] != units.StatusReserved {
+			t.Errorf("reservation is %s and the plot %s, want confirmed and reserved", res.Status, repository.units[1])
+		}
+	})
+}
+
+func TestPaymentCallbackUnknownRef(t *testing.T) {
+	usecase, _, _ := reserve(t)
+
+	body, signature := callback("fake_reservation-99", payments.StatusPaid)
+	if _, err := usecase.HandlePaymentCallback(body, signature); err == nil {
+		t.Errorf("callback of an unknown ref was accepted")
+	}
+}
@@ -1,8 +1,13 @@
 package users
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"golang.org/x/crypto/bcrypt"
@@ -51,8 +56,10 @@ type UserCredentialCheck struct {
 }
 
 type UserPassport struct {
-	User  *User      `json:"user"`
-	Token *UserToken `json:"token"`
+	User          *User               `json:"user,omitempty"`
+	Token         *UserToken          `json:"token,omitempty"`
+	TwoFactor     *TwoFactorChallenge `json:"two_factor,omitempty"`     // set instead of user and token when a code is needed
+	RecoveryCodes []string            `json:"recovery_codes,omitempty"` // set once when 2FA is set up during sign in
 }
 
 type UserToken struct {
@@ -75,6 +82,40 @@ type Oauth struct {
 	UserId string `db:"user_id" json:"user_id"`
 }
 
+// Session is an active login, one row of oauth.
+type Session struct {
+	Id         string `db:"id" json:"id"`
+	Device     string `db:"device" json:"device"` // user agent of the sign in
+	Ip         string `db:"ip" json:"ip"`
+	CreatedAt  string `db:"created_at" json:"created_at"`
+	LastUsedAt string `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  string `db:"expires_at" json:"expires_at"`
+	Current    bool   `db:"current" json:"current"` // the session making the request
+}
+
+// ErrSessionNotFound is returned when revoking a session the user does not have.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionDevice trims a user agent to what is kept on the session.
+func SessionDevice(userAgent string) string {
+	device := []rune(strings.TrimSpace(userAgent))
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	return string(device)
+}
+
+// RefreshTokenReusedError is returned when a refresh token that was already
+// rotated out comes back, which means it was copied. Its session is revoked.
+type RefreshTokenReusedError struct {
+	UserId  string
+	OauthId string
+}
+
+func (e *RefreshTokenReusedError) Error() string {
+	return "refresh token has already been used, the session is revoked"
+}
+
 type UserRemoveCredential struct {
 	OauthId string `db:"id" json:"oauth_id" form:"oauth_id"`
 }
@@ -101,4 +142,167 @@ type UserFilter struct {
 	Search string `query:"search"` // name & username & email
 	*entities.PaginationReq
 	*entities.SortReq
-}
\ No newline at end of file
+}
+
+// PasswordMinLength is the shortest password a reset link accepts.
+const PasswordMinLength = 8
+
+// ErrResetTokenInvalid is returned for reset tokens that are unknown, used or expired.
+var ErrResetTokenInvalid = errors.New("reset token is invalid or expired")
+
+type ForgotPasswordReq struct {
+	Email string `json:"email" form:"email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+func (r *ResetPasswordReq) Validate() error {
+	r.Token = strings.TrimSpace(r.Token)
+	if r.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+	if len([]rune(r.Password)) < PasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters", PasswordMinLength)
+	}
+	return nil
+}
+
+// HashToken is the form reset tokens and used refresh tokens are stored in.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	ErrTwoFactorCodeInvalid = errors.New("two factor code is invalid")
+	ErrTwoFactorChallenge   = errors.New("two factor challenge is invalid or expired")
+	ErrTwoFactorEnabled     = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two factor authentication has not been enrolled")
+	ErrTwoFactorRequired    = errors.New("two factor authentication is required for a role of the user")
+	ErrRoleNotFound         = errors.New("role not found")
+)
+
+// RecoveryCodeCount is how many recovery codes a user is given at a time.
+const RecoveryCodeCount = 10
+
+// TwoFactor is the 2FA state of a user.
+type TwoFactor struct {
+	Secret    *string `db:"totp_secret"`
+	EnabledAt *string `db:"totp_enabled_at"`
+	LastStep  int64   `db:"totp_last_step"`
+	Required  bool    `db:"required"` // a role of the user requires 2FA
+}
+
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// TwoFactorChallenge is returned by sign in in place of a passport. Its token is
+// sent back with a code to finish signing in.
+type TwoFactorChallenge struct {
+	Token         string `json:"token"`
+	ExpiresIn     int    `json:"expires_in"`     // seconds
+	SetupRequired bool   `json:"setup_required"` // enroll with the token before verifying
+}
+
+type TwoFactorChallengeReq struct {
+	Token string `json:"token" form:"token"`
+}
+
+type TwoFactorVerifyReq struct {
+	Token string `json:"token" form:"token"`
+	Code  string `json:"code" form:"code"` // authenticator code or recovery code
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" form:"code"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"` // otpauth uri to show as a QR code
+}
+
+type TwoFactorStatus struct {
+	Enabled       bool `json:"enabled"`
+	Required      bool `json:"required"`
+	RecoveryCodes int  `json:"recovery_codes"` // unused codes left
+}
+
+type RoleTwoFactorReq struct {
+	Required bool `json:"required" form:"required"`
+}
+
+// NormalizeRecoveryCode lets a recovery code be typed in any case, with or without its dash.
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// ErrInvalidCredentials is the one answer sign in gives for a wrong email or a
+// wrong password, so it does not tell which emails have an account.
+var ErrInvalidCredentials = errors.New("email or password is invalid")
+
+// LoginLockedError is returned while the email or the ip of a sign in is locked.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed sign in attempts, try again later"
+}
+
+// Kinds of login_throttles rows.
+const (
+	LoginThrottleEmail     = "email"
+	LoginThrottleIp        = "ip"
+	LoginThrottleTwoFactor = "two_factor" // keyed by user id
+)
+
+// TwoFactorFailedError is a 2FA code that was refused or sent while locked,
+// with the user whose challenge it answered.
+type TwoFactorFailedError struct {
+	UserId string
+	Err    error
+}
+
+func (e *TwoFactorFailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TwoFactorFailedError) Unwrap() error {
+	return e.Err
+}
+
+// LoginThrottle counts the recent failed sign ins of an email or an ip.
+type LoginThrottle struct {
+	Kind         string  `db:"kind" json:"kind"`
+	Key          string  `db:"key" json:"key"`
+	Failures     int     `db:"failures" json:"failures"`
+	LockedUntil  *string `db:"locked_until" json:"locked_until"`
+	LastFailedAt string  `db:"last_failed_at" json:"last_failed_at"`
+}
+
+// LoginEmailKey is the form an email is throttled under.
+func LoginEmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginBackoff is how long sign in waits after the nth failure in a row: nothing
+// for the first two, then 1s, 2s, 4s and so on, and the full lock from max on.
+func LoginBackoff(failures, max int, lock time.Duration) time.Duration {
+	if failures >= max {
+		return lock
+	}
+	if failures < 3 {
+		return 0
+	}
+	wait := time.Second << (failures - 3)
+	if wait > lock {
+		return lock
+	}
+	return wait
+}
@@ -2,7 +2,9 @@ package usersHandlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"path"
 	"strconv"
 	"strings"
@@ -21,15 +23,30 @@ import (
 type userHandlersErrCode string
 
 const (
-	SignUpErr             userHandlersErrCode = "users-001"
-	SignInErr             userHandlersErrCode = "users-002"
-	RefreshPassportErr    userHandlersErrCode = "users-003"
-	SignOutErr            userHandlersErrCode = "users-004"
-	GenerateAdminTokenErr userHandlersErrCode = "users-005"
-	UpdateUserErr         userHandlersErrCode = "users-006"
-	DeleteUserErr         userHandlersErrCode = "users-007"
-	FindOneUserErr        userHandlersErrCode = "users-008"
-	FindUserErr           userHandlersErrCode = "users-009"
+	SignUpErr              userHandlersErrCode = "users-001"
+	SignInErr              userHandlersErrCode = "users-002"
+	RefreshPassportErr     userHandlersErrCode = "users-003"
+	SignOutErr             userHandlersErrCode = "users-004"
+	GenerateAdminTokenErr  userHandlersErrCode = "users-005"
+	UpdateUserErr          userHandlersErrCode = "users-006"
+	DeleteUserErr          userHandlersErrCode = "users-007"
+	FindOneUserErr         userHandlersErrCode = "users-008"
+	FindUserErr            userHandlersErrCode = "users-009"
+	ForgotPasswordErr      userHandlersErrCode = "users-010"
+	ResetPasswordErr       userHandlersErrCode = "users-011"
+	FindSessionsErr        userHandlersErrCode = "users-012"
+	DeleteSessionErr       userHandlersErrCode = "users-013"
+	DeleteSessionsErr      userHandlersErrCode = "users-014"
+	VerifyTwoFactorErr     userHandlersErrCode = "users-015"
+	EnrollTwoFactorErr     userHandlersErrCode = "users-016"
+	FindTwoFactorErr       userHandlersErrCode = "users-017"
+	ActivateTwoFactorErr   userHandlersErrCode = "users-018"
+	DisableTwoFactorErr    userHandlersErrCode = "users-019"
+	RecoveryCodesErr       userHandlersErrCode = "users-020"
+	ResetTwoFactorErr      userHandlersErrCode = "users-021"
+	UpdateRoleTwoFactorErr userHandlersErrCode = "users-022"
+	FindLoginLocksErr      userHandlersErrCode = "users-023"
+	UnlockLoginErr         userHandlersErrCode = "users-024"
 )
 
 type IUsersHandler interface {
@@ -42,6 +59,25 @@ type IUsersHandler interface {
 	GenerateAdminToken(c *fiber.Ctx) error
 	UpdateUser(c *fiber.Ctx) error
 	DeleteUser(c *fiber.Ctx) error
+	ForgotPassword(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
+	FindMySessions(c *fiber.Ctx) error
+	DeleteMySession(c *fiber.Ctx) error
+	DeleteMySessions(c *fiber.Ctx) error
+	FindUserSessions(c *fiber.Ctx) error
+	DeleteUserSessions(c *fiber.Ctx) error
+	VerifyTwoFactor(c *fiber.Ctx) error
+	EnrollTwoFactorChallenge(c *fiber.Ctx) error
+	FindTwoFactor(c *fiber.Ctx) error
+	EnrollTwoFactor(c *fiber.Ctx) error
+	ActivateTwoFactor(c *fiber.Ctx) error
+	DisableTwoFactor(c *fiber.Ctx) error
+	RegenerateRecoveryCodes(c *fiber.Ctx) error
+	ResetTwoFactor(c *fiber.Ctx) error
+	UpdateRoleTwoFactor(c *fiber.Ctx) error
+	FindLoginLocks(c *fiber.Ctx) error
+	UnlockUserLogin(c *fiber.Ctx) error
+	UnlockIpLogin(c *fiber.Ctx) error
 }
 
 type usersHandler struct {
@@ -179,13 +215,49 @@ func (h *usersHandler) SignIn(c *fiber.Ctx, db *sql.DB) error {
 		).Res()
 	}
 
-	passport, err := h.usersUsecase.GetPassport(req)
+	passport, err := h.usersUsecase.GetPassport(req, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
-		return entities.NewResponse(c).Error(
-			fiber.ErrBadRequest.Code,
-			string(SignInErr),
-			err.Error(),
-		).Res()
+		var locked *users.LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			if err := h.logLoginAttempt(req.Email, fmt.Sprintf("เข้าสู่ระบบขณะถูกระงับชั่วคราว : %s (IP %s)", req.Email, c.IP())); err != nil {
+				return entities.NewResponse(c).Error(
+					fiber.ErrInternalServerError.Code,
+					fmt.Sprintf("Failed to log activity %v", req.Email),
+					err.Error(),
+				).Res()
+			}
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return entities.NewResponse(c).Error(
+				fiber.ErrTooManyRequests.Code,
+				string(SignInErr),
+				err.Error(),
+			).Res()
+		case errors.Is(err, users.ErrInvalidCredentials):
+			if err := h.logLoginAttempt(req.Email, fmt.Sprintf("เข้าสู่ระบบไม่สำเร็จ : %s (IP %s)", req.Email, c.IP())); err != nil {
+				return entities.NewResponse(c).Error(
+					fiber.ErrInternalServerError.Code,
+					fmt.Sprintf("Failed to log activity %v", req.Email),
+					err.Error(),
+				).Res()
+			}
+			return entities.NewResponse(c).Error(
+				fiber.ErrUnauthorized.Code,
+				string(SignInErr),
+				err.Error(),
+			).Res()
+		default:
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				string(SignInErr),
+				err.Error(),
+			).Res()
+		}
+	}
+
+	// Signed in only once the 2FA code is verified
+	if passport.TwoFactor != nil {
+		return entities.NewResponse(c).Success(fiber.StatusOK, passport).Res()
 	}
 
 	// // Log activity
@@ -234,6 +306,22 @@ func (h *usersHandler) RefreshPassport(c *fiber.Ctx) error {
 
 	passport, err := h.usersUsecase.RefreshPassport(req)
 	if err != nil {
+		var reused *users.RefreshTokenReusedError
+		if errors.As(err, &reused) {
+			details := fmt.Sprintf("ตรวจพบการใช้ refresh token ซ้ำ ยกเลิกเซสชัน %s (IP %s)", reused.OauthId, c.IP())
+			if err := utils.LogActivity(h.db, reused.UserId, "security", details); err != nil {
+				return entities.NewResponse(c).Error(
+					fiber.ErrInternalServerError.Code,
+					fmt.Sprintf("Failed to log activity %v", reused.UserId),
+					err.Error(),
+				).Res()
+			}
+			return entities.NewResponse(c).Error(
+				fiber.ErrUnauthorized.Code,
+				string(RefreshPassportErr),
+				err.Error(),
+			).Res()
+		}
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
 			string(RefreshPassportErr),
@@ -391,3 +479,585 @@ func (h *usersHandler) DeleteUser(c *fiber.Ctx) error {
 	// Return success response
 	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
 }
+
+func (h *usersHandler) ForgotPassword(c *fiber.Ctx) error {
+	req := new(users.ForgotPasswordReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(ForgotPasswordErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Same answer for unknown emails, the mail goes out in the background
+	h.usersUsecase.ForgotPassword(req, c.IP())
+	return entities.NewResponse(c).Success(fiber.StatusOK, nil).Res()
+}
+
+func (h *usersHandler) ResetPassword(c *fiber.Ctx) error {
+	req := new(users.ResetPasswordReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(ResetPasswordErr),
+			err.Error(),
+		).Res()
+	}
+	if err := req.Validate(); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(ResetPasswordErr),
+			err.Error(),
+		).Res()
+	}
+
+	userId, err := h.usersUsecase.ResetPassword(req)
+	if err != nil {
+		if errors.Is(err, users.ErrResetTokenInvalid) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(ResetPasswordErr),
+				err.Error(),
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(ResetPasswordErr),
+			err.Error(),
+		).Res()
+	}
+
+	err = utils.LogActivity(h.db, strconv.Itoa(userId), "updated", "รีเซ็ตรหัสผ่าน")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userId),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, nil).Res()
+}
+
+func (h *usersHandler) FindMySessions(c *fiber.Ctx) error {
+	sessionId, _ := c.Locals("sessionId").(string)
+
+	sessions, err := h.usersUsecase.FindSessions(utils.GetUserIDFromContext(c), sessionId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(FindSessionsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, sessions).Res()
+}
+
+func (h *usersHandler) DeleteMySession(c *fiber.Ctx) error {
+	sessionId := strings.Trim(c.Params("session_id"), " ")
+	userID := utils.GetUserIDFromContext(c)
+
+	if err := h.usersUsecase.DeleteSession(userID, sessionId); err != nil {
+		if errors.Is(err, users.ErrSessionNotFound) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(DeleteSessionErr),
+				err.Error(),
+			).Res()
+		}
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(DeleteSessionErr),
+			err.Error(),
+		).Res()
+	}
+
+	err := utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", "ออกจากระบบอุปกรณ์ : "+sessionId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *usersHandler) DeleteMySessions(c *fiber.Ctx) error {
+	userID := utils.GetUserIDFromContext(c)
+
+	count, err := h.usersUsecase.DeleteSessions(userID)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(DeleteSessionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", fmt.Sprintf("ออกจากระบบทุกอุปกรณ์ : %d รายการ", count))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *usersHandler) FindUserSessions(c *fiber.Ctx) error {
+	userId, err := strconv.Atoi(strings.Trim(c.Params("user_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(FindSessionsErr),
+			"user_id is invalid",
+		).Res()
+	}
+
+	sessions, err := h.usersUsecase.FindSessions(userId, "")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(FindSessionsErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, sessions).Res()
+}
+
+// DeleteUserSessions signs another user out of every device.
+func (h *usersHandler) DeleteUserSessions(c *fiber.Ctx) error {
+	userId := strings.Trim(c.Params("user_id"), " ")
+
+	user, err := h.usersUsecase.FindOneUser(userId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(DeleteSessionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	count, err := h.usersUsecase.DeleteSessions(user.Id)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(DeleteSessionsErr),
+			err.Error(),
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "deleted", fmt.Sprintf("บังคับออกจากระบบผู้ใช้งาน : %s (%d รายการ)", user.Username, count))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// twoFactorErrStatus maps the 2FA errors to their http status.
+func twoFactorErrStatus(err error) int {
+	switch {
+	case errors.Is(err, users.ErrTwoFactorChallenge):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, users.ErrTwoFactorCodeInvalid):
+		return fiber.StatusBadRequest
+	case errors.Is(err, users.ErrTwoFactorEnabled),
+		errors.Is(err, users.ErrTwoFactorNotEnabled),
+		errors.Is(err, users.ErrTwoFactorNotEnrolled),
+		errors.Is(err, users.ErrTwoFactorRequired):
+		return fiber.StatusConflict
+	case errors.Is(err, users.ErrRoleNotFound):
+		return fiber.StatusNotFound
+	case errors.As(err, new(*users.LoginLockedError)):
+		return fiber.StatusTooManyRequests
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// VerifyTwoFactor finishes a sign in that was answered with a 2FA challenge.
+func (h *usersHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	req := new(users.TwoFactorVerifyReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(VerifyTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	passport, err := h.usersUsecase.VerifyTwoFactor(req, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		var failed *users.TwoFactorFailedError
+		if errors.As(err, &failed) {
+			details := fmt.Sprintf("ยืนยันตัวตนสองขั้นตอนไม่สำเร็จ (IP %s)", c.IP())
+			var locked *users.LoginLockedError
+			if errors.As(err, &locked) {
+				details = fmt.Sprintf("ยืนยันตัวตนสองขั้นตอนขณะถูกระงับชั่วคราว (IP %s)", c.IP())
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			}
+			if err := utils.LogActivity(h.db, failed.UserId, "security", details); err != nil {
+				return entities.NewResponse(c).Error(
+					fiber.ErrInternalServerError.Code,
+					fmt.Sprintf("Failed to log activity %v", failed.UserId),
+					err.Error(),
+				).Res()
+			}
+		}
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(VerifyTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	err = utils.LogActivity(h.db, strconv.Itoa(passport.User.Id), "login", "เข้าสู่ระบบ (ยืนยันตัวตนสองขั้นตอน)")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", passport.User.Id),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(fiber.StatusOK, passport).Res()
+}
+
+// EnrollTwoFactorChallenge sets up 2FA for a user whose role requires it, using
+// the challenge token given by sign in.
+func (h *usersHandler) EnrollTwoFactorChallenge(c *fiber.Ctx) error {
+	req := new(users.TwoFactorChallengeReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(EnrollTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	enrollment, err := h.usersUsecase.EnrollTwoFactorChallenge(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(EnrollTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, enrollment).Res()
+}
+
+func (h *usersHandler) FindTwoFactor(c *fiber.Ctx) error {
+	userId := strconv.Itoa(utils.GetUserIDFromContext(c))
+
+	status, err := h.usersUsecase.FindTwoFactorStatus(userId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(FindTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, status).Res()
+}
+
+func (h *usersHandler) EnrollTwoFactor(c *fiber.Ctx) error {
+	userId := strconv.Itoa(utils.GetUserIDFromContext(c))
+
+	enrollment, err := h.usersUsecase.EnrollTwoFactor(userId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(EnrollTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, enrollment).Res()
+}
+
+func (h *usersHandler) ActivateTwoFactor(c *fiber.Ctx) error {
+	req := new(users.TwoFactorCodeReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(ActivateTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	userId := strconv.Itoa(utils.GetUserIDFromContext(c))
+	codes, err := h.usersUsecase.ActivateTwoFactor(userId, req.Code)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(ActivateTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	err = utils.LogActivity(h.db, userId, "updated", "เปิดใช้งานการยืนยันตัวตนสองขั้นตอน")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userId),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(
+		fiber.StatusOK,
+		&struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			RecoveryCodes: codes,
+		},
+	).Res()
+}
+
+func (h *usersHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	req := new(users.TwoFactorCodeReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(DisableTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	userId := strconv.Itoa(utils.GetUserIDFromContext(c))
+	if err := h.usersUsecase.DisableTwoFactor(userId, req.Code); err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(DisableTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	err := utils.LogActivity(h.db, userId, "updated", "ปิดการยืนยันตัวตนสองขั้นตอน")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userId),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *usersHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	req := new(users.TwoFactorCodeReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(RecoveryCodesErr),
+			err.Error(),
+		).Res()
+	}
+
+	userId := strconv.Itoa(utils.GetUserIDFromContext(c))
+	codes, err := h.usersUsecase.RegenerateRecoveryCodes(userId, req.Code)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(RecoveryCodesErr),
+			err.Error(),
+		).Res()
+	}
+
+	err = utils.LogActivity(h.db, userId, "updated", "สร้างรหัสกู้คืนการยืนยันตัวตนสองขั้นตอนใหม่")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userId),
+			err.Error(),
+		).Res()
+	}
+
+	return entities.NewResponse(c).Success(
+		fiber.StatusOK,
+		&struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			RecoveryCodes: codes,
+		},
+	).Res()
+}
+
+// ResetTwoFactor turns 2FA off for another user who lost their authenticator.
+func (h *usersHandler) ResetTwoFactor(c *fiber.Ctx) error {
+	userId := strings.Trim(c.Params("user_id"), " ")
+
+	user, err := h.usersUsecase.FindOneUser(userId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(ResetTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.usersUsecase.ResetTwoFactor(userId); err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(ResetTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "รีเซ็ตการยืนยันตัวตนสองขั้นตอนของผู้ใช้งาน : "+user.Username)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UpdateRoleTwoFactor makes 2FA mandatory, or optional again, for the users of a role.
+func (h *usersHandler) UpdateRoleTwoFactor(c *fiber.Ctx) error {
+	roleId, err := strconv.Atoi(strings.Trim(c.Params("role_id"), " "))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(UpdateRoleTwoFactorErr),
+			"role_id is invalid",
+		).Res()
+	}
+
+	req := new(users.RoleTwoFactorReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(UpdateRoleTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.usersUsecase.UpdateRoleTwoFactor(roleId, req.Required); err != nil {
+		return entities.NewResponse(c).Error(
+			twoFactorErrStatus(err),
+			string(UpdateRoleTwoFactorErr),
+			err.Error(),
+		).Res()
+	}
+
+	details := fmt.Sprintf("บังคับใช้การยืนยันตัวตนสองขั้นตอนสำหรับบทบาท : %d", roleId)
+	if !req.Required {
+		details = fmt.Sprintf("ยกเลิกการบังคับใช้การยืนยันตัวตนสองขั้นตอนสำหรับบทบาท : %d", roleId)
+	}
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", details)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, req).Res()
+}
+
+// logLoginAttempt records a failed or refused sign in, under the account of the
+// email when there is one.
+func (h *usersHandler) logLoginAttempt(email, details string) error {
+	userID, err := utils.GetUserIDByEmail(h.db, email)
+	if err != nil {
+		return utils.LogSystemActivity(h.db, "security", details)
+	}
+	return utils.LogActivity(h.db, strconv.Itoa(userID), "security", details)
+}
+
+// FindLoginLocks lists the emails and ips with recent failed sign ins.
+func (h *usersHandler) FindLoginLocks(c *fiber.Ctx) error {
+	throttles, err := h.usersUsecase.FindLoginThrottles()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(FindLoginLocksErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, throttles).Res()
+}
+
+// UnlockUserLogin lets a user locked out by failed sign ins try again.
+func (h *usersHandler) UnlockUserLogin(c *fiber.Ctx) error {
+	userId := strings.Trim(c.Params("user_id"), " ")
+
+	user, err := h.usersUsecase.FindOneUser(userId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(UnlockLoginErr),
+			err.Error(),
+		).Res()
+	}
+
+	for _, t := range []struct {
+		kind string
+		key  string
+	}{
+		{users.LoginThrottleEmail, user.Email},
+		{users.LoginThrottleTwoFactor, userId},
+	} {
+		if _, err := h.usersUsecase.UnlockLogin(t.kind, t.key); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				string(UnlockLoginErr),
+				err.Error(),
+			).Res()
+		}
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "ปลดล็อกการเข้าสู่ระบบของผู้ใช้งาน : "+user.Username)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// UnlockIpLogin lets an ip locked out by failed sign ins try again.
+func (h *usersHandler) UnlockIpLogin(c *fiber.Ctx) error {
+	ip := strings.Trim(c.Params("ip"), " ")
+
+	cleared, err := h.usersUsecase.UnlockLogin(users.LoginThrottleIp, ip)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(UnlockLoginErr),
+			err.Error(),
+		).Res()
+	}
+	if !cleared {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(UnlockLoginErr),
+			"ip has no failed sign ins",
+		).Res()
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	err = utils.LogActivity(h.db, strconv.Itoa(userID), "updated", "ปลดล็อกการเข้าสู่ระบบของ IP : "+ip)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			fmt.Sprintf("Failed to log activity %v", userID),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
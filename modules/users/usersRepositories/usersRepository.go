@@ -2,7 +2,9 @@ package usersRepositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -21,14 +23,35 @@ type IUsersRepository interface {
 	InsertUser(req *users.User) (*users.UserPassport, error)
 	FindOneUserByEmail(email string) (*users.UserCredentialCheck, error)
 	FindUser(req *users.UserFilter) ([]*users.User, int)
-	InsertOauth(req *users.UserPassport) error
+	InsertOauth(req *users.UserPassport, device, ip string) error
 	FindOneOauth(refreshToken string) (*users.Oauth, error)
 	FindOneUser(userId string) (*users.User, error)
-	UpdateOauth(req *users.UserToken) error
+	RotateOauth(refreshToken string, req *users.UserToken) (bool, error)
+	FindUsedRefreshToken(refreshToken string) (*users.Oauth, error)
 	GetProfile(userId string) (*users.User, error)
 	DeleteOauth(oauthId string) error
 	UpdateUser(req *users.User) (*users.User, error)
 	DeleteUser(userId string) error
+	InsertPasswordReset(userId int, tokenHash string, expiresAt time.Time, ip string) error
+	ResetPassword(tokenHash, password string) (int, error)
+	FindSessions(userId int, currentId string) ([]*users.Session, error)
+	DeleteSession(userId int, sessionId string) error
+	DeleteSessions(userId int) (int64, error)
+	FindTwoFactor(userId string) (*users.TwoFactor, error)
+	SetTwoFactorSecret(userId, secret string) error
+	UseTwoFactorStep(userId string, step int64) (bool, error)
+	EnableTwoFactor(userId string, step int64, codeHashes []string) (bool, error)
+	DisableTwoFactor(userId string) error
+	UseRecoveryCode(userId, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(userId string, codeHashes []string) error
+	CountRecoveryCodes(userId string) (int, error)
+	UpdateRoleTwoFactor(roleId int, required bool) error
+	FindLoginLock(email, ip string) (time.Duration, error)
+	FindTwoFactorLock(userId, ip string) (time.Duration, error)
+	RecordLoginFailure(kind, key string, window time.Duration) (int, error)
+	LockLogin(kind, key string, wait time.Duration) error
+	ClearLoginThrottle(kind, key string) (bool, error)
+	FindLoginThrottles(window time.Duration) ([]*users.LoginThrottle, error)
 }
 
 type usersRepository struct {
@@ -153,16 +176,19 @@ func (r *usersRepository) FindUser(req *users.UserFilter) ([]*users.User, int) {
 	return result, count
 }
 
-func (r *usersRepository) InsertOauth(req *users.UserPassport) error {
+func (r *usersRepository) InsertOauth(req *users.UserPassport, device, ip string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	query := `
 	INSERT INTO "oauth" (
 		"user_id",
 		"refresh_token",
-		"access_token"
+		"access_token",
+		"device",
+		"ip",
+		"expires_at"
 	)
-	VALUES ($1, $2, $3)
+	VALUES ($1, $2, $3, $4, $5, now() + make_interval(secs => $6::int))
 		RETURNING "id";
 	`
 	if err := r.db.QueryRowContext(
@@ -171,6 +197,9 @@ func (r *usersRepository) InsertOauth(req *users.UserPassport) error {
 		req.User.Id,
 		req.Token.RefreshToken,
 		req.Token.AccessToken,
+		device,
+		ip,
+		r.cfg.Jwt().RefreshExpiresAt(),
 	).Scan(&req.Token.Id); err != nil {
 		return fmt.Errorf("insert oauth failed: %v", err)
 	}
@@ -183,7 +212,8 @@ func (r *usersRepository) FindOneOauth(refreshToken string) (*users.Oauth, error
 		"id",
 		"user_id"
 	FROM "oauth"
-	WHERE "refresh_token" = $1;
+	WHERE "refresh_token" = $1
+	AND "expires_at" > now();
 	`
 	oauth := new(users.Oauth)
 	if err := r.db.Get(oauth, query, refreshToken); err != nil {
@@ -192,19 +222,76 @@ func (r *usersRepository) FindOneOauth(refreshToken string) (*users.Oauth, error
 	return oauth, nil
 }
 
-func (r *usersRepository) UpdateOauth(req *users.UserToken) error {
+// RotateOauth swaps the tokens of the session still holding refreshToken and
+// keeps the old refresh token as used. It reports false when the session no
+// longer holds it, e.g. a concurrent refresh rotated it first.
+func (r *usersRepository) RotateOauth(refreshToken string, req *users.UserToken) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
 	query := `
-	UPDATE "oauth" SET
-		"access_token" = :access_token,
-		"refresh_token" = :refresh_token
-	WHERE "id" = :id;
-	`
+	WITH "old" AS (
+		SELECT
+			"id",
+			"user_id",
+			"expires_at"
+		FROM "oauth"
+		WHERE "id" = $1
+		AND "refresh_token" = $2
+		AND "expires_at" > now()
+		FOR UPDATE
+	), "rotated" AS (
+		UPDATE "oauth" "o" SET
+			"access_token" = $3,
+			"refresh_token" = $4,
+			"expires_at" = now() + make_interval(secs => $5::int),
+			"last_used_at" = now()
+		FROM "old"
+		WHERE "o"."id" = "old"."id"
+	)
+	INSERT INTO "used_refresh_tokens" (
+		"token_hash",
+		"oauth_id",
+		"user_id",
+		"expires_at"
+	)
+	SELECT $6, "id", "user_id", "expires_at" FROM "old"
+	ON CONFLICT ("token_hash") DO NOTHING;`
 
-	if _, err := r.db.NamedExecContext(context.Background(), query, req); err != nil {
-		return fmt.Errorf("update oauth failed: %v", err)
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		req.Id,
+		refreshToken,
+		req.AccessToken,
+		req.RefreshToken,
+		r.cfg.Jwt().RefreshExpiresAt(),
+		users.HashToken(refreshToken),
+	)
+	if err != nil {
+		return false, fmt.Errorf("update oauth failed: %v", err)
 	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
 
-	return nil
+// FindUsedRefreshToken returns the session a rotated out refresh token belonged to.
+func (r *usersRepository) FindUsedRefreshToken(refreshToken string) (*users.Oauth, error) {
+	query := `
+	SELECT
+		"oauth_id" AS "id",
+		"user_id"
+	FROM "used_refresh_tokens"
+	WHERE "token_hash" = $1;`
+
+	oauth := new(users.Oauth)
+	if err := r.db.Get(oauth, query, users.HashToken(refreshToken)); err != nil {
+		return nil, fmt.Errorf("oauth not found")
+	}
+	return oauth, nil
 }
 
 func (r *usersRepository) GetProfile(userId string) (*users.User, error) {
@@ -258,7 +345,493 @@ func (r *usersRepository) DeleteUser(userId string) error {
 	return nil
 }
 
+// InsertPasswordReset stores a new reset token, the unused ones the user had
+// before stop working.
+func (r *usersRepository) InsertPasswordReset(userId int, tokenHash string, expiresAt time.Time, ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM "password_resets" WHERE "user_id" = $1 AND "used_at" IS NULL;`,
+		userId,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete password resets failed: %v", err)
+	}
+
+	query := `
+	INSERT INTO "password_resets" (
+		"user_id",
+		"token_hash",
+		"expires_at",
+		"ip"
+	)
+	VALUES ($1, $2, $3, NULLIF($4, ''));`
+
+	if _, err := tx.ExecContext(ctx, query, userId, tokenHash, expiresAt, ip); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insert password reset failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ResetPassword uses up the reset token, sets the hashed password and signs the
+// user out everywhere. It returns the id of the user.
+func (r *usersRepository) ResetPassword(tokenHash, password string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var userId int
+	if err := tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE "password_resets" SET
+			"used_at" = now()
+		WHERE "token_hash" = $1
+		AND "used_at" IS NULL
+		AND "expires_at" > now()
+		RETURNING "user_id";`,
+		tokenHash,
+	).Scan(&userId); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, users.ErrResetTokenInvalid
+		}
+		return 0, fmt.Errorf("update password reset failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE "users" SET "password" = $1 WHERE "id" = $2;`, password, userId); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("update password failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "oauth" WHERE "user_id" = $1;`, userId); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("delete oauth failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
 func verifyPassword(userPassword string, hashedPassword string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(userPassword))
 	return err == nil
 }
+
+// FindSessions lists the unexpired sessions of the user, most recently used first.
+func (r *usersRepository) FindSessions(userId int, currentId string) ([]*users.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `
+	SELECT
+		"id",
+		"device",
+		"ip",
+		"created_at",
+		"last_used_at",
+		"expires_at",
+		("id"::text = $2) AS "current"
+	FROM "oauth"
+	WHERE "user_id" = $1
+	AND "expires_at" > now()
+	ORDER BY "last_used_at" DESC;`
+
+	sessions := make([]*users.Session, 0)
+	if err := r.db.SelectContext(ctx, &sessions, query, userId, currentId); err != nil {
+		return nil, fmt.Errorf("get sessions failed: %v", err)
+	}
+	return sessions, nil
+}
+
+func (r *usersRepository) DeleteSession(userId int, sessionId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM "oauth" WHERE "id"::text = $1 AND "user_id" = $2;`, sessionId, userId)
+	if err != nil {
+		return fmt.Errorf("delete session failed: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return users.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteSessions signs the user out everywhere and returns how many sessions were revoked.
+func (r *usersRepository) DeleteSessions(userId int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM "oauth" WHERE "user_id" = $1;`, userId)
+	if err != nil {
+		return 0, fmt.Errorf("delete sessions failed: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *usersRepository) FindTwoFactor(userId string) (*users.TwoFactor, error) {
+	query := `
+	SELECT
+		"u"."totp_secret",
+		"u"."totp_enabled_at",
+		"u"."totp_last_step",
+		EXISTS (
+			SELECT 1
+			FROM "user_roles" "ur"
+			JOIN "roles" "r" ON "r"."id" = "ur"."role_id"
+			WHERE "ur"."user_id" = "u"."id"
+			AND "r"."require_two_factor"
+		) AS "required"
+	FROM "users" "u"
+	WHERE "u"."id" = $1;`
+
+	twoFactor := new(users.TwoFactor)
+	if err := r.db.Get(twoFactor, query, userId); err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return twoFactor, nil
+}
+
+// SetTwoFactorSecret keeps a new secret until its first code is confirmed. It
+// never replaces the secret of an enabled 2FA.
+func (r *usersRepository) SetTwoFactorSecret(userId, secret string) error {
+	query := `
+	UPDATE "users" SET
+		"totp_secret" = $2,
+		"totp_last_step" = 0
+	WHERE "id" = $1
+	AND "totp_enabled_at" IS NULL;`
+
+	result, err := r.db.ExecContext(context.Background(), query, userId, secret)
+	if err != nil {
+		return fmt.Errorf("update two factor secret failed: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return users.ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// UseTwoFactorStep records the time step of an accepted code. It reports false
+// when that step or a later one was already used.
+func (r *usersRepository) UseTwoFactorStep(userId string, step int64) (bool, error) {
+	query := `
+	UPDATE "users" SET
+		"totp_last_step" = $2
+	WHERE "id" = $1
+	AND "totp_last_step" < $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, userId, step)
+	if err != nil {
+		return false, fmt.Errorf("update two factor step failed: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// EnableTwoFactor turns on the enrolled secret and stores fresh recovery codes.
+// It reports false when the step was already used.
+func (r *usersRepository) EnableTwoFactor(userId string, step int64, codeHashes []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`
+		UPDATE "users" SET
+			"totp_enabled_at" = now(),
+			"totp_last_step" = $2
+		WHERE "id" = $1
+		AND "totp_secret" IS NOT NULL
+		AND "totp_enabled_at" IS NULL
+		AND "totp_last_step" < $2;`,
+		userId,
+		step,
+	)
+	if err != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("enable two factor failed: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	if err := insertRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *usersRepository) DisableTwoFactor(userId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`
+		UPDATE "users" SET
+			"totp_secret" = NULL,
+			"totp_enabled_at" = NULL
+		WHERE "id" = $1;`,
+		userId,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("disable two factor failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "user_recovery_codes" WHERE "user_id" = $1;`, userId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete recovery codes failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used. It reports
+// false when there is no such code.
+func (r *usersRepository) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	query := `
+	UPDATE "user_recovery_codes" SET
+		"used_at" = now()
+	WHERE "user_id" = $1
+	AND "code_hash" = $2
+	AND "used_at" IS NULL;`
+
+	result, err := r.db.ExecContext(context.Background(), query, userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("update recovery code failed: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *usersRepository) ReplaceRecoveryCodes(userId string, codeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// insertRecoveryCodes replaces every recovery code of the user.
+func insertRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userId string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "user_recovery_codes" WHERE "user_id" = $1;`, userId); err != nil {
+		return fmt.Errorf("delete recovery codes failed: %v", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO "user_recovery_codes" ("user_id", "code_hash") VALUES ($1, $2);`,
+			userId,
+			hash,
+		); err != nil {
+			return fmt.Errorf("insert recovery code failed: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *usersRepository) CountRecoveryCodes(userId string) (int, error) {
+	query := `
+	SELECT
+		COUNT(*)
+	FROM "user_recovery_codes"
+	WHERE "user_id" = $1
+	AND "used_at" IS NULL;`
+
+	var count int
+	if err := r.db.Get(&count, query, userId); err != nil {
+		return 0, fmt.Errorf("count recovery codes failed: %v", err)
+	}
+	return count, nil
+}
+
+func (r *usersRepository) UpdateRoleTwoFactor(roleId int, required bool) error {
+	result, err := r.db.ExecContext(
+		context.Background(),
+		`UPDATE "roles" SET "require_two_factor" = $2 WHERE "id" = $1;`,
+		roleId,
+		required,
+	)
+	if err != nil {
+		return fmt.Errorf("update role failed: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return users.ErrRoleNotFound
+	}
+	return nil
+}
+
+// FindLoginLock returns how long the email or the ip is still locked, 0 when neither is.
+func (r *usersRepository) FindLoginLock(email, ip string) (time.Duration, error) {
+	query := `
+	SELECT
+		COALESCE(EXTRACT(EPOCH FROM MAX("locked_until") - now()), 0)::float8
+	FROM "login_throttles"
+	WHERE (
+		("kind" = 'email' AND "key" = $1)
+		OR ("kind" = 'ip' AND "key" = $2)
+	)
+	AND "locked_until" > now();`
+
+	var seconds float64
+	if err := r.db.Get(&seconds, query, email, ip); err != nil {
+		return 0, fmt.Errorf("get login lock failed: %v", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// FindTwoFactorLock returns how long the 2FA codes of the user or the ip are
+// still locked, 0 when neither is.
+func (r *usersRepository) FindTwoFactorLock(userId, ip string) (time.Duration, error) {
+	query := `
+	SELECT
+		COALESCE(EXTRACT(EPOCH FROM MAX("locked_until") - now()), 0)::float8
+	FROM "login_throttles"
+	WHERE (
+		("kind" = 'two_factor' AND "key" = $1)
+		OR ("kind" = 'ip' AND "key" = $2)
+	)
+	AND "locked_until" > now();`
+
+	var seconds float64
+	if err := r.db.Get(&seconds, query, userId, ip); err != nil {
+		return 0, fmt.Errorf("get two factor lock failed: %v", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordLoginFailure counts a failed sign in and returns the failures in a row.
+// The count starts over when the last failure is older than window.
+func (r *usersRepository) RecordLoginFailure(kind, key string, window time.Duration) (int, error) {
+	query := `
+	INSERT INTO "login_throttles" (
+		"kind",
+		"key",
+		"failures"
+	)
+	VALUES ($1, $2, 1)
+	ON CONFLICT ("kind", "key") DO UPDATE SET
+		"failures" = CASE
+			WHEN "login_throttles"."last_failed_at" < now() - make_interval(secs => $3::int) THEN 1
+			ELSE "login_throttles"."failures" + 1
+		END,
+		"last_failed_at" = now()
+	RETURNING "failures";`
+
+	var failures int
+	if err := r.db.Get(&failures, query, kind, key, int(window.Seconds())); err != nil {
+		return 0, fmt.Errorf("insert login failure failed: %v", err)
+	}
+	return failures, nil
+}
+
+func (r *usersRepository) LockLogin(kind, key string, wait time.Duration) error {
+	query := `
+	UPDATE "login_throttles" SET
+		"locked_until" = now() + make_interval(secs => $3::float8)
+	WHERE "kind" = $1
+	AND "key" = $2;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, kind, key, wait.Seconds()); err != nil {
+		return fmt.Errorf("update login lock failed: %v", err)
+	}
+	return nil
+}
+
+// ClearLoginThrottle forgets the failures and lock of an email or ip. It reports
+// false when there was nothing to clear.
+func (r *usersRepository) ClearLoginThrottle(kind, key string) (bool, error) {
+	result, err := r.db.ExecContext(
+		context.Background(),
+		`DELETE FROM "login_throttles" WHERE "kind" = $1 AND "key" = $2;`,
+		kind,
+		key,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete login throttle failed: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// FindLoginThrottles lists the emails and ips that are locked or failed within window.
+func (r *usersRepository) FindLoginThrottles(window time.Duration) ([]*users.LoginThrottle, error) {
+	query := `
+	SELECT
+		"kind",
+		"key",
+		"failures",
+		"locked_until",
+		"last_failed_at"
+	FROM "login_throttles"
+	WHERE "locked_until" > now()
+	OR "last_failed_at" > now() - make_interval(secs => $1::int)
+	ORDER BY "last_failed_at" DESC;`
+
+	throttles := make([]*users.LoginThrottle, 0)
+	if err := r.db.Select(&throttles, query, int(window.Seconds())); err != nil {
+		return nil, fmt.Errorf("get login throttles failed: %v", err)
+	}
+	return throttles, nil
+}
@@ -1,15 +1,23 @@
 package usersUsecases
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/entities"
 	"github.com/yporn/sirarom-backend/modules/users"
 	"github.com/yporn/sirarom-backend/modules/users/usersRepositories"
 	"github.com/yporn/sirarom-backend/pkg/auth"
+	"github.com/yporn/sirarom-backend/pkg/mailer"
+	"github.com/yporn/sirarom-backend/pkg/totp"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,22 +25,40 @@ type IUsersUsecase interface {
 	FindOneUser(userId string) (*users.User, error)
 	FindUser(req *users.UserFilter) *entities.PaginateRes
 	InsertAdmin(req *users.User) (*users.UserPassport, error)
-	GetPassport(req *users.UserCredential) (*users.UserPassport, error)
+	GetPassport(req *users.UserCredential, device, ip string) (*users.UserPassport, error)
 	RefreshPassport(req *users.UserRefreshCredential) (*users.UserPassport, error)
 	DeleteOauth(oauthId string) error
 	UpdateUser(req *users.User) (*users.User, error)
 	DeleteUser(userId string) error
+	ForgotPassword(req *users.ForgotPasswordReq, ip string)
+	ResetPassword(req *users.ResetPasswordReq) (int, error)
+	FindSessions(userId int, currentId string) ([]*users.Session, error)
+	DeleteSession(userId int, sessionId string) error
+	DeleteSessions(userId int) (int64, error)
+	VerifyTwoFactor(req *users.TwoFactorVerifyReq, device, ip string) (*users.UserPassport, error)
+	EnrollTwoFactorChallenge(req *users.TwoFactorChallengeReq) (*users.TwoFactorEnrollment, error)
+	FindTwoFactorStatus(userId string) (*users.TwoFactorStatus, error)
+	EnrollTwoFactor(userId string) (*users.TwoFactorEnrollment, error)
+	ActivateTwoFactor(userId, code string) ([]string, error)
+	DisableTwoFactor(userId, code string) error
+	RegenerateRecoveryCodes(userId, code string) ([]string, error)
+	ResetTwoFactor(userId string) error
+	UpdateRoleTwoFactor(roleId int, required bool) error
+	FindLoginThrottles() ([]*users.LoginThrottle, error)
+	UnlockLogin(kind, key string) (bool, error)
 }
 
 type usersUsecase struct {
 	cfg             config.IConfig
 	usersRepository usersRepositories.IUsersRepository
+	mailer          mailer.IMailer
 }
 
-func UsersUsecase(cfg config.IConfig, usersRepository usersRepositories.IUsersRepository) IUsersUsecase {
+func UsersUsecase(cfg config.IConfig, usersRepository usersRepositories.IUsersRepository, mailer mailer.IMailer) IUsersUsecase {
 	return &usersUsecase{
 		cfg:             cfg,
 		usersRepository: usersRepository,
+		mailer:          mailer,
 	}
 }
 
@@ -69,41 +95,80 @@ func (u *usersUsecase) InsertAdmin(req *users.User) (*users.UserPassport, error)
 	return result, nil
 }
 
-func (u *usersUsecase) GetPassport(req *users.UserCredential) (*users.UserPassport, error) {
+// dummyPassword is compared against when the email has no account.
+var dummyPassword, _ = bcrypt.GenerateFromPassword([]byte("sirarom-dummy-password"), 10)
+
+func (u *usersUsecase) GetPassport(req *users.UserCredential, device, ip string) (*users.UserPassport, error) {
+	email := users.LoginEmailKey(req.Email)
+
+	// Locked emails and ips are refused before any password is compared
+	wait, err := u.usersRepository.FindLoginLock(email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &users.LoginLockedError{RetryAfter: wait}
+	}
+
 	//Find user
 	user, err := u.usersRepository.FindOneUserByEmail(req.Email)
 	if err != nil {
-		return nil, err
+		// Same bcrypt cost as a real account so the timing does not tell either
+		bcrypt.CompareHashAndPassword(dummyPassword, []byte(req.Password))
+		return nil, u.loginFailed(email, ip)
 	}
 
 	// Compare Password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, fmt.Errorf("password is invalid")
+		return nil, u.loginFailed(email, ip)
+	}
+	if _, err := u.usersRepository.ClearLoginThrottle(users.LoginThrottleEmail, email); err != nil {
+		return nil, err
 	}
 
-	// Sign token
-	accessToken, err := auth.NewAuth(auth.Access, u.cfg.Jwt(), &users.UserClaims{
-		Id:       user.Id,
+	// A 2FA code is asked for before the passport
+	twoFactor, err := u.usersRepository.FindTwoFactor(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled() || twoFactor.Required {
+		return u.twoFactorChallenge(user.Id, !twoFactor.Enabled())
+	}
+
+	id, err := strconv.Atoi(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return u.newPassport(&users.User{
+		Id:       id,
+		Email:    user.Email,
+		Username: user.Username,
+	}, device, ip)
+}
+
+// newPassport signs the tokens of a new session of the user.
+func (u *usersUsecase) newPassport(user *users.User, device, ip string) (*users.UserPassport, error) {
+	claims := &users.UserClaims{
+		Id:       strconv.Itoa(user.Id),
 		UserRole: make([]*users.UserRole, 0),
-	})
+	}
+
+	// Sign token
+	accessToken, err := auth.NewAuth(auth.Access, u.cfg.Jwt(), claims)
 	if err != nil {
 		return nil, err
 	}
 
 	// Refresh token
-	refreshToken, err := auth.NewAuth(auth.Refresh, u.cfg.Jwt(), &users.UserClaims{
-		Id:       user.Id,
-		UserRole: make([]*users.UserRole, 0),
-	})
+	refreshToken, err := auth.NewAuth(auth.Refresh, u.cfg.Jwt(), claims)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := strconv.Atoi(user.Id)
 	// Set passport
 	passport := &users.UserPassport{
 		User: &users.User{
-			Id:       id,
+			Id:       user.Id,
 			Email:    user.Email,
 			Username: user.Username,
 			Images:   make([]*entities.Image, 0),
@@ -115,7 +180,7 @@ func (u *usersUsecase) GetPassport(req *users.UserCredential) (*users.UserPasspo
 		},
 	}
 
-	if err := u.usersRepository.InsertOauth(passport); err != nil {
+	if err := u.usersRepository.InsertOauth(passport, users.SessionDevice(device), ip); err != nil {
 		return nil, err
 	}
 
@@ -128,11 +193,14 @@ func (u *usersUsecase) RefreshPassport(req *users.UserRefreshCredential) (*users
 	if err != nil {
 		return nil, err
 	}
+	if claims.Subject != "refresh-token" {
+		return nil, fmt.Errorf("token is not a refresh token")
+	}
 
 	// Check Oauth
 	oauth, err := u.usersRepository.FindOneOauth(req.RefreshToken)
 	if err != nil {
-		return nil, err
+		return nil, u.revokeReusedToken(req.RefreshToken, err)
 	}
 
 	// Find profile
@@ -155,28 +223,51 @@ func (u *usersUsecase) RefreshPassport(req *users.UserRefreshCredential) (*users
 		return nil, err
 	}
 
-	refreshToken := auth.RepeatToken(
+	refreshToken, err := auth.NewAuth(
+		auth.Refresh,
 		u.cfg.Jwt(),
 		newClaims,
-		claims.ExpiresAt.Unix(),
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	passport := &users.UserPassport{
 		User: profile,
 		Token: &users.UserToken{
 			Id:           oauth.Id,
 			AccessToken:  accessToken.SignToken(),
-			RefreshToken: refreshToken,
+			RefreshToken: refreshToken.SignToken(),
 		},
 	}
 
-	if err := u.usersRepository.UpdateOauth(passport.Token); err != nil {
+	rotated, err := u.usersRepository.RotateOauth(req.RefreshToken, passport.Token)
+	if err != nil {
 		return nil, err
 	}
+	if !rotated {
+		return nil, u.revokeReusedToken(req.RefreshToken, fmt.Errorf("oauth not found"))
+	}
 
 	return passport, nil
 }
 
+// revokeReusedToken ends the session a rotated out refresh token belonged to.
+// A token that was never issued gives back notFound.
+func (u *usersUsecase) revokeReusedToken(refreshToken string, notFound error) error {
+	oauth, err := u.usersRepository.FindUsedRefreshToken(refreshToken)
+	if err != nil {
+		return notFound
+	}
+	if err := u.usersRepository.DeleteOauth(oauth.Id); err != nil {
+		return err
+	}
+	return &users.RefreshTokenReusedError{
+		UserId:  oauth.UserId,
+		OauthId: oauth.Id,
+	}
+}
+
 func (u *usersUsecase) DeleteOauth(oauthId string) error {
 	if err := u.usersRepository.DeleteOauth(oauthId); err != nil {
 		return err
@@ -202,3 +293,428 @@ func (u *usersUsecase) DeleteUser(userId string) error {
 	}
 	return nil
 }
+
+// ForgotPassword mails a single use reset link to the user with the email in
+// the background. Known and unknown emails return at once and the same way, so
+// neither the answer nor its timing tells which emails have an account; a
+// failure is only logged.
+func (u *usersUsecase) ForgotPassword(req *users.ForgotPasswordReq, ip string) {
+	email, ip := strings.Clone(req.Email), strings.Clone(ip)
+	go func() {
+		if err := u.sendPasswordReset(email, ip); err != nil {
+			log.Printf("forgot password: %v\n", err)
+		}
+	}()
+}
+
+// sendPasswordReset stores a reset token of the user with the email and mails
+// its link. An unknown email sends nothing.
+func (u *usersUsecase) sendPasswordReset(email, ip string) error {
+	user, err := u.usersRepository.FindOneUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+	userId, err := strconv.Atoi(user.Id)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate reset token failed: %v", err)
+	}
+	token := hex.EncodeToString(b)
+
+	minutes := u.cfg.App().PasswordResetMinutes()
+	expiresAt := time.Now().Add(time.Duration(minutes) * time.Minute)
+	if err := u.usersRepository.InsertPasswordReset(userId, users.HashToken(token), expiresAt, ip); err != nil {
+		return err
+	}
+
+	return u.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "รีเซ็ตรหัสผ่าน",
+		Body: fmt.Sprintf(
+			"สวัสดีคุณ %s\n\nมีการขอรีเซ็ตรหัสผ่านของบัญชีนี้ ตั้งรหัสผ่านใหม่ได้ที่ลิงก์ด้านล่างภายใน %d นาที\n%s/reset-password?token=%s\n\nหากคุณไม่ได้เป็นผู้ขอ ไม่ต้องทำอะไร รหัสผ่านเดิมยังใช้งานได้ตามปกติ",
+			user.Username,
+			minutes,
+			u.cfg.App().AdminUrl(),
+			token,
+		),
+	})
+}
+
+// ResetPassword sets the new password of the reset token and returns the id of
+// its user, every session of the user is signed out.
+func (u *usersUsecase) ResetPassword(req *users.ResetPasswordReq) (int, error) {
+	hashed := &users.User{Password: req.Password}
+	if err := hashed.BcryptHashing(); err != nil {
+		return 0, err
+	}
+	userId, err := u.usersRepository.ResetPassword(users.HashToken(req.Token), hashed.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	// A new password ends a lockout of the account
+	profile, err := u.usersRepository.GetProfile(strconv.Itoa(userId))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := u.usersRepository.ClearLoginThrottle(users.LoginThrottleEmail, users.LoginEmailKey(profile.Email)); err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+func (u *usersUsecase) FindSessions(userId int, currentId string) ([]*users.Session, error) {
+	return u.usersRepository.FindSessions(userId, currentId)
+}
+
+func (u *usersUsecase) DeleteSession(userId int, sessionId string) error {
+	return u.usersRepository.DeleteSession(userId, sessionId)
+}
+
+func (u *usersUsecase) DeleteSessions(userId int) (int64, error) {
+	return u.usersRepository.DeleteSessions(userId)
+}
+
+func (u *usersUsecase) twoFactorChallenge(userId string, setupRequired bool) (*users.UserPassport, error) {
+	token, err := auth.NewAuth(auth.TwoFactor, u.cfg.Jwt(), &users.UserClaims{
+		Id:       userId,
+		UserRole: make([]*users.UserRole, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &users.UserPassport{
+		TwoFactor: &users.TwoFactorChallenge{
+			Token:         token.SignToken(),
+			ExpiresIn:     auth.TwoFactorExpiresAt,
+			SetupRequired: setupRequired,
+		},
+	}, nil
+}
+
+// parseTwoFactorChallenge returns the id of the user a challenge token was given to.
+func (u *usersUsecase) parseTwoFactorChallenge(token string) (string, error) {
+	claims, err := auth.ParseToken(u.cfg.Jwt(), token)
+	if err != nil || claims.Subject != "two-factor-token" || claims.Claims == nil {
+		return "", users.ErrTwoFactorChallenge
+	}
+	return claims.Claims.Id, nil
+}
+
+// VerifyTwoFactor finishes a sign in that was challenged for a 2FA code. A user
+// who had to set up 2FA turns it on with the code and gets the recovery codes.
+func (u *usersUsecase) VerifyTwoFactor(req *users.TwoFactorVerifyReq, device, ip string) (*users.UserPassport, error) {
+	userId, err := u.parseTwoFactorChallenge(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// A locked user or ip is refused before the code is looked at
+	wait, err := u.usersRepository.FindTwoFactorLock(userId, ip)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &users.TwoFactorFailedError{
+			UserId: userId,
+			Err:    &users.LoginLockedError{RetryAfter: wait},
+		}
+	}
+
+	twoFactor, err := u.usersRepository.FindTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if twoFactor.Enabled() {
+		err = u.checkTwoFactorCode(userId, twoFactor, req.Code, true)
+	} else {
+		if twoFactor.Secret == nil {
+			return nil, users.ErrTwoFactorNotEnrolled
+		}
+		recoveryCodes, err = u.enableTwoFactor(userId, *twoFactor.Secret, req.Code)
+	}
+	if errors.Is(err, users.ErrTwoFactorCodeInvalid) {
+		return nil, u.twoFactorFailed(userId, ip)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := u.usersRepository.ClearLoginThrottle(users.LoginThrottleTwoFactor, userId); err != nil {
+		return nil, err
+	}
+
+	profile, err := u.usersRepository.GetProfile(userId)
+	if err != nil {
+		return nil, err
+	}
+	passport, err := u.newPassport(profile, device, ip)
+	if err != nil {
+		return nil, err
+	}
+	passport.RecoveryCodes = recoveryCodes
+	return passport, nil
+}
+
+// EnrollTwoFactorChallenge enrolls the user of a challenge that requires setting up 2FA.
+func (u *usersUsecase) EnrollTwoFactorChallenge(req *users.TwoFactorChallengeReq) (*users.TwoFactorEnrollment, error) {
+	userId, err := u.parseTwoFactorChallenge(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	return u.EnrollTwoFactor(userId)
+}
+
+func (u *usersUsecase) FindTwoFactorStatus(userId string) (*users.TwoFactorStatus, error) {
+	twoFactor, err := u.usersRepository.FindTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := u.usersRepository.CountRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &users.TwoFactorStatus{
+		Enabled:       twoFactor.Enabled(),
+		Required:      twoFactor.Required,
+		RecoveryCodes: count,
+	}, nil
+}
+
+// EnrollTwoFactor gives the user a new secret. 2FA is on only once a code of
+// the secret is confirmed with ActivateTwoFactor.
+func (u *usersUsecase) EnrollTwoFactor(userId string) (*users.TwoFactorEnrollment, error) {
+	profile, err := u.usersRepository.GetProfile(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.usersRepository.SetTwoFactorSecret(userId, secret); err != nil {
+		return nil, err
+	}
+
+	return &users.TwoFactorEnrollment{
+		Secret: secret,
+		Uri:    totp.Uri(u.cfg.App().Name(), profile.Email, secret),
+	}, nil
+}
+
+func (u *usersUsecase) ActivateTwoFactor(userId, code string) ([]string, error) {
+	twoFactor, err := u.usersRepository.FindTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled() {
+		return nil, users.ErrTwoFactorEnabled
+	}
+	if twoFactor.Secret == nil {
+		return nil, users.ErrTwoFactorNotEnrolled
+	}
+	return u.enableTwoFactor(userId, *twoFactor.Secret, code)
+}
+
+func (u *usersUsecase) DisableTwoFactor(userId, code string) error {
+	twoFactor, err := u.usersRepository.FindTwoFactor(userId)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.Enabled() {
+		return users.ErrTwoFactorNotEnabled
+	}
+	if twoFactor.Required {
+		return users.ErrTwoFactorRequired
+	}
+	if err := u.checkTwoFactorCode(userId, twoFactor, code, true); err != nil {
+		return err
+	}
+	return u.usersRepository.DisableTwoFactor(userId)
+}
+
+func (u *usersUsecase) RegenerateRecoveryCodes(userId, code string) ([]string, error) {
+	twoFactor, err := u.usersRepository.FindTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactor.Enabled() {
+		return nil, users.ErrTwoFactorNotEnabled
+	}
+	if err := u.checkTwoFactorCode(userId, twoFactor, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.usersRepository.ReplaceRecoveryCodes(userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTwoFactor turns 2FA off for a user who lost both the authenticator and
+// the recovery codes. A role requiring 2FA makes them set it up again on sign in.
+func (u *usersUsecase) ResetTwoFactor(userId string) error {
+	if _, err := u.usersRepository.FindTwoFactor(userId); err != nil {
+		return err
+	}
+	return u.usersRepository.DisableTwoFactor(userId)
+}
+
+func (u *usersUsecase) UpdateRoleTwoFactor(roleId int, required bool) error {
+	return u.usersRepository.UpdateRoleTwoFactor(roleId, required)
+}
+
+// enableTwoFactor confirms the first code of an enrolled secret and returns the
+// recovery codes, which are shown only this once.
+func (u *usersUsecase) enableTwoFactor(userId, secret, code string) ([]string, error) {
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, users.ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := u.usersRepository.EnableTwoFactor(userId, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, users.ErrTwoFactorCodeInvalid
+	}
+	return codes, nil
+}
+
+// checkTwoFactorCode accepts an authenticator code once, or an unused recovery
+// code when allowRecovery is set.
+func (u *usersUsecase) checkTwoFactorCode(userId string, twoFactor *users.TwoFactor, code string, allowRecovery bool) error {
+	if step, ok := totp.Validate(*twoFactor.Secret, code, time.Now()); ok {
+		used, err := u.usersRepository.UseTwoFactorStep(userId, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return users.ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	if !allowRecovery {
+		return users.ErrTwoFactorCodeInvalid
+	}
+	used, err := u.usersRepository.UseRecoveryCode(userId, users.HashToken(users.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return users.ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// newRecoveryCodes returns codes like 3f9a1-c07be and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, users.RecoveryCodeCount)
+	hashes := make([]string, 0, users.RecoveryCodeCount)
+	for i := 0; i < users.RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery codes failed: %v", err)
+		}
+		code := hex.EncodeToString(b)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, users.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// loginFailed counts a failed sign in against the email and the ip, and locks
+// them for the backoff it has reached. The ip is not cleared by a good sign in,
+// so its failures only run out after the lock duration.
+func (u *usersUsecase) loginFailed(email, ip string) error {
+	lock := time.Duration(u.cfg.App().LoginLockMinutes()) * time.Minute
+
+	for _, t := range []struct {
+		kind string
+		key  string
+		max  int
+	}{
+		{users.LoginThrottleEmail, email, u.cfg.App().LoginMaxAttempts()},
+		{users.LoginThrottleIp, ip, u.cfg.App().LoginMaxIpAttempts()},
+	} {
+		failures, err := u.usersRepository.RecordLoginFailure(t.kind, t.key, lock)
+		if err != nil {
+			return err
+		}
+		if wait := users.LoginBackoff(failures, t.max, lock); wait > 0 {
+			if err := u.usersRepository.LockLogin(t.kind, t.key, wait); err != nil {
+				return err
+			}
+		}
+	}
+	return users.ErrInvalidCredentials
+}
+
+// twoFactorFailed counts a bad 2FA code against the user and the ip. Unlike the
+// email, the user's count is only cleared by a good code, so signing in again
+// with the password does not start it over. Once the user is locked, the lock
+// outlasts every challenge already handed out.
+func (u *usersUsecase) twoFactorFailed(userId, ip string) error {
+	lock := time.Duration(u.cfg.App().LoginLockMinutes()) * time.Minute
+	userLock := lock
+	if challenge := time.Duration(auth.TwoFactorExpiresAt) * time.Second; challenge > userLock {
+		userLock = challenge
+	}
+
+	for _, t := range []struct {
+		kind string
+		key  string
+		max  int
+		lock time.Duration
+	}{
+		{users.LoginThrottleTwoFactor, userId, u.cfg.App().LoginMaxAttempts(), userLock},
+		{users.LoginThrottleIp, ip, u.cfg.App().LoginMaxIpAttempts(), lock},
+	} {
+		failures, err := u.usersRepository.RecordLoginFailure(t.kind, t.key, lock)
+		if err != nil {
+			return err
+		}
+		if wait := users.LoginBackoff(failures, t.max, t.lock); wait > 0 {
+			if err := u.usersRepository.LockLogin(t.kind, t.key, wait); err != nil {
+				return err
+			}
+		}
+	}
+	return &users.TwoFactorFailedError{
+		UserId: userId,
+		Err:    users.ErrTwoFactorCodeInvalid,
+	}
+}
+
+func (u *usersUsecase) FindLoginThrottles() ([]*users.LoginThrottle, error) {
+	lock := time.Duration(u.cfg.App().LoginLockMinutes()) * time.Minute
+	return u.usersRepository.FindLoginThrottles(lock)
+}
+
+// UnlockLogin clears the failures and lock of an email or an ip.
+func (u *usersUsecase) UnlockLogin(kind, key string) (bool, error) {
+	if kind == users.LoginThrottleEmail {
+		key = users.LoginEmailKey(key)
+	}
+	return u.usersRepository.ClearLoginThrottle(kind, key)
+}
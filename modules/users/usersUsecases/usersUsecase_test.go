@@ -0,0 +1,407 @@
+package usersUsecases
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yporn/sirarom-backend/config"
+	"github.com/yporn/sirarom-backend/modules/users"
+	"github.com/yporn/sirarom-backend/modules/users/usersRepositories"
+	"github.com/yporn/sirarom-backend/pkg/auth"
+	"github.com/yporn/sirarom-backend/pkg/mailer"
+)
+
+type fakeConfig struct {
+	config.IConfig
+	app config.IAppConfig
+	jwt config.IJwtConfig
+}
+
+func (c *fakeConfig) App() config.IAppConfig { return c.app }
+func (c *fakeConfig) Jwt() config.IJwtConfig { return c.jwt }
+
+type fakeApp struct {
+	config.IAppConfig
+}
+
+func (a *fakeApp) AdminUrl() string          { return "https://admin.example.com" }
+func (a *fakeApp) PasswordResetMinutes() int { return 30 }
+
+type fakeJwt struct {
+	config.IJwtConfig
+}
+
+func (j *fakeJwt) AdminKey() []byte      { return []byte("test-key") }
+func (j *fakeJwt) AccessExpiresAt() int  { return 60 }
+func (j *fakeJwt) RefreshExpiresAt() int { return 3600 }
+
+// fakeSessions keeps the oauth rows and the used refresh tokens in memory.
+type fakeSessions struct {
+	usersRepositories.IUsersRepository
+	oauth    map[string]*users.Oauth // by refresh token
+	used     map[string]*users.Oauth // by rotated out refresh token
+	loseRace bool                    // RotateOauth finds the token already rotated
+}
+
+func (r *fakeSessions) FindOneOauth(refreshToken string) (*users.Oauth, error) {
+	if oauth, ok := r.oauth[refreshToken]; ok {
+		return oauth, nil
+	}
+	return nil, fmt.Errorf("oauth not found")
+}
+
+func (r *fakeSessions) GetProfile(userId string) (*users.User, error) {
+	return &users.User{Id: 1, Email: "admin@example.com"}, nil
+}
+
+func (r *fakeSessions) RotateOauth(refreshToken string, req *users.UserToken) (bool, error) {
+	oauth, ok := r.oauth[refreshToken]
+	if !ok || r.loseRace {
+		return false, nil
+	}
+	delete(r.oauth, refreshToken)
+	r.oauth[req.RefreshToken] = oauth
+	r.used[refreshToken] = oauth
+	return true, nil
+}
+
+func (r *fakeSessions) FindUsedRefreshToken(refreshToken string) (*users.Oauth, error) {
+	if oauth, ok := r.used[refreshToken]; ok {
+		return oauth, nil
+	}
+	return nil, fmt.Errorf("oauth not found")
+}
+
+func (r *fakeSessions) DeleteOauth(oauthId string) error {
+	for token, oauth := range r.oauth {
+		if oauth.Id == oauthId {
+			delete(r.oauth, token)
+		}
+	}
+	return nil
+}
+
+// newSession signs in user 1 and returns the usecase, its store and the refresh token.
+func newSession(t *testing.T) (IUsersUsecase, *fakeSessions, string) {
+	t.Helper()
+
+	cfg := &fakeConfig{jwt: &fakeJwt{}}
+	token, err := auth.NewAuth(auth.Refresh, cfg.Jwt(), &users.UserClaims{Id: "1"})
+	if err != nil {
+		t.Fatalf("sign refresh token failed: %v", err)
+	}
+
+	sessions := &fakeSessions{
+		oauth: map[string]*users.Oauth{token.SignToken(): {Id: "oauth-1", UserId: "1"}},
+		used:  make(map[string]*users.Oauth),
+	}
+	return UsersUsecase(cfg, sessions, nil), sessions, token.SignToken()
+}
+
+func TestRefreshPassportRotates(t *testing.T) {
+	usecase, sessions, first := newSession(t)
+
+	passport, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: first})
+	if err != nil {
+		t.Fatalf("RefreshPassport failed: %v", err)
+	}
+	second := passport.Token.RefreshToken
+	if second == "" || second == first {
+		t.Fatalf("refresh token was not rotated")
+	}
+	if passport.Token.Id != "oauth-1" {
+		t.Errorf("session id = %s, want oauth-1", passport.Token.Id)
+	}
+	if _, ok := sessions.used[first]; !ok {
+		t.Errorf("rotated out token is not kept as used")
+	}
+
+	if _, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: second}); err != nil {
+		t.Errorf("RefreshPassport with the new token failed: %v", err)
+	}
+}
+
+func TestRefreshPassportReplayRevokesSession(t *testing.T) {
+	usecase, sessions, first := newSession(t)
+
+	passport, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: first})
+	if err != nil {
+		t.Fatalf("RefreshPassport failed: %v", err)
+	}
+
+	_, err = usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: first})
+	var reused *users.RefreshTokenReusedError
+	if !errors.As(err, &reused) {
+		t.Fatalf("replayed token gave %v, want RefreshTokenReusedError", err)
+	}
+	if reused.OauthId != "oauth-1" || reused.UserId != "1" {
+		t.Errorf("reused error = %+v, want session oauth-1 of user 1", reused)
+	}
+	if len(sessions.oauth) != 0 {
+		t.Errorf("session was not revoked")
+	}
+
+	// the token handed out by the last rotation dies with the session
+	if _, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: passport.Token.RefreshToken}); err == nil {
+		t.Errorf("token of the revoked session still refreshes")
+	}
+}
+
+func TestRefreshPassportLostRace(t *testing.T) {
+	usecase, sessions, first := newSession(t)
+	sessions.loseRace = true
+	sessions.used[first] = sessions.oauth[first]
+
+	_, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: first})
+	var reused *users.RefreshTokenReusedError
+	if !errors.As(err, &reused) {
+		t.Fatalf("refresh losing the rotation gave %v, want RefreshTokenReusedError", err)
+	}
+}
+
+func TestRefreshPassportUnknownToken(t *testing.T) {
+	usecase, _, _ := newSession(t)
+
+	token, err := auth.NewAuth(auth.Refresh, &fakeJwt{}, &users.UserClaims{Id: "1"})
+	if err != nil {
+		t.Fatalf("sign refresh token failed: %v", err)
+	}
+	_, err = usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: token.SignToken()})
+	var reused *users.RefreshTokenReusedError
+	if err == nil || errors.As(err, &reused) {
+		t.Errorf("token never issued gave %v, want not found", err)
+	}
+}
+
+func TestRefreshPassportAccessToken(t *testing.T) {
+	usecase, _, _ := newSession(t)
+
+	token, err := auth.NewAuth(auth.Access, &fakeJwt{}, &users.UserClaims{Id: "1"})
+	if err != nil {
+		t.Fatalf("sign access token failed: %v", err)
+	}
+	if _, err := usecase.RefreshPassport(&users.UserRefreshCredential{RefreshToken: token.SignToken()}); err == nil {
+		t.Errorf("access token was accepted as a refresh token")
+	}
+}
+
+// fakeMailer keeps the mails it was given.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+	err  error
+	done chan struct{} // closed on the first mail
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{done: make(chan struct{})}
+}
+
+func (m *fakeMailer) Send(msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	if len(m.sent) == 1 {
+		close(m.done)
+	}
+	return m.err
+}
+
+func (m *fakeMailer) Sent() []*mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*mailer.Message(nil), m.sent...)
+}
+
+type passwordReset struct {
+	userId    int
+	expiresAt time.Time
+	used      bool
+}
+
+// fakeAccounts keeps one user, its password resets and its sessions in memory,
+// using the reset tokens the way the password_resets table does.
+type fakeAccounts struct {
+	usersRepositories.IUsersRepository
+	mu       sync.Mutex
+	user     *users.UserCredentialCheck
+	resets   map[string]*passwordReset // by token hash
+	oauth    map[string]int            // user id by oauth id
+	password string
+}
+
+func newFakeAccounts() *fakeAccounts {
+	return &fakeAccounts{
+		user:   &users.UserCredentialCheck{Id: "7", Email: "admin@example.com", Username: "admin"},
+		resets: make(map[string]*passwordReset),
+		oauth:  map[string]int{"oauth-1": 7, "oauth-2": 7, "oauth-3": 8},
+	}
+}
+
+func (r *fakeAccounts) FindOneUserByEmail(email string) (*users.UserCredentialCheck, error) {
+	if email != r.user.Email {
+		return nil, fmt.Errorf("user not found")
+	}
+	return r.user, nil
+}
+
+func (r *fakeAccounts) InsertPasswordReset(userId int, tokenHash string, expiresAt time.Time, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, reset := range r.resets {
+		if reset.userId == userId && !reset.used {
+			delete(r.resets, hash)
+		}
+	}
+	r.resets[tokenHash] = &passwordReset{userId: userId, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *fakeAccounts) ResetPassword(tokenHash, password string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reset, ok := r.resets[tokenHash]
+	if !ok || reset.used || !reset.expiresAt.After(time.Now()) {
+		return 0, users.ErrResetTokenInvalid
+	}
+	reset.used = true
+	r.password = password
+	for id, userId := range r.oauth {
+		if userId == reset.userId {
+			delete(r.oauth, id)
+		}
+	}
+	return reset.userId, nil
+}
+
+func (r *fakeAccounts) GetProfile(userId string) (*users.User, error) {
+	return &users.User{Id: 7, Email: r.user.Email}, nil
+}
+
+func (r *fakeAccounts) ClearLoginThrottle(kind, key string) (bool, error) {
+	return false, nil
+}
+
+func newResetUsecase(accounts *fakeAccounts, mail mailer.IMailer) *usersUsecase {
+	cfg := &fakeConfig{app: &fakeApp{}, jwt: &fakeJwt{}}
+	return UsersUsecase(cfg, accounts, mail).(*usersUsecase)
+}
+
+// resetToken reads the token from the link of a reset mail.
+func resetToken(t *testing.T, msg *mailer.Message) string {
+	t.Helper()
+	const link = "https://admin.example.com/reset-password?token="
+	i := strings.Index(msg.Body, link)
+	if i < 0 {
+		t.Fatalf("reset link not in the mail: %q", msg.Body)
+	}
+	return strings.Fields(msg.Body[i+len(link):])[0]
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	accounts, mail := newFakeAccounts(), newFakeMailer()
+	usecase := newResetUsecase(accounts, mail)
+
+	if err := usecase.sendPasswordReset("admin@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("sendPasswordReset failed: %v", err)
+	}
+
+	sent := mail.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(sent))
+	}
+	if sent[0].To != "admin@example.com" {
+		t.Errorf("mail to %s, want admin@example.com", sent[0].To)
+	}
+	token := resetToken(t, sent[0])
+	reset, ok := accounts.resets[users.HashToken(token)]
+	if !ok {
+		t.Fatalf("no stored reset has the hash of the mailed token")
+	}
+	if reset.userId != 7 {
+		t.Errorf("reset of user %d, want 7", reset.userId)
+	}
+	if d := time.Until(reset.expiresAt); d < 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("reset expires in %v, want 30m", d)
+	}
+}
+
+func TestSendPasswordResetUnknownEmail(t *testing.T) {
+	accounts, mail := newFakeAccounts(), newFakeMailer()
+	usecase := newResetUsecase(accounts, mail)
+
+	if err := usecase.sendPasswordReset("nobody@example.com", ""); err != nil {
+		t.Errorf("sendPasswordReset of an unknown email = %v, want nil", err)
+	}
+	if len(mail.Sent()) != 0 || len(accounts.resets) != 0 {
+		t.Errorf("unknown email sent %d mails and stored %d resets", len(mail.Sent()), len(accounts.resets))
+	}
+}
+
+func TestForgotPasswordSendsInBackground(t *testing.T) {
+	accounts, mail := newFakeAccounts(), newFakeMailer()
+	mail.err = fmt.Errorf("smtp down")
+	usecase := newResetUsecase(accounts, mail)
+
+	// a failing mail is not the caller's to see
+	usecase.ForgotPassword(&users.ForgotPasswordReq{Email: "admin@example.com"}, "")
+
+	select {
+	case <-mail.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reset mail was not sent")
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	accounts, mail := newFakeAccounts(), newFakeMailer()
+	usecase := newResetUsecase(accounts, mail)
+	if err := usecase.sendPasswordReset("admin@example.com", ""); err != nil {
+		t.Fatalf("sendPasswordReset failed: %v", err)
+	}
+	token := resetToken(t, mail.Sent()[0])
+
+	userId, err := usecase.ResetPassword(&users.ResetPasswordReq{Token: token, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	if userId != 7 {
+		t.Errorf("ResetPassword = user %d, want 7", userId)
+	}
+	if accounts.password == "" || accounts.password == "new-password" {
+		t.Errorf("password stored as %q, want its hash", accounts.password)
+	}
+	for id, owner := range accounts.oauth {
+		if owner == 7 {
+			t.Errorf("session %s of the user was not revoked", id)
+		}
+	}
+	if _, ok := accounts.oauth["oauth-3"]; !ok {
+		t.Errorf("session of another user was revoked")
+	}
+
+	if _, err := usecase.ResetPassword(&users.ResetPasswordReq{Token: token, Password: "other-password"}); !errors.Is(err, users.ErrResetTokenInvalid) {
+		t.Errorf("reused token gave %v, want ErrResetTokenInvalid", err)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	accounts, mail := newFakeAccounts(), newFakeMailer()
+	usecase := newResetUsecase(accounts, mail)
+	if err := usecase.sendPasswordReset("admin@example.com", ""); err != nil {
+		t.Fatalf("sendPasswordReset failed: %v", err)
+	}
+	token := resetToken(t, mail.Sent()[0])
+	accounts.resets[users.HashToken(token)].expiresAt = time.Now().Add(-time.Minute)
+
+	if _, err := usecase.ResetPassword(&users.ResetPasswordReq{Token: token, Password: "new-password"}); !errors.Is(err, users.ErrResetTokenInvalid) {
+		t.Errorf("expired token gave %v, want ErrResetTokenInvalid", err)
+	}
+	if len(accounts.oauth) != 3 {
+		t.Errorf("expired token revoked sessions")
+	}
+}
@@ -0,0 +1,38 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	const max = 10
+	lock := 15 * time.Minute
+
+	tests := []struct {
+		failures int
+		lock     time.Duration
+		want     time.Duration
+	}{
+		{failures: 0, lock: lock, want: 0},
+		{failures: 2, lock: lock, want: 0},
+		{failures: 3, lock: lock, want: time.Second},
+		{failures: 4, lock: lock, want: 2 * time.Second},
+		{failures: 9, lock: lock, want: 64 * time.Second},
+		{failures: 10, lock: lock, want: lock},
+		{failures: 25, lock: lock, want: lock},
+		{failures: 9, lock: 30 * time.Second, want: 30 * time.Second}, // never past the lock
+	}
+
+	for _, tt := range tests {
+		if got := LoginBackoff(tt.failures, max, tt.lock); got != tt.want {
+			t.Errorf("LoginBackoff(%d, %d, %v) = %v, want %v", tt.failures, max, tt.lock, got, tt.want)
+		}
+	}
+}
+
+func TestLoginEmailKey(t *testing.T) {
+	if got := LoginEmailKey("  Admin@Example.COM "); got != "admin@example.com" {
+		t.Errorf("LoginEmailKey = %q, want admin@example.com", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/yporn/sirarom-backend/config"
 	"github.com/yporn/sirarom-backend/modules/users"
 )
@@ -18,8 +19,13 @@ const (
 	Refresh TokenType = "refresh"
 	Admin   TokenType = "admin"
 	ApiKey  TokenType = "apikey"
+	// TwoFactor is the short lived challenge given by sign in when a 2FA code is needed.
+	TwoFactor TokenType = "two-factor"
 )
 
+// TwoFactorExpiresAt is how long a 2FA challenge can be answered, in seconds.
+const TwoFactorExpiresAt = 300
+
 type auth struct {
 	mapClaims *mapClaims //payload
 	cfg       config.IJwtConfig
@@ -50,10 +56,6 @@ func jwtTimeDurationCal(t int) *jwt.NumericDate {
 	return jwt.NewNumericDate(time.Now().Add(time.Duration(int64(t) * int64(math.Pow10(9)))))
 }
 
-func jwtTimeRepeatAdapter(t int64) *jwt.NumericDate {
-	return jwt.NewNumericDate(time.Unix(t, 0))
-}
-
 func (a *auth) SignToken() string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, a.mapClaims)
 	ss, _ := token.SignedString(a.cfg.AdminKey())
@@ -122,33 +124,18 @@ func ParseApiKey(cfg config.IJwtConfig, tokenString string) (*mapClaims, error)
 	}
 }
 
-func RepeatToken(cfg config.IJwtConfig, claims *users.UserClaims, exp int64) string {
-	obj := &auth{
-		cfg: cfg,
-		mapClaims: &mapClaims{
-			Claims: claims,
-			RegisteredClaims: jwt.RegisteredClaims{
-				Issuer:    "sirarom-api",
-				Subject:   "refresh-token",
-				ExpiresAt: jwtTimeRepeatAdapter(exp),
-				NotBefore: jwt.NewNumericDate(time.Now()),
-				IssuedAt:  jwt.NewNumericDate(time.Now()),
-			},
-		},
-	}
-	return obj.SignToken()
-}
-
 func NewAuth(tokenType TokenType, cfg config.IJwtConfig, claims *users.UserClaims) (IAuth, error) {
 	switch tokenType {
 	case Access:
 		return newAccessToken(cfg, claims), nil
 	case Refresh:
-		return newRefreshToken(cfg), nil
+		return newRefreshToken(cfg, claims), nil
 	case Admin:
 		return newAdminToken(cfg), nil
 	case ApiKey:
 		return newApiKey(cfg), nil
+	case TwoFactor:
+		return newTwoFactorToken(cfg, claims), nil
 	default:
 		return nil, fmt.Errorf("unknown token type")
 	}
@@ -162,6 +149,7 @@ func newAccessToken(cfg config.IJwtConfig, claims *users.UserClaims) IAuth {
 			RegisteredClaims: jwt.RegisteredClaims{
 				Issuer:    "sirarom-api",
 				Subject:   "access-token",
+				ID:        uuid.NewString(),
 				ExpiresAt: jwtTimeDurationCal(cfg.AccessExpiresAt()),
 				NotBefore: jwt.NewNumericDate(time.Now()),
 				IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -170,15 +158,34 @@ func newAccessToken(cfg config.IJwtConfig, claims *users.UserClaims) IAuth {
 	}
 }
 
-func newRefreshToken(cfg config.IJwtConfig) IAuth {
+// newRefreshToken carries a random id so every rotation signs a different token.
+func newRefreshToken(cfg config.IJwtConfig, claims *users.UserClaims) IAuth {
 	return &auth{
 		cfg: cfg,
 		mapClaims: &mapClaims{
-			Claims: nil,
+			Claims: claims,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "sirarom-api",
+				Subject:   "refresh-token",
+				ID:        uuid.NewString(),
+				ExpiresAt: jwtTimeDurationCal(cfg.RefreshExpiresAt()),
+				NotBefore: jwt.NewNumericDate(time.Now()),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+			},
+		},
+	}
+}
+
+func newTwoFactorToken(cfg config.IJwtConfig, claims *users.UserClaims) IAuth {
+	return &auth{
+		cfg: cfg,
+		mapClaims: &mapClaims{
+			Claims: claims,
 			RegisteredClaims: jwt.RegisteredClaims{
 				Issuer:    "sirarom-api",
-				Subject:   "admin-token",
-				ExpiresAt: jwtTimeDurationCal(600),
+				Subject:   "two-factor-token",
+				ID:        uuid.NewString(),
+				ExpiresAt: jwtTimeDurationCal(TwoFactorExpiresAt),
 				NotBefore: jwt.NewNumericDate(time.Now()),
 				IssuedAt:  jwt.NewNumericDate(time.Now()),
 			},
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "banner_images" DROP COLUMN IF EXISTS "srcset";
+ALTER TABLE "project_images" DROP COLUMN IF EXISTS "srcset";
+ALTER TABLE "house_model_images" DROP COLUMN IF EXISTS "srcset";
+ALTER TABLE "house_model_plan_images" DROP COLUMN IF EXISTS "srcset";
+
+COMMIT;
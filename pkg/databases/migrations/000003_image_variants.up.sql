@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "banner_images" ADD COLUMN "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE "project_images" ADD COLUMN "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE "house_model_images" ADD COLUMN "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE "house_model_plan_images" ADD COLUMN "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+COMMIT;
@@ -0,0 +1,30 @@
+BEGIN;
+
+DROP VIEW IF EXISTS "media_references";
+
+DROP TRIGGER IF EXISTS set_media_id_user_images_table ON "user_images";
+ALTER TABLE "user_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_banner_images_table ON "banner_images";
+ALTER TABLE "banner_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_logo_images_table ON "logo_images";
+ALTER TABLE "logo_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_data_setting_images_table ON "data_setting_images";
+ALTER TABLE "data_setting_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_project_images_table ON "project_images";
+ALTER TABLE "project_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_house_model_images_table ON "house_model_images";
+ALTER TABLE "house_model_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_house_model_plan_images_table ON "house_model_plan_images";
+ALTER TABLE "house_model_plan_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_interest_images_table ON "interest_images";
+ALTER TABLE "interest_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_promotion_images_table ON "promotion_images";
+ALTER TABLE "promotion_images" DROP COLUMN IF EXISTS "media_id";
+DROP TRIGGER IF EXISTS set_media_id_activities_images_table ON "activities_images";
+ALTER TABLE "activities_images" DROP COLUMN IF EXISTS "media_id";
+
+DROP FUNCTION IF EXISTS set_media_id_column;
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_media_table ON "media";
+DROP TABLE IF EXISTS "media" CASCADE;
+
+COMMIT;
@@ -0,0 +1,172 @@
+BEGIN;
+
+CREATE TABLE "media" (
+    "id" SERIAL PRIMARY KEY,
+    "filename" VARCHAR NOT NULL,
+    "url" VARCHAR NOT NULL UNIQUE,
+    "path" VARCHAR NOT NULL UNIQUE,
+    "hash" VARCHAR,
+    "size" INTEGER,
+    "width" INTEGER,
+    "height" INTEGER,
+    "mime_type" VARCHAR,
+    "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb,
+    "uploaded_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "media_hash_idx" ON "media" ("hash");
+
+ALTER TABLE "media"
+ADD FOREIGN KEY ("uploaded_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE TRIGGER set_updated_at_timestamp_media_table BEFORE
+UPDATE ON "media" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+--Link image rows to the media row with the same url
+CREATE OR REPLACE FUNCTION set_media_id_column()
+RETURNS TRIGGER AS
+$$
+BEGIN
+	SELECT "id" INTO NEW.media_id FROM "media" WHERE "url" = NEW.url LIMIT 1;
+	RETURN NEW;
+END;
+$$
+language
+'plpgsql';
+
+--Backfill media from the files already referenced
+INSERT INTO "media" ("filename", "url", "path")
+SELECT DISTINCT ON ("url")
+    COALESCE("filename", ''),
+    "url",
+    COALESCE(substring("url" from 'assets/.*$'), "url")
+FROM (
+    SELECT "filename", "url" FROM "user_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "banner_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "logo_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "data_setting_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "project_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "house_model_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "house_model_plan_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "interest_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "promotion_images"
+    UNION ALL
+    SELECT "filename", "url" FROM "activities_images"
+) AS "i"
+WHERE "url" IS NOT NULL AND "url" <> ''
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "user_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "user_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "user_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "user_images"."url";
+CREATE TRIGGER set_media_id_user_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "user_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "banner_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "banner_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "banner_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "banner_images"."url";
+CREATE TRIGGER set_media_id_banner_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "banner_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "logo_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "logo_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "logo_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "logo_images"."url";
+CREATE TRIGGER set_media_id_logo_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "logo_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "data_setting_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "data_setting_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "data_setting_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "data_setting_images"."url";
+CREATE TRIGGER set_media_id_data_setting_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "data_setting_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "project_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "project_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "project_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "project_images"."url";
+CREATE TRIGGER set_media_id_project_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "project_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "house_model_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "house_model_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "house_model_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "house_model_images"."url";
+CREATE TRIGGER set_media_id_house_model_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "house_model_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "house_model_plan_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "house_model_plan_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "house_model_plan_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "house_model_plan_images"."url";
+CREATE TRIGGER set_media_id_house_model_plan_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "house_model_plan_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "interest_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "interest_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "interest_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "interest_images"."url";
+CREATE TRIGGER set_media_id_interest_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "interest_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "promotion_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "promotion_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "promotion_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "promotion_images"."url";
+CREATE TRIGGER set_media_id_promotion_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "promotion_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+ALTER TABLE "activities_images" ADD COLUMN "media_id" INTEGER;
+ALTER TABLE "activities_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+UPDATE "activities_images" SET "media_id" = "m"."id" FROM "media" "m" WHERE "m"."url" = "activities_images"."url";
+CREATE TRIGGER set_media_id_activities_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "activities_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+--Every row that points at a media file
+CREATE VIEW "media_references" AS
+SELECT "media_id", 'user_images' AS "source" FROM "user_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'banner_images' AS "source" FROM "banner_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'logo_images' AS "source" FROM "logo_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'data_setting_images' AS "source" FROM "data_setting_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'project_images' AS "source" FROM "project_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_images' AS "source" FROM "house_model_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_plan_images' AS "source" FROM "house_model_plan_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'interest_images' AS "source" FROM "interest_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'promotion_images' AS "source" FROM "promotion_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'activities_images' AS "source" FROM "activities_images" WHERE "media_id" IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,36 @@
+BEGIN;
+
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "house_models" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "promotions" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "activities" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "banners" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "logos" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "interests" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "careers" DROP COLUMN IF EXISTS "publish_at", DROP COLUMN IF EXISTS "unpublish_at";
+
+-- Enum values cannot be dropped, so the type is rebuilt without them
+UPDATE "users" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "banners" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "logos" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "projects" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "house_models" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "interests" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "careers" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "promotions" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+UPDATE "activities" SET "display" = 'unpublished' WHERE "display"::text IN ('draft', 'scheduled', 'archived');
+
+ALTER TYPE "display" RENAME TO "display_old";
+CREATE TYPE "display" AS ENUM('published', 'unpublished');
+ALTER TABLE "users" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "banners" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "logos" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "projects" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "house_models" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "interests" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "careers" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "promotions" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+ALTER TABLE "activities" ALTER COLUMN "display" TYPE "display" USING "display"::text::"display";
+DROP TYPE "display_old";
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- 'unpublished' is kept for rows created before the workflow existed
+ALTER TYPE "display" ADD VALUE IF NOT EXISTS 'draft';
+ALTER TYPE "display" ADD VALUE IF NOT EXISTS 'scheduled';
+ALTER TYPE "display" ADD VALUE IF NOT EXISTS 'archived';
+
+ALTER TABLE "projects" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "house_models" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "promotions" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "activities" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "banners" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "logos" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "interests" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+ALTER TABLE "careers" ADD COLUMN "publish_at" TIMESTAMPTZ, ADD COLUMN "unpublish_at" TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,44 @@
+BEGIN;
+
+CREATE TABLE "revisions" (
+    "id" SERIAL PRIMARY KEY,
+    "entity" VARCHAR NOT NULL,
+    "entity_id" INTEGER NOT NULL,
+    "snapshot" JSONB NOT NULL,
+    "created_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "revisions"
+ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE INDEX "revisions_entity_idx" ON "revisions" ("entity", "entity_id", "id" DESC);
+
+-- Files used by an older revision must survive the media sweep so the revision can still be restored
+CREATE OR REPLACE VIEW "media_references" AS
+SELECT "media_id", 'user_images' AS "source" FROM "user_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'banner_images' AS "source" FROM "banner_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'logo_images' AS "source" FROM "logo_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'data_setting_images' AS "source" FROM "data_setting_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'project_images' AS "source" FROM "project_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_images' AS "source" FROM "house_model_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_plan_images' AS "source" FROM "house_model_plan_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'interest_images' AS "source" FROM "interest_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'promotion_images' AS "source" FROM "promotion_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'activities_images' AS "source" FROM "activities_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT DISTINCT "m"."id" AS "media_id", 'revisions' AS "source"
+FROM "revisions" "r"
+CROSS JOIN LATERAL jsonb_path_query("r"."snapshot", 'strict $.**.url') AS "u"("url")
+JOIN "media" "m" ON "m"."url" = "u"."url" #>> '{}';
+
+COMMIT;
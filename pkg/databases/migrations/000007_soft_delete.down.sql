@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "house_models" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "promotions" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "activities" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "banners" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "logos" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "interests" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "careers" DROP COLUMN IF EXISTS "deleted_at";
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Deleted rows stay in the trash until they are restored or purged
+ALTER TABLE "projects" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "house_models" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "promotions" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "activities" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "banners" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "logos" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "interests" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+ALTER TABLE "careers" ADD COLUMN "deleted_at" TIMESTAMPTZ;
+
+CREATE INDEX "projects_deleted_at_idx" ON "projects" ("deleted_at");
+CREATE INDEX "house_models_deleted_at_idx" ON "house_models" ("deleted_at");
+CREATE INDEX "promotions_deleted_at_idx" ON "promotions" ("deleted_at");
+CREATE INDEX "activities_deleted_at_idx" ON "activities" ("deleted_at");
+CREATE INDEX "banners_deleted_at_idx" ON "banners" ("deleted_at");
+CREATE INDEX "logos_deleted_at_idx" ON "logos" ("deleted_at");
+CREATE INDEX "interests_deleted_at_idx" ON "interests" ("deleted_at");
+CREATE INDEX "careers_deleted_at_idx" ON "careers" ("deleted_at");
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "lead_tasks";
+DROP TABLE IF EXISTS "lead_notes";
+DROP TABLE IF EXISTS "project_sales";
+DROP TABLE IF EXISTS "leads";
+DROP TYPE IF EXISTS "lead_status";
+
+DELETE FROM "roles" WHERE "title" = 'sales';
+
+COMMIT;
@@ -0,0 +1,91 @@
+BEGIN;
+
+-- Sales users take leads from the projects they are assigned to
+INSERT INTO "roles" ("id", "title") VALUES (7, 'sales') ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('roles', 'id'), (SELECT MAX("id") FROM "roles"));
+
+CREATE TYPE "lead_status" AS ENUM('new', 'contacted', 'visited', 'won', 'lost');
+
+CREATE TABLE "leads" (
+    "id" SERIAL PRIMARY KEY,
+    "name" VARCHAR NOT NULL,
+    "tel" VARCHAR,
+    "email" VARCHAR,
+    "message" TEXT,
+    "project_id" INTEGER,
+    "house_model_id" INTEGER,
+    "promotion_id" INTEGER,
+    "status" lead_status NOT NULL DEFAULT 'new',
+    "lost_reason" VARCHAR,
+    "assigned_to" INTEGER,
+    "ip" VARCHAR,
+    "sla_alerted_at" TIMESTAMPTZ,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Round-robin pool: the sales user assigned least recently gets the next lead of the project
+CREATE TABLE "project_sales" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "user_id" INTEGER NOT NULL,
+    "last_assigned_at" TIMESTAMPTZ,
+    UNIQUE ("project_id", "user_id")
+);
+
+CREATE TABLE "lead_notes" (
+    "id" SERIAL PRIMARY KEY,
+    "lead_id" INTEGER NOT NULL,
+    "note" TEXT NOT NULL,
+    "created_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "lead_tasks" (
+    "id" SERIAL PRIMARY KEY,
+    "lead_id" INTEGER NOT NULL,
+    "title" VARCHAR NOT NULL,
+    "due_at" TIMESTAMPTZ NOT NULL,
+    "done_at" TIMESTAMPTZ,
+    "assigned_to" INTEGER,
+    "created_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "leads"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE SET NULL;
+ALTER TABLE "leads"
+ADD FOREIGN KEY ("house_model_id") REFERENCES "house_models" ("id") ON DELETE SET NULL;
+ALTER TABLE "leads"
+ADD FOREIGN KEY ("promotion_id") REFERENCES "promotions" ("id") ON DELETE SET NULL;
+ALTER TABLE "leads"
+ADD FOREIGN KEY ("assigned_to") REFERENCES "users" ("id") ON DELETE SET NULL;
+ALTER TABLE "project_sales"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_sales"
+ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "lead_notes"
+ADD FOREIGN KEY ("lead_id") REFERENCES "leads" ("id") ON DELETE CASCADE;
+ALTER TABLE "lead_notes"
+ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+ALTER TABLE "lead_tasks"
+ADD FOREIGN KEY ("lead_id") REFERENCES "leads" ("id") ON DELETE CASCADE;
+ALTER TABLE "lead_tasks"
+ADD FOREIGN KEY ("assigned_to") REFERENCES "users" ("id") ON DELETE SET NULL;
+ALTER TABLE "lead_tasks"
+ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE INDEX "leads_status_idx" ON "leads" ("status", "created_at");
+CREATE INDEX "leads_assigned_to_idx" ON "leads" ("assigned_to");
+CREATE INDEX "lead_tasks_due_at_idx" ON "lead_tasks" ("due_at") WHERE "done_at" IS NULL;
+
+CREATE TRIGGER set_updated_at_timestamp_leads_table BEFORE
+UPDATE ON "leads" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+CREATE TRIGGER set_updated_at_timestamp_lead_tasks_table BEFORE
+UPDATE ON "lead_tasks" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+COMMIT;
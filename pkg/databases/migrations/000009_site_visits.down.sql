@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "site_visits";
+DROP TABLE IF EXISTS "project_visit_blackouts";
+DROP TABLE IF EXISTS "project_visit_hours";
+DROP TYPE IF EXISTS "site_visit_status";
+
+COMMIT;
@@ -0,0 +1,54 @@
+BEGIN;
+
+CREATE TYPE "site_visit_status" AS ENUM('confirmed', 'cancelled');
+
+-- Weekly show-home opening hours, weekday 0 is Sunday. Times are local wall time.
+CREATE TABLE "project_visit_hours" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "weekday" SMALLINT NOT NULL CHECK ("weekday" BETWEEN 0 AND 6),
+    "start_time" TIME NOT NULL,
+    "end_time" TIME NOT NULL,
+    "slot_minutes" INTEGER NOT NULL DEFAULT 60 CHECK ("slot_minutes" > 0),
+    CHECK ("end_time" > "start_time")
+);
+
+CREATE TABLE "project_visit_blackouts" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "date" DATE NOT NULL,
+    "reason" VARCHAR,
+    UNIQUE ("project_id", "date")
+);
+
+CREATE TABLE "site_visits" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "visit_date" DATE NOT NULL,
+    "start_time" TIME NOT NULL,
+    "end_time" TIME NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "tel" VARCHAR,
+    "email" VARCHAR,
+    "note" TEXT,
+    "status" site_visit_status NOT NULL DEFAULT 'confirmed',
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "project_visit_hours"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_visit_blackouts"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "site_visits"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+
+-- One confirmed booking per slot, concurrent bookings of the same slot fail here
+CREATE UNIQUE INDEX "site_visits_slot_key" ON "site_visits" ("project_id", "visit_date", "start_time") WHERE "status" = 'confirmed';
+CREATE INDEX "project_visit_hours_project_idx" ON "project_visit_hours" ("project_id", "weekday");
+
+CREATE TRIGGER set_updated_at_timestamp_site_visits_table BEFORE
+UPDATE ON "site_visits" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+COMMIT;
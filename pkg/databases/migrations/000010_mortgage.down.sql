@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "interest_rate_tiers";
+ALTER TABLE "house_models" DROP COLUMN IF EXISTS "price";
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Empty price falls back to the project price in the mortgage calculator
+ALTER TABLE "house_models" ADD COLUMN "price" FLOAT;
+
+-- Promotional rates for a span of loan years, e.g. years 1-3. Years without a
+-- tier use "interests"."interest_rate". An empty to_year runs to the end of the loan.
+CREATE TABLE "interest_rate_tiers" (
+    "id" SERIAL PRIMARY KEY,
+    "interest_id" INTEGER NOT NULL,
+    "from_year" INTEGER NOT NULL CHECK ("from_year" >= 1),
+    "to_year" INTEGER CHECK ("to_year" >= "from_year"),
+    "rate" FLOAT NOT NULL CHECK ("rate" >= 0)
+);
+
+ALTER TABLE "interest_rate_tiers"
+ADD FOREIGN KEY ("interest_id") REFERENCES "interests" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "interest_rate_tiers_interest_idx" ON "interest_rate_tiers" ("interest_id", "from_year");
+
+COMMIT;
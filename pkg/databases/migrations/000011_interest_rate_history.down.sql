@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "interest_rate_history";
+
+COMMIT;
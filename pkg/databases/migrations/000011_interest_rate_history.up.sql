@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Every advertised rate of a bank. "interests"."interest_rate" is the rate in
+-- effect now, the scheduler moves it to a future-dated row once that row starts.
+CREATE TABLE "interest_rate_history" (
+    "id" SERIAL PRIMARY KEY,
+    "interest_id" INTEGER NOT NULL,
+    "rate" FLOAT NOT NULL,
+    "effective_from" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "effective_to" TIMESTAMPTZ,
+    "created_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE ("interest_id", "effective_from")
+);
+
+ALTER TABLE "interest_rate_history"
+ADD FOREIGN KEY ("interest_id") REFERENCES "interests" ("id") ON DELETE CASCADE;
+ALTER TABLE "interest_rate_history"
+ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+-- The rates stored so far count from the day the bank was added
+INSERT INTO "interest_rate_history" ("interest_id", "rate", "effective_from")
+SELECT "id", "interest_rate", "created_at"
+FROM "interests"
+WHERE "interest_rate" IS NOT NULL;
+
+COMMIT;
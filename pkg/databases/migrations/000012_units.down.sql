@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "unit_reservations";
+DROP TABLE IF EXISTS "project_units";
+DROP TYPE IF EXISTS "unit_reservation_status";
+DROP TYPE IF EXISTS "unit_status";
+
+COMMIT;
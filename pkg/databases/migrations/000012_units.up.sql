@@ -0,0 +1,64 @@
+BEGIN;
+
+CREATE TYPE "unit_status" AS ENUM('available', 'reserved', 'sold');
+CREATE TYPE "unit_reservation_status" AS ENUM('pending', 'confirmed', 'released');
+
+-- One plot of a project. A plot without its own deposit takes APP_RESERVATION_DEPOSIT.
+CREATE TABLE "project_units" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "house_model_id" INTEGER,
+    "plot_no" VARCHAR NOT NULL,
+    "land_size" FLOAT NOT NULL DEFAULT 0 CHECK ("land_size" >= 0),
+    "price" FLOAT NOT NULL DEFAULT 0 CHECK ("price" >= 0),
+    "deposit" FLOAT CHECK ("deposit" > 0),
+    "status" unit_status NOT NULL DEFAULT 'available',
+    "note" TEXT,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE ("project_id", "plot_no")
+);
+
+-- An online hold of a plot. The project and plot number are kept so the record
+-- outlives the plot.
+CREATE TABLE "unit_reservations" (
+    "id" SERIAL PRIMARY KEY,
+    "unit_id" INTEGER,
+    "project_id" INTEGER NOT NULL,
+    "plot_no" VARCHAR NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "tel" VARCHAR NOT NULL,
+    "email" VARCHAR,
+    "note" TEXT,
+    "deposit" FLOAT NOT NULL,
+    "status" unit_reservation_status NOT NULL DEFAULT 'pending',
+    "payment_ref" VARCHAR UNIQUE,
+    "checkout_url" VARCHAR,
+    "expires_at" TIMESTAMP NOT NULL,
+    "paid_at" TIMESTAMP,
+    "ip" VARCHAR,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "project_units"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_units"
+ADD FOREIGN KEY ("house_model_id") REFERENCES "house_models" ("id") ON DELETE SET NULL;
+ALTER TABLE "unit_reservations"
+ADD FOREIGN KEY ("unit_id") REFERENCES "project_units" ("id") ON DELETE SET NULL;
+ALTER TABLE "unit_reservations"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "project_units_project_idx" ON "project_units" ("project_id", "house_model_id", "status");
+CREATE INDEX "unit_reservations_pending_idx" ON "unit_reservations" ("expires_at") WHERE "status" = 'pending';
+
+CREATE TRIGGER set_updated_at_timestamp_project_units_table BEFORE
+UPDATE ON "project_units" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+CREATE TRIGGER set_updated_at_timestamp_unit_reservations_table BEFORE
+UPDATE ON "unit_reservations" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+CREATE OR REPLACE VIEW "media_references" AS
+SELECT "media_id", 'user_images' AS "source" FROM "user_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'banner_images' AS "source" FROM "banner_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'logo_images' AS "source" FROM "logo_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'data_setting_images' AS "source" FROM "data_setting_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'project_images' AS "source" FROM "project_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_images' AS "source" FROM "house_model_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_plan_images' AS "source" FROM "house_model_plan_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'interest_images' AS "source" FROM "interest_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'promotion_images' AS "source" FROM "promotion_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'activities_images' AS "source" FROM "activities_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT DISTINCT "m"."id" AS "media_id", 'revisions' AS "source"
+FROM "revisions" "r"
+CROSS JOIN LATERAL jsonb_path_query("r"."snapshot", 'strict $.**.url') AS "u"("url")
+JOIN "media" "m" ON "m"."url" = "u"."url" #>> '{}';
+
+ALTER TABLE "house_models" DROP COLUMN IF EXISTS "phase_id";
+DROP TABLE IF EXISTS "project_phase_area_items";
+DROP TABLE IF EXISTS "project_phase_images";
+DROP TABLE IF EXISTS "project_phases";
+DROP TYPE IF EXISTS "phase_status";
+
+COMMIT;
@@ -0,0 +1,95 @@
+BEGIN;
+
+CREATE TYPE "phase_status" AS ENUM('upcoming', 'selling', 'sold_out');
+
+-- A phase or zone of a project, sold on its own launch date
+CREATE TABLE "project_phases" (
+    "id" SERIAL PRIMARY KEY,
+    "project_id" INTEGER NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "index" INTEGER NOT NULL DEFAULT 0,
+    "status" phase_status NOT NULL DEFAULT 'upcoming',
+    "launch_date" DATE,
+    "price_min" FLOAT CHECK ("price_min" >= 0),
+    "price_max" FLOAT CHECK ("price_max" >= 0),
+    "description" TEXT,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK ("price_min" IS NULL OR "price_max" IS NULL OR "price_max" >= "price_min")
+);
+
+CREATE TABLE "project_phase_images" (
+    "id" SERIAL PRIMARY KEY,
+    "phase_id" INTEGER NOT NULL,
+    "filename" VARCHAR,
+    "url" VARCHAR,
+    "srcset" JSONB NOT NULL DEFAULT '{}'::jsonb,
+    "media_id" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "project_phase_area_items" (
+    "id" SERIAL PRIMARY KEY,
+    "phase_id" INTEGER NOT NULL,
+    "item" VARCHAR,
+    "amount" INTEGER,
+    "unit" VARCHAR,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "house_models" ADD COLUMN "phase_id" INTEGER;
+
+ALTER TABLE "project_phases"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_phase_images"
+ADD FOREIGN KEY ("phase_id") REFERENCES "project_phases" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_phase_images"
+ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE SET NULL;
+ALTER TABLE "project_phase_area_items"
+ADD FOREIGN KEY ("phase_id") REFERENCES "project_phases" ("id") ON DELETE CASCADE;
+ALTER TABLE "house_models"
+ADD FOREIGN KEY ("phase_id") REFERENCES "project_phases" ("id") ON DELETE SET NULL;
+
+CREATE INDEX "project_phases_project_idx" ON "project_phases" ("project_id", "index");
+CREATE INDEX "house_models_phase_idx" ON "house_models" ("phase_id");
+
+CREATE TRIGGER set_updated_at_timestamp_project_phases_table BEFORE
+UPDATE ON "project_phases" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+CREATE TRIGGER set_media_id_project_phase_images_table BEFORE
+INSERT OR UPDATE OF "url" ON "project_phase_images" FOR EACH ROW
+EXECUTE PROCEDURE set_media_id_column ();
+
+--Every row that points at a media file
+CREATE OR REPLACE VIEW "media_references" AS
+SELECT "media_id", 'user_images' AS "source" FROM "user_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'banner_images' AS "source" FROM "banner_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'logo_images' AS "source" FROM "logo_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'data_setting_images' AS "source" FROM "data_setting_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'project_images' AS "source" FROM "project_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_images' AS "source" FROM "house_model_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'house_model_plan_images' AS "source" FROM "house_model_plan_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'interest_images' AS "source" FROM "interest_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'promotion_images' AS "source" FROM "promotion_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT "media_id", 'activities_images' AS "source" FROM "activities_images" WHERE "media_id" IS NOT NULL
+UNION ALL
+SELECT DISTINCT "m"."id" AS "media_id", 'revisions' AS "source"
+FROM "revisions" "r"
+CROSS JOIN LATERAL jsonb_path_query("r"."snapshot", 'strict $.**.url') AS "u"("url")
+JOIN "media" "m" ON "m"."url" = "u"."url" #>> '{}'
+UNION ALL
+SELECT "media_id", 'project_phase_images' AS "source" FROM "project_phase_images" WHERE "media_id" IS NOT NULL;
+
+COMMIT;
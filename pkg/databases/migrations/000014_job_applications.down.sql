@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "job_application_notes";
+DROP TABLE IF EXISTS "job_applications";
+DROP TYPE IF EXISTS "application_stage";
+
+COMMIT;
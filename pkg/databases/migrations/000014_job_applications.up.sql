@@ -0,0 +1,41 @@
+BEGIN;
+
+CREATE TYPE "application_stage" AS ENUM('new', 'screening', 'interview', 'offer', 'rejected');
+
+CREATE TABLE "job_applications" (
+    "id" SERIAL PRIMARY KEY,
+    "career_id" INTEGER NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "email" VARCHAR NOT NULL,
+    "tel" VARCHAR NOT NULL,
+    "cover_letter" TEXT,
+    "resume_filename" VARCHAR NOT NULL,
+    "resume_url" VARCHAR NOT NULL,
+    "stage" application_stage NOT NULL DEFAULT 'new',
+    "ip" VARCHAR,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "job_application_notes" (
+    "id" SERIAL PRIMARY KEY,
+    "application_id" INTEGER NOT NULL,
+    "note" TEXT NOT NULL,
+    "created_by" INTEGER,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "job_applications"
+ADD FOREIGN KEY ("career_id") REFERENCES "careers" ("id") ON DELETE CASCADE;
+ALTER TABLE "job_application_notes"
+ADD FOREIGN KEY ("application_id") REFERENCES "job_applications" ("id") ON DELETE CASCADE;
+ALTER TABLE "job_application_notes"
+ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE INDEX "job_applications_career_id_idx" ON "job_applications" ("career_id", "stage");
+
+CREATE TRIGGER set_updated_at_timestamp_job_applications_table BEFORE
+UPDATE ON "job_applications" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+COMMIT;
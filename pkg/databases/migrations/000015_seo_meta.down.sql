@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS delete_seo_meta_projects_table ON "projects";
+DROP TRIGGER IF EXISTS delete_seo_meta_house_models_table ON "house_models";
+DROP TRIGGER IF EXISTS delete_seo_meta_promotions_table ON "promotions";
+DROP TRIGGER IF EXISTS delete_seo_meta_activities_table ON "activities";
+DROP FUNCTION IF EXISTS delete_seo_meta;
+DROP TABLE IF EXISTS "seo_meta";
+
+COMMIT;
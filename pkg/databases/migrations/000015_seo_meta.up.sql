@@ -0,0 +1,47 @@
+BEGIN;
+
+-- Meta tags of a single page, the global "seo" row fills in whatever is empty
+CREATE TABLE "seo_meta" (
+    "id" SERIAL PRIMARY KEY,
+    "page" VARCHAR NOT NULL,
+    "entity_id" INTEGER NOT NULL,
+    "title" VARCHAR,
+    "description" TEXT,
+    "og_image" VARCHAR,
+    "canonical_url" VARCHAR,
+    "noindex" BOOLEAN NOT NULL DEFAULT false,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE ("page", "entity_id")
+);
+
+CREATE TRIGGER set_updated_at_timestamp_seo_meta_table BEFORE
+UPDATE ON "seo_meta" FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at_column ();
+
+-- "page" has no foreign key, the meta goes when its row is purged
+CREATE OR REPLACE FUNCTION delete_seo_meta()
+RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM "seo_meta" WHERE "page" = TG_ARGV[0] AND "entity_id" = OLD."id";
+    RETURN OLD;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER delete_seo_meta_projects_table AFTER
+DELETE ON "projects" FOR EACH ROW
+EXECUTE PROCEDURE delete_seo_meta ('projects');
+
+CREATE TRIGGER delete_seo_meta_house_models_table AFTER
+DELETE ON "house_models" FOR EACH ROW
+EXECUTE PROCEDURE delete_seo_meta ('house_models');
+
+CREATE TRIGGER delete_seo_meta_promotions_table AFTER
+DELETE ON "promotions" FOR EACH ROW
+EXECUTE PROCEDURE delete_seo_meta ('promotions');
+
+CREATE TRIGGER delete_seo_meta_activities_table AFTER
+DELETE ON "activities" FOR EACH ROW
+EXECUTE PROCEDURE delete_seo_meta ('activities');
+
+COMMIT;
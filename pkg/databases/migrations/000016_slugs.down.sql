@@ -0,0 +1,20 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS keep_slug_redirect_projects_table ON "projects";
+DROP TRIGGER IF EXISTS free_restored_slug_projects_table ON "projects";
+DROP TRIGGER IF EXISTS keep_slug_redirect_house_models_table ON "house_models";
+DROP TRIGGER IF EXISTS free_restored_slug_house_models_table ON "house_models";
+DROP TRIGGER IF EXISTS keep_slug_redirect_promotions_table ON "promotions";
+DROP TRIGGER IF EXISTS free_restored_slug_promotions_table ON "promotions";
+DROP TRIGGER IF EXISTS keep_slug_redirect_activities_table ON "activities";
+DROP TRIGGER IF EXISTS free_restored_slug_activities_table ON "activities";
+DROP FUNCTION IF EXISTS keep_slug_redirect;
+DROP FUNCTION IF EXISTS free_restored_slug;
+DROP TABLE IF EXISTS "slug_redirects";
+
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "slug";
+ALTER TABLE "house_models" DROP COLUMN IF EXISTS "slug";
+ALTER TABLE "promotions" DROP COLUMN IF EXISTS "slug";
+ALTER TABLE "activities" DROP COLUMN IF EXISTS "slug";
+
+COMMIT;
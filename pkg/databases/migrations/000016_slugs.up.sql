@@ -0,0 +1,108 @@
+BEGIN;
+
+-- Url slugs of the pages, only unique among the rows that are not in the trash
+-- so recreated content can take the slug back
+ALTER TABLE "projects" ADD COLUMN "slug" VARCHAR;
+ALTER TABLE "house_models" ADD COLUMN "slug" VARCHAR;
+ALTER TABLE "promotions" ADD COLUMN "slug" VARCHAR;
+ALTER TABLE "activities" ADD COLUMN "slug" VARCHAR;
+
+-- Existing rows keep the ascii part of their name, the id keeps them unique
+UPDATE "projects" SET "slug" = COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(lower("name"), '[^a-z0-9]+', '-', 'g')), '') || '-', '') || "id";
+UPDATE "house_models" SET "slug" = COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(lower("name"), '[^a-z0-9]+', '-', 'g')), '') || '-', '') || "id";
+UPDATE "promotions" SET "slug" = COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(lower("heading"), '[^a-z0-9]+', '-', 'g')), '') || '-', '') || "id";
+UPDATE "activities" SET "slug" = COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(lower("heading"), '[^a-z0-9]+', '-', 'g')), '') || '-', '') || "id";
+
+ALTER TABLE "projects" ALTER COLUMN "slug" SET NOT NULL;
+ALTER TABLE "house_models" ALTER COLUMN "slug" SET NOT NULL;
+ALTER TABLE "promotions" ALTER COLUMN "slug" SET NOT NULL;
+ALTER TABLE "activities" ALTER COLUMN "slug" SET NOT NULL;
+
+CREATE UNIQUE INDEX "projects_slug_key" ON "projects" ("slug") WHERE "deleted_at" IS NULL;
+CREATE UNIQUE INDEX "house_models_slug_key" ON "house_models" ("slug") WHERE "deleted_at" IS NULL;
+CREATE UNIQUE INDEX "promotions_slug_key" ON "promotions" ("slug") WHERE "deleted_at" IS NULL;
+CREATE UNIQUE INDEX "activities_slug_key" ON "activities" ("slug") WHERE "deleted_at" IS NULL;
+
+-- Old slugs of renamed rows, so their links still resolve
+CREATE TABLE "slug_redirects" (
+    "id" SERIAL PRIMARY KEY,
+    "table_name" VARCHAR NOT NULL,
+    "slug" VARCHAR NOT NULL,
+    "entity_id" INTEGER NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE ("table_name", "slug")
+);
+
+-- Records the old slug on a rename. A live slug always wins over an old one and
+-- the redirects go when their row is purged.
+CREATE OR REPLACE FUNCTION keep_slug_redirect()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM "slug_redirects" WHERE "table_name" = TG_TABLE_NAME AND "entity_id" = OLD."id";
+        RETURN OLD;
+    END IF;
+
+    DELETE FROM "slug_redirects" WHERE "table_name" = TG_TABLE_NAME AND "slug" = NEW."slug";
+    IF TG_OP = 'UPDATE' AND OLD."slug" <> NEW."slug" THEN
+        INSERT INTO "slug_redirects" ("table_name", "slug", "entity_id")
+        VALUES (TG_TABLE_NAME, OLD."slug", NEW."id")
+        ON CONFLICT ("table_name", "slug") DO UPDATE SET
+            "entity_id" = EXCLUDED."entity_id",
+            "created_at" = now();
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- A row restored from the trash while its slug was taken gets its id appended
+CREATE OR REPLACE FUNCTION free_restored_slug()
+RETURNS TRIGGER AS $$
+DECLARE
+    taken BOOLEAN;
+BEGIN
+    IF OLD."deleted_at" IS NOT NULL AND NEW."deleted_at" IS NULL THEN
+        EXECUTE format('SELECT EXISTS (SELECT 1 FROM %I WHERE "slug" = $1 AND "id" <> $2 AND "deleted_at" IS NULL)', TG_TABLE_NAME)
+        INTO taken
+        USING NEW."slug", NEW."id";
+        IF taken THEN
+            NEW."slug" := NEW."slug" || '-' || NEW."id";
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER keep_slug_redirect_projects_table AFTER
+INSERT OR UPDATE OF "slug" OR DELETE ON "projects" FOR EACH ROW
+EXECUTE PROCEDURE keep_slug_redirect ();
+
+CREATE TRIGGER free_restored_slug_projects_table BEFORE
+UPDATE OF "deleted_at" ON "projects" FOR EACH ROW
+EXECUTE PROCEDURE free_restored_slug ();
+
+CREATE TRIGGER keep_slug_redirect_house_models_table AFTER
+INSERT OR UPDATE OF "slug" OR DELETE ON "house_models" FOR EACH ROW
+EXECUTE PROCEDURE keep_slug_redirect ();
+
+CREATE TRIGGER free_restored_slug_house_models_table BEFORE
+UPDATE OF "deleted_at" ON "house_models" FOR EACH ROW
+EXECUTE PROCEDURE free_restored_slug ();
+
+CREATE TRIGGER keep_slug_redirect_promotions_table AFTER
+INSERT OR UPDATE OF "slug" OR DELETE ON "promotions" FOR EACH ROW
+EXECUTE PROCEDURE keep_slug_redirect ();
+
+CREATE TRIGGER free_restored_slug_promotions_table BEFORE
+UPDATE OF "deleted_at" ON "promotions" FOR EACH ROW
+EXECUTE PROCEDURE free_restored_slug ();
+
+CREATE TRIGGER keep_slug_redirect_activities_table AFTER
+INSERT OR UPDATE OF "slug" OR DELETE ON "activities" FOR EACH ROW
+EXECUTE PROCEDURE keep_slug_redirect ();
+
+CREATE TRIGGER free_restored_slug_activities_table BEFORE
+UPDATE OF "deleted_at" ON "activities" FOR EACH ROW
+EXECUTE PROCEDURE free_restored_slug ();
+
+COMMIT;
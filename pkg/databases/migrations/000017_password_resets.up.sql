@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Forgot password links, only the sha256 of the token is kept
+CREATE TABLE "password_resets" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL,
+    "token_hash" VARCHAR NOT NULL UNIQUE,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "used_at" TIMESTAMPTZ,
+    "ip" VARCHAR,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "password_resets"
+ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "password_resets_user_id_idx" ON "password_resets" ("user_id");
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Each oauth row is a login session
+ALTER TABLE "oauth"
+ADD COLUMN "device" VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN "ip" VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN "last_used_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+ADD COLUMN "expires_at" TIMESTAMPTZ;
+
+-- Refresh tokens so far lived 10 minutes from sign in and kept that expiry when refreshed
+UPDATE "oauth" SET
+    "last_used_at" = "updated_at",
+    "expires_at" = "created_at" + INTERVAL '10 minutes';
+
+ALTER TABLE "oauth" ALTER COLUMN "expires_at" SET NOT NULL;
+
+CREATE INDEX "oauth_user_id_idx" ON "oauth" ("user_id");
+CREATE INDEX "oauth_expires_at_idx" ON "oauth" ("expires_at");
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Refresh tokens rotated out of a session, kept until they expire to catch replays
+CREATE TABLE "used_refresh_tokens" (
+    "token_hash" VARCHAR PRIMARY KEY,
+    "oauth_id" uuid NOT NULL,
+    "user_id" INTEGER NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "used_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "used_refresh_tokens_expires_at_idx" ON "used_refresh_tokens" ("expires_at");
+
+COMMIT;
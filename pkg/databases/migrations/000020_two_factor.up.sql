@@ -0,0 +1,28 @@
+BEGIN;
+
+-- totp_secret is set on enrollment, totp_enabled_at once the first code is confirmed.
+-- totp_last_step is the last accepted time step so a code cannot be used twice.
+ALTER TABLE "users"
+ADD COLUMN "totp_secret" VARCHAR,
+ADD COLUMN "totp_enabled_at" TIMESTAMPTZ,
+ADD COLUMN "totp_last_step" BIGINT NOT NULL DEFAULT 0;
+
+-- Single use codes for a lost authenticator, only the sha256 is kept
+CREATE TABLE "user_recovery_codes" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL,
+    "code_hash" VARCHAR NOT NULL,
+    "used_at" TIMESTAMPTZ,
+    "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "user_recovery_codes"
+ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "user_recovery_codes_user_id_idx" ON "user_recovery_codes" ("user_id");
+
+-- Users holding a role with require_two_factor must sign in with 2FA
+ALTER TABLE "roles"
+ADD COLUMN "require_two_factor" BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Failed sign ins counted per email and per ip. A row is locked until locked_until,
+-- and its failures start over once none happened for the lock duration.
+CREATE TABLE "login_throttles" (
+    "kind" VARCHAR NOT NULL CHECK ("kind" IN ('email', 'ip')),
+    "key" VARCHAR NOT NULL,
+    "failures" INTEGER NOT NULL DEFAULT 0,
+    "locked_until" TIMESTAMPTZ,
+    "last_failed_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("kind", "key")
+);
+
+CREATE INDEX "login_throttles_last_failed_at_idx" ON "login_throttles" ("last_failed_at");
+
+COMMIT;
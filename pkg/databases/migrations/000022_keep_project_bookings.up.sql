@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Paid deposits and visit bookings are records of what happened with a
+-- customer, purging a trashed project must not take them along.
+ALTER TABLE "unit_reservations" DROP CONSTRAINT IF EXISTS "unit_reservations_project_id_fkey";
+ALTER TABLE "unit_reservations"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE RESTRICT;
+ALTER TABLE "site_visits" DROP CONSTRAINT IF EXISTS "site_visits_project_id_fkey";
+ALTER TABLE "site_visits"
+ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE RESTRICT;
+
+COMMIT;
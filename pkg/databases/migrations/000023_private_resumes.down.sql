@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE "job_applications" RENAME COLUMN "resume_path" TO "resume_url";
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Résumés are kept under the private storage prefix and only downloaded
+-- through the admin api, so the row holds a storage path instead of a url.
+ALTER TABLE "job_applications" RENAME COLUMN "resume_url" TO "resume_path";
+UPDATE "job_applications" SET
+    "resume_path" = substring("resume_path" from 'assets/images/.*$')
+WHERE "resume_path" ~ 'assets/images/';
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DELETE FROM "login_throttles" WHERE "kind" = 'two_factor';
+ALTER TABLE "login_throttles" DROP CONSTRAINT IF EXISTS "login_throttles_kind_check";
+ALTER TABLE "login_throttles"
+ADD CONSTRAINT "login_throttles_kind_check" CHECK ("kind" IN ('email', 'ip'));
+
+COMMIT;
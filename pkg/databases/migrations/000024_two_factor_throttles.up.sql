@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Bad 2FA codes are counted per user next to the sign in throttles.
+ALTER TABLE "login_throttles" DROP CONSTRAINT IF EXISTS "login_throttles_kind_check";
+ALTER TABLE "login_throttles"
+ADD CONSTRAINT "login_throttles_kind_check" CHECK ("kind" IN ('email', 'ip', 'two_factor'));
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE "job_applications" DROP CONSTRAINT IF EXISTS "job_applications_career_id_fkey";
+ALTER TABLE "job_applications"
+ADD FOREIGN KEY ("career_id") REFERENCES "careers" ("id") ON DELETE CASCADE;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Applicants and their resumes outlive the posting they applied to, purging
+-- a trashed career must not take them along.
+ALTER TABLE "job_applications" DROP CONSTRAINT IF EXISTS "job_applications_career_id_fkey";
+ALTER TABLE "job_applications"
+ADD FOREIGN KEY ("career_id") REFERENCES "careers" ("id") ON DELETE RESTRICT;
+
+COMMIT;
@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"strings"
+
+	"github.com/yporn/sirarom-backend/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // plain text
+}
+
+type IMailer interface {
+	Send(msg *Message) error
+}
+
+// NewMailer sends through SMTP. Without a MAIL_HOST the mails are only logged,
+// which is allowed when env is development or test, as they carry live links
+// such as password reset tokens.
+func NewMailer(cfg config.IMailConfig, env string) (IMailer, error) {
+	if cfg.Host() != "" {
+		return SmtpMailer(cfg), nil
+	}
+	if env != "development" && env != "test" {
+		return nil, fmt.Errorf("MAIL_HOST is required when APP_ENV is %q, mails are only logged in development or test", env)
+	}
+	return LogMailer(), nil
+}
+
+type smtpMailer struct {
+	cfg config.IMailConfig
+}
+
+func SmtpMailer(cfg config.IMailConfig) IMailer {
+	return &smtpMailer{
+		cfg: cfg,
+	}
+}
+
+func (m *smtpMailer) Send(msg *Message) error {
+	var auth smtp.Auth
+	if m.cfg.Username() != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username(), m.cfg.Password(), m.cfg.Host())
+	}
+
+	headers := []string{
+		"From: " + m.cfg.From(),
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(msg.Body, "\n", "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", m.cfg.Host(), m.cfg.Port())
+	if err := smtp.SendMail(addr, auth, m.cfg.From(), []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("send mail failed: %v", err)
+	}
+	return nil
+}
+
+type logMailer struct{}
+
+// LogMailer writes the mails to the log, for development. Never use it where
+// the log is kept, the body holds the links of the mail.
+func LogMailer() IMailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(msg *Message) error {
+	log.Printf("mail to %s: %s\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
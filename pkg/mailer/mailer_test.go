@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"testing"
+
+	"github.com/yporn/sirarom-backend/config"
+)
+
+type fakeMailConfig struct {
+	config.IMailConfig
+	host string
+}
+
+func (c *fakeMailConfig) Host() string { return c.host }
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		env     string
+		wantLog bool
+		wantErr bool
+	}{
+		{name: "smtp", host: "smtp.example.com", env: "production"},
+		{name: "log in development", env: "development", wantLog: true},
+		{name: "log in test", env: "test", wantLog: true},
+		{name: "no host in production", env: "production", wantErr: true},
+		{name: "no host in staging", env: "staging", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMailer(&fakeMailConfig{host: tt.host}, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewMailer = %T, want an error", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewMailer failed: %v", err)
+			}
+			if _, isLog := m.(*logMailer); isLog != tt.wantLog {
+				t.Errorf("NewMailer = %T, want log mailer %v", m, tt.wantLog)
+			}
+		})
+	}
+}
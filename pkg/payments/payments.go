@@ -0,0 +1,116 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	StatusPaid   = "paid"
+	StatusFailed = "failed"
+
+	// SignatureHeader carries the hex HMAC-SHA256 of a callback body.
+	SignatureHeader = "X-Payment-Signature"
+)
+
+type ChargeReq struct {
+	Ref         string
+	Amount      float64
+	Description string
+	Email       string
+}
+
+type Charge struct {
+	Ref         string `json:"ref"`
+	CheckoutUrl string `json:"checkout_url"`
+}
+
+// Callback is the outcome of a charge reported back by the gateway.
+type Callback struct {
+	Ref    string `json:"ref"`
+	Status string `json:"status"`
+}
+
+type IPaymentGateway interface {
+	CreateCharge(req *ChargeReq) (*Charge, error)
+	ParseCallback(body []byte, signature string) (*Callback, error)
+}
+
+// NewGateway returns the gateway named by APP_PAYMENT_GATEWAY, or nil when none
+// is set and reservations cannot be paid online. The fake gateway takes no money
+// so it is only allowed in development and test.
+func NewGateway(name, secret, env string) (IPaymentGateway, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("APP_PAYMENT_SECRET is required for the %s payment gateway", name)
+	}
+
+	switch name {
+	case "fake":
+		if env != "development" && env != "test" {
+			return nil, fmt.Errorf("the fake payment gateway is only allowed when APP_ENV is development or test, not %q", env)
+		}
+		return FakeGateway(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment gateway: %s", name)
+	}
+}
+
+type fakeGateway struct {
+	secret string
+}
+
+// FakeGateway takes no money. Charges are accepted as they are and settle when
+// a callback signed with secret is posted, e.g.
+//
+//	{"ref": "<ref>", "status": "paid"}
+//
+// with the X-Payment-Signature header set to Sign(secret, body).
+func FakeGateway(secret string) IPaymentGateway {
+	return &fakeGateway{
+		secret: secret,
+	}
+}
+
+func (g *fakeGateway) CreateCharge(req *ChargeReq) (*Charge, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("charge amount must be more than 0")
+	}
+	ref := "fake_" + req.Ref
+	return &Charge{
+		Ref:         ref,
+		CheckoutUrl: "fake://checkout/" + ref,
+	}, nil
+}
+
+func (g *fakeGateway) ParseCallback(body []byte, signature string) (*Callback, error) {
+	if !hmac.Equal([]byte(Sign(g.secret, body)), []byte(signature)) {
+		return nil, fmt.Errorf("payment signature is invalid")
+	}
+
+	callback := new(Callback)
+	if err := json.Unmarshal(body, callback); err != nil {
+		return nil, fmt.Errorf("unmarshal payment callback failed: %v", err)
+	}
+	switch callback.Status {
+	case StatusPaid, StatusFailed:
+	default:
+		return nil, fmt.Errorf("payment status is invalid: %s", callback.Status)
+	}
+	if callback.Ref == "" {
+		return nil, fmt.Errorf("payment ref is required")
+	}
+	return callback, nil
+}
+
+// Sign returns the callback signature of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
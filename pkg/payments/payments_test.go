@@ -0,0 +1,92 @@
+package payments
+
+import "testing"
+
+func TestNewGateway(t *testing.T) {
+	tests := []struct {
+		name     string
+		gateway  string
+		secret   string
+		env      string
+		wantNil  bool
+		wantFake bool
+		wantErr  bool
+	}{
+		{name: "none", env: "production", wantNil: true},
+		{name: "fake in development", gateway: "fake", secret: "s", env: "development", wantFake: true},
+		{name: "fake in test", gateway: "fake", secret: "s", env: "test", wantFake: true},
+		{name: "fake in production", gateway: "fake", secret: "s", env: "production", wantErr: true},
+		{name: "no secret", gateway: "fake", env: "development", wantErr: true},
+		{name: "unknown gateway", gateway: "paypal", secret: "s", env: "development", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGateway(tt.gateway, tt.secret, tt.env)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("NewGateway error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantNil && g != nil {
+				t.Errorf("NewGateway = %T, want nil", g)
+			}
+			if _, isFake := g.(*fakeGateway); isFake != tt.wantFake {
+				t.Errorf("NewGateway = %T, want fake %v", g, tt.wantFake)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayParseCallback(t *testing.T) {
+	g := FakeGateway("secret")
+	paid := []byte(`{"ref":"fake_reservation-1","status":"paid"}`)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		signature  string
+		wantStatus string
+		wantErr    bool
+	}{
+		{name: "paid", body: paid, signature: Sign("secret", paid), wantStatus: StatusPaid},
+		{name: "failed", body: []byte(`{"ref":"r","status":"failed"}`), signature: Sign("secret", []byte(`{"ref":"r","status":"failed"}`)), wantStatus: StatusFailed},
+		{name: "no signature", body: paid, wantErr: true},
+		{name: "signed with another secret", body: paid, signature: Sign("other", paid), wantErr: true},
+		{name: "body changed after signing", body: []byte(`{"ref":"fake_reservation-2","status":"paid"}`), signature: Sign("secret", paid), wantErr: true},
+		{name: "unknown status", body: []byte(`{"ref":"r","status":"refunded"}`), signature: Sign("secret", []byte(`{"ref":"r","status":"refunded"}`)), wantErr: true},
+		{name: "no ref", body: []byte(`{"status":"paid"}`), signature: Sign("secret", []byte(`{"status":"paid"}`)), wantErr: true},
+		{name: "not json", body: []byte(`paid`), signature: Sign("secret", []byte(`paid`)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, err := g.ParseCallback(tt.body, tt.signature)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCallback = %+v, want an error", callback)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCallback failed: %v", err)
+			}
+			if callback.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", callback.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayCreateCharge(t *testing.T) {
+	g := FakeGateway("secret")
+
+	charge, err := g.CreateCharge(&ChargeReq{Ref: "reservation-1", Amount: 5000})
+	if err != nil {
+		t.Fatalf("CreateCharge failed: %v", err)
+	}
+	if charge.Ref != "fake_reservation-1" || charge.CheckoutUrl == "" {
+		t.Errorf("CreateCharge = %+v", charge)
+	}
+	if _, err := g.CreateCharge(&ChargeReq{Ref: "reservation-2"}); err == nil {
+		t.Errorf("CreateCharge without an amount succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// The ascii secret "12345678901234567890" of RFC 4226 and RFC 6238.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeHotpVectors(t *testing.T) {
+	// RFC 4226 appendix D, the counter standing in for the time step
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		got, err := Code(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", counter, err)
+		}
+		if got != code {
+			t.Errorf("Code(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestCodeTotpVectors(t *testing.T) {
+	// RFC 6238 appendix B for SHA1, cut to the last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d failed: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code with an invalid secret succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name     string
+		at       int64 // step the code is made for
+		code     string
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "current step", at: step, wantStep: step, wantOk: true},
+		{name: "previous step", at: step - 1, wantStep: step - 1, wantOk: true},
+		{name: "next step", at: step + 1, wantStep: step + 1, wantOk: true},
+		{name: "outside the skew", at: step - 2},
+		{name: "spaces are ignored", code: "050 471", wantStep: step, wantOk: true},
+		{name: "short code", code: "05047"},
+		{name: "wrong code", code: "000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := tt.code
+			if code == "" {
+				var err error
+				if code, err = Code(rfcSecret, tt.at); err != nil {
+					t.Fatalf("Code failed: %v", err)
+				}
+			}
+
+			gotStep, ok := Validate(rfcSecret, code, now)
+			if ok != tt.wantOk || gotStep != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", code, gotStep, ok, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret %q has %d characters, want 32", secret, len(secret))
+	}
+	if _, err := Code(secret, 0); err != nil {
+		t.Errorf("Code of a generated secret failed: %v", err)
+	}
+}
+
+func TestUri(t *testing.T) {
+	uri, err := url.Parse(Uri("Sirarom CMS", "admin@example.com", rfcSecret))
+	if err != nil {
+		t.Fatalf("Uri is not a url: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("Uri = %s, want otpauth://totp/", uri)
+	}
+	if uri.Path != "/Sirarom CMS:admin@example.com" {
+		t.Errorf("Uri label = %q", uri.Path)
+	}
+	q := uri.Query()
+	if q.Get("secret") != rfcSecret || q.Get("issuer") != "Sirarom CMS" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("Uri query = %v", q)
+	}
+}
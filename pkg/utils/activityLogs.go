@@ -26,6 +26,12 @@ func LogActivity(db *sql.DB, userID, action, details string) error {
     return nil
 }
 
+// LogSystemActivity records an entry that was not triggered by a user, e.g. the publishing scheduler.
+func LogSystemActivity(db *sql.DB, action, details string) error {
+    _, err := db.Exec("INSERT INTO activity_logs (user_id, action, details) VALUES (NULL, $1, $2)", action, details)
+    return err
+}
+
 func GetUserIDFromContext(c *fiber.Ctx) int {
     userIDStr := c.Locals("userId")
     if userIDStr == nil {
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrUnknownOrderId is returned by Reorder when an id does not belong to the list being ordered.
+var ErrUnknownOrderId = errors.New("unknown id in order")
+
+// Reorder rewrites the "index" column of table in one transaction. The given ids
+// take 1..n in that order, rows left out keep their relative order after them.
+// When scope is set only rows whose scope column equals scopeValue are ordered,
+// e.g. the house models of one project.
+func Reorder(db *sqlx.DB, table string, ids []int, scope string, scopeValue any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	where := `"deleted_at" IS NULL`
+	args := []any{}
+	if scope != "" {
+		args = append(args, scopeValue)
+		where += fmt.Sprintf(` AND "%s" = $1`, scope)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	existing := make([]int, 0)
+	if err := tx.SelectContext(
+		ctx,
+		&existing,
+		fmt.Sprintf(`SELECT "id" FROM "%s" WHERE %s FOR UPDATE;`, table, where),
+		args...,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("get %s failed: %v", table, err)
+	}
+
+	if err := checkOrderIds(existing, ids); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	args = append(args, ids)
+	idsArg := len(args)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		fmt.Sprintf(`
+		UPDATE "%s" "t" SET
+			"index" = "o"."ord"
+		FROM unnest($1::int[]) WITH ORDINALITY AS "o"("id", "ord")
+		WHERE "t"."id" = "o"."id";`, table),
+		ids,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update %s order failed: %v", table, err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		fmt.Sprintf(`
+		UPDATE "%s" "t" SET
+			"index" = %d + "r"."rn"
+		FROM (
+			SELECT
+				"id",
+				row_number() OVER (ORDER BY "index", "id") AS "rn"
+			FROM "%s"
+			WHERE %s
+			AND "id" <> ALL($%d::int[])
+		) AS "r"
+		WHERE "t"."id" = "r"."id";`, table, len(ids), table, where, idsArg),
+		args...,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update %s order failed: %v", table, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkOrderIds returns ErrUnknownOrderId for the first of ids not in existing.
+func checkOrderIds(existing, ids []int) error {
+	known := make(map[int]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return fmt.Errorf("%w: %d", ErrUnknownOrderId, id)
+		}
+	}
+	return nil
+}
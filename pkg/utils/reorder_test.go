@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckOrderIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []int
+		ids      []int
+		wantErr  bool
+	}{
+		{name: "all ids", existing: []int{1, 2, 3}, ids: []int{3, 1, 2}},
+		{name: "some ids", existing: []int{1, 2, 3}, ids: []int{2}},
+		{name: "unknown id", existing: []int{1, 2, 3}, ids: []int{1, 4}, wantErr: true},
+		{name: "nothing to order", existing: []int{}, ids: []int{1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOrderIds(tt.existing, tt.ids)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkOrderIds(%v, %v) = %v, want error %v", tt.existing, tt.ids, err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrUnknownOrderId) {
+				t.Errorf("checkOrderIds(%v, %v) = %v, want ErrUnknownOrderId", tt.existing, tt.ids, err)
+			}
+		})
+	}
+}
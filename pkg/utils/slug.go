@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrSlugTaken is returned by UniqueSlug when a slug set by hand is used by another row.
+	ErrSlugTaken = errors.New("slug is already in use")
+	// ErrSlugNotFound is returned by FindSlug when neither a row nor a redirect has the slug.
+	ErrSlugNotFound = errors.New("slug not found")
+)
+
+// Initial and final sounds of the Thai consonants, romanized after RTGS.
+var thaiConsonants = map[rune][2]string{
+	'ก': {"k", "k"}, 'ข': {"kh", "k"}, 'ฃ': {"kh", "k"}, 'ค': {"kh", "k"}, 'ฅ': {"kh", "k"}, 'ฆ': {"kh", "k"},
+	'ง': {"ng", "ng"}, 'จ': {"ch", "t"}, 'ฉ': {"ch", "t"}, 'ช': {"ch", "t"}, 'ซ': {"s", "t"}, 'ฌ': {"ch", "t"},
+	'ญ': {"y", "n"}, 'ฎ': {"d", "t"}, 'ฏ': {"t", "t"}, 'ฐ': {"th", "t"}, 'ฑ': {"th", "t"}, 'ฒ': {"th", "t"},
+	'ณ': {"n", "n"}, 'ด': {"d", "t"}, 'ต': {"t", "t"}, 'ถ': {"th", "t"}, 'ท': {"th", "t"}, 'ธ': {"th", "t"},
+	'น': {"n", "n"}, 'บ': {"b", "p"}, 'ป': {"p", "p"}, 'ผ': {"ph", "p"}, 'ฝ': {"f", "p"}, 'พ': {"ph", "p"},
+	'ฟ': {"f", "p"}, 'ภ': {"ph", "p"}, 'ม': {"m", "m"}, 'ย': {"y", "i"}, 'ร': {"r", "n"}, 'ล': {"l", "n"},
+	'ว': {"w", "o"}, 'ศ': {"s", "t"}, 'ษ': {"s", "t"}, 'ส': {"s", "t"}, 'ห': {"h", ""}, 'ฬ': {"l", "n"},
+	'อ': {"", ""}, 'ฮ': {"h", ""},
+}
+
+// Thai vowels written after, above or below their consonant.
+var thaiVowels = map[rune]string{
+	'ะ': "a", 'ั': "a", 'า': "a", 'ำ': "am", 'ิ': "i", 'ี': "i", 'ึ': "ue", 'ื': "ue", 'ุ': "u", 'ู': "u", 'ๅ': "",
+}
+
+// Thai vowels written before their consonant.
+var thaiLeadingVowels = map[rune]string{
+	'เ': "e", 'แ': "ae", 'โ': "o", 'ใ': "ai", 'ไ': "ai",
+}
+
+// Sonorants that a silent ห leads, as in หมู or หนอง.
+func isThaiSonorant(r rune) bool {
+	return strings.ContainsRune("งญนมยรลว", r)
+}
+
+// isThaiCluster reports whether first and second start a syllable together, as
+// in กร or ปล, rather than first taking an implied a of its own.
+func isThaiCluster(first, second rune) bool {
+	return strings.ContainsRune("รลว", second) && strings.ContainsRune("กขคปพผตบฟ", first)
+}
+
+// Marks that are not pronounced on their own.
+func isThaiMark(r rune) bool {
+	return r == '่' || r == '้' || r == '๊' || r == '๋' || r == '็' || r == '์' || r == 'ๆ' || r == 'ฯ'
+}
+
+// Slugify turns a name into a url slug of lowercase ascii letters, digits and
+// dashes. Thai is romanized by sound, which is close to RTGS for common words
+// but not exact, so a slug may still be edited by hand.
+func Slugify(s string) string {
+	runes := []rune(strings.ToLower(s))
+
+	// next returns the index of the next rune that is not a tone mark
+	next := func(i int) int {
+		for i < len(runes) && isThaiMark(runes[i]) && runes[i] != '์' {
+			i++
+		}
+		return i
+	}
+	at := func(i int) rune {
+		if i < len(runes) {
+			return runes[i]
+		}
+		return 0
+	}
+	isVowel := func(r rune) bool {
+		_, ok := thaiVowels[r]
+		return ok
+	}
+	isConsonant := func(r rune) bool {
+		_, ok := thaiConsonants[r]
+		return ok
+	}
+	isThai := func(r rune) bool {
+		return r >= 'ก' && r <= '๛'
+	}
+	// endsBare reports whether the consonant at i is the last sound of its word
+	endsBare := func(i int) bool {
+		j := next(i + 1)
+		for isConsonant(at(j)) && at(next(j+1)) == '์' {
+			j = next(j+1) + 1
+		}
+		return !isThai(at(j))
+	}
+
+	var b strings.Builder
+	const (
+		open   = iota // nothing of the syllable written yet
+		bare          // an initial consonant without its vowel
+		voiced        // a vowel written, a consonant now closes the syllable
+	)
+	state := open
+	leading := ""
+	onset := rune(0) // the consonant of a bare syllable
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case isConsonant(r):
+			// A consonant under the thanthakhat is silent
+			if at(next(i+1)) == '์' {
+				continue
+			}
+			n := next(i + 1)
+
+			// A leading ห is silent and only sets the tone of the sonorant after it,
+			// unless that is a ว standing for the vowel as in ห้วย
+			if r == 'ห' && state == open && isThaiSonorant(at(n)) && (leading != "" || isThai(at(next(n+1)))) &&
+				!(at(n) == 'ว' && isConsonant(at(next(n+1)))) {
+				continue
+			}
+
+			if leading != "" {
+				b.WriteString(thaiConsonants[r][0])
+				// A cluster such as คร or กล takes the vowel after its second consonant
+				if c, after := at(n), at(next(n+1)); (c == 'ร' || c == 'ล' || c == 'ว') && (isVowel(after) || isConsonant(after)) && after != 'อ' {
+					b.WriteString(thaiConsonants[c][0])
+					n = next(n + 1)
+				}
+				switch {
+				case leading == "e" && at(n) == 'ื' && at(next(n+1)) == 'อ':
+					b.WriteString("uea")
+					i = next(n + 1)
+				case leading == "e" && at(n) == 'ี' && at(next(n+1)) == 'ย':
+					b.WriteString("ia")
+					i = next(n + 1)
+				case leading == "e" && at(n) == 'า' && at(next(n+1)) == 'ะ':
+					b.WriteString("o")
+					i = next(n + 1)
+				case leading == "e" && at(n) == 'า':
+					b.WriteString("ao")
+					i = n
+				case at(n) == 'ะ':
+					b.WriteString(leading)
+					i = n
+				default:
+					b.WriteString(leading)
+					i = n - 1
+				}
+				leading = ""
+				state = voiced
+				continue
+			}
+
+			switch {
+			case state == voiced && !isVowel(at(n)) && !(isConsonant(at(n)) && endsBare(n)):
+				final := thaiConsonants[r][1]
+				if r == 'ย' && strings.HasSuffix(b.String(), "i") {
+					final = ""
+				}
+				b.WriteString(final)
+				state = open
+			case state == bare && r == 'ว' && isConsonant(at(n)):
+				b.WriteString("ua")
+				state = voiced
+			case state == bare && r == 'อ' && isConsonant(at(n)):
+				b.WriteString("o")
+				state = voiced
+			case state == bare && !isVowel(at(n)):
+				b.WriteString("o" + thaiConsonants[r][1])
+				state = open
+			default:
+				// A bare consonant before a voiced one, as in ลดา, takes an implied a
+				if state == bare && !isThaiCluster(onset, r) {
+					b.WriteString("a")
+				}
+				b.WriteString(thaiConsonants[r][0])
+				state = bare
+				onset = r
+			}
+		case isVowel(r):
+			if r == 'ั' && at(next(i+1)) == 'ว' {
+				b.WriteString("ua")
+				i = next(i + 1)
+			} else {
+				b.WriteString(thaiVowels[r])
+			}
+			state = voiced
+		case thaiLeadingVowels[r] != "":
+			leading = thaiLeadingVowels[r]
+			state = open
+		case r == 'ฤ':
+			b.WriteString("rue")
+			state = voiced
+		case r == 'ฦ':
+			b.WriteString("lue")
+			state = voiced
+		case isThaiMark(r):
+		case r >= '๐' && r <= '๙':
+			b.WriteRune('0' + r - '๐')
+			state = open
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			state = open
+		default:
+			b.WriteByte('-')
+			state = open
+		}
+	}
+
+	// Collapse the separators
+	parts := strings.FieldsFunc(b.String(), func(r rune) bool { return r == '-' })
+	return strings.Join(parts, "-")
+}
+
+// UniqueSlug returns the slug to store for the row id of table, 0 for a new row.
+// A slug given by hand is normalized and must not be used by another row, else
+// one is made from name and numbered until it is free of other rows and of the
+// old slugs still redirecting to them.
+func UniqueSlug(db *sqlx.DB, table string, id int, name, slug string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	if slug != "" {
+		slug = Slugify(slug)
+		if slug == "" {
+			return "", fmt.Errorf("slug must contain a letter or digit")
+		}
+
+		var taken bool
+		if err := db.GetContext(
+			ctx,
+			&taken,
+			fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM "%s" WHERE "slug" = $1 AND "id" <> $2 AND "deleted_at" IS NULL);`, table),
+			slug,
+			id,
+		); err != nil {
+			return "", fmt.Errorf("get %s slug failed: %v", table, err)
+		}
+		if taken {
+			return "", fmt.Errorf("%w: %s", ErrSlugTaken, slug)
+		}
+		return slug, nil
+	}
+
+	base := Slugify(name)
+	if base == "" {
+		base = strings.ReplaceAll(table, "_", "-")
+	}
+
+	used := make([]string, 0)
+	if err := db.SelectContext(
+		ctx,
+		&used,
+		fmt.Sprintf(`
+		SELECT "slug" FROM "%s"
+		WHERE ("slug" = $1 OR "slug" LIKE $1 || '-%%')
+		AND "id" <> $2
+		AND "deleted_at" IS NULL
+		UNION
+		SELECT "slug" FROM "slug_redirects"
+		WHERE "table_name" = $3
+		AND ("slug" = $1 OR "slug" LIKE $1 || '-%%')
+		AND "entity_id" <> $2;`, table),
+		base,
+		id,
+		table,
+	); err != nil {
+		return "", fmt.Errorf("get %s slugs failed: %v", table, err)
+	}
+
+	taken := make(map[string]bool, len(used))
+	for _, s := range used {
+		taken[s] = true
+	}
+	slug = base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// FindSlug returns the id and current slug of the row of table with the slug,
+// or of the row an old slug redirects to.
+func FindSlug(db *sqlx.DB, table, slug string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	result := struct {
+		Id   int    `db:"id"`
+		Slug string `db:"slug"`
+	}{}
+
+	query := fmt.Sprintf(`
+	SELECT
+		"id",
+		"slug"
+	FROM "%s"
+	WHERE "slug" = $1
+	AND "deleted_at" IS NULL
+	UNION ALL
+	SELECT
+		"t"."id",
+		"t"."slug"
+	FROM "slug_redirects" "r"
+	JOIN "%s" "t" ON "t"."id" = "r"."entity_id"
+	WHERE "r"."table_name" = $2
+	AND "r"."slug" = $1
+	AND "t"."deleted_at" IS NULL
+	LIMIT 1;`, table, table)
+
+	if err := db.GetContext(ctx, &result, query, slug, table); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", fmt.Errorf("%w: %s", ErrSlugNotFound, slug)
+		}
+		return 0, "", fmt.Errorf("get %s slug failed: %v", table, err)
+	}
+	return result.Id, result.Slug, nil
+}
+
+// SlugPath swaps the slug at the end of a request path, e.g. to redirect an old
+// slug to the current one.
+func SlugPath(path, slug string) string {
+	return path[:strings.LastIndex(path, "/")+1] + slug
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "ascii", in: "Sirarom Village", want: "sirarom-village"},
+		{name: "separators collapse", in: "  Phase 2 -- Block A! ", want: "phase-2-block-a"},
+		{name: "empty", in: "", want: ""},
+		{name: "only punctuation", in: "!!!", want: ""},
+		{name: "thai digits", in: "เฟส ๒", want: "fet-2"},
+		{name: "mixed", in: "บ้าน Sirarom 2", want: "ban-sirarom-2"},
+		{name: "closed syllable", in: "คน", want: "khon"},
+		{name: "tone mark", in: "บ้าน", want: "ban"},
+		{name: "cluster", in: "กรุงเทพ", want: "krungthep"},
+		{name: "leading vowel", in: "เหลือง", want: "lueang"},
+		{name: "silent ห", in: "หมู่บ้าน", want: "muban"},
+		{name: "silent ห before อ", in: "หนองคาย", want: "nongkhai"},
+		{name: "silent ห after a leading vowel", in: "ใหม่", want: "mai"},
+		{name: "silent ห mid word", in: "สวนหลวง", want: "suanluang"},
+		{name: "voiced ห before the ว vowel", in: "ห้วยขวาง", want: "huaikhwang"},
+		{name: "lone ห syllable", in: "หน", want: "hon"},
+		{name: "implied a", in: "ลดาวัลย์", want: "ladawan"},
+		{name: "implied a before a vowel", in: "สบาย", want: "sabai"},
+		{name: "implied a in a long word", in: "ธนาคาร", want: "thanakhan"},
+		{name: "cluster without implied a", in: "ควาย", want: "khwai"},
+		{name: "final ล", in: "สีลม", want: "silom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slugify(tt.in); got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}